@@ -0,0 +1,235 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+
+	"go.mau.fi/util/jsontime"
+
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// ReqChangePassword is the JSON request for https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3accountpassword
+type ReqChangePassword struct {
+	Auth        any    `json:"auth,omitempty"`
+	NewPassword string `json:"new_password"`
+	// Whether other devices should be logged out. The server defaults to true.
+	LogoutDevices *bool `json:"logout_devices,omitempty"`
+}
+
+// ReqDeactivateAccount is the JSON request for https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3accountdeactivate
+type ReqDeactivateAccount struct {
+	Auth     any    `json:"auth,omitempty"`
+	Erase    bool   `json:"erase,omitempty"`
+	IDServer string `json:"id_server,omitempty"`
+}
+
+// RespDeactivateAccount is the JSON response for https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3accountdeactivate
+type RespDeactivateAccount struct {
+	// Either "success" or "no-support".
+	IDServerUnbindResult string `json:"id_server_unbind_result"`
+}
+
+// ThreePID is a third-party identifier associated with an account.
+type ThreePID struct {
+	Medium      string             `json:"medium"`
+	Address     string             `json:"address"`
+	AddedAt     jsontime.UnixMilli `json:"added_at"`
+	ValidatedAt jsontime.UnixMilli `json:"validated_at"`
+}
+
+// RespThreePIDs is the JSON response for https://spec.matrix.org/v1.12/client-server-api/#get_matrixclientv3account3pid
+type RespThreePIDs struct {
+	ThreePIDs []ThreePID `json:"threepids"`
+}
+
+// ReqRequestEmailToken is the JSON request for https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3account3pidemailrequesttoken
+type ReqRequestEmailToken struct {
+	ClientSecret  string `json:"client_secret"`
+	Email         string `json:"email"`
+	SendAttempt   int    `json:"send_attempt"`
+	NextLink      string `json:"next_link,omitempty"`
+	IDServer      string `json:"id_server,omitempty"`
+	IDAccessToken string `json:"id_access_token,omitempty"`
+}
+
+// RespRequestToken is the JSON response for https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3account3pidemailrequesttoken
+type RespRequestToken struct {
+	SID       string `json:"sid"`
+	SubmitURL string `json:"submit_url,omitempty"`
+}
+
+// ReqAddThreePID is the JSON request for https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3account3pidadd
+type ReqAddThreePID struct {
+	Auth         any    `json:"auth,omitempty"`
+	ClientSecret string `json:"client_secret"`
+	SID          string `json:"sid"`
+}
+
+// ReqBindThreePID is the JSON request for https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3account3pidbind
+type ReqBindThreePID = ThreePIDCredentials
+
+// ReqRemoveThreePID is the JSON request for https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3account3pidunbind
+// and https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3account3piddelete
+type ReqRemoveThreePID struct {
+	Medium   string `json:"medium"`
+	Address  string `json:"address"`
+	IDServer string `json:"id_server,omitempty"`
+}
+
+// RespRemoveThreePID is the JSON response for https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3account3pidunbind
+// and https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3account3piddelete
+type RespRemoveThreePID struct {
+	// Either "success" or "no-support".
+	IDServerUnbindResult string `json:"id_server_unbind_result"`
+}
+
+// ReqLoginToken is the JSON request for https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv1loginget_token
+type ReqLoginToken struct {
+	Auth any `json:"auth,omitempty"`
+}
+
+// RespLoginToken is the JSON response for https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv1loginget_token
+type RespLoginToken struct {
+	LoginToken  string `json:"login_token"`
+	ExpiresInMS int64  `json:"expires_in_ms"`
+}
+
+// ChangePassword changes the password of the current user. Other devices are logged out unless
+// LogoutDevices is explicitly set to false.
+//
+// See https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3accountpassword
+func (cli *Client) ChangePassword(ctx context.Context, req *ReqChangePassword, uia *UIAHelper) error {
+	urlPath := cli.BuildClientURL("v3", "account", "password")
+	return cli.makeUIARequest(ctx, uia, http.MethodPost, urlPath, req, func(auth any) { req.Auth = auth }, nil)
+}
+
+// DeactivateAccount permanently deactivates the current user's account. The access token is invalid afterwards.
+//
+// See https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3accountdeactivate
+func (cli *Client) DeactivateAccount(ctx context.Context, req *ReqDeactivateAccount, uia *UIAHelper) (resp *RespDeactivateAccount, err error) {
+	urlPath := cli.BuildClientURL("v3", "account", "deactivate")
+	err = cli.makeUIARequest(ctx, uia, http.MethodPost, urlPath, req, func(auth any) { req.Auth = auth }, &resp)
+	return
+}
+
+// GetThreePIDs returns the third-party identifiers associated with the current user's account.
+//
+// See https://spec.matrix.org/v1.12/client-server-api/#get_matrixclientv3account3pid
+func (cli *Client) GetThreePIDs(ctx context.Context) (resp *RespThreePIDs, err error) {
+	urlPath := cli.BuildClientURL("v3", "account", "3pid")
+	_, err = cli.MakeRequest(ctx, http.MethodGet, urlPath, nil, &resp)
+	return
+}
+
+// RequestEmailToken asks the homeserver to send a validation email for adding the address to the account.
+// The returned session ID and the client secret are then used with [Client.AddThreePID].
+//
+// See https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3account3pidemailrequesttoken
+func (cli *Client) RequestEmailToken(ctx context.Context, req *ReqRequestEmailToken) (resp *RespRequestToken, err error) {
+	urlPath := cli.BuildClientURL("v3", "account", "3pid", "email", "requestToken")
+	_, err = cli.MakeRequest(ctx, http.MethodPost, urlPath, req, &resp)
+	return
+}
+
+// AddThreePID adds a validated third-party identifier to the current user's account.
+//
+// See https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3account3pidadd
+func (cli *Client) AddThreePID(ctx context.Context, req *ReqAddThreePID, uia *UIAHelper) error {
+	urlPath := cli.BuildClientURL("v3", "account", "3pid", "add")
+	return cli.makeUIARequest(ctx, uia, http.MethodPost, urlPath, req, func(auth any) { req.Auth = auth }, nil)
+}
+
+// BindThreePID binds a validated third-party identifier to the current user on an identity server.
+//
+// See https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3account3pidbind
+func (cli *Client) BindThreePID(ctx context.Context, req *ReqBindThreePID) error {
+	urlPath := cli.BuildClientURL("v3", "account", "3pid", "bind")
+	_, err := cli.MakeRequest(ctx, http.MethodPost, urlPath, req, nil)
+	return err
+}
+
+// UnbindThreePID removes a third-party identifier binding from an identity server without removing it from the account.
+//
+// See https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3account3pidunbind
+func (cli *Client) UnbindThreePID(ctx context.Context, req *ReqRemoveThreePID) (resp *RespRemoveThreePID, err error) {
+	urlPath := cli.BuildClientURL("v3", "account", "3pid", "unbind")
+	_, err = cli.MakeRequest(ctx, http.MethodPost, urlPath, req, &resp)
+	return
+}
+
+// DeleteThreePID removes a third-party identifier from the current user's account.
+//
+// See https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3account3piddelete
+func (cli *Client) DeleteThreePID(ctx context.Context, req *ReqRemoveThreePID) (resp *RespRemoveThreePID, err error) {
+	urlPath := cli.BuildClientURL("v3", "account", "3pid", "delete")
+	_, err = cli.MakeRequest(ctx, http.MethodPost, urlPath, req, &resp)
+	return
+}
+
+// GetLoginToken creates a short-lived token that can be used to log in another device with m.login.token.
+//
+// See https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv1loginget_token
+func (cli *Client) GetLoginToken(ctx context.Context, uia *UIAHelper) (resp *RespLoginToken, err error) {
+	req := &ReqLoginToken{}
+	urlPath := cli.BuildClientURL("v1", "login", "get_token")
+	err = cli.makeUIARequest(ctx, uia, http.MethodPost, urlPath, req, func(auth any) { req.Auth = auth }, &resp)
+	return
+}
+
+// GetIgnoredUsers returns the users in the current user's m.ignored_user_list account data.
+func (cli *Client) GetIgnoredUsers(ctx context.Context) (map[id.UserID]event.IgnoredUser, error) {
+	var content event.IgnoredUserListEventContent
+	err := cli.GetAccountData(ctx, event.AccountDataIgnoredUserList.Type, &content)
+	if errors.Is(err, MNotFound) {
+		return map[id.UserID]event.IgnoredUser{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if content.IgnoredUsers == nil {
+		content.IgnoredUsers = map[id.UserID]event.IgnoredUser{}
+	}
+	return content.IgnoredUsers, nil
+}
+
+// IgnoreUsers adds the given users to the current user's ignore list.
+func (cli *Client) IgnoreUsers(ctx context.Context, userIDs ...id.UserID) error {
+	return cli.updateIgnoredUsers(ctx, func(ignored map[id.UserID]event.IgnoredUser) {
+		for _, userID := range userIDs {
+			ignored[userID] = event.IgnoredUser{}
+		}
+	})
+}
+
+// UnignoreUsers removes the given users from the current user's ignore list.
+func (cli *Client) UnignoreUsers(ctx context.Context, userIDs ...id.UserID) error {
+	return cli.updateIgnoredUsers(ctx, func(ignored map[id.UserID]event.IgnoredUser) {
+		for _, userID := range userIDs {
+			delete(ignored, userID)
+		}
+	})
+}
+
+func (cli *Client) updateIgnoredUsers(ctx context.Context, update func(map[id.UserID]event.IgnoredUser)) error {
+	ignored, err := cli.GetIgnoredUsers(ctx)
+	if err != nil {
+		return err
+	}
+	before := maps.Clone(ignored)
+	update(ignored)
+	if maps.Equal(before, ignored) {
+		return nil
+	}
+	return cli.SetAccountData(ctx, event.AccountDataIgnoredUserList.Type, &event.IgnoredUserListEventContent{
+		IgnoredUsers: ignored,
+	})
+}
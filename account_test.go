@@ -0,0 +1,132 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+	"github.com/iKonoTelecomunicaciones/go/mockserver"
+)
+
+func TestClient_ChangePassword(t *testing.T) {
+	ctx := context.Background()
+	server := mockserver.Create(t)
+	alice := server.NewClient(t, ctx, "@alice:localhost", "ALICE")
+	otherDevice := server.NewClient(t, ctx, "@alice:localhost", "OTHER")
+	server.Passwords[alice.UserID] = "hunter2"
+
+	err := alice.ChangePassword(ctx, &mautrix.ReqChangePassword{NewPassword: "correct horse"}, nil)
+	var httpErr mautrix.HTTPError
+	require.ErrorAs(t, err, &httpErr, "request without UIA helper should fail")
+	assert.True(t, httpErr.IsStatus(http.StatusUnauthorized))
+
+	wrongPassword := mautrix.NewUIAHelper(map[mautrix.AuthType]mautrix.UIAStageHandler{
+		mautrix.AuthTypePassword: mautrix.UIAPassword(alice.UserID, "hunter3"),
+	})
+	err = alice.ChangePassword(ctx, &mautrix.ReqChangePassword{NewPassword: "correct horse"}, wrongPassword)
+	require.ErrorIs(t, err, mautrix.MForbidden)
+
+	uia := mautrix.NewUIAHelper(map[mautrix.AuthType]mautrix.UIAStageHandler{
+		mautrix.AuthTypePassword: mautrix.UIAPassword(alice.UserID, "hunter2"),
+	})
+	err = alice.ChangePassword(ctx, &mautrix.ReqChangePassword{NewPassword: "correct horse"}, uia)
+	require.NoError(t, err)
+	assert.Equal(t, "correct horse", server.Passwords[alice.UserID])
+	_, err = otherDevice.Whoami(ctx)
+	assert.ErrorIs(t, err, mautrix.MUnknownToken, "other devices should be logged out")
+	_, err = alice.Whoami(ctx)
+	assert.NoError(t, err)
+}
+
+func TestClient_GetLoginToken_MultiStage(t *testing.T) {
+	ctx := context.Background()
+	server := mockserver.Create(t)
+	alice := server.NewClient(t, ctx, "@alice:localhost", "ALICE")
+
+	_, err := alice.GetLoginToken(ctx, mautrix.NewUIAHelper(map[mautrix.AuthType]mautrix.UIAStageHandler{
+		mautrix.AuthTypeDummy: mautrix.UIADummy,
+	}))
+	require.ErrorIs(t, err, mautrix.ErrNoSupportedUIAFlow)
+
+	var captchaKey string
+	resp, err := alice.GetLoginToken(ctx, mautrix.NewUIAHelper(map[mautrix.AuthType]mautrix.UIAStageHandler{
+		mautrix.AuthTypeReCAPTCHA: mautrix.UIAReCAPTCHA(func(ctx context.Context, publicKey string) (string, error) {
+			captchaKey = publicKey
+			return "solved", nil
+		}),
+		mautrix.AuthTypeDummy: mautrix.UIADummy,
+	}))
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.LoginToken)
+	assert.Positive(t, resp.ExpiresInMS)
+	assert.Equal(t, "mock", captchaKey)
+}
+
+func TestClient_ThreePIDs(t *testing.T) {
+	ctx := context.Background()
+	server := mockserver.Create(t)
+	alice := server.NewClient(t, ctx, "@alice:localhost", "ALICE")
+	server.Passwords[alice.UserID] = "hunter2"
+	uia := mautrix.NewUIAHelper(map[mautrix.AuthType]mautrix.UIAStageHandler{
+		mautrix.AuthTypePassword: mautrix.UIAPassword(alice.UserID, "hunter2"),
+	})
+
+	tokenResp, err := alice.RequestEmailToken(ctx, &mautrix.ReqRequestEmailToken{
+		ClientSecret: "secret",
+		Email:        "alice@example.com",
+		SendAttempt:  1,
+	})
+	require.NoError(t, err)
+	err = alice.AddThreePID(ctx, &mautrix.ReqAddThreePID{ClientSecret: "secret", SID: tokenResp.SID}, uia)
+	require.NoError(t, err)
+
+	resp, err := alice.GetThreePIDs(ctx)
+	require.NoError(t, err)
+	require.Len(t, resp.ThreePIDs, 1)
+	assert.Equal(t, "email", resp.ThreePIDs[0].Medium)
+	assert.Equal(t, "alice@example.com", resp.ThreePIDs[0].Address)
+
+	_, err = alice.DeleteThreePID(ctx, &mautrix.ReqRemoveThreePID{Medium: "email", Address: "alice@example.com"})
+	require.NoError(t, err)
+	resp, err = alice.GetThreePIDs(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, resp.ThreePIDs)
+
+	_, err = alice.DeactivateAccount(ctx, &mautrix.ReqDeactivateAccount{Erase: true}, uia)
+	require.NoError(t, err)
+	_, err = alice.Whoami(ctx)
+	assert.ErrorIs(t, err, mautrix.MUnknownToken)
+}
+
+func TestClient_IgnoreUsers(t *testing.T) {
+	ctx := context.Background()
+	server := mockserver.Create(t)
+	alice := server.NewClient(t, ctx, "@alice:localhost", "ALICE")
+	bob, carol := id.UserID("@bob:localhost"), id.UserID("@carol:localhost")
+
+	ignored, err := alice.GetIgnoredUsers(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, ignored)
+
+	require.NoError(t, alice.IgnoreUsers(ctx, bob, carol))
+	ignored, err = alice.GetIgnoredUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[id.UserID]event.IgnoredUser{bob: {}, carol: {}}, ignored)
+
+	require.NoError(t, alice.UnignoreUsers(ctx, bob))
+	ignored, err = alice.GetIgnoredUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[id.UserID]event.IgnoredUser{carol: {}}, ignored)
+}
@@ -112,6 +112,7 @@ func (br *Bridge) DoBackfillTask(ctx context.Context, task *database.BackfillTas
 		return
 	}
 	completed, err := br.actuallyDoBackfillTask(ctx, task)
+	br.TrackBackfillTask(completed, err)
 	if err != nil {
 		log.Err(err).Msg("Failed to do backfill task")
 		time.Sleep(BackfillQueueErrorBackoff)
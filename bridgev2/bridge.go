@@ -40,6 +40,8 @@ type Bridge struct {
 	Config   *bridgeconfig.BridgeConfig
 
 	DisappearLoop *DisappearLoop
+	RetentionLoop *RetentionLoop
+	Outbox        *Outbox
 
 	usersByMXID    map[id.UserID]*User
 	userLoginsByID map[networkid.UserLoginID]*UserLogin
@@ -48,6 +50,9 @@ type Bridge struct {
 	ghostsByID     map[networkid.UserID]*Ghost
 	cacheLock      sync.Mutex
 
+	// Cache of custom emojis used for bridging reactions. Emojis that haven't been bridged are cached as nil.
+	customEmojis *exsync.Map[networkid.EmojiID, *database.CustomEmoji]
+
 	didSplitPortals bool
 
 	Background          bool
@@ -83,6 +88,7 @@ func NewBridge(
 		portalsByKey:   make(map[networkid.PortalKey]*Portal),
 		portalsByMXID:  make(map[id.RoomID]*Portal),
 		ghostsByID:     make(map[networkid.UserID]*Ghost),
+		customEmojis:   exsync.NewMap[networkid.EmojiID, *database.CustomEmoji](),
 
 		wakeupBackfillQueue: make(chan struct{}),
 		stopBackfillQueue:   exsync.NewEvent(),
@@ -95,6 +101,8 @@ func NewBridge(
 	br.Bot = br.Matrix.BotIntent()
 	br.Network.Init(br)
 	br.DisappearLoop = &DisappearLoop{br: br}
+	br.RetentionLoop = &RetentionLoop{br: br}
+	br.Outbox = &Outbox{br: br, wakeup: make(chan struct{}, 1)}
 	return br
 }
 
@@ -202,6 +210,12 @@ func (br *Bridge) StartConnectors(ctx context.Context) error {
 	if br.Network.GetCapabilities().DisappearingMessages && !br.Background {
 		go br.DisappearLoop.Start()
 	}
+	if br.Config.Retention.Enabled && !br.Background {
+		go br.RetentionLoop.Start()
+	}
+	if br.Config.Outbox.Enabled && !br.Background {
+		go br.Outbox.Start()
+	}
 	return nil
 }
 
@@ -354,6 +368,14 @@ func (br *Bridge) StartLogins(ctx context.Context) error {
 				br.Log.Info().Str("id", string(login.ID)).Msg("Starting user login")
 				login.Client.Connect(login.Log.WithContext(ctx))
 			}
+			if !br.Background {
+				go func() {
+					err := user.SyncIgnoredUsers(user.Log.WithContext(br.BackgroundCtx))
+					if err != nil {
+						user.Log.Err(err).Msg("Failed to sync ignored user list")
+					}
+				}()
+			}
 		}
 	}
 	if !startedAny {
@@ -379,6 +401,8 @@ func (br *Bridge) StopWithTimeout(timeout time.Duration) {
 func (br *Bridge) stop(isRunOnce bool, timeout time.Duration) {
 	br.Log.Info().Msg("Shutting down bridge")
 	br.DisappearLoop.Stop()
+	br.RetentionLoop.Stop()
+	br.Outbox.Stop()
 	br.stopBackfillQueue.Set()
 	br.Matrix.PreStop()
 	if !isRunOnce {
@@ -0,0 +1,65 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/util/dbutil"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/bridgeconfig"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/database"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+func newTestDB(t *testing.T) *dbutil.Database {
+	rawDB, err := sql.Open("sqlite3", ":memory:?_busy_timeout=5000")
+	require.NoError(t, err)
+	rawDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = rawDB.Close() })
+	db, err := dbutil.NewWithDB(rawDB, "sqlite3")
+	require.NoError(t, err)
+	return db
+}
+
+func newTestBridge(t *testing.T, db *dbutil.Database, cfg *bridgeconfig.BridgeConfig, matrix MatrixConnector, network NetworkConnector) *Bridge {
+	if matrix == nil {
+		matrix = &testMatrixConnector{}
+	}
+	if network == nil {
+		network = &testNetworkConnector{}
+	}
+	br := NewBridge("test", db, zerolog.Nop(), cfg, matrix, network, func(*Bridge) CommandProcessor { return nil })
+	br.BackgroundCtx = context.Background()
+	require.NoError(t, br.DB.Upgrade(context.Background()))
+	return br
+}
+
+type testMatrixConnector struct {
+	MatrixConnector
+}
+
+func (m *testMatrixConnector) Init(*Bridge)                                      {}
+func (m *testMatrixConnector) BotIntent() MatrixAPI                              { return nil }
+func (m *testMatrixConnector) GhostIntent(networkid.UserID) MatrixAPI            { return nil }
+func (m *testMatrixConnector) ParseGhostMXID(id.UserID) (networkid.UserID, bool) { return "", false }
+
+type testNetworkConnector struct {
+	NetworkConnector
+}
+
+func (n *testNetworkConnector) Init(*Bridge)                       {}
+func (n *testNetworkConnector) GetDBMetaTypes() database.MetaTypes { return database.MetaTypes{} }
+func (n *testNetworkConnector) GetCapabilities() *NetworkGeneralCapabilities {
+	return &NetworkGeneralCapabilities{}
+}
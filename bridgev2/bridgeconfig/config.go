@@ -26,6 +26,8 @@ type Config struct {
 	Matrix       MatrixConfig       `yaml:"matrix"`
 	Analytics    AnalyticsConfig    `yaml:"analytics"`
 	Provisioning ProvisioningConfig `yaml:"provisioning"`
+	Metrics      MetricsConfig      `yaml:"metrics"`
+	Tracing      TracingConfig      `yaml:"tracing"`
 	PublicMedia  PublicMediaConfig  `yaml:"public_media"`
 	DirectMedia  DirectMediaConfig  `yaml:"direct_media"`
 	Backfill     BackfillConfig     `yaml:"backfill"`
@@ -59,6 +61,11 @@ type CleanupOnLogouts struct {
 	BadCredentials CleanupOnLogout `yaml:"bad_credentials"`
 }
 
+type PresenceConfig struct {
+	FromRemote bool `yaml:"from_remote"`
+	FromMatrix bool `yaml:"from_matrix"`
+}
+
 type BridgeConfig struct {
 	CommandPrefix             string           `yaml:"command_prefix"`
 	PersonalFilteringSpaces   bool             `yaml:"personal_filtering_spaces"`
@@ -82,6 +89,9 @@ type BridgeConfig struct {
 	Relay                     RelayConfig      `yaml:"relay"`
 	Permissions               PermissionConfig `yaml:"permissions"`
 	Backfill                  BackfillConfig   `yaml:"backfill"`
+	Presence                  PresenceConfig   `yaml:"presence"`
+	Retention                 RetentionConfig  `yaml:"retention"`
+	Outbox                    OutboxConfig     `yaml:"outbox"`
 	RenameRoom                bool             `yaml:"rename_room"`
 	DeleteMessages            bool             `yaml:"delete_messages"`
 }
@@ -93,6 +103,15 @@ type MatrixConfig struct {
 	SyncDirectChatList  bool  `yaml:"sync_direct_chat_list"`
 	FederateRooms       bool  `yaml:"federate_rooms"`
 	UploadFileThreshold int64 `yaml:"upload_file_threshold"`
+
+	ImageProcessing ImageProcessingConfig `yaml:"image_processing"`
+}
+
+type ImageProcessingConfig struct {
+	Enabled       bool  `yaml:"enabled"`
+	ThumbnailSize int   `yaml:"thumbnail_size"`
+	MaxFileSize   int64 `yaml:"max_file_size"`
+	MaxPixels     int   `yaml:"max_pixels"`
 }
 
 type AnalyticsConfig struct {
@@ -107,6 +126,19 @@ type ProvisioningConfig struct {
 	EnableSessionTransfers bool   `yaml:"enable_session_transfers"`
 }
 
+type MetricsConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	SharedSecret string `yaml:"shared_secret"`
+}
+
+type TracingConfig struct {
+	Enabled     bool              `yaml:"enabled"`
+	Endpoint    string            `yaml:"endpoint"`
+	Headers     map[string]string `yaml:"headers"`
+	ServiceName string            `yaml:"service_name"`
+	SampleRate  float64           `yaml:"sample_rate"`
+}
+
 type DirectMediaConfig struct {
 	Enabled                bool   `yaml:"enabled"`
 	MediaIDPrefix          string `yaml:"media_id_prefix"`
@@ -0,0 +1,39 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgeconfig
+
+import (
+	"time"
+)
+
+type RetentionConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	CheckInterval    time.Duration `yaml:"check_interval"`
+	BatchSize        int           `yaml:"batch_size"`
+	UseRoomRetention bool          `yaml:"use_room_retention"`
+
+	Default RetentionPolicy `yaml:"default"`
+	DM      RetentionPolicy `yaml:"dm"`
+	GroupDM RetentionPolicy `yaml:"group_dm"`
+}
+
+type RetentionPolicy struct {
+	MaxAge  time.Duration `yaml:"max_age"`
+	MaxRows int           `yaml:"max_rows"`
+}
+
+// GetPolicy returns the retention policy for portals of the given room type.
+func (rc *RetentionConfig) GetPolicy(roomType string) RetentionPolicy {
+	switch roomType {
+	case "dm":
+		return rc.DM
+	case "group_dm":
+		return rc.GroupDM
+	default:
+		return rc.Default
+	}
+}
@@ -55,6 +55,20 @@ func doUpgrade(helper up.Helper) {
 	helper.Copy(up.List, "bridge", "relay", "default_relays")
 	helper.Copy(up.Map, "bridge", "relay", "message_formats")
 	helper.Copy(up.Str, "bridge", "relay", "displayname_format")
+	helper.Copy(up.Bool, "bridge", "presence", "from_remote")
+	helper.Copy(up.Bool, "bridge", "presence", "from_matrix")
+	helper.Copy(up.Bool, "bridge", "retention", "enabled")
+	helper.Copy(up.Str|up.Int, "bridge", "retention", "check_interval")
+	helper.Copy(up.Int, "bridge", "retention", "batch_size")
+	helper.Copy(up.Bool, "bridge", "retention", "use_room_retention")
+	for _, portalType := range []string{"default", "dm", "group_dm"} {
+		helper.Copy(up.Str|up.Int, "bridge", "retention", portalType, "max_age")
+		helper.Copy(up.Int, "bridge", "retention", portalType, "max_rows")
+	}
+	helper.Copy(up.Bool, "bridge", "outbox", "enabled")
+	helper.Copy(up.Str|up.Int, "bridge", "outbox", "initial_backoff")
+	helper.Copy(up.Str|up.Int, "bridge", "outbox", "max_backoff")
+	helper.Copy(up.Str|up.Int, "bridge", "outbox", "max_age")
 	helper.Copy(up.Bool, "bridge", "rename_room")
 	helper.Copy(up.Bool, "bridge", "delete_messages")
 	helper.Copy(up.Map, "bridge", "permissions")
@@ -101,6 +115,10 @@ func doUpgrade(helper up.Helper) {
 	helper.Copy(up.Bool, "matrix", "sync_direct_chat_list")
 	helper.Copy(up.Bool, "matrix", "federate_rooms")
 	helper.Copy(up.Int, "matrix", "upload_file_threshold")
+	helper.Copy(up.Bool, "matrix", "image_processing", "enabled")
+	helper.Copy(up.Int, "matrix", "image_processing", "thumbnail_size")
+	helper.Copy(up.Int, "matrix", "image_processing", "max_file_size")
+	helper.Copy(up.Int, "matrix", "image_processing", "max_pixels")
 
 	helper.Copy(up.Str|up.Null, "analytics", "token")
 	helper.Copy(up.Str|up.Null, "analytics", "url")
@@ -115,6 +133,15 @@ func doUpgrade(helper up.Helper) {
 	helper.Copy(up.Bool, "provisioning", "debug_endpoints")
 	helper.Copy(up.Bool, "provisioning", "enable_session_transfers")
 
+	helper.Copy(up.Bool, "metrics", "enabled")
+	helper.Copy(up.Str|up.Null, "metrics", "shared_secret")
+
+	helper.Copy(up.Bool, "tracing", "enabled")
+	helper.Copy(up.Str, "tracing", "endpoint")
+	helper.Copy(up.Map, "tracing", "headers")
+	helper.Copy(up.Str|up.Null, "tracing", "service_name")
+	helper.Copy(up.Float|up.Int, "tracing", "sample_rate")
+
 	helper.Copy(up.Bool, "direct_media", "enabled")
 	helper.Copy(up.Str|up.Null, "direct_media", "media_id_prefix")
 	helper.Copy(up.Str, "direct_media", "server_name")
@@ -194,6 +221,9 @@ var SpacedBlocks = [][]string{
 	{"bridge", "cleanup_on_logout"},
 	{"bridge", "relay"},
 	{"bridge", "permissions"},
+	{"bridge", "presence"},
+	{"bridge", "retention"},
+	{"bridge", "outbox"},
 	{"database"},
 	{"homeserver"},
 	{"homeserver", "software"},
@@ -207,6 +237,8 @@ var SpacedBlocks = [][]string{
 	{"matrix"},
 	{"analytics"},
 	{"provisioning"},
+	{"metrics"},
+	{"tracing"},
 	{"public_media"},
 	{"direct_media"},
 	{"backfill"},
@@ -285,6 +285,9 @@ func (bsq *BridgeStateQueue) Send(state status.BridgeState) {
 
 	state = state.Fill(bsq.login)
 	bsq.prevUnsent = &state
+	if state.StateEvent == status.StateConnected {
+		bsq.bridge.Outbox.LoginConnected(bsq.login)
+	}
 
 	if len(bsq.ch) >= 8 {
 		bsq.login.Log.Warn().Msg("Bridge state queue is nearly full, discarding an item")
@@ -0,0 +1,136 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package commands_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/util/dbutil"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/bridgeconfig"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/commands"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/database"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/matrix"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+	"github.com/iKonoTelecomunicaciones/go/mockserver"
+)
+
+type testNetworkConnector struct {
+	bridgev2.NetworkConnector
+}
+
+func (n *testNetworkConnector) Init(*bridgev2.Bridge) {}
+
+func (n *testNetworkConnector) GetName() bridgev2.BridgeName {
+	return bridgev2.BridgeName{DisplayName: "Test", NetworkURL: "https://example.com", NetworkID: "test"}
+}
+
+func (n *testNetworkConnector) GetDBMetaTypes() database.MetaTypes { return database.MetaTypes{} }
+
+func (n *testNetworkConnector) GetCapabilities() *bridgev2.NetworkGeneralCapabilities {
+	return &bridgev2.NetworkGeneralCapabilities{}
+}
+
+func newTestConnector(t *testing.T, ctx context.Context, server *mockserver.MockServer) *matrix.Connector {
+	rawDB, err := sql.Open("sqlite3", ":memory:?_busy_timeout=5000")
+	require.NoError(t, err)
+	rawDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = rawDB.Close() })
+	db, err := dbutil.NewWithDB(rawDB, "sqlite3")
+	require.NoError(t, err)
+
+	cfg := &bridgeconfig.Config{}
+	cfg.Homeserver.Address = server.Server.URL
+	cfg.Homeserver.Domain = server.ServerName
+	cfg.AppService.ID = "test"
+	cfg.AppService.Bot.Username = "testbot"
+	cfg.AppService.ASToken = "as_token"
+	cfg.AppService.HSToken = "hs_token"
+	cfg.AppService.UsernameTemplate = "test_{{.}}"
+	cfg.Bridge.CommandPrefix = "!test"
+	cfg.Bridge.Permissions = bridgeconfig.PermissionConfig{"*": &bridgeconfig.PermissionLevelUser}
+
+	connector := matrix.NewConnector(cfg)
+	br := bridgev2.NewBridge("test", db, zerolog.Nop(), &cfg.Bridge, connector, &testNetworkConnector{}, commands.NewProcessor)
+	br.BackgroundCtx = ctx
+	require.NoError(t, br.DB.Upgrade(ctx))
+	require.NoError(t, connector.StateStore.Upgrade(ctx))
+
+	asServer := httptest.NewServer(connector.AS.Router)
+	t.Cleanup(asServer.Close)
+	reg := connector.AS.Registration
+	reg.URL = asServer.URL
+	reg.Namespaces.UserIDs.Register(regexp.MustCompile(regexp.QuoteMeta(connector.Bot.UserID.String())), true)
+	reg.Namespaces.UserIDs.Register(cfg.MakeUserIDRegex(".*"), true)
+	server.RegisterAppservice(t, reg)
+	connector.EventProcessor.Start(ctx)
+	t.Cleanup(connector.EventProcessor.Stop)
+	connector.AS.Ready = true
+	return connector
+}
+
+func findBotMessage(t *testing.T, ctx context.Context, client *mautrix.Client, roomID id.RoomID, botUserID id.UserID, contains string) *event.Event {
+	var found *event.Event
+	require.Eventually(t, func() bool {
+		resp, err := client.Messages(ctx, roomID, "", "", mautrix.DirectionBackward, nil, 50)
+		if err != nil {
+			return false
+		}
+		for _, evt := range resp.Chunk {
+			body, _ := evt.Content.Raw["body"].(string)
+			if evt.Sender == botUserID && evt.Type == event.EventMessage && strings.Contains(body, contains) {
+				found = evt
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 20*time.Millisecond)
+	return found
+}
+
+func TestProcessor_Mockserver(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	server := mockserver.Create(t)
+	connector := newTestConnector(t, ctx, server)
+	botUserID := connector.Bot.UserID
+
+	alice := server.NewClient(t, ctx, "@alice:localhost", "ALICE")
+	createResp, err := alice.CreateRoom(ctx, &mautrix.ReqCreateRoom{Invite: []id.UserID{botUserID}, IsDirect: true})
+	require.NoError(t, err)
+	roomID := createResp.RoomID
+
+	// The bot accepts the invite and marks the room as the management room
+	findBotMessage(t, ctx, alice, roomID, botUserID, "This room has been marked as your management room")
+	user, err := connector.Bridge.GetExistingUserByMXID(ctx, alice.UserID)
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	assert.Equal(t, roomID, user.ManagementRoom)
+
+	// Commands in the management room don't need the prefix
+	_, err = alice.SendText(ctx, roomID, "help")
+	require.NoError(t, err)
+	reply := findBotMessage(t, ctx, alice, roomID, botUserID, "General")
+	assert.Equal(t, string(event.MsgNotice), reply.Content.Raw["msgtype"])
+
+	_, err = alice.SendText(ctx, roomID, "!test nonexistent-command")
+	require.NoError(t, err)
+	findBotMessage(t, ctx, alice, roomID, botUserID, "Unknown command")
+}
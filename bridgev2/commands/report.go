@@ -0,0 +1,112 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package commands
+
+import (
+	"github.com/iKonoTelecomunicaciones/go/bridgev2"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+var CommandReport = &FullHandler{
+	Func: func(ce *Event) {
+		login, _, err := ce.Portal.FindPreferredLogin(ce.Ctx, ce.User, false)
+		if err != nil {
+			ce.Log.Err(err).Msg("Failed to find login for report")
+			ce.Reply("Failed to find login: %v", err)
+			return
+		} else if login == nil {
+			ce.Reply("You're not logged in to this chat")
+			return
+		}
+		err = ce.Portal.ReportEvent(ce.Ctx, login, ce.ReplyTo, ce.RawArgs)
+		if err != nil {
+			ce.Log.Err(err).Stringer("target_event_id", ce.ReplyTo).Msg("Failed to report chat")
+			ce.Reply("Failed to send report: %v", err)
+			return
+		}
+		ce.React("✅️")
+	},
+	Name: "report",
+	Help: HelpMeta{
+		Section:     HelpSectionChats,
+		Description: "Report the current chat, or the replied-to message, to the remote network",
+		Args:        "[_reason_]",
+	},
+	RequiresPortal: true,
+	RequiresLogin:  true,
+	NetworkAPI:     NetworkAPIImplements[bridgev2.ReportingNetworkAPI],
+}
+
+var CommandBlock = &FullHandler{
+	Func: func(ce *Event) {
+		fnSetUserBlocked(ce, true)
+	},
+	Name: "block",
+	Help: HelpMeta{
+		Section:     HelpSectionChats,
+		Description: "Block a user on the remote network. Defaults to the other user in the current DM.",
+		Args:        "[_user ID or Matrix ID_]",
+	},
+	RequiresLogin: true,
+	NetworkAPI:    NetworkAPIImplements[bridgev2.ReportingNetworkAPI],
+}
+
+var CommandUnblock = &FullHandler{
+	Func: func(ce *Event) {
+		fnSetUserBlocked(ce, false)
+	},
+	Name: "unblock",
+	Help: HelpMeta{
+		Section:     HelpSectionChats,
+		Description: "Unblock a user on the remote network. Defaults to the other user in the current DM.",
+		Args:        "[_user ID or Matrix ID_]",
+	},
+	RequiresLogin: true,
+	NetworkAPI:    NetworkAPIImplements[bridgev2.ReportingNetworkAPI],
+}
+
+func fnSetUserBlocked(ce *Event, blocked bool) {
+	var userID networkid.UserID
+	if len(ce.Args) > 0 {
+		var isGhost bool
+		userID, isGhost = ce.Bridge.Matrix.ParseGhostMXID(id.UserID(ce.Args[0]))
+		if !isGhost {
+			userID = networkid.UserID(ce.Args[0])
+		}
+	} else if ce.Portal != nil && ce.Portal.OtherUserID != "" {
+		userID = ce.Portal.OtherUserID
+	} else {
+		ce.Reply("Usage: `$cmdprefix %s <user ID or Matrix ID>`", ce.Command)
+		return
+	}
+	ghost, err := ce.Bridge.GetExistingGhostByID(ce.Ctx, userID)
+	if err != nil {
+		ce.Log.Err(err).Msg("Failed to get ghost to change block status")
+		ce.Reply("Failed to get user: %v", err)
+		return
+	} else if ghost == nil {
+		ce.Reply("User `%s` not found", userID)
+		return
+	}
+	login := ce.User.GetDefaultLogin()
+	if ce.Portal != nil {
+		portalLogin, _, err := ce.Portal.FindPreferredLogin(ce.Ctx, ce.User, false)
+		if err != nil {
+			ce.Log.Err(err).Msg("Failed to find login in portal")
+		} else if portalLogin != nil {
+			login = portalLogin
+		}
+	}
+	err = login.SetUserBlocked(ce.Ctx, ghost, blocked)
+	if err != nil {
+		ce.Log.Err(err).Str("ghost_id", string(userID)).Bool("blocked", blocked).Msg("Failed to change block status")
+		ce.Reply("Failed to change block status: %v", err)
+		return
+	}
+	ce.React("✅️")
+}
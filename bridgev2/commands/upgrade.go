@@ -0,0 +1,39 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package commands
+
+import (
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+var CommandUpgradeRoom = &FullHandler{
+	Func: func(ce *Event) {
+		newVersion := id.RoomV12
+		if len(ce.Args) > 0 {
+			newVersion = id.RoomVersion(ce.Args[0])
+		}
+		if !newVersion.IsKnown() {
+			ce.Reply("Unknown room version `%s`", newVersion)
+			return
+		}
+		newRoomID, err := ce.Portal.UpgradeRoom(ce.Ctx, newVersion, ce.User)
+		if err != nil {
+			ce.Log.Err(err).Msg("Failed to upgrade portal room")
+			ce.Reply("Failed to upgrade room: %v", err)
+			return
+		}
+		ce.Reply("Room upgraded to version %s: %s", newVersion, newRoomID.URI(ce.Bridge.Matrix.ServerName()).MatrixToURL())
+	},
+	Name: "upgrade-room",
+	Help: HelpMeta{
+		Section:     HelpSectionAdmin,
+		Description: "Upgrade the current portal room to a new room version",
+		Args:        "[_room version_]",
+	},
+	RequiresAdmin:  true,
+	RequiresPortal: true,
+}
@@ -0,0 +1,359 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mau.fi/util/exmime"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/database"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// CustomEmoji is a custom emoji or sticker on the remote network.
+type CustomEmoji struct {
+	ID networkid.EmojiID
+	// The shortcode of the emoji without colons, used as the key in the image pack.
+	Shortcode string
+	Body      string
+	// The usage of the image. If empty, the usage of the pack is used.
+	Usage []event.ImagePackUsage
+	Get   func(ctx context.Context) ([]byte, error)
+
+	// The SHA-256 hash of the emoji image, if it's known without downloading the image.
+	// If set, the emoji is reuploaded when the hash changes. Otherwise, an emoji is only uploaded once per ID.
+	Hash [32]byte
+
+	// For pre-uploaded emojis, the MXC URI and mime type can be provided directly
+	MXC      id.ContentURIString
+	MimeType string
+}
+
+func (emoji *CustomEmoji) matchesCached(cached *database.CustomEmoji) bool {
+	if emoji.MXC != "" {
+		return cached.MXC == emoji.MXC
+	}
+	return emoji.Hash == [32]byte{} || cached.Hash == emoji.Hash
+}
+
+// EmojiPack is a set of custom emojis or stickers on the remote network.
+type EmojiPack struct {
+	// The ID of the pack, used as the state key of the room image pack.
+	ID          string
+	Name        string
+	Usage       []event.ImagePackUsage
+	Attribution string
+	// The ID of an emoji in the pack to use as the avatar of the pack.
+	AvatarEmojiID networkid.EmojiID
+	// If true, the pack was deleted on the remote network. The image pack is cleared
+	// and the emojis of the pack are removed from the database.
+	Remove bool
+
+	Emojis []*CustomEmoji
+}
+
+// ReuploadCustomEmoji uploads the given emoji to Matrix, unless it has already been uploaded before.
+// The mapping between the remote emoji ID and the Matrix content URI is cached in the database,
+// which is also used for bridging custom emoji reactions.
+func (br *Bridge) ReuploadCustomEmoji(ctx context.Context, intent MatrixAPI, emoji *CustomEmoji) (*database.CustomEmoji, error) {
+	cached, err := br.getCustomEmoji(ctx, emoji.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached custom emoji: %w", err)
+	}
+	var packID string
+	if cached != nil {
+		packID = cached.PackID
+	}
+	return br.reuploadCustomEmoji(ctx, intent, emoji, cached, packID)
+}
+
+func (br *Bridge) reuploadCustomEmoji(
+	ctx context.Context, intent MatrixAPI, emoji *CustomEmoji, cached *database.CustomEmoji, packID string,
+) (*database.CustomEmoji, error) {
+	if cached != nil && emoji.matchesCached(cached) {
+		if (emoji.Shortcode == "" || cached.Shortcode == emoji.Shortcode) && cached.PackID == packID {
+			return cached, nil
+		}
+		// Copy the cached emoji, as the original may be in use by other goroutines
+		updated := *cached
+		if emoji.Shortcode != "" {
+			updated.Shortcode = emoji.Shortcode
+		}
+		updated.PackID = packID
+		err := br.putCustomEmoji(ctx, &updated)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update cached custom emoji: %w", err)
+		}
+		return &updated, nil
+	}
+	dbEmoji := &database.CustomEmoji{
+		ID:        emoji.ID,
+		PackID:    packID,
+		MXC:       emoji.MXC,
+		Hash:      emoji.Hash,
+		Shortcode: emoji.Shortcode,
+		MimeType:  emoji.MimeType,
+	}
+	if dbEmoji.MXC == "" {
+		if emoji.Get == nil {
+			return nil, fmt.Errorf("no Get function provided for custom emoji")
+		}
+		data, err := emoji.Get(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download custom emoji: %w", err)
+		}
+		if dbEmoji.Hash == [32]byte{} {
+			dbEmoji.Hash = sha256.Sum256(data)
+		}
+		if dbEmoji.MimeType == "" {
+			dbEmoji.MimeType = http.DetectContentType(data)
+		}
+		fileName := "emoji" + exmime.ExtensionFromMimetype(dbEmoji.MimeType)
+		if emoji.Shortcode != "" {
+			fileName = emoji.Shortcode + exmime.ExtensionFromMimetype(dbEmoji.MimeType)
+		}
+		dbEmoji.MXC, _, err = intent.UploadMedia(ctx, "", data, fileName, dbEmoji.MimeType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload custom emoji: %w", err)
+		}
+	}
+	err := br.putCustomEmoji(ctx, dbEmoji)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save custom emoji to database: %w", err)
+	}
+	return dbEmoji, nil
+}
+
+func (br *Bridge) getCustomEmoji(ctx context.Context, emojiID networkid.EmojiID) (*database.CustomEmoji, error) {
+	if cached, ok := br.customEmojis.Get(emojiID); ok {
+		return cached, nil
+	}
+	dbEmoji, err := br.DB.CustomEmoji.GetByID(ctx, emojiID)
+	if err != nil {
+		return nil, err
+	}
+	br.customEmojis.Set(emojiID, dbEmoji)
+	return dbEmoji, nil
+}
+
+func (br *Bridge) putCustomEmoji(ctx context.Context, emoji *database.CustomEmoji) error {
+	err := br.DB.CustomEmoji.Put(ctx, emoji)
+	if err != nil {
+		br.customEmojis.Delete(emoji.ID)
+		return err
+	}
+	br.customEmojis.Set(emoji.ID, emoji)
+	return nil
+}
+
+func (br *Bridge) deleteCustomEmoji(ctx context.Context, emojiID networkid.EmojiID) error {
+	br.customEmojis.Delete(emojiID)
+	return br.DB.CustomEmoji.Delete(ctx, emojiID)
+}
+
+// deleteEmojiPack removes all emojis of the given pack from the database.
+func (br *Bridge) deleteEmojiPack(ctx context.Context, packID string) error {
+	emojis, err := br.DB.CustomEmoji.GetByPack(ctx, packID)
+	if err != nil {
+		return fmt.Errorf("failed to get emojis in pack: %w", err)
+	}
+	for _, emoji := range emojis {
+		err = br.deleteCustomEmoji(ctx, emoji.ID)
+		if err != nil {
+			return fmt.Errorf("failed to delete custom emoji %s: %w", emoji.ID, err)
+		}
+	}
+	return nil
+}
+
+// ConvertEmojiPack reuploads new and changed emojis in the given pack and converts it into an MSC2545 image pack.
+// Emojis that fail to reupload are logged and left out of the pack. Emojis that were previously converted
+// as a part of the pack, but are no longer in it, are removed from the database.
+//
+// If the pack has the Remove flag set, all emojis of the pack are removed and an empty image pack is returned.
+func (br *Bridge) ConvertEmojiPack(ctx context.Context, intent MatrixAPI, pack *EmojiPack) (*event.ImagePackEventContent, error) {
+	log := zerolog.Ctx(ctx)
+	if pack.Remove {
+		if pack.ID != "" {
+			err := br.deleteEmojiPack(ctx, pack.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &event.ImagePackEventContent{Images: map[string]*event.ImagePackImage{}}, nil
+	}
+	existing := make(map[networkid.EmojiID]*database.CustomEmoji)
+	if pack.ID != "" {
+		existingList, err := br.DB.CustomEmoji.GetByPack(ctx, pack.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get existing emojis in pack: %w", err)
+		}
+		for _, emoji := range existingList {
+			existing[emoji.ID] = emoji
+		}
+	}
+	content := &event.ImagePackEventContent{
+		Images: make(map[string]*event.ImagePackImage, len(pack.Emojis)),
+		Pack: &event.ImagePackMetadata{
+			DisplayName: pack.Name,
+			Usage:       pack.Usage,
+			Attribution: pack.Attribution,
+		},
+	}
+	for _, emoji := range pack.Emojis {
+		cached, ok := existing[emoji.ID]
+		delete(existing, emoji.ID)
+		var err error
+		if !ok {
+			// The emoji may have been converted before outside this pack
+			cached, err = br.getCustomEmoji(ctx, emoji.ID)
+			if err != nil {
+				log.Err(err).Str("emoji_id", string(emoji.ID)).Msg("Failed to get cached custom emoji")
+				continue
+			}
+		}
+		dbEmoji, err := br.reuploadCustomEmoji(ctx, intent, emoji, cached, pack.ID)
+		if err != nil {
+			log.Err(err).Str("emoji_id", string(emoji.ID)).Msg("Failed to reupload custom emoji")
+			continue
+		}
+		shortcode := strings.Trim(dbEmoji.Shortcode, ":")
+		if shortcode == "" {
+			shortcode = string(dbEmoji.ID)
+		}
+		if _, alreadyExists := content.Images[shortcode]; alreadyExists {
+			log.Warn().Str("emoji_id", string(emoji.ID)).Str("shortcode", shortcode).
+				Msg("Dropping custom emoji with duplicate shortcode")
+			continue
+		}
+		img := &event.ImagePackImage{
+			URL:   dbEmoji.MXC,
+			Body:  emoji.Body,
+			Usage: emoji.Usage,
+		}
+		if dbEmoji.MimeType != "" {
+			img.Info = &event.FileInfo{MimeType: dbEmoji.MimeType}
+		}
+		content.Images[shortcode] = img
+		if emoji.ID == pack.AvatarEmojiID {
+			content.Pack.AvatarURL = dbEmoji.MXC
+		}
+	}
+	for emojiID := range existing {
+		err := br.deleteCustomEmoji(ctx, emojiID)
+		if err != nil {
+			log.Err(err).Str("emoji_id", string(emojiID)).Msg("Failed to delete custom emoji removed from pack")
+		}
+	}
+	return content, nil
+}
+
+func (portal *Portal) updateEmojiPacks(ctx context.Context, packs []*EmojiPack, sender MatrixAPI, ts time.Time, excludeFromTimeline bool) {
+	if portal.MXID == "" {
+		return
+	}
+	log := zerolog.Ctx(ctx)
+	stateGetter, canGetState := portal.Bridge.Matrix.(MatrixConnectorWithArbitraryRoomState)
+	for _, pack := range packs {
+		content, err := portal.Bridge.ConvertEmojiPack(ctx, portal.Bridge.Bot, pack)
+		if err != nil {
+			log.Err(err).Str("pack_id", pack.ID).Msg("Failed to convert emoji pack")
+			continue
+		}
+		if canGetState {
+			existing, err := stateGetter.GetStateEvent(ctx, portal.MXID, event.StateImagePack, pack.ID)
+			if err != nil {
+				log.Debug().Err(err).Str("pack_id", pack.ID).Msg("Failed to get existing emoji pack state")
+			} else if existing != nil && imagePackEqual(existing.Content.VeryRaw, content) {
+				continue
+			}
+		}
+		portal.sendRoomMeta(ctx, sender, ts, event.StateImagePack, pack.ID, content, excludeFromTimeline, nil)
+	}
+}
+
+func (portal *Portal) getInitialEmojiPackState(ctx context.Context, packs []*EmojiPack) []*event.Event {
+	states := make([]*event.Event, 0, len(packs))
+	for _, pack := range packs {
+		content, err := portal.Bridge.ConvertEmojiPack(ctx, portal.Bridge.Bot, pack)
+		if err != nil {
+			zerolog.Ctx(ctx).Err(err).Str("pack_id", pack.ID).Msg("Failed to convert emoji pack")
+			continue
+		} else if pack.Remove {
+			continue
+		}
+		stateKey := pack.ID
+		states = append(states, &event.Event{
+			StateKey: &stateKey,
+			Type:     event.StateImagePack,
+			Content:  event.Content{Parsed: content},
+		})
+	}
+	return states
+}
+
+func imagePackEqual(existing json.RawMessage, content *event.ImagePackEventContent) bool {
+	if len(existing) == 0 {
+		return false
+	}
+	var parsedExisting event.ImagePackEventContent
+	if json.Unmarshal(existing, &parsedExisting) != nil {
+		return false
+	}
+	existingJSON, err1 := json.Marshal(&parsedExisting)
+	newJSON, err2 := json.Marshal(content)
+	return err1 == nil && err2 == nil && bytes.Equal(existingJSON, newJSON)
+}
+
+// UpdateUserEmojiPack reuploads the given pack and sets it as the user's personal image pack
+// (im.ponies.user_emotes account data) using their double puppet. If the pack has the Remove flag set,
+// the user's image pack is cleared. If double puppeting isn't enabled, this does nothing.
+func (ul *UserLogin) UpdateUserEmojiPack(ctx context.Context, pack *EmojiPack) error {
+	dp := ul.User.DoublePuppet(ctx)
+	if dp == nil {
+		return nil
+	}
+	packAPI, ok := dp.(ImagePackMatrixAPI)
+	if !ok {
+		return nil
+	}
+	content, err := ul.Bridge.ConvertEmojiPack(ctx, dp, pack)
+	if err != nil {
+		return err
+	}
+	return packAPI.SetUserImagePack(ctx, content)
+}
+
+// getCustomEmojiReactionKey returns the Matrix reaction key and extra content for a reaction with a remote
+// custom emoji, or an empty key if the emoji hasn't been bridged.
+func (portal *Portal) getCustomEmojiReactionKey(ctx context.Context, emojiID networkid.EmojiID) (string, map[string]any) {
+	dbEmoji, err := portal.Bridge.getCustomEmoji(ctx, emojiID)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Str("emoji_id", string(emojiID)).Msg("Failed to get custom emoji for reaction")
+		return "", nil
+	} else if dbEmoji == nil {
+		return "", nil
+	}
+	var extra map[string]any
+	if dbEmoji.Shortcode != "" {
+		extra = map[string]any{
+			"com.beeper.reaction.shortcode": ":" + strings.Trim(dbEmoji.Shortcode, ":") + ":",
+		}
+	}
+	return string(dbEmoji.MXC), extra
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+type emojiTestIntent struct {
+	MatrixAPI
+	uploads int
+}
+
+func (i *emojiTestIntent) UploadMedia(_ context.Context, _ id.RoomID, _ []byte, _, _ string) (id.ContentURIString, *event.EncryptedFileInfo, error) {
+	i.uploads++
+	return id.ContentURIString(fmt.Sprintf("mxc://example.com/%d", i.uploads)), nil, nil
+}
+
+func testEmoji(emojiID networkid.EmojiID, data string) *CustomEmoji {
+	return &CustomEmoji{
+		ID:        emojiID,
+		Shortcode: string(emojiID),
+		Hash:      sha256.Sum256([]byte(data)),
+		Get: func(ctx context.Context) ([]byte, error) {
+			return []byte(data), nil
+		},
+	}
+}
+
+func TestBridge_ConvertEmojiPack(t *testing.T) {
+	ctx := context.Background()
+	br := newTestBridge(t, newTestDB(t), nil, nil, nil)
+	intent := &emojiTestIntent{}
+	pack := &EmojiPack{
+		ID:     "pack",
+		Name:   "Pack",
+		Emojis: []*CustomEmoji{testEmoji("party", "party v1"), testEmoji("wave", "wave v1")},
+	}
+
+	content, err := br.ConvertEmojiPack(ctx, intent, pack)
+	require.NoError(t, err)
+	assert.Equal(t, 2, intent.uploads)
+	require.Len(t, content.Images, 2)
+	assert.Equal(t, id.ContentURIString("mxc://example.com/1"), content.Images["party"].URL)
+
+	// Unchanged emojis aren't downloaded or uploaded again
+	pack.Emojis[0].Get = nil
+	content, err = br.ConvertEmojiPack(ctx, intent, pack)
+	require.NoError(t, err)
+	assert.Equal(t, 2, intent.uploads)
+	assert.Equal(t, id.ContentURIString("mxc://example.com/1"), content.Images["party"].URL)
+
+	// Changing the image reuploads only that emoji, and removed emojis are deleted
+	pack.Emojis = []*CustomEmoji{testEmoji("party", "party v2")}
+	content, err = br.ConvertEmojiPack(ctx, intent, pack)
+	require.NoError(t, err)
+	assert.Equal(t, 3, intent.uploads)
+	require.Len(t, content.Images, 1)
+	assert.Equal(t, id.ContentURIString("mxc://example.com/3"), content.Images["party"].URL)
+	wave, err := br.DB.CustomEmoji.GetByID(ctx, "wave")
+	require.NoError(t, err)
+	assert.Nil(t, wave)
+
+	// Removing the pack deletes all of its emojis
+	content, err = br.ConvertEmojiPack(ctx, intent, &EmojiPack{ID: "pack", Remove: true})
+	require.NoError(t, err)
+	assert.Empty(t, content.Images)
+	emojis, err := br.DB.CustomEmoji.GetByPack(ctx, "pack")
+	require.NoError(t, err)
+	assert.Empty(t, emojis)
+}
+
+func TestBridge_ReuploadCustomEmoji_NoHash(t *testing.T) {
+	ctx := context.Background()
+	br := newTestBridge(t, newTestDB(t), nil, nil, nil)
+	intent := &emojiTestIntent{}
+	emoji := testEmoji("party", "party")
+	emoji.Hash = [32]byte{}
+
+	dbEmoji, err := br.ReuploadCustomEmoji(ctx, intent, emoji)
+	require.NoError(t, err)
+	assert.Equal(t, sha256.Sum256([]byte("party")), dbEmoji.Hash)
+	// Without a known hash, emojis are only uploaded once
+	_, err = br.ReuploadCustomEmoji(ctx, intent, emoji)
+	require.NoError(t, err)
+	assert.Equal(t, 1, intent.uploads)
+}
+
+func TestPortal_GetCustomEmojiReactionKey(t *testing.T) {
+	ctx := context.Background()
+	br := newTestBridge(t, newTestDB(t), nil, nil, nil)
+	portal := &Portal{Bridge: br}
+
+	key, extra := portal.getCustomEmojiReactionKey(ctx, "party")
+	assert.Empty(t, key)
+	assert.Nil(t, extra)
+
+	// Misses are cached too, so reuploading must update the cache
+	_, err := br.ReuploadCustomEmoji(ctx, &emojiTestIntent{}, testEmoji("party", "party"))
+	require.NoError(t, err)
+	key, extra = portal.getCustomEmojiReactionKey(ctx, "party")
+	assert.Equal(t, "mxc://example.com/1", key)
+	assert.Equal(t, map[string]any{"com.beeper.reaction.shortcode": ":party:"}, extra)
+
+	// Further lookups are served from the cache without querying the database
+	_, err = br.DB.Exec(ctx, "DELETE FROM custom_emoji")
+	require.NoError(t, err)
+	key, _ = portal.getCustomEmojiReactionKey(ctx, "party")
+	assert.Equal(t, "mxc://example.com/1", key)
+
+	require.NoError(t, br.deleteCustomEmoji(ctx, "party"))
+	key, _ = portal.getCustomEmojiReactionKey(ctx, "party")
+	assert.Empty(t, key)
+}
@@ -98,6 +98,15 @@ const (
 		FROM backfill_task
 		WHERE bridge_id = $1 AND portal_id = $2 AND portal_receiver = $3 AND is_done = false AND user_login_id <> ''
 	`
+	getBackfillQueueStatsQuery = `
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(CASE WHEN is_done THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN NOT is_done AND user_login_id <> '' AND next_dispatch_min_ts < 9223372036854775807 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN batch_count > 0 THEN batch_count ELSE 0 END), 0)
+		FROM backfill_task
+		WHERE bridge_id = $1
+	`
 	deleteBackfillQueueQuery = `
 		DELETE FROM backfill_task
 		WHERE bridge_id = $1 AND portal_id = $2 AND portal_receiver = $3
@@ -144,6 +153,19 @@ func (btq *BackfillTaskQuery) GetNextForPortal(ctx context.Context, portalKey ne
 	return btq.QueryOne(ctx, getNextBackfillQueryForPortal, btq.BridgeID, portalKey.ID, portalKey.Receiver)
 }
 
+type BackfillQueueStats struct {
+	Total   int
+	Done    int
+	Pending int
+	Batches int
+}
+
+func (btq *BackfillTaskQuery) GetStats(ctx context.Context) (stats BackfillQueueStats, err error) {
+	err = btq.GetDB().QueryRow(ctx, getBackfillQueueStatsQuery, btq.BridgeID).
+		Scan(&stats.Total, &stats.Done, &stats.Pending, &stats.Batches)
+	return
+}
+
 func (btq *BackfillTaskQuery) Delete(ctx context.Context, portalKey networkid.PortalKey) error {
 	return btq.Exec(ctx, deleteBackfillQueueQuery, btq.BridgeID, portalKey.ID, portalKey.Receiver)
 }
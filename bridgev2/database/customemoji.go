@@ -0,0 +1,101 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+
+	"go.mau.fi/util/dbutil"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+type CustomEmojiQuery struct {
+	BridgeID networkid.BridgeID
+	*dbutil.QueryHelper[*CustomEmoji]
+}
+
+// CustomEmoji is a remote custom emoji or sticker that has been reuploaded to Matrix.
+type CustomEmoji struct {
+	BridgeID networkid.BridgeID
+	ID       networkid.EmojiID
+	// The ID of the pack the emoji was last converted in, or empty if it was reuploaded outside a pack.
+	PackID string
+	MXC    id.ContentURIString
+	// The SHA-256 hash of the emoji image.
+	Hash      [32]byte
+	Shortcode string
+	MimeType  string
+}
+
+const (
+	getCustomEmojiBaseQuery = `
+		SELECT bridge_id, id, pack_id, mxc, hash, shortcode, mimetype FROM custom_emoji
+	`
+	getCustomEmojiByIDQuery   = getCustomEmojiBaseQuery + `WHERE bridge_id=$1 AND id=$2`
+	getCustomEmojiByMXCQuery  = getCustomEmojiBaseQuery + `WHERE bridge_id=$1 AND mxc=$2`
+	getCustomEmojiByPackQuery = getCustomEmojiBaseQuery + `WHERE bridge_id=$1 AND pack_id=$2`
+	upsertCustomEmojiQuery    = `
+		INSERT INTO custom_emoji (bridge_id, id, pack_id, mxc, hash, shortcode, mimetype)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (bridge_id, id) DO UPDATE
+			SET pack_id=excluded.pack_id, mxc=excluded.mxc, hash=excluded.hash,
+			    shortcode=excluded.shortcode, mimetype=excluded.mimetype
+	`
+	deleteCustomEmojiQuery = `
+		DELETE FROM custom_emoji WHERE bridge_id=$1 AND id=$2
+	`
+)
+
+func (ceq *CustomEmojiQuery) GetByID(ctx context.Context, emojiID networkid.EmojiID) (*CustomEmoji, error) {
+	return ceq.QueryOne(ctx, getCustomEmojiByIDQuery, ceq.BridgeID, emojiID)
+}
+
+func (ceq *CustomEmojiQuery) GetByMXC(ctx context.Context, mxc id.ContentURIString) (*CustomEmoji, error) {
+	return ceq.QueryOne(ctx, getCustomEmojiByMXCQuery, ceq.BridgeID, mxc)
+}
+
+func (ceq *CustomEmojiQuery) GetByPack(ctx context.Context, packID string) ([]*CustomEmoji, error) {
+	return ceq.QueryMany(ctx, getCustomEmojiByPackQuery, ceq.BridgeID, packID)
+}
+
+func (ceq *CustomEmojiQuery) Put(ctx context.Context, emoji *CustomEmoji) error {
+	ensureBridgeIDMatches(&emoji.BridgeID, ceq.BridgeID)
+	return ceq.Exec(ctx, upsertCustomEmojiQuery, emoji.sqlVariables()...)
+}
+
+func (ceq *CustomEmojiQuery) Delete(ctx context.Context, emojiID networkid.EmojiID) error {
+	return ceq.Exec(ctx, deleteCustomEmojiQuery, ceq.BridgeID, emojiID)
+}
+
+func (ce *CustomEmoji) Scan(row dbutil.Scannable) (*CustomEmoji, error) {
+	var mimetype sql.NullString
+	var hash string
+	err := row.Scan(&ce.BridgeID, &ce.ID, &ce.PackID, &ce.MXC, &hash, &ce.Shortcode, &mimetype)
+	if err != nil {
+		return nil, err
+	}
+	if hash != "" {
+		data, _ := hex.DecodeString(hash)
+		if len(data) == 32 {
+			ce.Hash = *(*[32]byte)(data)
+		}
+	}
+	ce.MimeType = mimetype.String
+	return ce, nil
+}
+
+func (ce *CustomEmoji) sqlVariables() []any {
+	var hash string
+	if ce.Hash != [32]byte{} {
+		hash = hex.EncodeToString(ce.Hash[:])
+	}
+	return []any{ce.BridgeID, ce.ID, ce.PackID, ce.MXC, hash, ce.Shortcode, dbutil.StrPtr(ce.MimeType)}
+}
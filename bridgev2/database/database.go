@@ -35,6 +35,8 @@ type Database struct {
 	BackfillTask        *BackfillTaskQuery
 	KV                  *KVQuery
 	PublicMedia         *PublicMediaQuery
+	CustomEmoji         *CustomEmojiQuery
+	Outbox              *OutboxQuery
 }
 
 type MetaMerger interface {
@@ -148,6 +150,18 @@ func New(bridgeID networkid.BridgeID, mt MetaTypes, db *dbutil.Database) *Databa
 				return &PublicMedia{}
 			}),
 		},
+		CustomEmoji: &CustomEmojiQuery{
+			BridgeID: bridgeID,
+			QueryHelper: dbutil.MakeQueryHelper(db, func(_ *dbutil.QueryHelper[*CustomEmoji]) *CustomEmoji {
+				return &CustomEmoji{}
+			}),
+		},
+		Outbox: &OutboxQuery{
+			BridgeID: bridgeID,
+			QueryHelper: dbutil.MakeQueryHelper(db, func(_ *dbutil.QueryHelper[*OutboxMessage]) *OutboxMessage {
+				return &OutboxMessage{}
+			}),
+		},
 	}
 }
 
@@ -0,0 +1,320 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mau.fi/util/dbutil"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+)
+
+// ExportFormatVersion is the version of the archive format written by [Database.Export].
+// Archives with a newer version can't be imported.
+const ExportFormatVersion = 1
+
+type ExportRecordType string
+
+const (
+	ExportRecordHeader              ExportRecordType = "header"
+	ExportRecordUser                ExportRecordType = "user"
+	ExportRecordUserLogin           ExportRecordType = "user_login"
+	ExportRecordPortal              ExportRecordType = "portal"
+	ExportRecordGhost               ExportRecordType = "ghost"
+	ExportRecordMessage             ExportRecordType = "message"
+	ExportRecordReaction            ExportRecordType = "reaction"
+	ExportRecordUserPortal          ExportRecordType = "user_portal"
+	ExportRecordDisappearingMessage ExportRecordType = "disappearing_message"
+	ExportRecordBackfillTask        ExportRecordType = "backfill_task"
+	ExportRecordKV                  ExportRecordType = "kv_store"
+	ExportRecordPublicMedia         ExportRecordType = "public_media"
+	ExportRecordCustomEmoji         ExportRecordType = "custom_emoji"
+	ExportRecordOutboxMessage       ExportRecordType = "outbox_message"
+)
+
+// ExportHeader is the first record in an exported archive.
+type ExportHeader struct {
+	FormatVersion int                `json:"format_version"`
+	BridgeID      networkid.BridgeID `json:"bridge_id"`
+	ExportedAt    time.Time          `json:"exported_at"`
+}
+
+// ExportKV is a single key-value pair in the kv_store table.
+type ExportKV struct {
+	Key   Key    `json:"key"`
+	Value string `json:"value"`
+}
+
+type exportRecord struct {
+	Type ExportRecordType `json:"type"`
+	Data any              `json:"data"`
+}
+
+type importRecord struct {
+	Type ExportRecordType `json:"type"`
+	Data json.RawMessage  `json:"data"`
+}
+
+var (
+	ErrImportTargetNotEmpty         = errors.New("target database already contains bridge data")
+	ErrImportMissingHeader          = errors.New("archive doesn't start with a header")
+	ErrImportUnsupportedVersion     = errors.New("unsupported archive format version")
+	ErrImportBridgeIDMismatch       = errors.New("archive was exported from a different bridge")
+	ErrImportUnknownRecordType      = errors.New("unknown record type")
+	ErrExportUnresolvablePortalTree = errors.New("failed to resolve portal parent order")
+)
+
+const (
+	exportAllUsersQuery               = getUserBaseQuery + `WHERE bridge_id=$1`
+	exportAllUserLoginsQuery          = getUserLoginBaseQuery + `WHERE bridge_id=$1`
+	exportAllGhostsQuery              = getGhostBaseQuery + `WHERE bridge_id=$1`
+	exportAllMessagesQuery            = getMessageBaseQuery + `WHERE bridge_id=$1 ORDER BY rowid`
+	exportAllReactionsQuery           = getReactionBaseQuery + `WHERE bridge_id=$1`
+	exportAllUserPortalsQuery         = getUserPortalBaseQuery + `WHERE bridge_id=$1`
+	exportAllDisappearingMessageQuery = `
+		SELECT bridge_id, mx_room, mxid, timestamp, type, timer, disappear_at
+		FROM disappearing_message WHERE bridge_id=$1
+	`
+	exportAllBackfillTasksQuery = `
+		SELECT
+			bridge_id, portal_id, portal_receiver, user_login_id, batch_count, is_done,
+			cursor, oldest_message_id, dispatched_at, completed_at, next_dispatch_min_ts
+		FROM backfill_task WHERE bridge_id=$1
+	`
+	exportAllKVQuery          = `SELECT key, value FROM kv_store WHERE bridge_id=$1`
+	exportAllPublicMediaQuery = `
+		SELECT bridge_id, public_id, mxc, keys, mimetype, expiry FROM public_media WHERE bridge_id=$1
+	`
+	exportAllCustomEmojiQuery = getCustomEmojiBaseQuery + `WHERE bridge_id=$1`
+	exportAllOutboxQuery      = getOutboxMessageBaseQuery + `WHERE bridge_id=$1 ORDER BY queued_at`
+	countExistingDataQuery    = `
+		SELECT (SELECT COUNT(*) FROM portal WHERE bridge_id=$1) + (SELECT COUNT(*) FROM user_login WHERE bridge_id=$1)
+	`
+)
+
+var scanExportKV = dbutil.ConvertRowFn[*ExportKV](func(row dbutil.Scannable) (*ExportKV, error) {
+	var kv ExportKV
+	return &kv, row.Scan(&kv.Key, &kv.Value)
+})
+
+func exportRows[T any](enc *json.Encoder, recordType ExportRecordType, iter dbutil.RowIter[T]) (count int, err error) {
+	err = iter.Iter(func(item T) (bool, error) {
+		count++
+		return true, enc.Encode(&exportRecord{Type: recordType, Data: item})
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to export %s: %w", recordType, err)
+	}
+	return
+}
+
+// sortPortalsForInsert orders portals so that parent portals always come before their children,
+// which is required by the foreign key on the parent columns.
+func sortPortalsForInsert(portals []*Portal) ([]*Portal, error) {
+	inserted := make(map[networkid.PortalKey]struct{}, len(portals))
+	sorted := make([]*Portal, 0, len(portals))
+	remaining := portals
+	for len(remaining) > 0 {
+		var next []*Portal
+		for _, portal := range remaining {
+			_, parentInserted := inserted[portal.ParentKey]
+			if portal.ParentKey.ID == "" || parentInserted {
+				inserted[portal.PortalKey] = struct{}{}
+				sorted = append(sorted, portal)
+			} else {
+				next = append(next, portal)
+			}
+		}
+		if len(next) == len(remaining) {
+			return nil, fmt.Errorf("%w: %d portals have missing parents", ErrExportUnresolvablePortalTree, len(next))
+		}
+		remaining = next
+	}
+	return sorted, nil
+}
+
+// Export writes all data of this bridge into the given writer as JSON lines. The first line is an [ExportHeader],
+// and the rest are records in an order that satisfies foreign keys when inserted sequentially.
+func (db *Database) Export(ctx context.Context, w io.Writer) error {
+	log := zerolog.Ctx(ctx)
+	enc := json.NewEncoder(w)
+	err := enc.Encode(&exportRecord{Type: ExportRecordHeader, Data: &ExportHeader{
+		FormatVersion: ExportFormatVersion,
+		BridgeID:      db.BridgeID,
+		ExportedAt:    time.Now(),
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	portals, err := db.Portal.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get portals: %w", err)
+	}
+	portals, err = sortPortalsForInsert(portals)
+	if err != nil {
+		return err
+	}
+	steps := []struct {
+		recordType ExportRecordType
+		export     func() (int, error)
+	}{
+		{ExportRecordUser, func() (int, error) {
+			return exportRows(enc, ExportRecordUser, db.User.QueryManyIter(ctx, exportAllUsersQuery, db.BridgeID))
+		}},
+		{ExportRecordUserLogin, func() (int, error) {
+			return exportRows(enc, ExportRecordUserLogin, db.UserLogin.QueryManyIter(ctx, exportAllUserLoginsQuery, db.BridgeID))
+		}},
+		{ExportRecordPortal, func() (int, error) {
+			return exportRows(enc, ExportRecordPortal, dbutil.NewSliceIter(portals))
+		}},
+		{ExportRecordGhost, func() (int, error) {
+			return exportRows(enc, ExportRecordGhost, db.Ghost.QueryManyIter(ctx, exportAllGhostsQuery, db.BridgeID))
+		}},
+		{ExportRecordMessage, func() (int, error) {
+			return exportRows(enc, ExportRecordMessage, db.Message.QueryManyIter(ctx, exportAllMessagesQuery, db.BridgeID))
+		}},
+		{ExportRecordReaction, func() (int, error) {
+			return exportRows(enc, ExportRecordReaction, db.Reaction.QueryManyIter(ctx, exportAllReactionsQuery, db.BridgeID))
+		}},
+		{ExportRecordUserPortal, func() (int, error) {
+			return exportRows(enc, ExportRecordUserPortal, db.UserPortal.QueryManyIter(ctx, exportAllUserPortalsQuery, db.BridgeID))
+		}},
+		{ExportRecordDisappearingMessage, func() (int, error) {
+			return exportRows(enc, ExportRecordDisappearingMessage, db.DisappearingMessage.QueryManyIter(ctx, exportAllDisappearingMessageQuery, db.BridgeID))
+		}},
+		{ExportRecordBackfillTask, func() (int, error) {
+			return exportRows(enc, ExportRecordBackfillTask, db.BackfillTask.QueryManyIter(ctx, exportAllBackfillTasksQuery, db.BridgeID))
+		}},
+		{ExportRecordKV, func() (int, error) {
+			return exportRows(enc, ExportRecordKV, scanExportKV.NewRowIter(db.Query(ctx, exportAllKVQuery, db.BridgeID)))
+		}},
+		{ExportRecordPublicMedia, func() (int, error) {
+			return exportRows(enc, ExportRecordPublicMedia, db.PublicMedia.QueryManyIter(ctx, exportAllPublicMediaQuery, db.BridgeID))
+		}},
+		{ExportRecordCustomEmoji, func() (int, error) {
+			return exportRows(enc, ExportRecordCustomEmoji, db.CustomEmoji.QueryManyIter(ctx, exportAllCustomEmojiQuery, db.BridgeID))
+		}},
+		{ExportRecordOutboxMessage, func() (int, error) {
+			return exportRows(enc, ExportRecordOutboxMessage, db.Outbox.QueryManyIter(ctx, exportAllOutboxQuery, db.BridgeID))
+		}},
+	}
+	for _, step := range steps {
+		count, err := step.export()
+		if err != nil {
+			return err
+		}
+		log.Debug().Str("record_type", string(step.recordType)).Int("count", count).Msg("Exported records")
+	}
+	return nil
+}
+
+func importInto[T any](ctx context.Context, data json.RawMessage, newFunc func() T, insert func(context.Context, T) error) error {
+	item := newFunc()
+	err := json.Unmarshal(data, item)
+	if err != nil {
+		return fmt.Errorf("failed to parse record: %w", err)
+	}
+	return insert(ctx, item)
+}
+
+// Import reads an archive written by [Database.Export] and inserts all the data into this database.
+// The database must not contain any data for this bridge yet. The import is done in a single transaction,
+// so a failed import won't leave partial data behind.
+//
+// Records are decoded and inserted one at a time, so the memory usage of the bridge process doesn't grow with
+// the size of the archive. However, the database has to hold the entire import as one uncommitted transaction:
+// on SQLite the journal or WAL file grows to roughly the size of the imported data, and on Postgres the new rows
+// are only visible once the whole archive has been imported.
+//
+// Metadata is parsed using the [MetaTypes] this database was created with,
+// so the same network connector must be used for exporting and importing.
+func (db *Database) Import(ctx context.Context, r io.Reader) error {
+	log := zerolog.Ctx(ctx)
+	dec := json.NewDecoder(r)
+	var header ExportHeader
+	var rec importRecord
+	if err := dec.Decode(&rec); err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	} else if rec.Type != ExportRecordHeader {
+		return ErrImportMissingHeader
+	} else if err = json.Unmarshal(rec.Data, &header); err != nil {
+		return fmt.Errorf("failed to parse header: %w", err)
+	} else if header.FormatVersion > ExportFormatVersion || header.FormatVersion < 1 {
+		return fmt.Errorf("%w %d", ErrImportUnsupportedVersion, header.FormatVersion)
+	} else if header.BridgeID != db.BridgeID {
+		return fmt.Errorf("%w (archive has %q, database has %q)", ErrImportBridgeIDMismatch, header.BridgeID, db.BridgeID)
+	}
+	log.Info().
+		Int("format_version", header.FormatVersion).
+		Time("exported_at", header.ExportedAt).
+		Msg("Importing bridge data")
+	return db.DoTxn(ctx, nil, func(ctx context.Context) error {
+		var existingCount int
+		err := db.QueryRow(ctx, countExistingDataQuery, db.BridgeID).Scan(&existingCount)
+		if err != nil {
+			return fmt.Errorf("failed to check if database is empty: %w", err)
+		} else if existingCount > 0 {
+			return ErrImportTargetNotEmpty
+		}
+		counts := make(map[ExportRecordType]int)
+		for lineNum := 2; ; lineNum++ {
+			rec = importRecord{}
+			err = dec.Decode(&rec)
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return fmt.Errorf("failed to read record #%d: %w", lineNum, err)
+			}
+			switch rec.Type {
+			case ExportRecordUser:
+				err = importInto(ctx, rec.Data, db.User.New, db.User.Insert)
+			case ExportRecordUserLogin:
+				err = importInto(ctx, rec.Data, db.UserLogin.New, db.UserLogin.Insert)
+			case ExportRecordPortal:
+				err = importInto(ctx, rec.Data, db.Portal.New, db.Portal.Insert)
+			case ExportRecordGhost:
+				err = importInto(ctx, rec.Data, db.Ghost.New, db.Ghost.Insert)
+			case ExportRecordMessage:
+				err = importInto(ctx, rec.Data, db.Message.New, db.Message.Insert)
+			case ExportRecordReaction:
+				err = importInto(ctx, rec.Data, db.Reaction.New, db.Reaction.Upsert)
+			case ExportRecordUserPortal:
+				err = importInto(ctx, rec.Data, db.UserPortal.New, db.UserPortal.Put)
+			case ExportRecordDisappearingMessage:
+				err = importInto(ctx, rec.Data, db.DisappearingMessage.New, db.DisappearingMessage.Put)
+			case ExportRecordBackfillTask:
+				err = importInto(ctx, rec.Data, db.BackfillTask.New, db.BackfillTask.Upsert)
+			case ExportRecordKV:
+				err = importInto(ctx, rec.Data, func() *ExportKV { return &ExportKV{} }, func(ctx context.Context, kv *ExportKV) error {
+					_, err := db.Exec(ctx, setKVQuery, db.BridgeID, kv.Key, kv.Value)
+					return err
+				})
+			case ExportRecordPublicMedia:
+				err = importInto(ctx, rec.Data, db.PublicMedia.New, db.PublicMedia.Put)
+			case ExportRecordCustomEmoji:
+				err = importInto(ctx, rec.Data, db.CustomEmoji.New, db.CustomEmoji.Put)
+			case ExportRecordOutboxMessage:
+				err = importInto(ctx, rec.Data, db.Outbox.New, db.Outbox.Insert)
+			default:
+				err = fmt.Errorf("%w %q", ErrImportUnknownRecordType, rec.Type)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to import record #%d (%s): %w", lineNum, rec.Type, err)
+			}
+			counts[rec.Type]++
+		}
+		log.Info().Any("counts", counts).Msg("Finished importing bridge data")
+		return nil
+	})
+}
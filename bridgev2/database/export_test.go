@@ -0,0 +1,262 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/util/dbutil"
+	"go.mau.fi/util/ptr"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/status"
+	"github.com/iKonoTelecomunicaciones/go/crypto/attachment"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+type testMeta struct {
+	Value string `json:"value"`
+}
+
+func newTestMeta() any {
+	return &testMeta{}
+}
+
+func newTestDatabase(t *testing.T, bridgeID networkid.BridgeID) *Database {
+	rawDB, err := sql.Open("sqlite3", ":memory:?_busy_timeout=5000&_foreign_keys=1")
+	require.NoError(t, err)
+	rawDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = rawDB.Close() })
+	db, err := dbutil.NewWithDB(rawDB, "sqlite3")
+	require.NoError(t, err)
+	mt := MetaTypes{Portal: newTestMeta, Ghost: newTestMeta, Message: newTestMeta, Reaction: newTestMeta, UserLogin: newTestMeta}
+	bridgeDB := New(bridgeID, mt, db)
+	require.NoError(t, bridgeDB.Upgrade(context.Background()))
+	return bridgeDB
+}
+
+func fillTestDatabase(t *testing.T, db *Database) {
+	ctx := context.Background()
+	now := time.UnixMilli(time.Now().UnixMilli())
+	space := networkid.PortalKey{ID: "space"}
+	portal := networkid.PortalKey{ID: "chat", Receiver: "login"}
+
+	require.NoError(t, db.User.Insert(ctx, &User{
+		MXID:           "@user:example.com",
+		ManagementRoom: "!management:example.com",
+		AccessToken:    "token",
+		IgnoredGhosts:  []networkid.UserID{"spammer"},
+	}))
+	require.NoError(t, db.UserLogin.Insert(ctx, &UserLogin{
+		UserMXID:      "@user:example.com",
+		ID:            "login",
+		RemoteName:    "User",
+		RemoteProfile: status.RemoteProfile{Name: "User"},
+		Metadata:      &testMeta{Value: "login"},
+	}))
+	// The child is inserted before its parent to make sure the export sorts portals by their parents
+	chat := &Portal{
+		PortalKey:       portal,
+		MXID:            "!chat:example.com",
+		Name:            "Chat",
+		NameSet:         true,
+		Disappear:       DisappearingSetting{Type: event.DisappearingTypeAfterSend, Timer: time.Hour},
+		RetentionMaxAge: 24 * time.Hour,
+		Metadata:        &testMeta{Value: "chat"},
+	}
+	require.NoError(t, db.Portal.Insert(ctx, chat))
+	require.NoError(t, db.Portal.Insert(ctx, &Portal{
+		PortalKey: space,
+		MXID:      "!space:example.com",
+		Name:      "Space",
+		RoomType:  RoomTypeSpace,
+		Metadata:  &testMeta{Value: "space"},
+	}))
+	chat.ParentKey = space
+	require.NoError(t, db.Portal.Update(ctx, chat))
+	require.NoError(t, db.Ghost.Insert(ctx, &Ghost{
+		ID:          "spammer",
+		Name:        "Spammer",
+		Identifiers: []string{"tel:+123"},
+		Metadata:    &testMeta{Value: "ghost"},
+	}))
+	require.NoError(t, db.Message.Insert(ctx, &Message{
+		ID:         "msg1",
+		MXID:       "$msg1",
+		Room:       portal,
+		SenderID:   "spammer",
+		SenderMXID: "@ghost:example.com",
+		Timestamp:  now,
+		Metadata:   &testMeta{Value: "message"},
+	}))
+	require.NoError(t, db.Reaction.Upsert(ctx, &Reaction{
+		Room:       portal,
+		MessageID:  "msg1",
+		SenderID:   "spammer",
+		SenderMXID: "@ghost:example.com",
+		EmojiID:    "emoji1",
+		Emoji:      "👍",
+		MXID:       "$reaction1",
+		Timestamp:  now,
+		Metadata:   &testMeta{Value: "reaction"},
+	}))
+	require.NoError(t, db.UserPortal.Put(ctx, &UserPortal{
+		UserMXID:  "@user:example.com",
+		LoginID:   "login",
+		Portal:    portal,
+		InSpace:   ptr.Ptr(true),
+		Preferred: ptr.Ptr(true),
+		LastRead:  now,
+	}))
+	require.NoError(t, db.DisappearingMessage.Put(ctx, &DisappearingMessage{
+		RoomID:    "!chat:example.com",
+		EventID:   "$msg1",
+		Timestamp: now,
+		DisappearingSetting: DisappearingSetting{
+			Type:        event.DisappearingTypeAfterSend,
+			Timer:       time.Hour,
+			DisappearAt: now.Add(time.Hour),
+		},
+	}))
+	require.NoError(t, db.BackfillTask.Upsert(ctx, &BackfillTask{
+		PortalKey:         portal,
+		UserLoginID:       "login",
+		BatchCount:        2,
+		Cursor:            "cursor",
+		OldestMessageID:   "msg1",
+		DispatchedAt:      now,
+		NextDispatchMinTS: now.Add(time.Minute),
+	}))
+	db.KV.Set(ctx, KeyEncryptionStateResynced, "true")
+	require.NoError(t, db.PublicMedia.Put(ctx, &PublicMedia{
+		PublicID: "public",
+		MXC:      id.ContentURI{Homeserver: "example.com", FileID: "media"},
+		Keys:     &attachment.EncryptedFile{Key: attachment.JSONWebKey{Key: "key"}, InitVector: "iv"},
+		MimeType: "image/png",
+		Expiry:   now.Add(time.Hour),
+	}))
+	require.NoError(t, db.CustomEmoji.Put(ctx, &CustomEmoji{
+		ID:        "emoji1",
+		PackID:    "pack1",
+		MXC:       "mxc://example.com/emoji",
+		Hash:      [32]byte{1, 2, 3},
+		Shortcode: "party",
+		MimeType:  "image/png",
+	}))
+	require.NoError(t, db.Outbox.Insert(ctx, &OutboxMessage{
+		EventID:     "$queued",
+		Portal:      portal,
+		UserLoginID: "login",
+		Sender:      "@user:example.com",
+		Event: &event.Event{
+			ID:      "$queued",
+			Type:    event.EventMessage,
+			Sender:  "@user:example.com",
+			RoomID:  "!chat:example.com",
+			Content: event.Content{Parsed: &event.MessageEventContent{MsgType: event.MsgText, Body: "queued"}},
+		},
+		QueuedAt:      now,
+		Attempts:      1,
+		NextAttemptAt: now.Add(time.Minute),
+		LastError:     "not connected",
+	}))
+}
+
+// exportRecords exports the database and returns the records without the header, which contains the export time.
+func exportRecords(t *testing.T, db *Database) (header ExportHeader, records []string) {
+	var buf bytes.Buffer
+	require.NoError(t, db.Export(context.Background(), &buf))
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.NotEmpty(t, lines)
+	var rec importRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+	require.Equal(t, ExportRecordHeader, rec.Type)
+	require.NoError(t, json.Unmarshal(rec.Data, &header))
+	return header, lines[1:]
+}
+
+func TestDatabase_ExportImport(t *testing.T) {
+	ctx := context.Background()
+	src := newTestDatabase(t, "test")
+	fillTestDatabase(t, src)
+
+	var archive bytes.Buffer
+	require.NoError(t, src.Export(ctx, &archive))
+	header, records := exportRecords(t, src)
+	assert.Equal(t, ExportFormatVersion, header.FormatVersion)
+	assert.Equal(t, networkid.BridgeID("test"), header.BridgeID)
+
+	seenTypes := make(map[ExportRecordType]bool)
+	for _, line := range records {
+		var rec importRecord
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		seenTypes[rec.Type] = true
+	}
+	for _, recordType := range []ExportRecordType{
+		ExportRecordUser, ExportRecordUserLogin, ExportRecordPortal, ExportRecordGhost, ExportRecordMessage,
+		ExportRecordReaction, ExportRecordUserPortal, ExportRecordDisappearingMessage, ExportRecordBackfillTask,
+		ExportRecordKV, ExportRecordPublicMedia, ExportRecordCustomEmoji, ExportRecordOutboxMessage,
+	} {
+		assert.True(t, seenTypes[recordType], "archive should contain %s records", recordType)
+	}
+
+	dst := newTestDatabase(t, "test")
+	require.NoError(t, dst.Import(ctx, bytes.NewReader(archive.Bytes())))
+	_, reexported := exportRecords(t, dst)
+	assert.Equal(t, records, reexported)
+
+	user, err := dst.User.GetByMXID(ctx, "@user:example.com")
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	assert.Equal(t, []networkid.UserID{"spammer"}, user.IgnoredGhosts)
+	portal, err := dst.Portal.GetByKey(ctx, networkid.PortalKey{ID: "chat", Receiver: "login"})
+	require.NoError(t, err)
+	require.NotNil(t, portal)
+	assert.Equal(t, networkid.PortalKey{ID: "space"}, portal.ParentKey)
+	assert.Equal(t, &testMeta{Value: "chat"}, portal.Metadata)
+	emoji, err := dst.CustomEmoji.GetByID(ctx, "emoji1")
+	require.NoError(t, err)
+	require.NotNil(t, emoji)
+	assert.Equal(t, "party", emoji.Shortcode)
+	assert.Equal(t, [32]byte{1, 2, 3}, emoji.Hash)
+	queued, err := dst.Outbox.GetByEventID(ctx, "$queued")
+	require.NoError(t, err)
+	require.NotNil(t, queued)
+	assert.Equal(t, "queued", queued.Event.Content.AsMessage().Body)
+
+	// Importing into a database that already has data must fail without changing anything
+	err = dst.Import(ctx, bytes.NewReader(archive.Bytes()))
+	assert.ErrorIs(t, err, ErrImportTargetNotEmpty)
+	other := newTestDatabase(t, "other")
+	err = other.Import(ctx, bytes.NewReader(archive.Bytes()))
+	assert.ErrorIs(t, err, ErrImportBridgeIDMismatch)
+}
+
+func TestDatabase_Import_RollbackOnError(t *testing.T) {
+	ctx := context.Background()
+	src := newTestDatabase(t, "test")
+	fillTestDatabase(t, src)
+	var archive bytes.Buffer
+	require.NoError(t, src.Export(ctx, &archive))
+	archive.WriteString(`{"type":"unknown","data":{}}` + "\n")
+
+	dst := newTestDatabase(t, "test")
+	err := dst.Import(ctx, &archive)
+	assert.ErrorIs(t, err, ErrImportUnknownRecordType)
+	_, records := exportRecords(t, dst)
+	assert.Empty(t, records)
+}
@@ -96,6 +96,25 @@ const (
 	deleteMessagePartByRowIDQuery = `
 		DELETE FROM message WHERE bridge_id=$1 AND rowid=$2
 	`
+	pruneMessagesOlderThanQuery = `
+		DELETE FROM message WHERE rowid IN (
+			SELECT rowid FROM message
+			WHERE bridge_id=$1 AND room_id=$2 AND room_receiver=$3 AND timestamp<$4 AND rowid<>(
+				SELECT rowid FROM message
+				WHERE bridge_id=$1 AND room_id=$2 AND room_receiver=$3
+				ORDER BY timestamp DESC, part_id DESC LIMIT 1
+			)
+			LIMIT $5
+		)
+	`
+	pruneExcessMessagesQuery = `
+		DELETE FROM message WHERE rowid IN (
+			SELECT rowid FROM message
+			WHERE bridge_id=$1 AND room_id=$2 AND room_receiver=$3
+			ORDER BY timestamp DESC, rowid DESC
+			LIMIT $5 OFFSET $4
+		)
+	`
 )
 
 func (mq *MessageQuery) GetAllPartsByID(ctx context.Context, receiver networkid.UserLoginID, id networkid.MessageID) ([]*Message, error) {
@@ -180,6 +199,28 @@ func (mq *MessageQuery) Delete(ctx context.Context, rowID int64) error {
 	return mq.Exec(ctx, deleteMessagePartByRowIDQuery, mq.BridgeID, rowID)
 }
 
+// PruneOlderThan deletes up to limit message parts in the given portal that are older than the given time.
+// Reactions to the deleted messages are deleted too.
+//
+// The newest part in the portal is never deleted, as it's used as the anchor for forward backfilling.
+func (mq *MessageQuery) PruneOlderThan(ctx context.Context, portal networkid.PortalKey, before time.Time, limit int) (int64, error) {
+	res, err := mq.GetDB().Exec(ctx, pruneMessagesOlderThanQuery, mq.BridgeID, portal.ID, portal.Receiver, before.UnixNano(), limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PruneExcess deletes up to limit of the oldest message parts in the given portal,
+// skipping the newest keep parts.
+func (mq *MessageQuery) PruneExcess(ctx context.Context, portal networkid.PortalKey, keep, limit int) (int64, error) {
+	res, err := mq.GetDB().Exec(ctx, pruneExcessMessagesQuery, mq.BridgeID, portal.ID, portal.Receiver, keep, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 func (mq *MessageQuery) CountMessagesInPortal(ctx context.Context, key networkid.PortalKey) (count int, err error) {
 	err = mq.GetDB().QueryRow(ctx, countMessagesInPortalQuery, mq.BridgeID, key.ID, key.Receiver).Scan(&count)
 	return
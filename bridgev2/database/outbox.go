@@ -0,0 +1,156 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"go.mau.fi/util/dbutil"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+type OutboxQuery struct {
+	BridgeID networkid.BridgeID
+	*dbutil.QueryHelper[*OutboxMessage]
+}
+
+// OutboxMessage is a Matrix event that couldn't be sent to the remote network because of a retriable error
+// and is waiting to be retried. The whole event is stored, so messages from encrypted rooms are stored decrypted.
+type OutboxMessage struct {
+	BridgeID    networkid.BridgeID
+	EventID     id.EventID
+	Portal      networkid.PortalKey
+	UserLoginID networkid.UserLoginID
+	Sender      id.UserID
+	Event       *event.Event
+
+	QueuedAt      time.Time
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+}
+
+const (
+	getOutboxMessageBaseQuery = `
+		SELECT bridge_id, event_id, portal_id, portal_receiver, user_login_id, sender_mxid, event,
+		       queued_at, attempts, next_attempt_at, last_error
+		FROM outbox_message
+	`
+	getOutboxMessageByEventIDQuery      = getOutboxMessageBaseQuery + `WHERE bridge_id=$1 AND event_id=$2`
+	getFirstOutboxMessageForPortalQuery = getOutboxMessageBaseQuery + `
+		WHERE bridge_id=$1 AND portal_id=$2 AND portal_receiver=$3
+		ORDER BY queued_at, event_id LIMIT 1
+	`
+	getDueOutboxMessagesQuery = getOutboxMessageBaseQuery + `
+		WHERE bridge_id=$1 AND next_attempt_at<=$2 AND queued_at=(
+			SELECT MIN(queued_at) FROM outbox_message head
+			WHERE head.bridge_id=outbox_message.bridge_id
+				AND head.portal_id=outbox_message.portal_id
+				AND head.portal_receiver=outbox_message.portal_receiver
+		)
+		ORDER BY queued_at
+	`
+	getExpiredOutboxMessagesQuery = getOutboxMessageBaseQuery + `
+		WHERE bridge_id=$1 AND queued_at<$2
+		ORDER BY queued_at
+	`
+	insertOutboxMessageQuery = `
+		INSERT INTO outbox_message (
+			bridge_id, event_id, portal_id, portal_receiver, user_login_id, sender_mxid, event,
+			queued_at, attempts, next_attempt_at, last_error
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	updateOutboxMessageQuery = `
+		UPDATE outbox_message SET attempts=$3, next_attempt_at=$4, last_error=$5
+		WHERE bridge_id=$1 AND event_id=$2
+	`
+	resetOutboxBackoffForLoginQuery = `
+		UPDATE outbox_message SET next_attempt_at=$3
+		WHERE bridge_id=$1 AND user_login_id=$2 AND next_attempt_at>$3
+	`
+	deleteOutboxMessageQuery = `
+		DELETE FROM outbox_message WHERE bridge_id=$1 AND event_id=$2
+	`
+)
+
+// GetByEventID returns the queued message with the given Matrix event ID, or nil if it's not in the outbox.
+func (oq *OutboxQuery) GetByEventID(ctx context.Context, eventID id.EventID) (*OutboxMessage, error) {
+	return oq.QueryOne(ctx, getOutboxMessageByEventIDQuery, oq.BridgeID, eventID)
+}
+
+// GetFirstForPortal returns the oldest queued message in the given portal, or nil if the portal has no queued messages.
+func (oq *OutboxQuery) GetFirstForPortal(ctx context.Context, portal networkid.PortalKey) (*OutboxMessage, error) {
+	return oq.QueryOne(ctx, getFirstOutboxMessageForPortalQuery, oq.BridgeID, portal.ID, portal.Receiver)
+}
+
+// GetDue returns the oldest queued message of every portal where that message is due to be retried.
+func (oq *OutboxQuery) GetDue(ctx context.Context) ([]*OutboxMessage, error) {
+	return oq.QueryMany(ctx, getDueOutboxMessagesQuery, oq.BridgeID, time.Now().UnixNano())
+}
+
+// GetQueuedBefore returns all messages that were queued before the given time.
+func (oq *OutboxQuery) GetQueuedBefore(ctx context.Context, cutoff time.Time) ([]*OutboxMessage, error) {
+	return oq.QueryMany(ctx, getExpiredOutboxMessagesQuery, oq.BridgeID, cutoff.UnixNano())
+}
+
+func (oq *OutboxQuery) Insert(ctx context.Context, msg *OutboxMessage) error {
+	ensureBridgeIDMatches(&msg.BridgeID, oq.BridgeID)
+	return oq.Exec(ctx, insertOutboxMessageQuery, msg.sqlVariables()...)
+}
+
+func (oq *OutboxQuery) Update(ctx context.Context, msg *OutboxMessage) error {
+	ensureBridgeIDMatches(&msg.BridgeID, oq.BridgeID)
+	return oq.Exec(
+		ctx, updateOutboxMessageQuery,
+		msg.BridgeID, msg.EventID, msg.Attempts, msg.NextAttemptAt.UnixNano(), dbutil.StrPtr(msg.LastError),
+	)
+}
+
+// ResetBackoffForLogin makes all messages queued by the given login due immediately.
+func (oq *OutboxQuery) ResetBackoffForLogin(ctx context.Context, loginID networkid.UserLoginID) error {
+	return oq.Exec(ctx, resetOutboxBackoffForLoginQuery, oq.BridgeID, loginID, time.Now().UnixNano())
+}
+
+func (oq *OutboxQuery) Delete(ctx context.Context, eventID id.EventID) error {
+	return oq.Exec(ctx, deleteOutboxMessageQuery, oq.BridgeID, eventID)
+}
+
+func (om *OutboxMessage) Scan(row dbutil.Scannable) (*OutboxMessage, error) {
+	var queuedAt, nextAttemptAt int64
+	var lastError sql.NullString
+	err := row.Scan(
+		&om.BridgeID, &om.EventID, &om.Portal.ID, &om.Portal.Receiver, &om.UserLoginID, &om.Sender,
+		dbutil.JSON{Data: &om.Event}, &queuedAt, &om.Attempts, &nextAttemptAt, &lastError,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if om.Event != nil {
+		om.Event.Type.Class = event.MessageEventType
+		if err = om.Event.Content.ParseRaw(om.Event.Type); err != nil && !errors.Is(err, event.ErrUnsupportedContentType) {
+			return nil, err
+		}
+	}
+	om.QueuedAt = time.Unix(0, queuedAt)
+	om.NextAttemptAt = time.Unix(0, nextAttemptAt)
+	om.LastError = lastError.String
+	return om, nil
+}
+
+func (om *OutboxMessage) sqlVariables() []any {
+	return []any{
+		om.BridgeID, om.EventID, om.Portal.ID, om.Portal.Receiver, om.UserLoginID, om.Sender,
+		dbutil.JSON{Data: om.Event}, om.QueuedAt.UnixNano(), om.Attempts, om.NextAttemptAt.UnixNano(),
+		dbutil.StrPtr(om.LastError),
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package database
+
+import (
+	"slices"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+)
+
+// PollResults contains the aggregated results of a poll bridged from the remote network.
+type PollResults struct {
+	// The text of each option, keyed by option ID.
+	Options map[string]string `json:"options,omitempty"`
+	// The order of the options in the poll.
+	OptionOrder []string `json:"option_order,omitempty"`
+	// The option IDs that each user has currently selected.
+	Votes map[networkid.UserID][]string `json:"votes,omitempty"`
+	Ended bool                          `json:"ended,omitempty"`
+}
+
+// SetVote replaces the selected options of the given user. An empty list removes the user's vote.
+func (pr *PollResults) SetVote(userID networkid.UserID, optionIDs []string) {
+	if len(optionIDs) == 0 {
+		delete(pr.Votes, userID)
+		return
+	}
+	if pr.Votes == nil {
+		pr.Votes = make(map[networkid.UserID][]string)
+	}
+	pr.Votes[userID] = optionIDs
+}
+
+// Counts returns the number of votes for each option ID.
+func (pr *PollResults) Counts() map[string]int {
+	counts := make(map[string]int, len(pr.OptionOrder))
+	for _, selected := range pr.Votes {
+		for _, optionID := range selected {
+			counts[optionID]++
+		}
+	}
+	return counts
+}
+
+// TopOptions returns the IDs of the options with the most votes and the number of votes they have.
+// Multiple options are returned if there's a tie. If nobody has voted, this returns nil.
+func (pr *PollResults) TopOptions() ([]string, int) {
+	counts := pr.Counts()
+	var top []string
+	var topCount int
+	order := pr.OptionOrder
+	if len(order) == 0 {
+		for optionID := range counts {
+			order = append(order, optionID)
+		}
+		slices.Sort(order)
+	}
+	for _, optionID := range order {
+		count := counts[optionID]
+		if count == 0 {
+			continue
+		} else if count > topCount {
+			top = []string{optionID}
+			topCount = count
+		} else if count == topCount {
+			top = append(top, optionID)
+		}
+	}
+	return top, topCount
+}
+
+// MetaWithPollResults is an optional interface for message metadata types. If the metadata of a remote poll message
+// implements it, the bridge will keep the aggregated results of the poll up to date in the database.
+//
+// The easiest way to implement this is embedding [PollMetadata] in the metadata struct.
+type MetaWithPollResults interface {
+	GetPollResults() *PollResults
+	SetPollResults(results *PollResults)
+}
+
+// PollMetadata is a simple implementation of [MetaWithPollResults] which can be embedded in message metadata structs.
+type PollMetadata struct {
+	Poll *PollResults `json:"poll,omitempty"`
+}
+
+var _ MetaWithPollResults = (*PollMetadata)(nil)
+
+func (pm *PollMetadata) GetPollResults() *PollResults {
+	return pm.Poll
+}
+
+func (pm *PollMetadata) SetPollResults(results *PollResults) {
+	pm.Poll = results
+}
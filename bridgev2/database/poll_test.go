@@ -0,0 +1,61 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+)
+
+func TestPollResults_TopOptions(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		order         []string
+		votes         map[networkid.UserID][]string
+		expectedTop   []string
+		expectedCount int
+	}{
+		{"NoVotes", []string{"a", "b"}, nil, nil, 0},
+		{"SingleWinner", []string{"a", "b", "c"}, map[networkid.UserID][]string{
+			"alice": {"b"}, "bob": {"b"}, "carol": {"a"},
+		}, []string{"b"}, 2},
+		{"TieKeepsOptionOrder", []string{"c", "b", "a"}, map[networkid.UserID][]string{
+			"alice": {"a"}, "bob": {"c"},
+		}, []string{"c", "a"}, 1},
+		{"MultipleSelections", []string{"a", "b"}, map[networkid.UserID][]string{
+			"alice": {"a", "b"}, "bob": {"b"},
+		}, []string{"b"}, 2},
+		{"NoOrderSortsIDs", nil, map[networkid.UserID][]string{
+			"alice": {"y"}, "bob": {"x"},
+		}, []string{"x", "y"}, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			results := &PollResults{OptionOrder: tc.order, Votes: tc.votes}
+			top, count := results.TopOptions()
+			assert.Equal(t, tc.expectedTop, top)
+			assert.Equal(t, tc.expectedCount, count)
+		})
+	}
+}
+
+func TestPollResults_SetVote(t *testing.T) {
+	results := &PollResults{OptionOrder: []string{"a", "b"}}
+	results.SetVote("alice", []string{"a"})
+	results.SetVote("bob", []string{"a"})
+	assert.Equal(t, map[string]int{"a": 2}, results.Counts())
+
+	// Voting again replaces the previous vote
+	results.SetVote("alice", []string{"b"})
+	assert.Equal(t, map[string]int{"a": 1, "b": 1}, results.Counts())
+
+	// An empty vote removes the user's vote entirely
+	results.SetVote("bob", nil)
+	assert.Equal(t, map[networkid.UserID][]string{"alice": {"b"}}, results.Votes)
+}
@@ -72,7 +72,11 @@ type Portal struct {
 	RoomType     RoomType
 	Disappear    DisappearingSetting
 	CapState     CapabilityState
-	Metadata     any
+	// Whether the portal is a pending chat invite or message request that the user hasn't accepted yet.
+	MessageRequest bool
+	// The max_lifetime from the m.room.retention state event in the portal room, or zero if it's not set.
+	RetentionMaxAge time.Duration
+	Metadata        any
 }
 
 const (
@@ -80,8 +84,8 @@ const (
 		SELECT bridge_id, id, receiver, mxid, parent_id, parent_receiver, relay_login_id, other_user_id,
 		       name, topic, avatar_id, avatar_hash, avatar_mxc,
 		       name_set, topic_set, avatar_set, name_is_custom, in_space,
-		       room_type, disappear_type, disappear_timer, cap_state,
-		       metadata
+		       room_type, disappear_type, disappear_timer, cap_state, message_request,
+		       retention_max_age, metadata
 		FROM portal
 	`
 	getPortalByKeyQuery                     = getPortalBaseQuery + `WHERE bridge_id=$1 AND id=$2 AND receiver=$3`
@@ -93,6 +97,12 @@ const (
 	getDMPortalQuery                        = getPortalBaseQuery + `WHERE bridge_id=$1 AND room_type='dm' AND receiver=$2 AND other_user_id=$3`
 	getAllPortalsQuery                      = getPortalBaseQuery + `WHERE bridge_id=$1`
 	getChildPortalsQuery                    = getPortalBaseQuery + `WHERE bridge_id=$1 AND parent_id=$2 AND parent_receiver=$3`
+	getMessageRequestPortalsForLoginQuery   = getPortalBaseQuery + `
+		WHERE bridge_id=$1 AND message_request=true AND mxid IS NOT NULL AND (receiver=$2 OR (receiver='' AND EXISTS(
+			SELECT 1 FROM user_portal WHERE user_portal.bridge_id=portal.bridge_id AND user_portal.login_id=$2
+			                                AND user_portal.portal_id=portal.id AND user_portal.portal_receiver=portal.receiver
+		)))
+	`
 
 	findPortalReceiverQuery = `SELECT id, receiver FROM portal WHERE bridge_id=$1 AND id=$2 AND (receiver=$3 OR receiver='') LIMIT 1`
 
@@ -102,10 +112,10 @@ const (
 			parent_id, parent_receiver, relay_login_id, other_user_id,
 			name, topic, avatar_id, avatar_hash, avatar_mxc,
 			name_set, avatar_set, topic_set, name_is_custom, in_space,
-			room_type, disappear_type, disappear_timer, cap_state,
-			metadata, relay_bridge_id
+			room_type, disappear_type, disappear_timer, cap_state, message_request,
+			retention_max_age, metadata, relay_bridge_id
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, cast($7 AS TEXT), $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23,
+			$1, $2, $3, $4, $5, $6, cast($7 AS TEXT), $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25,
 			CASE WHEN cast($7 AS TEXT) IS NULL THEN NULL ELSE $1 END
 		)
 	`
@@ -115,7 +125,8 @@ const (
 		    relay_login_id=cast($7 AS TEXT), relay_bridge_id=CASE WHEN cast($7 AS TEXT) IS NULL THEN NULL ELSE bridge_id END,
 		    other_user_id=$8, name=$9, topic=$10, avatar_id=$11, avatar_hash=$12, avatar_mxc=$13,
 		    name_set=$14, avatar_set=$15, topic_set=$16, name_is_custom=$17, in_space=$18,
-		    room_type=$19, disappear_type=$20, disappear_timer=$21, cap_state=$22, metadata=$23
+		    room_type=$19, disappear_type=$20, disappear_timer=$21, cap_state=$22, message_request=$23,
+		    retention_max_age=$24, metadata=$25
 		WHERE bridge_id=$1 AND id=$2 AND receiver=$3
 	`
 	deletePortalQuery = `
@@ -202,6 +213,10 @@ func (pq *PortalQuery) GetChildren(ctx context.Context, parentKey networkid.Port
 	return pq.QueryMany(ctx, getChildPortalsQuery, pq.BridgeID, parentKey.ID, parentKey.Receiver)
 }
 
+func (pq *PortalQuery) GetMessageRequestsForLogin(ctx context.Context, loginID networkid.UserLoginID) ([]*Portal, error) {
+	return pq.QueryMany(ctx, getMessageRequestPortalsForLoginQuery, pq.BridgeID, loginID)
+}
+
 func (pq *PortalQuery) ReID(ctx context.Context, oldID, newID networkid.PortalKey) error {
 	return pq.Exec(ctx, reIDPortalQuery, pq.BridgeID, oldID.ID, oldID.Receiver, newID.ID, newID.Receiver)
 }
@@ -242,7 +257,7 @@ func (pq *PortalQuery) UpdateSetRelayFromUser(ctx context.Context, loginID strin
 
 func (p *Portal) Scan(row dbutil.Scannable) (*Portal, error) {
 	var mxid, parentID, parentReceiver, relayLoginID, otherUserID, disappearType sql.NullString
-	var disappearTimer sql.NullInt64
+	var disappearTimer, retentionMaxAge sql.NullInt64
 	var avatarHash string
 	err := row.Scan(
 		&p.BridgeID, &p.ID, &p.Receiver, &mxid,
@@ -250,7 +265,7 @@ func (p *Portal) Scan(row dbutil.Scannable) (*Portal, error) {
 		&p.Name, &p.Topic, &p.AvatarID, &avatarHash, &p.AvatarMXC,
 		&p.NameSet, &p.TopicSet, &p.AvatarSet, &p.NameIsCustom, &p.InSpace,
 		&p.RoomType, &disappearType, &disappearTimer,
-		dbutil.JSON{Data: &p.CapState}, dbutil.JSON{Data: p.Metadata},
+		dbutil.JSON{Data: &p.CapState}, &p.MessageRequest, &retentionMaxAge, dbutil.JSON{Data: p.Metadata},
 	)
 	if err != nil {
 		return nil, err
@@ -267,6 +282,7 @@ func (p *Portal) Scan(row dbutil.Scannable) (*Portal, error) {
 			Timer: time.Duration(disappearTimer.Int64),
 		}
 	}
+	p.RetentionMaxAge = time.Duration(retentionMaxAge.Int64)
 	p.MXID = id.RoomID(mxid.String)
 	p.OtherUserID = networkid.UserID(otherUserID.String)
 	if parentID.Valid {
@@ -297,6 +313,6 @@ func (p *Portal) sqlVariables() []any {
 		p.Name, p.Topic, p.AvatarID, avatarHash, p.AvatarMXC,
 		p.NameSet, p.TopicSet, p.AvatarSet, p.NameIsCustom, p.InSpace,
 		p.RoomType, dbutil.StrPtr(p.Disappear.Type), dbutil.NumPtr(p.Disappear.Timer),
-		dbutil.JSON{Data: p.CapState}, dbutil.JSON{Data: p.Metadata},
+		dbutil.JSON{Data: p.CapState}, p.MessageRequest, dbutil.NumPtr(p.RetentionMaxAge), dbutil.JSON{Data: p.Metadata},
 	}
 }
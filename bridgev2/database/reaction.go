@@ -56,6 +56,13 @@ const (
 	deleteReactionQuery = `
 		DELETE FROM reaction WHERE bridge_id=$1 AND room_receiver=$2 AND message_id=$3 AND message_part_id=$4 AND sender_id=$5 AND emoji_id=$6
 	`
+	pruneReactionsOlderThanQuery = `
+		DELETE FROM reaction WHERE bridge_id=$1 AND mxid IN (
+			SELECT mxid FROM reaction
+			WHERE bridge_id=$1 AND room_id=$2 AND room_receiver=$3 AND timestamp<$4
+			LIMIT $5
+		)
+	`
 )
 
 func (rq *ReactionQuery) GetByID(ctx context.Context, receiver networkid.UserLoginID, messageID networkid.MessageID, messagePartID networkid.PartID, senderID networkid.UserID, emojiID networkid.EmojiID) (*Reaction, error) {
@@ -92,6 +99,15 @@ func (rq *ReactionQuery) Delete(ctx context.Context, reaction *Reaction) error {
 	return rq.Exec(ctx, deleteReactionQuery, reaction.BridgeID, reaction.Room.Receiver, reaction.MessageID, reaction.MessagePartID, reaction.SenderID, reaction.EmojiID)
 }
 
+// PruneOlderThan deletes up to limit reactions in the given portal that are older than the given time.
+func (rq *ReactionQuery) PruneOlderThan(ctx context.Context, portal networkid.PortalKey, before time.Time, limit int) (int64, error) {
+	res, err := rq.GetDB().Exec(ctx, pruneReactionsOlderThanQuery, rq.BridgeID, portal.ID, portal.Receiver, before.UnixNano(), limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 func (r *Reaction) Scan(row dbutil.Scannable) (*Reaction, error) {
 	var timestamp int64
 	err := row.Scan(
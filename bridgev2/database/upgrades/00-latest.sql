@@ -1,10 +1,11 @@
--- v0 -> v23 (compatible with v9+): Latest revision
+-- v0 -> v31 (compatible with v9+): Latest revision
 CREATE TABLE "user" (
 	bridge_id       TEXT NOT NULL,
 	mxid            TEXT NOT NULL,
 
 	management_room TEXT,
 	access_token    TEXT,
+	ignored_ghosts  jsonb,
 
 	PRIMARY KEY (bridge_id, mxid)
 );
@@ -52,6 +53,8 @@ CREATE TABLE portal (
 	disappear_type  TEXT,
 	disappear_timer BIGINT,
 	cap_state       jsonb,
+	message_request BOOLEAN NOT NULL DEFAULT false,
+	retention_max_age BIGINT,
 	metadata        jsonb   NOT NULL,
 
 	PRIMARY KEY (bridge_id, id, receiver),
@@ -122,6 +125,7 @@ CREATE TABLE message (
 	CONSTRAINT message_txn_id_unique UNIQUE (bridge_id, room_receiver, send_txn_id)
 );
 CREATE INDEX message_room_idx ON message (bridge_id, room_id, room_receiver);
+CREATE INDEX message_room_timestamp_idx ON message (bridge_id, room_id, room_receiver, timestamp);
 
 CREATE TABLE disappearing_message (
 	bridge_id    TEXT   NOT NULL,
@@ -167,6 +171,7 @@ CREATE TABLE reaction (
 	CONSTRAINT reaction_mxid_unique UNIQUE (bridge_id, mxid)
 );
 CREATE INDEX reaction_room_idx ON reaction (bridge_id, room_id, room_receiver);
+CREATE INDEX reaction_room_timestamp_idx ON reaction (bridge_id, room_id, room_receiver, timestamp);
 
 CREATE TABLE user_portal (
 	bridge_id       TEXT    NOT NULL,
@@ -227,3 +232,42 @@ CREATE TABLE public_media (
 
 	PRIMARY KEY (bridge_id, public_id)
 );
+
+CREATE TABLE custom_emoji (
+	bridge_id TEXT NOT NULL,
+	id        TEXT NOT NULL,
+	pack_id   TEXT NOT NULL DEFAULT '',
+	mxc       TEXT NOT NULL,
+	hash      TEXT NOT NULL DEFAULT '',
+	shortcode TEXT NOT NULL,
+	mimetype  TEXT,
+
+	PRIMARY KEY (bridge_id, id)
+);
+
+CREATE INDEX custom_emoji_mxc_idx ON custom_emoji (bridge_id, mxc);
+CREATE INDEX custom_emoji_pack_idx ON custom_emoji (bridge_id, pack_id);
+
+CREATE TABLE outbox_message (
+	bridge_id       TEXT    NOT NULL,
+	event_id        TEXT    NOT NULL,
+	portal_id       TEXT    NOT NULL,
+	portal_receiver TEXT    NOT NULL,
+	user_login_id   TEXT    NOT NULL,
+	sender_mxid     TEXT    NOT NULL,
+	event           jsonb   NOT NULL,
+	queued_at       BIGINT  NOT NULL,
+	attempts        INTEGER NOT NULL,
+	next_attempt_at BIGINT  NOT NULL,
+	last_error      TEXT,
+
+	PRIMARY KEY (bridge_id, event_id),
+	CONSTRAINT outbox_message_portal_fkey FOREIGN KEY (bridge_id, portal_id, portal_receiver)
+		REFERENCES portal (bridge_id, id, receiver)
+		ON DELETE CASCADE ON UPDATE CASCADE,
+	CONSTRAINT outbox_message_user_login_fkey FOREIGN KEY (bridge_id, user_login_id)
+		REFERENCES user_login (bridge_id, id)
+		ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX outbox_message_portal_idx ON outbox_message (bridge_id, portal_id, portal_receiver, queued_at);
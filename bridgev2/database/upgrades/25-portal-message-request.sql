@@ -0,0 +1,2 @@
+-- v25 (compatible with v9+): Save whether portals are pending message requests
+ALTER TABLE portal ADD COLUMN message_request BOOLEAN NOT NULL DEFAULT false;
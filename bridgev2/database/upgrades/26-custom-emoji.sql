@@ -0,0 +1,12 @@
+-- v26 (compatible with v9+): Cache reuploaded custom emojis
+CREATE TABLE custom_emoji (
+	bridge_id TEXT NOT NULL,
+	id        TEXT NOT NULL,
+	mxc       TEXT NOT NULL,
+	shortcode TEXT NOT NULL,
+	mimetype  TEXT,
+
+	PRIMARY KEY (bridge_id, id)
+);
+
+CREATE INDEX custom_emoji_mxc_idx ON custom_emoji (bridge_id, mxc);
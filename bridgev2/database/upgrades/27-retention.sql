@@ -0,0 +1,4 @@
+-- v27 (compatible with v9+): Add room retention and indexes for pruning old messages
+ALTER TABLE portal ADD COLUMN retention_max_age BIGINT;
+CREATE INDEX message_room_timestamp_idx ON message (bridge_id, room_id, room_receiver, timestamp);
+CREATE INDEX reaction_room_timestamp_idx ON reaction (bridge_id, room_id, room_receiver, timestamp);
@@ -0,0 +1,24 @@
+-- v28 (compatible with v9+): Add outbox for retrying outgoing Matrix messages
+CREATE TABLE outbox_message (
+	bridge_id       TEXT    NOT NULL,
+	event_id        TEXT    NOT NULL,
+	portal_id       TEXT    NOT NULL,
+	portal_receiver TEXT    NOT NULL,
+	user_login_id   TEXT    NOT NULL,
+	sender_mxid     TEXT    NOT NULL,
+	event           jsonb   NOT NULL,
+	queued_at       BIGINT  NOT NULL,
+	attempts        INTEGER NOT NULL,
+	next_attempt_at BIGINT  NOT NULL,
+	last_error      TEXT,
+
+	PRIMARY KEY (bridge_id, event_id),
+	CONSTRAINT outbox_message_portal_fkey FOREIGN KEY (bridge_id, portal_id, portal_receiver)
+		REFERENCES portal (bridge_id, id, receiver)
+		ON DELETE CASCADE ON UPDATE CASCADE,
+	CONSTRAINT outbox_message_user_login_fkey FOREIGN KEY (bridge_id, user_login_id)
+		REFERENCES user_login (bridge_id, id)
+		ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX outbox_message_portal_idx ON outbox_message (bridge_id, portal_id, portal_receiver, queued_at);
@@ -0,0 +1,2 @@
+-- v29 (compatible with v9+): Save ghosts in the ignored user list of users
+ALTER TABLE "user" ADD COLUMN ignored_ghosts jsonb;
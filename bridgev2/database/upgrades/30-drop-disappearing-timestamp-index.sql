@@ -0,0 +1,2 @@
+-- v30 (compatible with v9+): Drop unused disappearing message timestamp index
+DROP INDEX IF EXISTS disappearing_message_room_timestamp_idx;
@@ -0,0 +1,4 @@
+-- v31 (compatible with v9+): Save the pack and hash of custom emojis
+ALTER TABLE custom_emoji ADD COLUMN pack_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE custom_emoji ADD COLUMN hash TEXT NOT NULL DEFAULT '';
+CREATE INDEX custom_emoji_pack_idx ON custom_emoji (bridge_id, pack_id);
@@ -27,19 +27,21 @@ type User struct {
 
 	ManagementRoom id.RoomID
 	AccessToken    string
+	// The ghosts in the user's Matrix ignored user list that have been blocked on the remote network.
+	IgnoredGhosts []networkid.UserID
 }
 
 const (
 	getUserBaseQuery = `
-		SELECT bridge_id, mxid, management_room, access_token FROM "user"
+		SELECT bridge_id, mxid, management_room, access_token, ignored_ghosts FROM "user"
 	`
 	getUserByMXIDQuery = getUserBaseQuery + `WHERE bridge_id=$1 AND mxid=$2`
 	insertUserQuery    = `
-		INSERT INTO "user" (bridge_id, mxid, management_room, access_token)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO "user" (bridge_id, mxid, management_room, access_token, ignored_ghosts)
+		VALUES ($1, $2, $3, $4, $5)
 	`
 	updateUserQuery = `
-		UPDATE "user" SET management_room=$3, access_token=$4
+		UPDATE "user" SET management_room=$3, access_token=$4, ignored_ghosts=$5
 		WHERE bridge_id=$1 AND mxid=$2
 	`
 )
@@ -60,7 +62,7 @@ func (uq *UserQuery) Update(ctx context.Context, user *User) error {
 
 func (u *User) Scan(row dbutil.Scannable) (*User, error) {
 	var managementRoom, accessToken sql.NullString
-	err := row.Scan(&u.BridgeID, &u.MXID, &managementRoom, &accessToken)
+	err := row.Scan(&u.BridgeID, &u.MXID, &managementRoom, &accessToken, dbutil.JSON{Data: &u.IgnoredGhosts})
 	if err != nil {
 		return nil, err
 	}
@@ -70,5 +72,9 @@ func (u *User) Scan(row dbutil.Scannable) (*User, error) {
 }
 
 func (u *User) sqlVariables() []any {
-	return []any{u.BridgeID, u.MXID, dbutil.StrPtr(u.ManagementRoom), dbutil.StrPtr(u.AccessToken)}
+	var ignoredGhosts any
+	if u.IgnoredGhosts != nil {
+		ignoredGhosts = dbutil.JSON{Data: u.IgnoredGhosts}
+	}
+	return []any{u.BridgeID, u.MXID, dbutil.StrPtr(u.ManagementRoom), dbutil.StrPtr(u.AccessToken), ignoredGhosts}
 }
@@ -34,6 +34,11 @@ var ErrResolveIdentifierTryNext = errors.New("that identifier is not available v
 
 var ErrNotLoggedIn = errors.New("not logged in")
 
+// ErrRemoteNotConnected can be returned (or wrapped) by Matrix event handlers in [NetworkAPI] to indicate that
+// the event couldn't be sent because the connection to the remote network is down. If the outbox is enabled
+// in the bridge config, messages that fail with this error are stored and retried after the login reconnects.
+var ErrRemoteNotConnected = errors.New("not connected to the remote network")
+
 // ErrDirectMediaNotEnabled may be returned by Matrix connectors if [MatrixConnector.GenerateContentURI] is called,
 // but direct media is not enabled.
 var ErrDirectMediaNotEnabled = errors.New("direct media is not enabled")
@@ -60,6 +65,7 @@ var (
 	ErrInvalidStateKey                 error = WrapErrorInStatus(errors.New("room metadata state key is unset or non-empty")).WithErrorAsMessage().WithIsCertain(true).WithSendNotice(false)
 	ErrDatabaseError                   error = WrapErrorInStatus(errors.New("database error")).WithMessage("internal database error").WithIsCertain(true).WithSendNotice(true)
 	ErrTargetMessageNotFound           error = WrapErrorInStatus(errors.New("target message not found")).WithErrorAsMessage().WithIsCertain(true).WithSendNotice(false)
+	ErrTargetMessagePruned             error = WrapErrorInStatus(errors.New("target message not found, it may be too old")).WithErrorAsMessage().WithIsCertain(true).WithSendNotice(false).WithErrorReason(event.MessageStatusTooOld)
 	ErrUnsupportedMessageType          error = WrapErrorInStatus(errors.New("unsupported message type")).WithErrorAsMessage().WithIsCertain(true).WithSendNotice(true).WithErrorReason(event.MessageStatusUnsupported)
 	ErrUnsupportedMediaType            error = WrapErrorInStatus(errors.New("unsupported media type")).WithErrorAsMessage().WithIsCertain(true).WithSendNotice(true).WithErrorReason(event.MessageStatusUnsupported)
 	ErrMediaDurationTooLong            error = WrapErrorInStatus(errors.New("media duration too long")).WithErrorAsMessage().WithSendNotice(true).WithErrorReason(event.MessageStatusUnsupported)
@@ -74,6 +80,7 @@ var (
 	ErrPowerLevelsNotSupported         error = WrapErrorInStatus(errors.New("this bridge does not support changing group power levels")).WithIsCertain(true).WithErrorAsMessage().WithSendNotice(false).WithErrorReason(event.MessageStatusUnsupported)
 	ErrRemoteEchoTimeout                     = WrapErrorInStatus(errors.New("remote echo timed out")).WithIsCertain(false).WithSendNotice(true).WithErrorReason(event.MessageStatusTooOld)
 	ErrRemoteAckTimeout                      = WrapErrorInStatus(errors.New("remote ack timed out")).WithIsCertain(false).WithSendNotice(true).WithErrorReason(event.MessageStatusTooOld)
+	ErrOutboxMessageExpired                  = WrapErrorInStatus(errors.New("gave up retrying message")).WithIsCertain(true).WithErrorAsMessage().WithSendNotice(true).WithStatus(event.MessageStatusFail).WithErrorReason(event.MessageStatusTooOld)
 
 	ErrPublicMediaDisabled         = WrapErrorInStatus(errors.New("public media is not enabled in the bridge config")).WithIsCertain(true).WithErrorAsMessage().WithErrorReason(event.MessageStatusUnsupported)
 	ErrPublicMediaDatabaseDisabled = WrapErrorInStatus(errors.New("public media database storage is disabled")).WithIsCertain(true).WithErrorAsMessage().WithErrorReason(event.MessageStatusUnsupported)
@@ -87,6 +94,24 @@ var (
 	ErrInvalidLoginFlowID error = RespError(mautrix.MNotFound.WithMessage("Invalid login flow ID"))
 )
 
+// Room upgrade errors
+var (
+	ErrRoomUpgradeNotSupported error = RespError(mautrix.MUnrecognized.WithMessage("This bridge does not support upgrading rooms"))
+	ErrPortalHasNoRoom         error = RespError(mautrix.MBadState.WithMessage("This portal does not have a Matrix room").WithStatus(http.StatusBadRequest))
+)
+
+// Message request errors
+var (
+	ErrMessageRequestsNotSupported error = RespError(mautrix.MUnrecognized.WithMessage("This bridge does not support responding to message requests"))
+	ErrNotMessageRequest           error = RespError(mautrix.MBadState.WithMessage("This chat is not a pending message request").WithStatus(http.StatusBadRequest))
+)
+
+// Reporting and blocking errors
+var (
+	ErrReportingNotSupported error = RespError(mautrix.MUnrecognized.WithMessage("This bridge does not support reporting or blocking users"))
+	ErrReportTargetNotFound  error = RespError(mautrix.MNotFound.WithMessage("Reported message not found"))
+)
+
 // RespError is a class of error that certain network interface methods can return to ensure that the error
 // is properly translated into an HTTP error when the method is called via the provisioning API.
 //
@@ -73,8 +73,10 @@ type Connector struct {
 	uploadSema     *semaphore.Weighted
 	dmaSigKey      [32]byte
 	pubMediaSigKey []byte
+	metrics        *bridgeMetrics
 
 	doublePuppetIntents *exsync.Map[id.UserID, *appservice.IntentAPI]
+	processedImages     *exsync.Map[id.ContentURIString, *processedImage]
 
 	deterministicEventIDServer string
 
@@ -117,6 +119,7 @@ func NewConnector(cfg *bridgeconfig.Config) *Connector {
 	c.uploadSema = semaphore.NewWeighted(c.MediaConfig.UploadSize + 1)
 	c.Capabilities = &bridgev2.MatrixCapabilities{}
 	c.doublePuppetIntents = exsync.NewMap[id.UserID, *appservice.IntentAPI]()
+	c.processedImages = exsync.NewMap[id.ContentURIString, *processedImage]()
 	return c
 }
 
@@ -147,10 +150,15 @@ func (br *Connector) Init(bridge *bridgev2.Bridge) {
 	br.EventProcessor.On(event.StateRoomAvatar, br.handleRoomEvent)
 	br.EventProcessor.On(event.StateTopic, br.handleRoomEvent)
 	br.EventProcessor.On(event.StateTombstone, br.handleRoomEvent)
+	br.EventProcessor.On(event.StateRetention, br.handleRoomEvent)
 	br.EventProcessor.On(event.StateBeeperDisappearingTimer, br.handleRoomEvent)
 	br.EventProcessor.On(event.BeeperDeleteChat, br.handleRoomEvent)
 	br.EventProcessor.On(event.EphemeralEventReceipt, br.handleEphemeralEvent)
 	br.EventProcessor.On(event.EphemeralEventTyping, br.handleEphemeralEvent)
+	br.EventProcessor.On(event.EphemeralEventPresence, br.handleEphemeralEvent)
+	br.EventProcessor.On(event.AccountDataIgnoredUserList, br.handleAccountDataEvent)
+	// Homeservers that push account data of double puppeted users to appservices put it in the ephemeral section
+	br.EventProcessor.On(event.Type{Type: event.AccountDataIgnoredUserList.Type, Class: event.EphemeralEventType}, br.handleAccountDataEvent)
 	br.Bot = br.AS.BotIntent()
 	br.Crypto = NewCryptoHelper(br)
 	br.Bridge.Commands.(*commands.Processor).AddHandlers(
@@ -164,6 +172,7 @@ func (br *Connector) Init(bridge *bridgev2.Bridge) {
 
 func (br *Connector) Start(ctx context.Context) error {
 	br.Provisioning.Init()
+	br.initMetrics()
 	err := br.initDirectMedia()
 	if err != nil {
 		return err
@@ -75,7 +75,9 @@ func (br *Connector) sendCryptoStatusError(ctx context.Context, evt *event.Event
 		SendNotice:    true,
 		RetryNum:      retryNum,
 	}
-	if !isFinal {
+	if isFinal {
+		br.trackDecryptionFailure(err)
+	} else {
 		ms.Status = event.MessageStatusPending
 		// Don't send notice for first error
 		if retryNum == 0 {
@@ -0,0 +1,128 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package matrix
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/thumbnail"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// processedImageTTL is how long the metadata of an uploaded image is kept while waiting for a message that uses it.
+const processedImageTTL = 30 * time.Minute
+
+type processedImage struct {
+	info      *event.FileInfo
+	createdAt time.Time
+}
+
+func (as *ASIntent) shouldProcessImage(mimeType string, size int64) bool {
+	cfg := &as.Connector.Config.Matrix.ImageProcessing
+	return cfg.Enabled && thumbnail.IsSupported(mimeType) && (cfg.MaxFileSize <= 0 || size <= cfg.MaxFileSize)
+}
+
+// processImage extracts the dimensions and blurhash of an image that is about to be uploaded,
+// and uploads a thumbnail of it if the image is large. Errors are logged and result in a nil return value,
+// as the metadata is optional and shouldn't prevent the image itself from being uploaded.
+func (as *ASIntent) processImage(ctx context.Context, roomID id.RoomID, r io.Reader) *event.FileInfo {
+	log := zerolog.Ctx(ctx)
+	cfg := &as.Connector.Config.Matrix.ImageProcessing
+	res, err := thumbnail.Process(r, cfg.ThumbnailSize, cfg.MaxPixels)
+	if errors.Is(err, thumbnail.ErrUnsupportedFormat) {
+		log.Debug().Msg("Not processing uploaded image as the format isn't supported")
+		return nil
+	} else if errors.Is(err, thumbnail.ErrImageTooLarge) {
+		log.Debug().Err(err).Msg("Not processing uploaded image as it's too large")
+		return nil
+	} else if err != nil {
+		log.Warn().Err(err).Msg("Failed to process uploaded image")
+		return nil
+	}
+	info := &event.FileInfo{
+		Width:        res.Width,
+		Height:       res.Height,
+		Blurhash:     res.Blurhash,
+		AnoaBlurhash: res.Blurhash,
+	}
+	if res.Thumbnail != nil {
+		info.ThumbnailURL, info.ThumbnailFile, err = as.uploadMedia(ctx, roomID, res.Thumbnail, "", res.ThumbnailMimeType)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to upload thumbnail of image")
+		} else {
+			info.ThumbnailInfo = &event.FileInfo{
+				MimeType: res.ThumbnailMimeType,
+				Width:    res.ThumbnailWidth,
+				Height:   res.ThumbnailHeight,
+				Size:     len(res.Thumbnail),
+			}
+		}
+	}
+	return info
+}
+
+// rememberImageInfo stores the metadata of an uploaded image so that it can be filled into the message
+// that uses the image in [ASIntent.SendMessage].
+func (as *ASIntent) rememberImageInfo(url id.ContentURIString, file *event.EncryptedFileInfo, info *event.FileInfo) {
+	if info == nil {
+		return
+	}
+	if file != nil {
+		url = file.URL
+	}
+	if url == "" {
+		return
+	}
+	now := time.Now()
+	for key, val := range as.Connector.processedImages.CopyData() {
+		if now.Sub(val.createdAt) > processedImageTTL {
+			as.Connector.processedImages.Delete(key)
+		}
+	}
+	as.Connector.processedImages.Set(url, &processedImage{info: info, createdAt: now})
+}
+
+// fillImageInfo fills the dimensions, blurhash and thumbnail of an image message from the metadata
+// that was generated when the image was uploaded. Fields that are already set are not changed.
+func (as *ASIntent) fillImageInfo(eventType event.Type, content *event.MessageEventContent) {
+	if content.MsgType != event.MsgImage && eventType != event.EventSticker {
+		return
+	}
+	url := content.URL
+	if content.File != nil {
+		url = content.File.URL
+	}
+	if url == "" {
+		return
+	}
+	// The info isn't removed here, as the same image may be sent again (e.g. when a message is resent).
+	// Old entries are removed by rememberImageInfo after processedImageTTL.
+	processed, ok := as.Connector.processedImages.Get(url)
+	if !ok || time.Since(processed.createdAt) > processedImageTTL {
+		return
+	}
+	info := content.GetInfo()
+	if info.Width == 0 && info.Height == 0 {
+		info.Width = processed.info.Width
+		info.Height = processed.info.Height
+	}
+	if info.Blurhash == "" && info.AnoaBlurhash == "" {
+		info.Blurhash = processed.info.Blurhash
+		info.AnoaBlurhash = processed.info.AnoaBlurhash
+	}
+	if info.ThumbnailURL == "" && info.ThumbnailFile == nil && info.ThumbnailInfo == nil {
+		info.ThumbnailURL = processed.info.ThumbnailURL
+		info.ThumbnailFile = processed.info.ThumbnailFile
+		info.ThumbnailInfo = processed.info.ThumbnailInfo
+	}
+}
@@ -43,6 +43,10 @@ type ASIntent struct {
 
 var _ bridgev2.MatrixAPI = (*ASIntent)(nil)
 var _ bridgev2.MarkAsDMMatrixAPI = (*ASIntent)(nil)
+var _ bridgev2.RoomUpgradingMatrixAPI = (*ASIntent)(nil)
+var _ bridgev2.PresenceMatrixAPI = (*ASIntent)(nil)
+var _ bridgev2.ImagePackMatrixAPI = (*ASIntent)(nil)
+var _ bridgev2.IgnoredUserListMatrixAPI = (*ASIntent)(nil)
 
 func (as *ASIntent) SendMessage(ctx context.Context, roomID id.RoomID, eventType event.Type, content *event.Content, extra *bridgev2.MatrixSendExtra) (*mautrix.RespSendEvent, error) {
 	if extra == nil {
@@ -61,6 +65,7 @@ func (as *ASIntent) SendMessage(ctx context.Context, roomID id.RoomID, eventType
 		msgContent, ok := content.Parsed.(*event.MessageEventContent)
 		if ok {
 			msgContent.AddPerMessageProfileFallback()
+			as.fillImageInfo(eventType, msgContent)
 		}
 		if encrypted, err := as.Matrix.StateStore.IsEncrypted(ctx, roomID); err != nil {
 			return nil, fmt.Errorf("failed to check if room is encrypted: %w", err)
@@ -273,6 +278,18 @@ func (as *ASIntent) DownloadMediaToFile(ctx context.Context, uri id.ContentURISt
 }
 
 func (as *ASIntent) UploadMedia(ctx context.Context, roomID id.RoomID, data []byte, fileName, mimeType string) (url id.ContentURIString, file *event.EncryptedFileInfo, err error) {
+	var imageInfo *event.FileInfo
+	if roomID != "" && as.shouldProcessImage(mimeType, int64(len(data))) {
+		imageInfo = as.processImage(ctx, roomID, bytes.NewReader(data))
+	}
+	url, file, err = as.uploadMedia(ctx, roomID, data, fileName, mimeType)
+	if err == nil {
+		as.rememberImageInfo(url, file, imageInfo)
+	}
+	return
+}
+
+func (as *ASIntent) uploadMedia(ctx context.Context, roomID id.RoomID, data []byte, fileName, mimeType string) (url id.ContentURIString, file *event.EncryptedFileInfo, err error) {
 	if int64(len(data)) > as.Connector.MediaConfig.UploadSize {
 		return "", nil, fmt.Errorf("file too large (%.2f MB > %.2f MB)", float64(len(data))/1000/1000, float64(as.Connector.MediaConfig.UploadSize)/1000/1000)
 	}
@@ -378,6 +395,17 @@ func (as *ASIntent) UploadMediaStream(
 			return
 		}
 	}
+	var imageInfo *event.FileInfo
+	if roomID != "" {
+		if stat, statErr := replFile.Stat(); statErr == nil && as.shouldProcessImage(res.MimeType, stat.Size()) {
+			imageInfo = as.processImage(ctx, roomID, replFile)
+			_, err = replFile.Seek(0, io.SeekStart)
+			if err != nil {
+				err = fmt.Errorf("failed to seek to start of temp file after processing image: %w", err)
+				return
+			}
+		}
+	}
 	if file != nil {
 		res.FileName = ""
 		res.MimeType = "application/octet-stream"
@@ -429,6 +457,9 @@ func (as *ASIntent) UploadMediaStream(
 		file.URL = url
 		url = ""
 	}
+	if err == nil {
+		as.rememberImageInfo(url, file, imageInfo)
+	}
 	return
 }
 
@@ -543,6 +574,43 @@ func (as *ASIntent) CreateRoom(ctx context.Context, req *mautrix.ReqCreateRoom)
 	return resp.RoomID, nil
 }
 
+func (as *ASIntent) UpgradeRoom(ctx context.Context, roomID id.RoomID, newVersion id.RoomVersion) (id.RoomID, error) {
+	resp, err := as.Matrix.UpgradeRoom(ctx, roomID, &mautrix.ReqUpgradeRoom{NewVersion: newVersion})
+	if err != nil {
+		return "", err
+	}
+	err = as.Matrix.CopyRoomState(ctx, roomID, resp.ReplacementRoom, mautrix.RoomUpgradeExtraStateTypes...)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).
+			Stringer("replacement_room_id", resp.ReplacementRoom).
+			Msg("Failed to copy extra state to replacement room")
+	}
+	return resp.ReplacementRoom, nil
+}
+
+func (as *ASIntent) SetPresence(ctx context.Context, presence event.Presence, statusMsg string) error {
+	if as.Matrix.IsCustomPuppet {
+		// Don't override the real user's presence, it's managed by their own clients
+		return nil
+	}
+	err := as.Matrix.EnsureRegistered(ctx)
+	if err != nil {
+		return err
+	}
+	return as.Matrix.SetPresence(ctx, mautrix.ReqPresence{
+		Presence:  presence,
+		StatusMsg: statusMsg,
+	})
+}
+
+func (as *ASIntent) SetUserImagePack(ctx context.Context, content *event.ImagePackEventContent) error {
+	return as.Matrix.SetAccountData(ctx, event.AccountDataImagePack.Type, content)
+}
+
+func (as *ASIntent) GetIgnoredUsers(ctx context.Context) (map[id.UserID]event.IgnoredUser, error) {
+	return as.Matrix.GetIgnoredUsers(ctx)
+}
+
 func (as *ASIntent) MarkAsDM(ctx context.Context, roomID id.RoomID, withUser id.UserID) error {
 	if !as.Connector.Config.Matrix.SyncDirectChatList {
 		return nil
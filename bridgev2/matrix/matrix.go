@@ -68,7 +68,20 @@ func (br *Connector) handleEphemeralEvent(ctx context.Context, evt *event.Event)
 	case event.EphemeralEventTyping:
 		typingContent := evt.Content.AsTyping()
 		typingContent.UserIDs = slices.DeleteFunc(typingContent.UserIDs, br.shouldIgnoreEventFromUser)
+	case event.EphemeralEventPresence:
+		if br.shouldIgnoreEventFromUser(evt.Sender) {
+			return
+		}
+	}
+	br.Bridge.QueueMatrixEvent(ctx, evt)
+}
+
+func (br *Connector) handleAccountDataEvent(ctx context.Context, evt *event.Event) {
+	// Account data pushed to appservices has the owner of the data as the sender
+	if evt.Sender == "" || br.shouldIgnoreEventFromUser(evt.Sender) {
+		return
 	}
+	evt.Type.Class = event.AccountDataEventType
 	br.Bridge.QueueMatrixEvent(ctx, evt)
 }
 
@@ -0,0 +1,317 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package matrix
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mau.fi/util/exstrings"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2"
+	"github.com/iKonoTelecomunicaciones/go/event"
+)
+
+var _ bridgev2.MatrixConnectorWithMetrics = (*Connector)(nil)
+
+const metricsNamespace = "bridge"
+
+var eventDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+type bridgeMetrics struct {
+	registry *prometheus.Registry
+
+	matrixEvents        *prometheus.CounterVec
+	matrixEventDuration *prometheus.HistogramVec
+	remoteEvents        *prometheus.CounterVec
+	remoteEventDuration *prometheus.HistogramVec
+	backfillMessages    *prometheus.CounterVec
+	backfillDuration    *prometheus.HistogramVec
+	backfillTasks       *prometheus.CounterVec
+	decryptionFailures  *prometheus.CounterVec
+}
+
+func newBridgeMetrics(br *Connector) *bridgeMetrics {
+	bm := &bridgeMetrics{
+		registry: prometheus.NewRegistry(),
+		matrixEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "matrix_events_total",
+			Help:      "Number of Matrix events handled by portals",
+		}, []string{"event_type", "result"}),
+		matrixEventDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "matrix_event_duration_seconds",
+			Help:      "Time taken to handle Matrix events in portals",
+			Buckets:   eventDurationBuckets,
+		}, []string{"event_type"}),
+		remoteEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "remote_events_total",
+			Help:      "Number of remote network events handled by portals",
+		}, []string{"event_type", "result"}),
+		remoteEventDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "remote_event_duration_seconds",
+			Help:      "Time taken to handle remote network events in portals",
+			Buckets:   eventDurationBuckets,
+		}, []string{"event_type"}),
+		backfillMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "backfill_messages_total",
+			Help:      "Number of messages sent to Matrix by backfill",
+		}, []string{"direction"}),
+		backfillDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "backfill_batch_duration_seconds",
+			Help:      "Time taken to send backfill batches to Matrix",
+			Buckets:   eventDurationBuckets,
+		}, []string{"direction"}),
+		backfillTasks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "backfill_queue_tasks_total",
+			Help:      "Number of backfill queue tasks dispatched",
+		}, []string{"result"}),
+		decryptionFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "decryption_failures_total",
+			Help:      "Number of incoming Matrix events that couldn't be decrypted",
+		}, []string{"reason"}),
+	}
+	bm.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		bm.matrixEvents,
+		bm.matrixEventDuration,
+		bm.remoteEvents,
+		bm.remoteEventDuration,
+		bm.backfillMessages,
+		bm.backfillDuration,
+		bm.backfillTasks,
+		bm.decryptionFailures,
+		&bridgeStateCollector{br: br},
+	)
+	return bm
+}
+
+func (br *Connector) initMetrics() {
+	if !br.Config.Metrics.Enabled {
+		return
+	}
+	br.metrics = newBridgeMetrics(br)
+	handler := promhttp.HandlerFor(br.metrics.registry, promhttp.HandlerOpts{})
+	br.AS.Router.Handle("GET /metrics", br.metricsAuthMiddleware(handler))
+	br.Log.Debug().Msg("Enabled metrics endpoint at /metrics")
+}
+
+func (br *Connector) metricsAuthMiddleware(h http.Handler) http.Handler {
+	secret := br.Config.Metrics.SharedSecret
+	if secret == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if auth == "" {
+			mautrix.MMissingToken.WithMessage("Missing auth token").Write(w)
+		} else if !exstrings.ConstantTimeEqual(auth, secret) {
+			mautrix.MUnknownToken.WithMessage("Invalid auth token").Write(w)
+		} else {
+			h.ServeHTTP(w, r)
+		}
+	})
+}
+
+func resultToMetricLabel(res bridgev2.EventHandlingResult) string {
+	switch {
+	case res.Success:
+		return "success"
+	case res.Ignored:
+		return "ignored"
+	case res.Queued:
+		return "queued"
+	default:
+		return "failed"
+	}
+}
+
+// knownMatrixEventTypes contains the event types that portals handle. Event types are chosen by Matrix users,
+// so any other type is reported as "other" to keep the number of metric series bounded.
+var knownMatrixEventTypes = map[string]struct{}{
+	event.EventMessage.Type:                 {},
+	event.EventSticker.Type:                 {},
+	event.EventReaction.Type:                {},
+	event.EventRedaction.Type:               {},
+	event.EventEncrypted.Type:               {},
+	event.EventUnstablePollStart.Type:       {},
+	event.EventUnstablePollResponse.Type:    {},
+	event.StateRoomName.Type:                {},
+	event.StateTopic.Type:                   {},
+	event.StateRoomAvatar.Type:              {},
+	event.StateBeeperDisappearingTimer.Type: {},
+	event.StateEncryption.Type:              {},
+	event.StateMember.Type:                  {},
+	event.StatePowerLevels.Type:             {},
+	event.StateTombstone.Type:               {},
+	event.StateRetention.Type:               {},
+	event.AccountDataMarkedUnread.Type:      {},
+	event.AccountDataRoomTags.Type:          {},
+	event.AccountDataBeeperMute.Type:        {},
+	event.EphemeralEventReceipt.Type:        {},
+	event.EphemeralEventTyping.Type:         {},
+	event.BeeperDeleteChat.Type:             {},
+}
+
+func matrixEventTypeToMetricLabel(evtType event.Type) string {
+	if _, ok := knownMatrixEventTypes[evtType.Type]; ok {
+		return evtType.Type
+	}
+	return "other"
+}
+
+func (br *Connector) TrackMatrixEvent(evtType event.Type, res bridgev2.EventHandlingResult, duration time.Duration) {
+	if br.metrics == nil {
+		return
+	}
+	typeLabel := matrixEventTypeToMetricLabel(evtType)
+	br.metrics.matrixEvents.WithLabelValues(typeLabel, resultToMetricLabel(res)).Inc()
+	br.metrics.matrixEventDuration.WithLabelValues(typeLabel).Observe(duration.Seconds())
+}
+
+func (br *Connector) TrackRemoteEvent(evtType bridgev2.RemoteEventType, res bridgev2.EventHandlingResult, duration time.Duration) {
+	if br.metrics == nil {
+		return
+	}
+	br.metrics.remoteEvents.WithLabelValues(evtType.String(), resultToMetricLabel(res)).Inc()
+	br.metrics.remoteEventDuration.WithLabelValues(evtType.String()).Observe(duration.Seconds())
+}
+
+func (br *Connector) TrackBackfill(direction bridgev2.BackfillDirection, messageCount int, duration time.Duration) {
+	if br.metrics == nil {
+		return
+	}
+	br.metrics.backfillMessages.WithLabelValues(string(direction)).Add(float64(messageCount))
+	br.metrics.backfillDuration.WithLabelValues(string(direction)).Observe(duration.Seconds())
+}
+
+func (br *Connector) TrackBackfillTask(completed bool, err error) {
+	if br.metrics == nil {
+		return
+	}
+	result := "completed"
+	if err != nil {
+		result = "failed"
+	} else if !completed {
+		result = "canceled"
+	}
+	br.metrics.backfillTasks.WithLabelValues(result).Inc()
+}
+
+func decryptionErrorToMetricLabel(err error) string {
+	var withheld *event.RoomKeyWithheldEventContent
+	switch {
+	case errors.Is(err, errNoCrypto):
+		return "no_crypto"
+	case errors.Is(err, errDeviceNotTrusted):
+		return "device_not_trusted"
+	case errors.Is(err, errNoDecryptionKeys), errors.Is(err, NoSessionFound):
+		return "no_session"
+	case errors.Is(err, UnknownMessageIndex):
+		return "unknown_message_index"
+	case errors.Is(err, DuplicateMessageIndex):
+		return "duplicate_message_index"
+	case errors.As(err, &withheld):
+		return "withheld"
+	case errors.Is(err, errMessageNotEncrypted):
+		return "not_encrypted"
+	default:
+		return "other"
+	}
+}
+
+func (br *Connector) trackDecryptionFailure(err error) {
+	if br.metrics == nil {
+		return
+	}
+	br.metrics.decryptionFailures.WithLabelValues(decryptionErrorToMetricLabel(err)).Inc()
+}
+
+// bridgeStateCollector collects gauges that are computed from the bridge state at scrape time.
+type bridgeStateCollector struct {
+	br *Connector
+}
+
+var (
+	loadedPortalsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "loaded_portals"),
+		"Number of portals loaded in memory", nil, nil,
+	)
+	portalQueueDepthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "portal_event_queue_depth"),
+		"Total number of events waiting in portal event queues", nil, nil,
+	)
+	portalQueueMaxDepthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "portal_event_queue_max_depth"),
+		"Highest number of events waiting in a single portal event queue", nil, nil,
+	)
+	loginStatesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "logins"),
+		"Number of user logins loaded in memory by bridge state", []string{"state"}, nil,
+	)
+	backfillQueueTasksDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "backfill_queue_tasks"),
+		"Number of tasks in the backfill queue by status", []string{"status"}, nil,
+	)
+	backfillQueueBatchesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "backfill_queue_batches"),
+		"Total number of batches backfilled by all backfill queue tasks", nil, nil,
+	)
+)
+
+func (bsc *bridgeStateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- loadedPortalsDesc
+	ch <- portalQueueDepthDesc
+	ch <- portalQueueMaxDepthDesc
+	ch <- loginStatesDesc
+	ch <- backfillQueueTasksDesc
+	ch <- backfillQueueBatchesDesc
+}
+
+func (bsc *bridgeStateCollector) Collect(ch chan<- prometheus.Metric) {
+	queueStats := bsc.br.Bridge.GetEventQueueStats()
+	ch <- prometheus.MustNewConstMetric(loadedPortalsDesc, prometheus.GaugeValue, float64(queueStats.LoadedPortals))
+	ch <- prometheus.MustNewConstMetric(portalQueueDepthDesc, prometheus.GaugeValue, float64(queueStats.QueuedEvents))
+	ch <- prometheus.MustNewConstMetric(portalQueueMaxDepthDesc, prometheus.GaugeValue, float64(queueStats.MaxQueuedEvents))
+	for state, count := range bsc.br.Bridge.GetLoginStateCounts() {
+		stateLabel := string(state)
+		if stateLabel == "" {
+			stateLabel = "UNKNOWN"
+		}
+		ch <- prometheus.MustNewConstMetric(loginStatesDesc, prometheus.GaugeValue, float64(count), stateLabel)
+	}
+	if !bsc.br.Config.Backfill.Queue.Enabled {
+		return
+	}
+	ctx, cancel := context.WithTimeout(bsc.br.Bridge.BackgroundCtx, 5*time.Second)
+	defer cancel()
+	backfillStats, err := bsc.br.Bridge.DB.BackfillTask.GetStats(ctx)
+	if err != nil {
+		bsc.br.Log.Warn().Err(err).Str("component", "metrics").Msg("Failed to get backfill queue stats")
+		ch <- prometheus.NewInvalidMetric(backfillQueueTasksDesc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(backfillQueueTasksDesc, prometheus.GaugeValue, float64(backfillStats.Pending), "pending")
+	ch <- prometheus.MustNewConstMetric(backfillQueueTasksDesc, prometheus.GaugeValue, float64(backfillStats.Done), "done")
+	ch <- prometheus.MustNewConstMetric(backfillQueueTasksDesc, prometheus.GaugeValue, float64(backfillStats.Total-backfillStats.Done-backfillStats.Pending), "paused")
+	ch <- prometheus.MustNewConstMetric(backfillQueueBatchesDesc, prometheus.GaugeValue, float64(backfillStats.Batches))
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mxmain
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+// runDataTransfer handles the --export-data and --import-data flags. The database schema is upgraded first,
+// so that archives are always written and read using the latest schema. This always exits the process.
+//
+// Imports are done in a single database transaction, see the Import method of the bridgev2 database for the disk usage implications.
+func (br *BridgeMain) runDataTransfer(exportPath, importPath string) {
+	if exportPath != "" && importPath != "" {
+		_, _ = fmt.Fprintln(os.Stderr, "--export-data and --import-data can't be used at the same time")
+		os.Exit(1)
+	}
+	ctx := br.Log.WithContext(context.Background())
+	err := br.Bridge.DB.Upgrade(ctx)
+	if err != nil {
+		br.LogDBUpgradeErrorAndExit("main", err, "Failed to initialize database")
+	}
+	if exportPath != "" {
+		err = br.exportData(ctx, exportPath)
+	} else {
+		err = br.importData(ctx, importPath)
+	}
+	if err != nil {
+		br.Log.WithLevel(zerolog.FatalLevel).Err(err).Msg("Failed to transfer bridge data")
+		os.Exit(31)
+	}
+	_ = br.DB.Close()
+	os.Exit(0)
+}
+
+func (br *BridgeMain) exportData(ctx context.Context, path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	err = br.Bridge.DB.Export(ctx, writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	closeErr := file.Close()
+	if err != nil {
+		_ = os.Remove(path)
+		return err
+	} else if closeErr != nil {
+		return fmt.Errorf("failed to close export file: %w", closeErr)
+	}
+	br.Log.Info().Str("path", path).Msg("Exported bridge data")
+	return nil
+}
+
+func (br *BridgeMain) importData(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer file.Close()
+	err = br.Bridge.DB.Import(ctx, bufio.NewReader(file))
+	if err != nil {
+		return err
+	}
+	br.Log.Info().Str("path", path).Msg("Imported bridge data")
+	return nil
+}
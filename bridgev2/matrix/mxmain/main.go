@@ -27,6 +27,7 @@ import (
 	"go.mau.fi/util/exerrors"
 	"go.mau.fi/util/exzerolog"
 	"go.mau.fi/util/progver"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"gopkg.in/yaml.v3"
 	flag "maunium.net/go/mauflag"
 
@@ -47,6 +48,8 @@ var versionJSON = flag.Make().LongKey("version-json").Usage("Print a JSON object
 var ignoreUnsupportedDatabase = flag.Make().LongKey("ignore-unsupported-database").Usage("Run even if the database schema is too new").Default("false").Bool()
 var ignoreForeignTables = flag.Make().LongKey("ignore-foreign-tables").Usage("Run even if the database contains tables from other programs (like Synapse)").Default("false").Bool()
 var ignoreUnsupportedServer = flag.Make().LongKey("ignore-unsupported-server").Usage("Run even if the Matrix homeserver is outdated").Default("false").Bool()
+var exportDataPath = flag.Make().LongKey("export-data").Usage("Export all bridge data in the database to the given path as JSON lines and quit.").String()
+var importDataPath = flag.Make().LongKey("import-data").Usage("Import bridge data from an archive created with --export-data into an empty database and quit.").String()
 var wantHelp, _ = flag.MakeHelpFlag()
 
 // BridgeMain contains the main function for a Matrix bridge.
@@ -90,7 +93,8 @@ type BridgeMain struct {
 	RegistrationPath string
 	SaveConfig       bool
 
-	ver progver.ProgramVersion
+	ver            progver.ProgramVersion
+	tracerProvider *sdktrace.TracerProvider
 
 	AdditionalShortFlags string
 	AdditionalLongFlags  string
@@ -231,6 +235,7 @@ func (br *BridgeMain) Init() {
 		Str("go_version", runtime.Version()).
 		Msg("Initializing bridge")
 
+	br.initTracing()
 	br.initDB()
 	br.Matrix = matrix.NewConnector(br.Config)
 	br.Matrix.OnWebsocketReplaced = func() {
@@ -252,6 +257,9 @@ func (br *BridgeMain) Init() {
 	if br.PostInit != nil {
 		br.PostInit()
 	}
+	if *exportDataPath != "" || *importDataPath != "" {
+		br.runDataTransfer(*exportDataPath, *importDataPath)
+	}
 }
 
 func (br *BridgeMain) initDB() {
@@ -305,6 +313,8 @@ func (br *BridgeMain) validateConfig() error {
 		return errors.New("bridge.permissions not configured")
 	case !strings.Contains(br.Config.AppService.FormatUsername("1234567890"), "1234567890"):
 		return errors.New("username template is missing user ID placeholder")
+	case br.Config.Tracing.SampleRate < 0 || br.Config.Tracing.SampleRate > 1:
+		return errors.New("tracing.sample_rate must be between 0 and 1")
 	default:
 		cfgValidator, ok := br.Connector.(bridgev2.ConfigValidatingNetwork)
 		if ok {
@@ -408,6 +418,9 @@ func (br *BridgeMain) TriggerStop(exitCode int) {
 // Stop cleanly stops the bridge. This is called by [Run] and does not need to be called manually.
 func (br *BridgeMain) Stop() {
 	br.Bridge.StopWithTimeout(5 * time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	br.stopTracing(ctx)
 }
 
 // InitVersion formats the bridge version and build time nicely for things like
@@ -0,0 +1,64 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mxmain
+
+import (
+	"context"
+	"os"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func (br *BridgeMain) initTracing() {
+	cfg := br.Config.Tracing
+	if !cfg.Enabled {
+		return
+	}
+	exporter, err := otlptracehttp.New(
+		context.Background(),
+		otlptracehttp.WithEndpointURL(cfg.Endpoint),
+		otlptracehttp.WithHeaders(cfg.Headers),
+	)
+	if err != nil {
+		br.Log.WithLevel(zerolog.FatalLevel).Err(err).Msg("Failed to initialize tracing exporter")
+		os.Exit(23)
+	}
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = br.Name
+	}
+	br.tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", serviceName),
+			attribute.String("service.version", br.ver.FormattedVersion),
+		)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRate))),
+	)
+	otel.SetTracerProvider(br.tracerProvider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	br.Log.Info().
+		Str("endpoint", cfg.Endpoint).
+		Float64("sample_rate", cfg.SampleRate).
+		Msg("Initialized tracing")
+}
+
+func (br *BridgeMain) stopTracing(ctx context.Context) {
+	if br.tracerProvider == nil {
+		return
+	}
+	err := br.tracerProvider.Shutdown(ctx)
+	if err != nil {
+		br.Log.Warn().Err(err).Msg("Failed to flush traces")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/pprof"
 	"strings"
@@ -128,9 +129,17 @@ func (prov *ProvisioningAPI) Init() {
 	prov.Router.HandleFunc("GET /v3/logins", prov.GetLogins)
 	prov.Router.HandleFunc("GET /v3/contacts", prov.GetContactList)
 	prov.Router.HandleFunc("POST /v3/search_users", prov.PostSearchUsers)
+	prov.Router.HandleFunc("POST /v3/search_messages", prov.PostSearchMessages)
 	prov.Router.HandleFunc("GET /v3/resolve_identifier/{identifier}", prov.GetResolveIdentifier)
 	prov.Router.HandleFunc("POST /v3/create_dm/{identifier}", prov.PostCreateDM)
 	prov.Router.HandleFunc("POST /v3/create_group/{type}", prov.PostCreateGroup)
+	prov.Router.HandleFunc("POST /v3/upgrade_room/{roomID}", prov.PostUpgradeRoom)
+	prov.Router.HandleFunc("GET /v3/message_requests", prov.GetMessageRequests)
+	prov.Router.HandleFunc("POST /v3/message_requests/{roomID}/accept", prov.PostAcceptMessageRequest)
+	prov.Router.HandleFunc("POST /v3/message_requests/{roomID}/reject", prov.PostRejectMessageRequest)
+	prov.Router.HandleFunc("POST /v3/report/{roomID}", prov.PostReport)
+	prov.Router.HandleFunc("POST /v3/block/{userID}", prov.PostBlockUser)
+	prov.Router.HandleFunc("POST /v3/unblock/{userID}", prov.PostUnblockUser)
 
 	if prov.br.Config.Provisioning.EnableSessionTransfers {
 		prov.log.Debug().Msg("Enabling session transfer API")
@@ -658,6 +667,37 @@ func (prov *ProvisioningAPI) PostSearchUsers(w http.ResponseWriter, r *http.Requ
 	exhttp.WriteJSONResponse(w, http.StatusOK, resp)
 }
 
+type ReqSearchMessages struct {
+	Query string `json:"query"`
+	// Optional room ID to limit the search to a single portal.
+	RoomID id.RoomID                  `json:"room_id,omitempty"`
+	Cursor networkid.PaginationCursor `json:"cursor,omitempty"`
+	Limit  int                        `json:"limit,omitempty"`
+}
+
+func (prov *ProvisioningAPI) PostSearchMessages(w http.ResponseWriter, r *http.Request) {
+	var req ReqSearchMessages
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Err(err).Msg("Failed to decode request body")
+		mautrix.MNotJSON.WithMessage("Failed to decode request body").Write(w)
+		return
+	} else if req.Query == "" {
+		mautrix.MBadJSON.WithMessage("Search query is required").Write(w)
+		return
+	}
+	login := prov.GetLoginForRequest(w, r)
+	if login == nil {
+		return
+	}
+	resp, err := provisionutil.SearchMessages(r.Context(), login, req.Query, req.RoomID, req.Cursor, req.Limit)
+	if err != nil {
+		RespondWithError(w, err, "Internal error searching messages")
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
 func (prov *ProvisioningAPI) GetResolveIdentifier(w http.ResponseWriter, r *http.Request) {
 	prov.doResolveIdentifier(w, r, false)
 }
@@ -687,6 +727,129 @@ func (prov *ProvisioningAPI) PostCreateGroup(w http.ResponseWriter, r *http.Requ
 	exhttp.WriteJSONResponse(w, http.StatusOK, resp)
 }
 
+type ReqUpgradeRoom struct {
+	NewVersion id.RoomVersion `json:"new_version"`
+}
+
+func (prov *ProvisioningAPI) PostUpgradeRoom(w http.ResponseWriter, r *http.Request) {
+	var req ReqUpgradeRoom
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		zerolog.Ctx(r.Context()).Err(err).Msg("Failed to decode request body")
+		mautrix.MNotJSON.WithMessage("Failed to decode request body").Write(w)
+		return
+	}
+	if req.NewVersion == "" {
+		req.NewVersion = id.RoomV12
+	} else if !req.NewVersion.IsKnown() {
+		mautrix.MUnsupportedRoomVersion.WithMessage("Unknown room version %q", req.NewVersion).WithStatus(http.StatusBadRequest).Write(w)
+		return
+	}
+	user := prov.GetUser(r)
+	if !user.Permissions.Admin {
+		mautrix.MForbidden.WithMessage("Only bridge admins can upgrade rooms").Write(w)
+		return
+	}
+	portal, err := prov.br.Bridge.GetPortalByMXID(r.Context(), id.RoomID(r.PathValue("roomID")))
+	if err != nil {
+		RespondWithError(w, err, "Internal error getting portal")
+		return
+	} else if portal == nil {
+		mautrix.MNotFound.WithMessage("Portal not found").Write(w)
+		return
+	}
+	newRoomID, err := portal.UpgradeRoom(r.Context(), req.NewVersion, user)
+	if err != nil {
+		RespondWithError(w, err, "Internal error upgrading room")
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespUpgradeRoom{ReplacementRoom: newRoomID})
+}
+
+func (prov *ProvisioningAPI) GetMessageRequests(w http.ResponseWriter, r *http.Request) {
+	login := prov.GetLoginForRequest(w, r)
+	if login == nil {
+		return
+	}
+	resp, err := provisionutil.GetMessageRequests(r.Context(), login)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Err(err).Msg("Failed to get message requests")
+		RespondWithError(w, err, "Internal error getting message requests")
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (prov *ProvisioningAPI) PostAcceptMessageRequest(w http.ResponseWriter, r *http.Request) {
+	prov.doRespondToMessageRequest(w, r, true)
+}
+
+func (prov *ProvisioningAPI) PostRejectMessageRequest(w http.ResponseWriter, r *http.Request) {
+	prov.doRespondToMessageRequest(w, r, false)
+}
+
+func (prov *ProvisioningAPI) doRespondToMessageRequest(w http.ResponseWriter, r *http.Request, accept bool) {
+	login := prov.GetLoginForRequest(w, r)
+	if login == nil {
+		return
+	}
+	err := provisionutil.RespondToMessageRequest(r.Context(), login, id.RoomID(r.PathValue("roomID")), accept)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Err(err).Bool("accept", accept).Msg("Failed to respond to message request")
+		RespondWithError(w, err, "Internal error responding to message request")
+		return
+	}
+	exhttp.WriteEmptyJSONResponse(w, http.StatusOK)
+}
+
+type ReqReport struct {
+	EventID id.EventID `json:"event_id,omitempty"`
+	Reason  string     `json:"reason,omitempty"`
+}
+
+func (prov *ProvisioningAPI) PostReport(w http.ResponseWriter, r *http.Request) {
+	var req ReqReport
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		zerolog.Ctx(r.Context()).Err(err).Msg("Failed to decode request body")
+		mautrix.MNotJSON.WithMessage("Failed to decode request body").Write(w)
+		return
+	}
+	login := prov.GetLoginForRequest(w, r)
+	if login == nil {
+		return
+	}
+	err = provisionutil.Report(r.Context(), login, id.RoomID(r.PathValue("roomID")), req.EventID, req.Reason)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Err(err).Msg("Failed to report chat")
+		RespondWithError(w, err, "Internal error reporting chat")
+		return
+	}
+	exhttp.WriteEmptyJSONResponse(w, http.StatusOK)
+}
+
+func (prov *ProvisioningAPI) PostBlockUser(w http.ResponseWriter, r *http.Request) {
+	prov.doSetUserBlocked(w, r, true)
+}
+
+func (prov *ProvisioningAPI) PostUnblockUser(w http.ResponseWriter, r *http.Request) {
+	prov.doSetUserBlocked(w, r, false)
+}
+
+func (prov *ProvisioningAPI) doSetUserBlocked(w http.ResponseWriter, r *http.Request, blocked bool) {
+	login := prov.GetLoginForRequest(w, r)
+	if login == nil {
+		return
+	}
+	err := provisionutil.SetUserBlocked(r.Context(), login, networkid.UserID(r.PathValue("userID")), blocked)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Err(err).Bool("blocked", blocked).Msg("Failed to change user block status")
+		RespondWithError(w, err, "Internal error changing user block status")
+		return
+	}
+	exhttp.WriteEmptyJSONResponse(w, http.StatusOK)
+}
+
 type ReqExportCredentials struct {
 	RemoteID networkid.UserLoginID `json:"remote_id"`
 }
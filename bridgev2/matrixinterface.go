@@ -93,6 +93,13 @@ type MatrixConnectorWithAnalytics interface {
 	TrackAnalytics(userID id.UserID, event string, properties map[string]any)
 }
 
+type MatrixConnectorWithMetrics interface {
+	TrackMatrixEvent(evtType event.Type, res EventHandlingResult, duration time.Duration)
+	TrackRemoteEvent(evtType RemoteEventType, res EventHandlingResult, duration time.Duration)
+	TrackBackfill(direction BackfillDirection, messageCount int, duration time.Duration)
+	TrackBackfillTask(completed bool, err error)
+}
+
 type DirectNotificationData struct {
 	Portal    *Portal
 	Sender    *Ghost
@@ -187,6 +194,22 @@ type StreamOrderReadingMatrixAPI interface {
 	MarkStreamOrderRead(ctx context.Context, roomID id.RoomID, streamOrder int64, ts time.Time) error
 }
 
+type RoomUpgradingMatrixAPI interface {
+	UpgradeRoom(ctx context.Context, roomID id.RoomID, newVersion id.RoomVersion) (id.RoomID, error)
+}
+
+type PresenceMatrixAPI interface {
+	SetPresence(ctx context.Context, presence event.Presence, statusMsg string) error
+}
+
+type IgnoredUserListMatrixAPI interface {
+	GetIgnoredUsers(ctx context.Context) (map[id.UserID]event.IgnoredUser, error)
+}
+
+type ImagePackMatrixAPI interface {
+	SetUserImagePack(ctx context.Context, content *event.ImagePackEventContent) error
+}
+
 type MarkAsDMMatrixAPI interface {
 	MarkAsDM(ctx context.Context, roomID id.RoomID, otherUser id.UserID) error
 }
@@ -0,0 +1,95 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"time"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/status"
+	"github.com/iKonoTelecomunicaciones/go/event"
+)
+
+func (br *Bridge) getMetricsTracker() MatrixConnectorWithMetrics {
+	tracker, _ := br.Matrix.(MatrixConnectorWithMetrics)
+	return tracker
+}
+
+func (br *Bridge) TrackMatrixEvent(evtType event.Type, res EventHandlingResult, duration time.Duration) {
+	if tracker := br.getMetricsTracker(); tracker != nil {
+		tracker.TrackMatrixEvent(evtType, res, duration)
+	}
+}
+
+func (br *Bridge) TrackRemoteEvent(evtType RemoteEventType, res EventHandlingResult, duration time.Duration) {
+	if tracker := br.getMetricsTracker(); tracker != nil {
+		tracker.TrackRemoteEvent(evtType, res, duration)
+	}
+}
+
+// BackfillDirection is the kind of backfill batch reported to [MatrixConnectorWithMetrics.TrackBackfill].
+type BackfillDirection string
+
+const (
+	BackfillDirectionForward  BackfillDirection = "forward"
+	BackfillDirectionBackward BackfillDirection = "backward"
+	BackfillDirectionThread   BackfillDirection = "thread"
+)
+
+func getBackfillDirection(forward, inThread bool) BackfillDirection {
+	if inThread {
+		return BackfillDirectionThread
+	} else if forward {
+		return BackfillDirectionForward
+	}
+	return BackfillDirectionBackward
+}
+
+func (br *Bridge) TrackBackfill(direction BackfillDirection, messageCount int, duration time.Duration) {
+	if tracker := br.getMetricsTracker(); tracker != nil {
+		tracker.TrackBackfill(direction, messageCount, duration)
+	}
+}
+
+func (br *Bridge) TrackBackfillTask(completed bool, err error) {
+	if tracker := br.getMetricsTracker(); tracker != nil {
+		tracker.TrackBackfillTask(completed, err)
+	}
+}
+
+type EventQueueStats struct {
+	// The number of portals currently loaded in memory.
+	LoadedPortals int
+	// The total number of events waiting in portal event queues.
+	QueuedEvents int
+	// The highest number of events waiting in a single portal's queue.
+	MaxQueuedEvents int
+}
+
+// GetEventQueueStats returns the current state of the event queues of all portals that are loaded in memory.
+func (br *Bridge) GetEventQueueStats() EventQueueStats {
+	br.cacheLock.Lock()
+	defer br.cacheLock.Unlock()
+	stats := EventQueueStats{LoadedPortals: len(br.portalsByKey)}
+	for _, portal := range br.portalsByKey {
+		queued := len(portal.events)
+		stats.QueuedEvents += queued
+		stats.MaxQueuedEvents = max(stats.MaxQueuedEvents, queued)
+	}
+	return stats
+}
+
+// GetLoginStateCounts returns the number of user logins loaded in memory in each bridge state.
+// Logins that haven't sent any bridge state yet are counted under an empty state event.
+func (br *Bridge) GetLoginStateCounts() map[status.BridgeStateEvent]int {
+	br.cacheLock.Lock()
+	defer br.cacheLock.Unlock()
+	counts := make(map[status.BridgeStateEvent]int)
+	for _, login := range br.userLoginsByID {
+		counts[login.BridgeState.GetPrev().StateEvent]++
+	}
+	return counts
+}
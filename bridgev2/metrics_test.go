@@ -0,0 +1,39 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type metricsTestMatrix struct {
+	testMatrixConnector
+	MatrixConnectorWithMetrics
+	backfills map[BackfillDirection]int
+}
+
+func (m *metricsTestMatrix) TrackBackfill(direction BackfillDirection, messageCount int, _ time.Duration) {
+	m.backfills[direction] += messageCount
+}
+
+func TestGetBackfillDirection(t *testing.T) {
+	assert.Equal(t, BackfillDirectionForward, getBackfillDirection(true, false))
+	assert.Equal(t, BackfillDirectionBackward, getBackfillDirection(false, false))
+	// Thread backfill is always sent forward, but it's reported separately from forward backfill of the main timeline
+	assert.Equal(t, BackfillDirectionThread, getBackfillDirection(true, true))
+}
+
+func TestBridge_TrackBackfill(t *testing.T) {
+	matrix := &metricsTestMatrix{backfills: make(map[BackfillDirection]int)}
+	br := newTestBridge(t, newTestDB(t), nil, matrix, nil)
+	br.TrackBackfill(getBackfillDirection(true, false), 3, time.Second)
+	br.TrackBackfill(getBackfillDirection(true, true), 2, time.Second)
+	assert.Equal(t, map[BackfillDirection]int{BackfillDirectionForward: 3, BackfillDirectionThread: 2}, matrix.backfills)
+}
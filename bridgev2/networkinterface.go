@@ -357,6 +357,23 @@ type NetworkGeneralCapabilities struct {
 	OutgoingMessageTimeouts *OutgoingTimeoutConfig
 	// Capabilities related to the provisioning API.
 	Provisioning ProvisioningCapabilities
+	// Settings for the automatic typing notification repeat loop.
+	Typing TypingCapabilities
+}
+
+// TypingCapabilities configures how the bridge repeats typing notifications in both directions.
+type TypingCapabilities struct {
+	// If set, the bridge will call [TypingHandlingNetworkAPI.HandleMatrixTyping] again at this interval
+	// while a Matrix user is still typing. This should be set for networks where typing notifications
+	// expire automatically unless they're repeated.
+	MatrixRefreshInterval time.Duration
+	// If true, typing notifications from [RemoteTyping] events are refreshed on Matrix before their timeout
+	// expires, until a stop event (zero timeout) is received or the user sends a message. This should be set
+	// for networks that only send typing start and stop events without repeating them.
+	RefreshRemote bool
+	// The maximum time to keep refreshing a typing notification in either direction.
+	// Defaults to [DefaultMaxTypingDuration].
+	MaxDuration time.Duration
 }
 
 // NetworkAPI is an interface representing a remote network client for a single user login.
@@ -639,12 +656,45 @@ type ChatViewingNetworkAPI interface {
 // TypingHandlingNetworkAPI is an optional interface that network connectors can implement to handle typing events.
 type TypingHandlingNetworkAPI interface {
 	NetworkAPI
-	// HandleMatrixTyping is called when a user starts typing in a portal room.
-	// In the future, the central bridge module will likely get a loop to automatically repeat
-	// calls to this function until the user stops typing.
+	// HandleMatrixTyping is called when a user starts or stops typing in a portal room.
+	// If [TypingCapabilities.MatrixRefreshInterval] is set, the bridge will automatically repeat
+	// calls to this function until the user stops typing or sends a message.
 	HandleMatrixTyping(ctx context.Context, msg *MatrixTyping) error
 }
 
+// MessageRequestHandlingNetworkAPI is an optional interface that network connectors can implement to
+// accept or reject chat invites and message requests created with [RemoteMessageRequest] events.
+type MessageRequestHandlingNetworkAPI interface {
+	NetworkAPI
+	// HandleMatrixMessageRequest is called when the user accepts or rejects a pending message request,
+	// either by joining or leaving the Matrix room, or via the provisioning API.
+	// If this returns an error, the portal will stay in the pending state.
+	HandleMatrixMessageRequest(ctx context.Context, msg *MatrixMessageRequest) error
+}
+
+// ReportingNetworkAPI is an optional interface that network connectors can implement to bridge
+// spam reports and user blocks from Matrix to the remote network.
+//
+// Reports made with the Matrix /report endpoints go to the homeserver admins and aren't forwarded to appservices,
+// so reports can only be bridged with the report command or the provisioning API.
+type ReportingNetworkAPI interface {
+	NetworkAPI
+	// HandleMatrixReport is called when the user reports a message or a whole chat.
+	HandleMatrixReport(ctx context.Context, msg *MatrixReport) error
+	// HandleMatrixBlockUser is called when the user blocks or unblocks a remote user,
+	// either with a command or by changing their Matrix ignored user list.
+	HandleMatrixBlockUser(ctx context.Context, msg *MatrixBlockUser) error
+}
+
+// PresenceHandlingNetworkAPI is an optional interface that network connectors can implement to bridge
+// the presence of the logged-in Matrix user to the remote network.
+type PresenceHandlingNetworkAPI interface {
+	NetworkAPI
+	// HandleMatrixPresence is called when the homeserver sends a presence update for the user who owns the login.
+	// Presence is only forwarded if the bridge config enables bridging presence from Matrix.
+	HandleMatrixPresence(ctx context.Context, msg *MatrixPresence) error
+}
+
 type MarkedUnreadHandlingNetworkAPI interface {
 	NetworkAPI
 	HandleMarkedUnread(ctx context.Context, msg *MatrixMarkedUnread) error
@@ -778,6 +828,42 @@ type UserSearchingNetworkAPI interface {
 	SearchUsers(ctx context.Context, query string) ([]*ResolveIdentifierResponse, error)
 }
 
+// MessageSearchParams contains the parameters for [MessageSearchingNetworkAPI.SearchMessages].
+type MessageSearchParams struct {
+	Query string
+	// The portal to search in. If nil, all chats of the user login should be searched.
+	Portal *Portal
+	// The cursor from a previous search response, used for fetching more results.
+	Cursor networkid.PaginationCursor
+	// The maximum number of results to return. The network connector may return fewer results.
+	Limit int
+}
+
+// MessageSearchResult is a single message found by [MessageSearchingNetworkAPI.SearchMessages].
+type MessageSearchResult struct {
+	PortalKey networkid.PortalKey
+	MessageID networkid.MessageID
+	// The specific part that matched the query. If not set, the first part of the message is used.
+	PartID    *networkid.PartID
+	Sender    EventSender
+	Timestamp time.Time
+	// A plain text snippet of the message to show to the user, such as the text surrounding the match.
+	Snippet string
+}
+
+type MessageSearchResponse struct {
+	Results []*MessageSearchResult
+	// The cursor for fetching more results. Empty if there are no more results.
+	NextCursor networkid.PaginationCursor
+}
+
+// MessageSearchingNetworkAPI is an optional interface that network connectors can implement to allow searching
+// message history on the remote network. The bridge maps the results to Matrix event IDs where possible.
+type MessageSearchingNetworkAPI interface {
+	NetworkAPI
+	SearchMessages(ctx context.Context, params *MessageSearchParams) (*MessageSearchResponse, error)
+}
+
 type ProvisioningCapabilities struct {
 	ResolveIdentifier ResolveIdentifierCapabilities    `json:"resolve_identifier"`
 	GroupCreation     map[string]GroupTypeCapabilities `json:"group_creation"`
@@ -1051,6 +1137,16 @@ func (ret RemoteEventType) String() string {
 		return "RemoteEventChatDelete"
 	case RemoteEventBackfill:
 		return "RemoteEventBackfill"
+	case RemoteEventPresence:
+		return "RemoteEventPresence"
+	case RemoteEventMessageRequest:
+		return "RemoteEventMessageRequest"
+	case RemoteEventPollStart:
+		return "RemoteEventPollStart"
+	case RemoteEventPollVote:
+		return "RemoteEventPollVote"
+	case RemoteEventPollEnd:
+		return "RemoteEventPollEnd"
 	default:
 		return fmt.Sprintf("RemoteEventType(%d)", int(ret))
 	}
@@ -1073,6 +1169,11 @@ const (
 	RemoteEventChatResync
 	RemoteEventChatDelete
 	RemoteEventBackfill
+	RemoteEventPresence
+	RemoteEventMessageRequest
+	RemoteEventPollStart
+	RemoteEventPollVote
+	RemoteEventPollEnd
 )
 
 // RemoteEvent represents a single event from the remote network, such as a message or a reaction.
@@ -1229,6 +1330,25 @@ func (rsd *ReactionSyncData) ToBackfill() []*BackfillReaction {
 	return reactions
 }
 
+// RemotePollStart is a remote event that creates a new poll. It's handled exactly like a [RemoteMessage],
+// so ConvertMessage should return the poll as a message part, e.g. using [Poll.ToConvertedPart].
+type RemotePollStart interface {
+	RemoteMessage
+}
+
+// RemotePollVote is a remote event where the sender changes their selected options in a poll.
+type RemotePollVote interface {
+	RemoteEventWithTargetMessage
+	// GetVotes returns the IDs of all options the sender currently has selected.
+	// An empty list means the sender retracted their vote.
+	GetVotes() []string
+}
+
+// RemotePollEnd is a remote event that closes a poll.
+type RemotePollEnd interface {
+	RemoteEventWithTargetMessage
+}
+
 type RemoteReactionSync interface {
 	RemoteEventWithTargetMessage
 	GetReactions() *ReactionSyncData
@@ -1296,6 +1416,30 @@ type RemoteTypingWithType interface {
 	GetTypingType() TypingType
 }
 
+// RemoteMessageRequest is a remote event for a chat invite or message request that the user hasn't accepted yet.
+//
+// If the portal doesn't exist yet, the bridge creates the room without joining the user,
+// and the sender of the event invites the user instead. The portal then stays in the pending state
+// until the user accepts or rejects the request (see [MessageRequestHandlingNetworkAPI]),
+// or until a chat info update sets [ChatInfo.MessageRequest] to false.
+type RemoteMessageRequest interface {
+	RemoteEvent
+	GetChatInfo(ctx context.Context, portal *Portal) (*ChatInfo, error)
+}
+
+// RemotePresence is a remote event that updates the presence of a ghost.
+//
+// Presence isn't tied to a portal, so the portal key of these events is ignored.
+type RemotePresence interface {
+	RemoteEvent
+	GetPresence() event.Presence
+}
+
+type RemotePresenceWithStatus interface {
+	RemotePresence
+	GetStatusMessage() string
+}
+
 type OrigSender struct {
 	User   *User
 	UserID id.UserID
@@ -1350,6 +1494,8 @@ type MatrixReaction struct {
 	MatrixEventBase[*event.ReactionEventContent]
 	TargetMessage *database.Message
 	PreHandleResp *MatrixReactionPreResponse
+	// If the reaction key is a custom emoji that was bridged from the remote network, this is the cached emoji info.
+	CustomEmoji *database.CustomEmoji
 
 	// When EmojiID is blank and there's already an existing reaction, this is the old reaction that is being overridden.
 	ReactionToOverride *database.Reaction
@@ -1407,6 +1553,35 @@ type MatrixTyping struct {
 	Type     TypingType
 }
 
+type MatrixReport struct {
+	Portal *Portal
+	// The message being reported. If nil, the whole chat is being reported.
+	TargetMessage *database.Message
+	// The reason given by the user. May be empty.
+	Reason string
+}
+
+type MatrixBlockUser struct {
+	Ghost *Ghost
+	// Whether the user should be blocked. If false, the user should be unblocked.
+	Blocked bool
+}
+
+type MatrixMessageRequest struct {
+	Portal *Portal
+	// Whether the user accepted the request. If false, the request was rejected and the portal will be deleted.
+	Accept bool
+}
+
+type MatrixPresence struct {
+	// The raw presence event.
+	Event *event.Event
+	// The parsed content of the event.
+	Content *event.PresenceEventContent
+	// The user whose presence changed.
+	User *User
+}
+
 type MatrixViewingChat struct {
 	// The portal that the user is viewing. This will be nil when the user switches to a chat from a different bridge.
 	Portal *Portal
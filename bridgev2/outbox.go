@@ -0,0 +1,368 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/database"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/status"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// Outbox retries Matrix messages that couldn't be sent because the remote network was disconnected.
+//
+// Failed messages are stored in the database and retried with exponential backoff, and immediately when the
+// login reconnects. Messages are always sent in order within a portal: new messages are queued behind any
+// pending ones, and later messages aren't attempted until the oldest one has been sent or given up on.
+// Edits are queued the same way, and redacting a queued message removes it from the outbox.
+type Outbox struct {
+	br     *Bridge
+	wakeup chan struct{}
+	stop   atomic.Pointer[context.CancelFunc]
+}
+
+const (
+	DefaultOutboxInitialBackoff = 10 * time.Second
+	DefaultOutboxMaxBackoff     = 5 * time.Minute
+	DefaultOutboxMaxAge         = 1 * time.Hour
+	// OutboxCheckInterval is how often the outbox loop checks for messages that are due to be retried.
+	OutboxCheckInterval = 5 * time.Second
+)
+
+func (ob *Outbox) Start() {
+	log := ob.br.Log.With().Str("component", "outbox").Logger()
+	ctx, stop := context.WithCancel(log.WithContext(ob.br.BackgroundCtx))
+	if oldStop := ob.stop.Swap(&stop); oldStop != nil {
+		(*oldStop)()
+	}
+	log.Debug().Msg("Outbox loop starting")
+	for {
+		ob.expire(ctx)
+		ob.retryDue(ctx)
+		select {
+		case <-time.After(OutboxCheckInterval):
+		case <-ob.wakeup:
+		case <-ctx.Done():
+			log.Debug().Msg("Outbox loop stopping")
+			return
+		}
+	}
+}
+
+func (ob *Outbox) Stop() {
+	if ob == nil {
+		return
+	}
+	if stop := ob.stop.Load(); stop != nil {
+		(*stop)()
+	}
+}
+
+func (ob *Outbox) isEnabled() bool {
+	return ob != nil && ob.br.Config.Outbox.Enabled && !ob.br.Background
+}
+
+// LoginConnected makes all messages queued by the given login due immediately and wakes up the outbox loop.
+func (ob *Outbox) LoginConnected(login *UserLogin) {
+	if !ob.isEnabled() {
+		return
+	}
+	go func() {
+		ctx := login.Log.WithContext(ob.br.BackgroundCtx)
+		err := ob.br.DB.Outbox.ResetBackoffForLogin(ctx, login.ID)
+		if err != nil {
+			login.Log.Err(err).Msg("Failed to reset outbox backoff after reconnecting")
+			return
+		}
+		select {
+		case ob.wakeup <- struct{}{}:
+		default:
+		}
+	}()
+}
+
+func (ob *Outbox) backoff(attempts int) time.Duration {
+	delay := ob.br.Config.Outbox.InitialBackoff
+	if delay <= 0 {
+		delay = DefaultOutboxInitialBackoff
+	}
+	maxDelay := ob.br.Config.Outbox.MaxBackoff
+	if maxDelay <= 0 {
+		maxDelay = DefaultOutboxMaxBackoff
+	}
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+func (ob *Outbox) expire(ctx context.Context) {
+	maxAge := ob.br.Config.Outbox.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultOutboxMaxAge
+	}
+	expired, err := ob.br.DB.Outbox.GetQueuedBefore(ctx, time.Now().Add(-maxAge))
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to get expired outbox messages")
+		return
+	}
+	for _, msg := range expired {
+		zerolog.Ctx(ctx).Warn().
+			Stringer("event_id", msg.EventID).
+			Object("portal_key", msg.Portal).
+			Int("attempts", msg.Attempts).
+			Str("last_error", msg.LastError).
+			Msg("Giving up on outbox message")
+		err = ob.br.DB.Outbox.Delete(ctx, msg.EventID)
+		if err != nil {
+			zerolog.Ctx(ctx).Err(err).Stringer("event_id", msg.EventID).Msg("Failed to delete expired outbox message")
+			continue
+		}
+		if msg.Event != nil {
+			ms := WrapErrorInStatus(ErrOutboxMessageExpired)
+			ms.RetryNum = msg.Attempts
+			ob.br.Matrix.SendMessageStatus(ctx, &ms, StatusEventInfoFromEvent(msg.Event))
+		}
+	}
+}
+
+func (ob *Outbox) retryDue(ctx context.Context) {
+	due, err := ob.br.DB.Outbox.GetDue(ctx)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to get due outbox messages")
+		return
+	}
+	for _, msg := range due {
+		ob.retryPortal(ctx, msg)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// retryPortal retries queued messages in a portal in order, starting with the given message,
+// until one of them fails with a retriable error or there are no more messages left.
+func (ob *Outbox) retryPortal(ctx context.Context, msg *database.OutboxMessage) {
+	log := zerolog.Ctx(ctx).With().Object("portal_key", msg.Portal).Logger()
+	ctx = log.WithContext(ctx)
+	portal, err := ob.br.GetExistingPortalByKey(ctx, msg.Portal)
+	if err != nil {
+		log.Err(err).Msg("Failed to get portal to retry outbox messages")
+		return
+	} else if portal == nil {
+		return
+	}
+	for msg != nil {
+		if login := ob.br.GetCachedUserLoginByID(msg.UserLoginID); login != nil && !isLoginConnected(login) {
+			// Don't waste attempts while the login is known to be disconnected, the backoff is reset when it reconnects
+			msg.NextAttemptAt = time.Now().Add(ob.backoff(msg.Attempts + 1))
+			if err = ob.br.DB.Outbox.Update(ctx, msg); err != nil {
+				log.Err(err).Stringer("event_id", msg.EventID).Msg("Failed to postpone outbox message")
+			}
+			return
+		}
+		sender, err := ob.br.GetUserByMXID(ctx, msg.Sender)
+		if err != nil {
+			log.Err(err).Stringer("event_id", msg.EventID).Msg("Failed to get sender of outbox message")
+			return
+		}
+		retry := &portalOutboxEvent{msg: msg, sender: sender, done: make(chan EventHandlingResult, 1)}
+		portal.queueEvent(ctx, retry)
+		var res EventHandlingResult
+		select {
+		case res = <-retry.done:
+		case <-ctx.Done():
+			return
+		}
+		if retry.sendErr != nil {
+			msg.Attempts++
+			msg.NextAttemptAt = time.Now().Add(ob.backoff(msg.Attempts))
+			msg.LastError = retry.sendErr.Error()
+			log.Debug().
+				Stringer("event_id", msg.EventID).
+				Int("attempts", msg.Attempts).
+				Time("next_attempt_at", msg.NextAttemptAt).
+				Msg("Outbox message failed to send again")
+			if err = ob.br.DB.Outbox.Update(ctx, msg); err != nil {
+				log.Err(err).Stringer("event_id", msg.EventID).Msg("Failed to update outbox message")
+			}
+			ob.sendPendingStatus(ctx, msg, retry.sendErr)
+			return
+		}
+		// The message was either sent or failed permanently, the status has already been sent in both cases
+		log.Debug().
+			Stringer("event_id", msg.EventID).
+			Bool("success", res.Success).
+			Msg("Finished retrying outbox message")
+		if err = ob.br.DB.Outbox.Delete(ctx, msg.EventID); err != nil {
+			log.Err(err).Stringer("event_id", msg.EventID).Msg("Failed to delete outbox message")
+			return
+		}
+		msg, err = ob.br.DB.Outbox.GetFirstForPortal(ctx, portal.PortalKey)
+		if err != nil {
+			log.Err(err).Msg("Failed to get next outbox message")
+			return
+		}
+	}
+}
+
+func (ob *Outbox) sendPendingStatus(ctx context.Context, msg *database.OutboxMessage, err error) {
+	message := "Waiting for earlier messages to be sent"
+	if err != nil {
+		message = "Waiting for the connection to the remote network"
+	}
+	ob.br.Matrix.SendMessageStatus(ctx, &MessageStatus{
+		Status:        event.MessageStatusPending,
+		RetryNum:      msg.Attempts,
+		InternalError: err,
+		Message:       message,
+	}, StatusEventInfoFromEvent(msg.Event))
+}
+
+func isLoginConnected(login *UserLogin) bool {
+	switch login.BridgeState.GetPrevUnsent().StateEvent {
+	case "", status.StateConnected, status.StateBackfilling:
+		return true
+	default:
+		return false
+	}
+}
+
+// isRetriableSendError checks if a message that failed to send with the given error should be retried later.
+func isRetriableSendError(login *UserLogin, err error) bool {
+	if errors.Is(err, ErrRemoteNotConnected) {
+		return true
+	} else if WrapErrorInStatus(err).IsCertain {
+		return false
+	}
+	switch login.BridgeState.GetPrevUnsent().StateEvent {
+	case status.StateConnecting, status.StateTransientDisconnect, status.StateUnknownError:
+		return true
+	default:
+		return false
+	}
+}
+
+type portalOutboxEvent struct {
+	msg    *database.OutboxMessage
+	sender *User
+	done   chan EventHandlingResult
+	// The retriable error the message failed with, set if the message needs to stay in the outbox.
+	sendErr error
+}
+
+func (poe *portalOutboxEvent) isPortalEvent() {}
+
+type outboxContextKey int
+
+const contextKeyOutboxRetry outboxContextKey = iota
+
+func (portal *Portal) handleOutboxRetry(ctx context.Context, evt *portalOutboxEvent) (res EventHandlingResult) {
+	// Deferred so that the outbox loop isn't left waiting if the handler panics
+	defer func() {
+		evt.done <- res
+	}()
+	ctx = context.WithValue(ctx, contextKeyOutboxRetry, evt)
+	// The message may have been redacted while the retry was waiting in the portal event queue
+	if queued, err := portal.Bridge.DB.Outbox.GetByEventID(ctx, evt.msg.EventID); err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to check if outbox message is still queued")
+	} else if queued == nil {
+		zerolog.Ctx(ctx).Debug().Msg("Outbox message was removed before retrying")
+		return EventHandlingResultIgnored
+	}
+	start := time.Now()
+	res = portal.handleMatrixEvent(ctx, evt.sender, evt.msg.Event)
+	portal.Bridge.TrackMatrixEvent(evt.msg.Event.Type, res, time.Since(start))
+	if res.SendMSS {
+		if res.Error != nil {
+			portal.sendErrorStatus(ctx, evt.msg.Event, res.Error)
+		} else {
+			portal.sendSuccessStatus(ctx, evt.msg.Event, 0, "")
+		}
+	}
+	return
+}
+
+// removeFromOutbox deletes a queued message that was redacted on Matrix before it could be sent.
+// If the redaction target isn't in the outbox of this portal, it returns false.
+func (portal *Portal) removeFromOutbox(ctx context.Context, eventID id.EventID) (bool, error) {
+	queued, err := portal.Bridge.DB.Outbox.GetByEventID(ctx, eventID)
+	if err != nil {
+		return false, err
+	} else if queued == nil || queued.Portal != portal.PortalKey {
+		return false, nil
+	}
+	err = portal.Bridge.DB.Outbox.Delete(ctx, eventID)
+	if err != nil {
+		return false, err
+	}
+	zerolog.Ctx(ctx).Debug().Int("attempts", queued.Attempts).Msg("Removed redacted message from outbox")
+	return true, nil
+}
+
+// checkOutbox is called before a Matrix message is sent to the remote network and again if sending fails.
+// If the message should wait in the outbox instead, it returns the result to use for the event and true.
+func (portal *Portal) checkOutbox(ctx context.Context, login *UserLogin, evt *event.Event, sendErr error) (EventHandlingResult, bool) {
+	ob := portal.Bridge.Outbox
+	if !ob.isEnabled() {
+		return EventHandlingResult{}, false
+	}
+	if retry, ok := ctx.Value(contextKeyOutboxRetry).(*portalOutboxEvent); ok {
+		// The message is already in the outbox, the outbox loop takes care of updating it
+		if sendErr != nil && isRetriableSendError(login, sendErr) {
+			retry.sendErr = sendErr
+			return EventHandlingResultQueued, true
+		}
+		return EventHandlingResult{}, false
+	}
+	log := zerolog.Ctx(ctx)
+	if sendErr == nil {
+		// Messages must be sent in order, so new messages have to wait behind any already queued ones
+		pending, err := portal.Bridge.DB.Outbox.GetFirstForPortal(ctx, portal.PortalKey)
+		if err != nil {
+			log.Err(err).Msg("Failed to check for queued outbox messages")
+			return EventHandlingResult{}, false
+		} else if pending == nil {
+			return EventHandlingResult{}, false
+		}
+	} else if !isRetriableSendError(login, sendErr) {
+		return EventHandlingResult{}, false
+	}
+	now := time.Now()
+	msg := &database.OutboxMessage{
+		EventID:       evt.ID,
+		Portal:        portal.PortalKey,
+		UserLoginID:   login.ID,
+		Sender:        evt.Sender,
+		Event:         evt,
+		QueuedAt:      now,
+		NextAttemptAt: now,
+	}
+	if sendErr != nil {
+		msg.Attempts = 1
+		msg.NextAttemptAt = now.Add(ob.backoff(msg.Attempts))
+		msg.LastError = sendErr.Error()
+	}
+	err := portal.Bridge.DB.Outbox.Insert(ctx, msg)
+	if err != nil {
+		log.Err(err).Msg("Failed to insert message into outbox")
+		return EventHandlingResult{}, false
+	}
+	log.Debug().
+		AnErr("send_error", sendErr).
+		Time("next_attempt_at", msg.NextAttemptAt).
+		Msg("Queued message in outbox")
+	ob.sendPendingStatus(ctx, msg, sendErr)
+	return EventHandlingResultQueued, true
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/bridgeconfig"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/database"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+type outboxTestMatrix struct {
+	testMatrixConnector
+	lock     sync.Mutex
+	statuses []*MessageStatus
+}
+
+func (m *outboxTestMatrix) SendMessageStatus(_ context.Context, status *MessageStatus, _ *MessageStatusEventInfo) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.statuses = append(m.statuses, status)
+}
+
+func newOutboxTestBridge(t *testing.T) (*Bridge, *outboxTestMatrix) {
+	matrix := &outboxTestMatrix{}
+	cfg := &bridgeconfig.BridgeConfig{Outbox: bridgeconfig.OutboxConfig{Enabled: true}}
+	return newTestBridge(t, newTestDB(t), cfg, matrix, nil), matrix
+}
+
+func insertOutboxMessage(
+	t *testing.T, br *Bridge, portal networkid.PortalKey, eventID id.EventID, queuedAt, nextAttemptAt time.Time,
+) *database.OutboxMessage {
+	ctx := context.Background()
+	if existing, err := br.DB.Portal.GetByKey(ctx, portal); err == nil && existing == nil {
+		require.NoError(t, br.DB.Portal.Insert(ctx, &database.Portal{PortalKey: portal}))
+	}
+	msg := &database.OutboxMessage{
+		EventID:     eventID,
+		Portal:      portal,
+		UserLoginID: "login",
+		Sender:      "@user:example.com",
+		Event: &event.Event{
+			ID:      eventID,
+			Type:    event.EventMessage,
+			Sender:  "@user:example.com",
+			RoomID:  "!room:example.com",
+			Content: event.Content{Parsed: &event.MessageEventContent{MsgType: event.MsgText, Body: "hello"}},
+		},
+		QueuedAt:      queuedAt,
+		Attempts:      1,
+		NextAttemptAt: nextAttemptAt,
+		LastError:     "not connected",
+	}
+	require.NoError(t, br.DB.Outbox.Insert(ctx, msg))
+	return msg
+}
+
+func outboxEventIDs(msgs []*database.OutboxMessage) []id.EventID {
+	ids := make([]id.EventID, len(msgs))
+	for i, msg := range msgs {
+		ids[i] = msg.EventID
+	}
+	return ids
+}
+
+func TestOutboxQuery_GetDue_HeadNotDue(t *testing.T) {
+	ctx := context.Background()
+	br, _ := newOutboxTestBridge(t)
+	now := time.Now()
+	portalA := networkid.PortalKey{ID: "a"}
+	portalB := networkid.PortalKey{ID: "b"}
+
+	// The head of portal A is backing off, so the later message behind it must not be retried yet
+	insertOutboxMessage(t, br, portalA, "$a1", now.Add(-2*time.Minute), now.Add(time.Minute))
+	insertOutboxMessage(t, br, portalA, "$a2", now.Add(-time.Minute), now.Add(-time.Minute))
+	insertOutboxMessage(t, br, portalB, "$b1", now.Add(-time.Minute), now.Add(-time.Second))
+	insertOutboxMessage(t, br, portalB, "$b2", now.Add(-time.Second), now.Add(-time.Second))
+
+	due, err := br.DB.Outbox.GetDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []id.EventID{"$b1"}, outboxEventIDs(due))
+
+	first, err := br.DB.Outbox.GetFirstForPortal(ctx, portalA)
+	require.NoError(t, err)
+	require.NotNil(t, first)
+	assert.Equal(t, id.EventID("$a1"), first.EventID)
+	assert.Equal(t, "hello", first.Event.Content.AsMessage().Body)
+}
+
+func TestPortal_HandleMatrixRedaction_Outbox(t *testing.T) {
+	ctx := zerolog.Nop().WithContext(context.Background())
+	br, _ := newOutboxTestBridge(t)
+	now := time.Now()
+	portalA := networkid.PortalKey{ID: "a"}
+	portalB := networkid.PortalKey{ID: "b"}
+	insertOutboxMessage(t, br, portalA, "$a1", now.Add(-2*time.Minute), now.Add(time.Minute))
+	insertOutboxMessage(t, br, portalA, "$a2", now.Add(-time.Minute), now.Add(time.Minute))
+	insertOutboxMessage(t, br, portalB, "$b1", now.Add(-time.Minute), now.Add(time.Minute))
+
+	portal, err := br.GetExistingPortalByKey(ctx, portalA)
+	require.NoError(t, err)
+	require.NotNil(t, portal)
+	redact := func(target id.EventID) EventHandlingResult {
+		return portal.handleMatrixRedaction(ctx, nil, nil, &event.Event{
+			ID:      "$redaction",
+			Type:    event.EventRedaction,
+			Redacts: target,
+			Content: event.Content{Parsed: &event.RedactionEventContent{}},
+		})
+	}
+
+	res := redact("$a1")
+	assert.True(t, res.Success)
+	assert.True(t, res.SendMSS)
+	queued, err := br.DB.Outbox.GetByEventID(ctx, "$a1")
+	require.NoError(t, err)
+	assert.Nil(t, queued)
+	first, err := br.DB.Outbox.GetFirstForPortal(ctx, portalA)
+	require.NoError(t, err)
+	require.NotNil(t, first)
+	assert.Equal(t, id.EventID("$a2"), first.EventID)
+
+	// Messages queued in other portals can't be removed through this portal
+	removed, err := portal.removeFromOutbox(ctx, "$b1")
+	require.NoError(t, err)
+	assert.False(t, removed)
+	queued, err = br.DB.Outbox.GetByEventID(ctx, "$b1")
+	require.NoError(t, err)
+	assert.NotNil(t, queued)
+}
+
+func TestOutbox_Expire(t *testing.T) {
+	ctx := context.Background()
+	br, matrix := newOutboxTestBridge(t)
+	now := time.Now()
+	old := insertOutboxMessage(t, br, networkid.PortalKey{ID: "a"}, "$old", now.Add(-2*time.Hour), now.Add(time.Minute))
+	old.Attempts = 5
+	require.NoError(t, br.DB.Outbox.Update(ctx, old))
+	insertOutboxMessage(t, br, networkid.PortalKey{ID: "a"}, "$new", now.Add(-time.Minute), now.Add(time.Minute))
+
+	br.Outbox.expire(ctx)
+
+	queued, err := br.DB.Outbox.GetByEventID(ctx, "$old")
+	require.NoError(t, err)
+	assert.Nil(t, queued)
+	queued, err = br.DB.Outbox.GetByEventID(ctx, "$new")
+	require.NoError(t, err)
+	assert.NotNil(t, queued)
+
+	require.Len(t, matrix.statuses, 1)
+	status := matrix.statuses[0]
+	assert.Equal(t, ErrOutboxMessageExpired.InternalError, status.InternalError)
+	assert.Equal(t, event.MessageStatusFail, status.Status)
+	assert.Equal(t, event.MessageStatusTooOld, status.ErrorReason)
+	assert.Equal(t, 5, status.RetryNum)
+}
+
+func TestOutbox_LoginConnected(t *testing.T) {
+	ctx := context.Background()
+	br, _ := newOutboxTestBridge(t)
+	now := time.Now()
+	insertOutboxMessage(t, br, networkid.PortalKey{ID: "a"}, "$a1", now.Add(-time.Minute), now.Add(time.Hour))
+	insertOutboxMessage(t, br, networkid.PortalKey{ID: "b"}, "$b1", now.Add(-time.Minute), now.Add(time.Hour))
+	_, err := br.DB.Exec(ctx, "UPDATE outbox_message SET user_login_id='other' WHERE event_id='$b1'")
+	require.NoError(t, err)
+
+	due, err := br.DB.Outbox.GetDue(ctx)
+	require.NoError(t, err)
+	require.Empty(t, due)
+
+	br.Outbox.LoginConnected(&UserLogin{UserLogin: &database.UserLogin{ID: "login"}, Log: zerolog.Nop()})
+	select {
+	case <-br.Outbox.wakeup:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Outbox loop wasn't woken up")
+	}
+	due, err = br.DB.Outbox.GetDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []id.EventID{"$a1"}, outboxEventIDs(due))
+}
@@ -24,6 +24,8 @@ import (
 	"go.mau.fi/util/exsync"
 	"go.mau.fi/util/ptr"
 	"go.mau.fi/util/variationselector"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
 
@@ -77,9 +79,10 @@ type Portal struct {
 	Relay  *UserLogin
 
 	currentlyTyping       []id.UserID
-	currentlyTypingLogins map[id.UserID]*UserLogin
+	currentlyTypingUsers  map[id.UserID]*typingMatrixUser
+	currentlyTypingGhosts map[id.UserID]*typingGhost
 	currentlyTypingLock   sync.Mutex
-	currentlyTypingGhosts *exsync.Set[id.UserID]
+	typingLoopRunning     bool
 
 	outgoingMessages     map[networkid.TransactionID]*outgoingMessage
 	outgoingMessagesLock sync.Mutex
@@ -122,8 +125,8 @@ func (br *Bridge) loadPortal(ctx context.Context, dbPortal *database.Portal, que
 		Portal: dbPortal,
 		Bridge: br,
 
-		currentlyTypingLogins: make(map[id.UserID]*UserLogin),
-		currentlyTypingGhosts: exsync.NewSet[id.UserID](),
+		currentlyTypingUsers:  make(map[id.UserID]*typingMatrixUser),
+		currentlyTypingGhosts: make(map[id.UserID]*typingGhost),
 		outgoingMessages:      make(map[networkid.TransactionID]*outgoingMessage),
 
 		RoomCreated: exsync.NewEvent(),
@@ -473,8 +476,17 @@ func (portal *Portal) getEventCtxWithLog(rawEvt any, idx int) context.Context {
 				logWith = logWith.Int64("remote_stream_order", remoteStreamOrder)
 			}
 		}
+	case *portalOutboxEvent:
+		logWith = portal.Log.With().Int("event_loop_index", idx).
+			Str("action", "retry outbox message").
+			Stringer("event_id", evt.msg.EventID).
+			Str("event_type", evt.msg.Event.Type.Type).
+			Stringer("sender", evt.sender.MXID).
+			Int("attempt", evt.msg.Attempts+1)
 	case *portalCreateEvent:
 		return evt.ctx
+	case *portalMessageRequestEvent:
+		return evt.ctx
 	}
 	return logWith.Logger().WithContext(portal.Bridge.BackgroundCtx)
 }
@@ -502,17 +514,28 @@ func (portal *Portal) handleSingleEvent(ctx context.Context, rawEvt any, doneCal
 				if evt.evt.ID != "" {
 					go portal.sendErrorStatus(ctx, evt.evt, ErrPanicInEventHandler)
 				}
+			case *portalOutboxEvent:
+				go portal.sendErrorStatus(ctx, evt.msg.Event, ErrPanicInEventHandler)
 			case *portalCreateEvent:
 				evt.cb(fmt.Errorf("portal creation panicked"))
+			case *portalMessageRequestEvent:
+				evt.cb(fmt.Errorf("message request response panicked"))
 			}
 			portal.Bridge.TrackAnalytics("", "Bridge Event Handler Panic", map[string]any{
 				"error": errorString,
 			})
 		}
 	}()
+	start := time.Now()
 	switch evt := rawEvt.(type) {
 	case *portalMatrixEvent:
+		ctx, span := tracer.Start(ctx, "Portal.handleMatrixEvent", trace.WithAttributes(
+			attribute.String("room_id", portal.MXID.String()),
+			attribute.String("event_id", evt.evt.ID.String()),
+			attribute.String("event_type", evt.evt.Type.Type),
+		))
 		res = portal.handleMatrixEvent(ctx, evt.sender, evt.evt)
+		portal.Bridge.TrackMatrixEvent(evt.evt.Type, res, time.Since(start))
 		if res.SendMSS {
 			if res.Error != nil {
 				portal.sendErrorStatus(ctx, evt.evt, res.Error)
@@ -523,12 +546,32 @@ func (portal *Portal) handleSingleEvent(ctx context.Context, rawEvt any, doneCal
 		if res.Error != nil && evt.evt.StateKey != nil {
 			portal.revertRoomMeta(ctx, evt.evt)
 		}
+		endEventSpan(span, res)
 	case *portalRemoteEvent:
+		ctx, span := tracer.Start(ctx, "Portal.handleRemoteEvent", trace.WithAttributes(
+			attribute.String("room_id", portal.MXID.String()),
+			attribute.String("event_type", evt.evtType.String()),
+			attribute.String("source_login_id", string(evt.source.ID)),
+		))
 		res = portal.handleRemoteEvent(ctx, evt.source, evt.evtType, evt.evt)
+		portal.Bridge.TrackRemoteEvent(evt.evtType, res, time.Since(start))
+		endEventSpan(span, res)
+	case *portalOutboxEvent:
+		ctx, span := tracer.Start(ctx, "Portal.handleOutboxRetry", trace.WithAttributes(
+			attribute.String("room_id", portal.MXID.String()),
+			attribute.String("event_id", evt.msg.EventID.String()),
+			attribute.Int("attempt", evt.msg.Attempts+1),
+		))
+		res = portal.handleOutboxRetry(ctx, evt)
+		endEventSpan(span, res)
 	case *portalCreateEvent:
 		err := portal.createMatrixRoomInLoop(evt.ctx, evt.source, evt.info, nil)
 		res.Success = err == nil
 		evt.cb(err)
+	case *portalMessageRequestEvent:
+		err := portal.handleMessageRequestResponse(evt.ctx, evt.login, evt.accept)
+		res.Success = err == nil
+		evt.cb(err)
 	default:
 		panic(fmt.Errorf("illegal type %T in eventLoop", evt))
 	}
@@ -643,6 +686,9 @@ func (portal *Portal) handleMatrixEvent(ctx context.Context, sender *User, evt *
 	if evt.Type == event.StateTombstone {
 		// Tombstones aren't bridged so they don't need a login
 		return portal.handleMatrixTombstone(ctx, evt)
+	} else if evt.Type == event.StateRetention {
+		// Retention policies only affect the bridge database, so they don't need a login either
+		return portal.handleMatrixRetention(ctx, evt)
 	}
 	login, userPortal, err := portal.FindPreferredLogin(ctx, sender, true)
 	if err != nil {
@@ -873,13 +919,16 @@ func (portal *Portal) handleMatrixTyping(ctx context.Context, evt *event.Event)
 	portal.sendTypings(ctx, stoppedTyping, false)
 	portal.sendTypings(ctx, startedTyping, true)
 	portal.currentlyTyping = content.UserIDs
+	if len(portal.currentlyTypingUsers) > 0 {
+		portal.startTypingLoop()
+	}
 	// TODO actual status
 	return EventHandlingResultSuccess
 }
 
 func (portal *Portal) sendTypings(ctx context.Context, userIDs []id.UserID, typing bool) {
 	for _, userID := range userIDs {
-		login, ok := portal.currentlyTypingLogins[userID]
+		typingUser, ok := portal.currentlyTypingUsers[userID]
 		if !ok && !typing {
 			continue
 		} else if !ok {
@@ -890,7 +939,7 @@ func (portal *Portal) sendTypings(ctx context.Context, userIDs []id.UserID, typi
 			} else if user == nil {
 				continue
 			}
-			login, _, err = portal.FindPreferredLogin(ctx, user, false)
+			login, _, err := portal.FindPreferredLogin(ctx, user, false)
 			if err != nil {
 				if !errors.Is(err, ErrNotLoggedIn) {
 					zerolog.Ctx(ctx).Err(err).Stringer("user_id", userID).Msg("Failed to get user login to send typing event")
@@ -901,67 +950,51 @@ func (portal *Portal) sendTypings(ctx context.Context, userIDs []id.UserID, typi
 			} else if _, ok = login.Client.(TypingHandlingNetworkAPI); !ok {
 				continue
 			}
-			portal.currentlyTypingLogins[userID] = login
+			typingUser = &typingMatrixUser{login: login, started: time.Now()}
+			portal.currentlyTypingUsers[userID] = typingUser
 		}
 		if !typing {
-			delete(portal.currentlyTypingLogins, userID)
-		}
-		typingAPI, ok := login.Client.(TypingHandlingNetworkAPI)
-		if !ok {
-			continue
-		}
-		err := typingAPI.HandleMatrixTyping(ctx, &MatrixTyping{
-			Portal:   portal,
-			IsTyping: typing,
-			Type:     TypingTypeText,
-		})
-		if err != nil {
-			zerolog.Ctx(ctx).Err(err).Stringer("user_id", userID).Msg("Failed to bridge Matrix typing event")
-		} else {
-			zerolog.Ctx(ctx).Debug().
-				Stringer("user_id", userID).
-				Bool("typing", typing).
-				Msg("Sent typing event")
+			delete(portal.currentlyTypingUsers, userID)
 		}
+		typingUser.lastSent = time.Now()
+		portal.sendTyping(ctx, userID, typingUser, typing)
 	}
 }
 
-func (portal *Portal) periodicTypingUpdater() {
-	// TODO actually call this function
-	log := portal.Log.With().Str("component", "typing updater").Logger()
-	ctx := log.WithContext(context.Background())
-	for {
-		// TODO make delay configurable by network connector
-		time.Sleep(5 * time.Second)
-		portal.currentlyTypingLock.Lock()
-		if len(portal.currentlyTyping) == 0 {
-			portal.currentlyTypingLock.Unlock()
-			continue
-		}
-		for _, userID := range portal.currentlyTyping {
-			login, ok := portal.currentlyTypingLogins[userID]
-			if !ok {
-				continue
-			}
-			typingAPI, ok := login.Client.(TypingHandlingNetworkAPI)
-			if !ok {
-				continue
-			}
-			err := typingAPI.HandleMatrixTyping(ctx, &MatrixTyping{
-				Portal:   portal,
-				IsTyping: true,
-				Type:     TypingTypeText,
-			})
-			if err != nil {
-				log.Err(err).Stringer("user_id", userID).Msg("Failed to repeat Matrix typing event")
-			} else {
-				log.Debug().
-					Stringer("user_id", userID).
-					Bool("typing", true).
-					Msg("Sent repeated typing event")
-			}
-		}
-		portal.currentlyTypingLock.Unlock()
+func (portal *Portal) sendTyping(ctx context.Context, userID id.UserID, typingUser *typingMatrixUser, typing bool) {
+	typingAPI, ok := typingUser.login.Client.(TypingHandlingNetworkAPI)
+	if !ok {
+		return
+	}
+	err := typingAPI.HandleMatrixTyping(ctx, &MatrixTyping{
+		Portal:   portal,
+		IsTyping: typing,
+		Type:     TypingTypeText,
+	})
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Stringer("user_id", userID).Msg("Failed to bridge Matrix typing event")
+	} else {
+		zerolog.Ctx(ctx).Debug().
+			Stringer("user_id", userID).
+			Bool("typing", typing).
+			Msg("Sent typing event")
+	}
+}
+
+// stopMatrixTyping stops the typing notification of the given Matrix user on the remote network,
+// e.g. because they just sent a message.
+func (portal *Portal) stopMatrixTyping(ctx context.Context, userID id.UserID) {
+	portal.currentlyTypingLock.Lock()
+	typingUser, ok := portal.currentlyTypingUsers[userID]
+	if ok {
+		delete(portal.currentlyTypingUsers, userID)
+		portal.currentlyTyping = slices.DeleteFunc(portal.currentlyTyping, func(typingUserID id.UserID) bool {
+			return typingUserID == userID
+		})
+	}
+	portal.currentlyTypingLock.Unlock()
+	if ok {
+		portal.sendTyping(ctx, userID, typingUser, false)
 	}
 }
 
@@ -1041,6 +1074,7 @@ func (portal *Portal) handleMatrixMessage(ctx context.Context, sender *UserLogin
 		return EventHandlingResultFailed.
 			WithMSSError(fmt.Errorf("%w: %T", ErrUnexpectedParsedContentType, evt.Content.Parsed))
 	}
+	portal.stopMatrixTyping(ctx, evt.Sender)
 	caps := sender.Client.GetCapabilities(ctx, portal)
 
 	if relatesTo.GetReplaceID() != "" {
@@ -1162,6 +1196,10 @@ func (portal *Portal) handleMatrixMessage(ctx context.Context, sender *UserLogin
 		}
 	}
 
+	if res, queued := portal.checkOutbox(ctx, sender, evt, nil); queued {
+		return res
+	}
+
 	var resp *MatrixMessageResponse
 	if msgContent != nil {
 		resp, err = sender.Client.HandleMatrixMessage(ctx, wrappedMsgEvt)
@@ -1181,6 +1219,10 @@ func (portal *Portal) handleMatrixMessage(ctx context.Context, sender *UserLogin
 		return EventHandlingResultFailed.WithMSSError(fmt.Errorf("all contents are nil"))
 	}
 	if err != nil {
+		if res, queued := portal.checkOutbox(ctx, sender, evt, err); queued {
+			log.Warn().Err(err).Msg("Failed to handle Matrix message, queued for retrying")
+			return res
+		}
 		log.Err(err).Msg("Failed to handle Matrix message")
 		return EventHandlingResultFailed.WithMSSError(err)
 	}
@@ -1379,13 +1421,17 @@ func (portal *Portal) handleMatrixEdit(
 	} else if err := portal.checkMessageContentCaps(caps, content); err != nil {
 		return EventHandlingResultFailed.WithMSSError(err)
 	}
+	// Edits of queued messages must wait for the target to be sent
+	if res, queued := portal.checkOutbox(ctx, sender, evt, nil); queued {
+		return res
+	}
 	editTarget, err := portal.Bridge.DB.Message.GetPartByMXID(ctx, editTargetID)
 	if err != nil {
 		log.Err(err).Msg("Failed to get edit target message from database")
 		return EventHandlingResultFailed.WithMSSError(fmt.Errorf("%w: failed to get edit target: %w", ErrDatabaseError, err))
 	} else if editTarget == nil {
 		log.Warn().Msg("Edit target message not found in database")
-		return EventHandlingResultFailed.WithMSSError(fmt.Errorf("edit %w", ErrTargetMessageNotFound))
+		return portal.matrixTargetNotFound("edit")
 	} else if caps.EditMaxAge != nil && caps.EditMaxAge.Duration > 0 && time.Since(editTarget.Timestamp) > caps.EditMaxAge.Duration {
 		return EventHandlingResultFailed.WithMSSError(ErrEditTargetTooOld)
 	} else if caps.EditMaxCount > 0 && editTarget.EditCount >= caps.EditMaxCount {
@@ -1406,6 +1452,10 @@ func (portal *Portal) handleMatrixEdit(
 		EditTarget: editTarget,
 	})
 	if err != nil {
+		if res, queued := portal.checkOutbox(ctx, sender, evt, err); queued {
+			log.Warn().Err(err).Msg("Failed to handle Matrix edit, queued for retrying")
+			return res
+		}
 		log.Err(err).Msg("Failed to handle Matrix edit")
 		return EventHandlingResultFailed.WithMSSError(err)
 	}
@@ -1439,7 +1489,7 @@ func (portal *Portal) handleMatrixReaction(ctx context.Context, sender *UserLogi
 		return EventHandlingResultFailed.WithMSSError(fmt.Errorf("%w: failed to get reaction target: %w", ErrDatabaseError, err))
 	} else if reactionTarget == nil {
 		log.Warn().Msg("Reaction target message not found in database")
-		return EventHandlingResultFailed.WithMSSError(fmt.Errorf("reaction %w", ErrTargetMessageNotFound))
+		return portal.matrixTargetNotFound("reaction")
 	}
 	log.UpdateContext(func(c zerolog.Context) zerolog.Context {
 		return c.Str("reaction_target_remote_id", string(reactionTarget.ID))
@@ -1454,6 +1504,13 @@ func (portal *Portal) handleMatrixReaction(ctx context.Context, sender *UserLogi
 		},
 		TargetMessage: reactionTarget,
 	}
+	if strings.HasPrefix(content.RelatesTo.Key, "mxc://") {
+		react.CustomEmoji, err = portal.Bridge.DB.CustomEmoji.GetByMXC(ctx, id.ContentURIString(content.RelatesTo.Key))
+		if err != nil {
+			log.Err(err).Msg("Failed to get custom emoji of reaction from database")
+			return EventHandlingResultFailed.WithMSSError(fmt.Errorf("%w: failed to get custom emoji: %w", ErrDatabaseError, err))
+		}
+	}
 	preResp, err := reactingAPI.PreHandleMatrixReaction(ctx, react)
 	if err != nil {
 		log.Err(err).Msg("Failed to pre-handle Matrix reaction")
@@ -1773,10 +1830,6 @@ func (portal *Portal) handleMatrixMembership(
 			Str("prev_membership", string(prevContent.Membership)).
 			Str("target_user_id", evt.GetStateKey())
 	})
-	api, ok := sender.Client.(MembershipHandlingNetworkAPI)
-	if !ok {
-		return EventHandlingResultIgnored.WithMSSError(ErrMembershipNotSupported)
-	}
 	targetMXID := id.UserID(*evt.StateKey)
 	isSelf := sender.User.MXID == targetMXID
 	target, err := portal.getTargetUser(ctx, targetMXID)
@@ -1786,6 +1839,18 @@ func (portal *Portal) handleMatrixMembership(
 	}
 
 	membershipChangeType := MembershipChangeType{From: prevContent.Membership, To: content.Membership, IsSelf: isSelf}
+	if portal.MessageRequest && origSender == nil && (membershipChangeType == AcceptInvite || membershipChangeType == RejectInvite) {
+		err = portal.handleMessageRequestResponse(ctx, sender, membershipChangeType == AcceptInvite)
+		if err != nil {
+			log.Err(err).Msg("Failed to respond to message request")
+			return EventHandlingResultFailed.WithMSSError(err)
+		}
+		return EventHandlingResultSuccess
+	}
+	api, ok := sender.Client.(MembershipHandlingNetworkAPI)
+	if !ok {
+		return EventHandlingResultIgnored.WithMSSError(ErrMembershipNotSupported)
+	}
 	if !portal.Bridge.Config.BridgeMatrixLeave && membershipChangeType == Leave {
 		log.Debug().Msg("Dropping leave event")
 		return EventHandlingResultIgnored //.WithMSSError(ErrIgnoringLeaveEvent)
@@ -2191,6 +2256,12 @@ func (portal *Portal) handleMatrixRedaction(
 	log.UpdateContext(func(c zerolog.Context) zerolog.Context {
 		return c.Stringer("redaction_target_mxid", content.Redacts)
 	})
+	if removed, err := portal.removeFromOutbox(ctx, content.Redacts); err != nil {
+		log.Err(err).Msg("Failed to remove redaction target from outbox")
+		return EventHandlingResultFailed.WithMSSError(fmt.Errorf("%w: failed to remove redaction target from outbox: %w", ErrDatabaseError, err))
+	} else if removed {
+		return EventHandlingResultSuccess.WithMSS()
+	}
 	deletingAPI, deleteOK := sender.Client.(RedactionHandlingNetworkAPI)
 	reactingAPI, reactOK := sender.Client.(ReactionHandlingNetworkAPI)
 	if !deleteOK && !reactOK {
@@ -2252,7 +2323,7 @@ func (portal *Portal) handleMatrixRedaction(
 
 func (portal *Portal) handleRemoteEvent(ctx context.Context, source *UserLogin, evtType RemoteEventType, evt RemoteEvent) (res EventHandlingResult) {
 	log := zerolog.Ctx(ctx)
-	if portal.MXID == "" {
+	if portal.MXID == "" && evtType != RemoteEventMessageRequest {
 		mcp, ok := evt.(RemoteEventThatMayCreatePortal)
 		if !ok || !mcp.ShouldCreatePortal() {
 			log.Debug().Msg("Dropping event as portal doesn't exist")
@@ -2295,7 +2366,7 @@ func (portal *Portal) handleRemoteEvent(ctx context.Context, source *UserLogin,
 	case RemoteEventUnknown:
 		log.Debug().Msg("Ignoring remote event with type unknown")
 		res = EventHandlingResultIgnored
-	case RemoteEventMessage, RemoteEventMessageUpsert:
+	case RemoteEventMessage, RemoteEventMessageUpsert, RemoteEventPollStart:
 		res = portal.handleRemoteMessage(ctx, source, evt.(RemoteMessage))
 	case RemoteEventEdit:
 		res = portal.handleRemoteEdit(ctx, source, evt.(RemoteEdit))
@@ -2324,6 +2395,12 @@ func (portal *Portal) handleRemoteEvent(ctx context.Context, source *UserLogin,
 		//portal.handleRemoteChatDelete(ctx, source, evt.(RemoteChatDelete))
 	case RemoteEventBackfill:
 		res = portal.handleRemoteBackfill(ctx, source, evt.(RemoteBackfill))
+	case RemoteEventMessageRequest:
+		res = portal.handleRemoteMessageRequest(ctx, source, evt.(RemoteMessageRequest))
+	case RemoteEventPollVote:
+		res = portal.handleRemotePollVote(ctx, source, evt.(RemotePollVote))
+	case RemoteEventPollEnd:
+		res = portal.handleRemotePollEnd(ctx, source, evt.(RemotePollEnd))
 	default:
 		log.Warn().Msg("Got remote event with unknown type")
 	}
@@ -2777,12 +2854,7 @@ func (portal *Portal) handleRemoteMessage(ctx context.Context, source *UserLogin
 		}
 	}
 	_, res = portal.sendConvertedMessage(ctx, evt.GetID(), intent, evt.GetSender().Sender, converted, ts, getStreamOrder(evt), nil)
-	if portal.currentlyTypingGhosts.Pop(intent.GetMXID()) {
-		err = intent.MarkTyping(ctx, portal.MXID, TypingTypeText, 0)
-		if err != nil {
-			log.Warn().Err(err).Msg("Failed to send stop typing event after bridging message")
-		}
-	}
+	portal.stopGhostTyping(ctx, intent)
 	return
 }
 
@@ -2846,12 +2918,7 @@ func (portal *Portal) handleRemoteEdit(ctx context.Context, source *UserLogin, e
 		return EventHandlingResultFailed.WithError(err)
 	}
 	res := portal.sendConvertedEdit(ctx, existing[0].ID, evt.GetSender().Sender, converted, intent, ts, getStreamOrder(evt))
-	if portal.currentlyTypingGhosts.Pop(intent.GetMXID()) {
-		err = intent.MarkTyping(ctx, portal.MXID, TypingTypeText, 0)
-		if err != nil {
-			log.Warn().Err(err).Msg("Failed to send stop typing event after bridging edit")
-		}
-	}
+	portal.stopGhostTyping(ctx, intent)
 	return res
 }
 
@@ -3192,6 +3259,16 @@ func (portal *Portal) sendConvertedReaction(
 	}
 	if emojiID == "" {
 		dbReaction.Emoji = emoji
+	} else if customKey, customExtra := portal.getCustomEmojiReactionKey(ctx, emojiID); customKey != "" {
+		emoji = customKey
+		if len(customExtra) > 0 {
+			extraContent = maps.Clone(extraContent)
+			if extraContent == nil {
+				extraContent = customExtra
+			} else {
+				maps.Copy(extraContent, customExtra)
+			}
+		}
 	}
 	resp, err := intent.SendMessage(ctx, portal.MXID, event.EventReaction, &event.Content{
 		Parsed: &event.ReactionEventContent{
@@ -3500,10 +3577,27 @@ func (portal *Portal) handleRemoteTyping(ctx context.Context, source *UserLogin,
 		zerolog.Ctx(ctx).Err(err).Msg("Failed to bridge typing event")
 		return EventHandlingResultFailed.WithError(err)
 	}
+	portal.currentlyTypingLock.Lock()
+	defer portal.currentlyTypingLock.Unlock()
 	if timeout == 0 {
-		portal.currentlyTypingGhosts.Remove(intent.GetMXID())
+		delete(portal.currentlyTypingGhosts, intent.GetMXID())
 	} else {
-		portal.currentlyTypingGhosts.Add(intent.GetMXID())
+		now := time.Now()
+		existing, alreadyTyping := portal.currentlyTypingGhosts[intent.GetMXID()]
+		started := now
+		if alreadyTyping {
+			started = existing.started
+		}
+		portal.currentlyTypingGhosts[intent.GetMXID()] = &typingGhost{
+			intent:     intent,
+			typingType: typingType,
+			timeout:    timeout,
+			started:    started,
+			lastSent:   now,
+		}
+		if portal.Bridge.Network.GetCapabilities().Typing.RefreshRemote {
+			portal.startTypingLoop()
+		}
 	}
 	return EventHandlingResultSuccess
 }
@@ -3862,6 +3956,15 @@ type ChatInfo struct {
 
 	ExcludeChangesFromTimeline bool
 
+	// MessageRequest can be set to false to mark a pending message request as accepted,
+	// e.g. when the user accepted it on another device. New requests are created with [RemoteMessageRequest] events.
+	MessageRequest *bool
+
+	// EmojiPacks are custom emoji and sticker packs of the chat, which are bridged as MSC2545 room image packs.
+	// Existing packs are only updated if their content changed, but any new emojis are reuploaded every time,
+	// so this should only be set when the packs are expected to have changed.
+	EmojiPacks []*EmojiPack
+
 	ExtraUpdates ExtraUpdater[*Portal]
 }
 
@@ -4168,7 +4271,9 @@ func (portal *Portal) revertRoomMeta(ctx context.Context, evt *event.Event) {
 
 func (portal *Portal) getInitialMemberList(ctx context.Context, members *ChatMemberList, source *UserLogin, pl *event.PowerLevelsEventContent) (invite, functional []id.UserID, err error) {
 	if members == nil {
-		invite = []id.UserID{source.UserMXID}
+		if !portal.MessageRequest {
+			invite = []id.UserID{source.UserMXID}
+		}
 		return
 	}
 	var loginsInPortal []*UserLogin
@@ -4197,6 +4302,10 @@ func (portal *Portal) getInitialMemberList(ctx context.Context, members *ChatMem
 				ghost.UpdateInfo(ctx, member.UserInfo)
 			}
 		}
+		if portal.MessageRequest && member.IsFromMe {
+			// The user is invited separately after the room is created
+			continue
+		}
 		intent, extraUserID, err := portal.getIntentAndUserMXIDFor(ctx, member.EventSender, source, loginsInPortal, 0)
 		if err != nil {
 			return nil, nil, err
@@ -4278,6 +4387,10 @@ func (portal *Portal) syncParticipants(
 		return fmt.Errorf("failed to get current members: %w", err)
 	}
 	delete(currentMembers, portal.Bridge.Bot.GetMXID())
+	if portal.MessageRequest {
+		// Don't touch the user's membership while the message request is pending
+		delete(currentMembers, source.UserMXID)
+	}
 	powerChanged := members.PowerLevels.Apply(portal.Bridge.Bot.GetMXID(), currentPower)
 	addExcludeFromTimeline := func(raw map[string]any) {
 		_, hasKey := raw["com.beeper.exclude_from_timeline"]
@@ -4401,6 +4514,9 @@ func (portal *Portal) syncParticipants(
 				ghost.UpdateInfo(ctx, member.UserInfo)
 			}
 		}
+		if portal.MessageRequest && member.IsFromMe {
+			continue
+		}
 		intent, extraUserID, err := portal.getIntentAndUserMXIDFor(ctx, member.EventSender, source, loginsInPortal, 0)
 		if err != nil {
 			return err
@@ -4702,6 +4818,9 @@ func (portal *Portal) UpdateInfo(ctx context.Context, info *ChatInfo, source *Us
 		// TODO change detection instead of spamming this every time?
 		portal.sendRoomMeta(ctx, sender, ts, event.StateJoinRules, "", info.JoinRule, info.ExcludeChangesFromTimeline, nil)
 	}
+	if len(info.EmojiPacks) > 0 {
+		portal.updateEmojiPacks(ctx, info.EmojiPacks, sender, ts, info.ExcludeChangesFromTimeline)
+	}
 	if info.Type != nil && portal.RoomType != *info.Type {
 		if portal.MXID != "" && (*info.Type == database.RoomTypeSpace || portal.RoomType == database.RoomTypeSpace) {
 			zerolog.Ctx(ctx).Warn().
@@ -4713,6 +4832,13 @@ func (portal *Portal) UpdateInfo(ctx context.Context, info *ChatInfo, source *Us
 			portal.RoomType = *info.Type
 		}
 	}
+	acceptedMessageRequest := false
+	if info.MessageRequest != nil && !*info.MessageRequest && portal.MessageRequest {
+		zerolog.Ctx(ctx).Info().Msg("Message request was accepted on the remote network")
+		portal.MessageRequest = false
+		acceptedMessageRequest = portal.MXID != ""
+		changed = true
+	}
 	if info.Members != nil && portal.MXID != "" && source != nil {
 		err := portal.syncParticipants(ctx, info.Members, source, nil, time.Time{})
 		if err != nil {
@@ -4722,6 +4848,9 @@ func (portal *Portal) UpdateInfo(ctx context.Context, info *ChatInfo, source *Us
 	} else if info.Members != nil {
 		portal.updateOtherUser(ctx, info.Members)
 	}
+	if acceptedMessageRequest && source != nil && info.Members == nil {
+		portal.joinAcceptedMessageRequest(ctx, source)
+	}
 	changed = portal.UpdateInfoFromGhost(ctx, nil) || changed
 	if source != nil {
 		source.MarkInPortal(ctx, portal)
@@ -4933,6 +5062,9 @@ func (portal *Portal) createMatrixRoomInLoop(ctx context.Context, source *UserLo
 			Content: event.Content{Parsed: info.JoinRule},
 		})
 	}
+	if len(info.EmojiPacks) > 0 {
+		req.InitialState = append(req.InitialState, portal.getInitialEmojiPackState(ctx, info.EmojiPacks)...)
+	}
 	roomID, err := portal.Bridge.Bot.CreateRoom(ctx, &req)
 	if err != nil {
 		log.Err(err).Msg("Failed to create Matrix room")
@@ -4975,7 +5107,9 @@ func (portal *Portal) createMatrixRoomInLoop(ctx context.Context, source *UserLo
 	}
 	portal.updateUserLocalInfo(ctx, info.UserLocal, source, true)
 	if !autoJoinInvites {
-		if info.Members == nil {
+		if info.Members == nil && portal.MessageRequest {
+			// The user will be invited by the caller
+		} else if info.Members == nil {
 			dp := source.User.DoublePuppet(ctx)
 			if dp != nil {
 				err = dp.EnsureJoined(ctx, portal.MXID)
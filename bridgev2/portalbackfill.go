@@ -281,6 +281,7 @@ func (portal *Portal) sendBackfill(
 	inThread bool,
 	done func(),
 ) {
+	start := time.Now()
 	canBatchSend := portal.Bridge.Matrix.GetCapabilities().BatchSending
 	unreadThreshold := time.Duration(portal.Bridge.Config.Backfill.UnreadHoursThreshold) * time.Hour
 	forceMarkRead := unreadThreshold > 0 && time.Since(messages[len(messages)-1].Timestamp) > unreadThreshold
@@ -298,6 +299,7 @@ func (portal *Portal) sendBackfill(
 	if done != nil {
 		done()
 	}
+	portal.Bridge.TrackBackfill(getBackfillDirection(forceForward, inThread), len(messages), time.Since(start))
 	zerolog.Ctx(ctx).Debug().Msg("Backfill finished")
 	if !canBatchSend && !inThread && portal.Bridge.Config.Backfill.Threads.MaxInitialMessages > 0 {
 		for _, msg := range messages {
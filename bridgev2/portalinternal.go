@@ -85,8 +85,12 @@ func (portal *PortalInternals) SendTypings(ctx context.Context, userIDs []id.Use
 	(*Portal)(portal).sendTypings(ctx, userIDs, typing)
 }
 
-func (portal *PortalInternals) PeriodicTypingUpdater() {
-	(*Portal)(portal).periodicTypingUpdater()
+func (portal *PortalInternals) SendTyping(ctx context.Context, userID id.UserID, typingUser *typingMatrixUser, typing bool) {
+	(*Portal)(portal).sendTyping(ctx, userID, typingUser, typing)
+}
+
+func (portal *PortalInternals) StopMatrixTyping(ctx context.Context, userID id.UserID) {
+	(*Portal)(portal).stopMatrixTyping(ctx, userID)
 }
 
 func (portal *PortalInternals) CheckMessageContentCaps(caps *event.RoomFeatures, content *event.MessageEventContent) error {
@@ -0,0 +1,172 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/database"
+	"github.com/iKonoTelecomunicaciones/go/event"
+)
+
+func (portal *Portal) handleRemoteMessageRequest(ctx context.Context, source *UserLogin, evt RemoteMessageRequest) EventHandlingResult {
+	log := zerolog.Ctx(ctx)
+	if portal.MXID != "" {
+		log.Debug().
+			Bool("pending_message_request", portal.MessageRequest).
+			Msg("Ignoring message request for portal that already has a room")
+		return EventHandlingResultIgnored
+	}
+	info, err := evt.GetChatInfo(ctx, portal)
+	if err != nil {
+		log.Err(err).Msg("Failed to get chat info for message request")
+		return EventHandlingResultFailed.WithError(err)
+	}
+	portal.MessageRequest = true
+	err = portal.createMatrixRoomInLoop(ctx, source, info, nil)
+	if err != nil {
+		portal.MessageRequest = false
+		log.Err(err).Msg("Failed to create portal for message request")
+		return EventHandlingResultFailed.WithError(err)
+	}
+	inviter := portal.Bridge.Bot
+	if sender := evt.GetSender(); !sender.IsFromMe {
+		intent, ok := portal.GetIntentFor(ctx, sender, source, RemoteEventMessageRequest)
+		if ok {
+			inviter = intent
+		}
+	}
+	_, err = portal.sendStateWithIntentOrBot(ctx, inviter, event.StateMember, source.UserMXID.String(), &event.Content{
+		Parsed: &event.MemberEventContent{
+			Membership: event.MembershipInvite,
+			IsDirect:   portal.RoomType == database.RoomTypeDM,
+		},
+	}, getEventTS(evt))
+	if err != nil {
+		log.Err(err).Msg("Failed to invite user to message request portal")
+		return EventHandlingResultFailed.WithError(err)
+	}
+	log.Info().Stringer("inviter_mxid", inviter.GetMXID()).Msg("Invited user to message request portal")
+	return EventHandlingResultSuccess
+}
+
+type portalMessageRequestEvent struct {
+	ctx    context.Context
+	login  *UserLogin
+	accept bool
+	cb     func(error)
+}
+
+func (pmre *portalMessageRequestEvent) isPortalEvent() {}
+
+// AcceptMessageRequest accepts a pending message request on the remote network and joins the user to the portal
+// room using their double puppet. If double puppeting isn't enabled, the user stays invited to the room.
+func (portal *Portal) AcceptMessageRequest(ctx context.Context, login *UserLogin) error {
+	return portal.queueMessageRequestResponse(ctx, login, true)
+}
+
+// RejectMessageRequest rejects a pending message request on the remote network and deletes the portal room.
+func (portal *Portal) RejectMessageRequest(ctx context.Context, login *UserLogin) error {
+	return portal.queueMessageRequestResponse(ctx, login, false)
+}
+
+// queueMessageRequestResponse responds to a message request in the portal event loop, so that it doesn't race with
+// other events modifying the portal, and waits for the response to finish.
+func (portal *Portal) queueMessageRequestResponse(ctx context.Context, login *UserLogin, accept bool) error {
+	done := make(chan error, 1)
+	evt := &portalMessageRequestEvent{
+		ctx:    ctx,
+		login:  login,
+		accept: accept,
+		cb: func(err error) {
+			select {
+			case done <- err:
+			default:
+			}
+		},
+	}
+	if PortalEventBuffer == 0 {
+		go portal.queueEvent(ctx, evt)
+	} else {
+		portal.events <- evt
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}
+
+// handleMessageRequestResponse responds to a pending message request on the remote network. Accepted requests are
+// joined using the user's double puppet and rejected ones are deleted. This is used both for the public methods above
+// and for invites that the user accepts or rejects on Matrix.
+func (portal *Portal) handleMessageRequestResponse(ctx context.Context, login *UserLogin, accept bool) error {
+	err := portal.respondToMessageRequest(ctx, login, accept)
+	if err != nil {
+		return err
+	}
+	if !accept {
+		return portal.deleteRejectedMessageRequest(ctx)
+	}
+	portal.joinAcceptedMessageRequest(ctx, login)
+	return nil
+}
+
+func (portal *Portal) respondToMessageRequest(ctx context.Context, login *UserLogin, accept bool) error {
+	if !portal.MessageRequest {
+		return ErrNotMessageRequest
+	}
+	api, ok := login.Client.(MessageRequestHandlingNetworkAPI)
+	if !ok {
+		return ErrMessageRequestsNotSupported
+	}
+	err := api.HandleMatrixMessageRequest(ctx, &MatrixMessageRequest{
+		Portal: portal,
+		Accept: accept,
+	})
+	if err != nil {
+		return err
+	}
+	zerolog.Ctx(ctx).Info().Bool("accept", accept).Msg("Responded to message request")
+	portal.MessageRequest = false
+	err = portal.Save(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save portal after responding to message request: %w", err)
+	}
+	return nil
+}
+
+func (portal *Portal) joinAcceptedMessageRequest(ctx context.Context, login *UserLogin) {
+	dp := login.User.DoublePuppet(ctx)
+	if dp == nil {
+		return
+	}
+	err := dp.EnsureJoined(ctx, portal.MXID)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to join accepted message request portal with double puppet")
+	}
+}
+
+func (portal *Portal) deleteRejectedMessageRequest(ctx context.Context) error {
+	log := zerolog.Ctx(ctx)
+	log.Info().Msg("Deleting portal after message request was rejected")
+	roomID := portal.MXID
+	err := portal.Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete portal from database: %w", err)
+	}
+	err = portal.Bridge.Bot.DeleteRoom(ctx, roomID, false)
+	if err != nil {
+		// The portal is already gone, so there's no point in failing here
+		log.Err(err).Msg("Failed to delete Matrix room of rejected message request")
+	}
+	return nil
+}
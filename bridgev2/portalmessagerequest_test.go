@@ -0,0 +1,156 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/database"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+type messageRequestTestState struct {
+	lock      sync.Mutex
+	responses []bool
+	joined    []id.RoomID
+	deleted   []id.RoomID
+	err       error
+}
+
+type messageRequestTestMatrix struct {
+	testMatrixConnector
+	state *messageRequestTestState
+}
+
+func (m *messageRequestTestMatrix) BotIntent() MatrixAPI {
+	return &messageRequestTestIntent{state: m.state}
+}
+
+func (m *messageRequestTestMatrix) NewUserIntent(_ context.Context, _ id.UserID, token string) (MatrixAPI, string, error) {
+	return &messageRequestTestIntent{state: m.state}, token, nil
+}
+
+type messageRequestTestIntent struct {
+	MatrixAPI
+	state *messageRequestTestState
+}
+
+func (i *messageRequestTestIntent) EnsureJoined(_ context.Context, roomID id.RoomID, _ ...EnsureJoinedParams) error {
+	i.state.lock.Lock()
+	defer i.state.lock.Unlock()
+	i.state.joined = append(i.state.joined, roomID)
+	return nil
+}
+
+func (i *messageRequestTestIntent) DeleteRoom(_ context.Context, roomID id.RoomID, _ bool) error {
+	i.state.lock.Lock()
+	defer i.state.lock.Unlock()
+	i.state.deleted = append(i.state.deleted, roomID)
+	return nil
+}
+
+type messageRequestTestClient struct {
+	NetworkAPI
+	state *messageRequestTestState
+}
+
+func (c *messageRequestTestClient) HandleMatrixMessageRequest(_ context.Context, msg *MatrixMessageRequest) error {
+	c.state.lock.Lock()
+	defer c.state.lock.Unlock()
+	if c.state.err != nil {
+		return c.state.err
+	}
+	c.state.responses = append(c.state.responses, msg.Accept)
+	return nil
+}
+
+var messageRequestPortalKey = networkid.PortalKey{ID: "request"}
+
+func newMessageRequestTest(t *testing.T) (*Portal, *UserLogin, *messageRequestTestState) {
+	ctx := context.Background()
+	state := &messageRequestTestState{}
+	br := newTestBridge(t, newTestDB(t), nil, &messageRequestTestMatrix{state: state}, nil)
+	require.NoError(t, br.DB.Portal.Insert(ctx, &database.Portal{
+		PortalKey:      messageRequestPortalKey,
+		MXID:           "!request:example.com",
+		MessageRequest: true,
+	}))
+	portal, err := br.GetExistingPortalByKey(ctx, messageRequestPortalKey)
+	require.NoError(t, err)
+	user, err := br.GetUserByMXID(ctx, "@user:example.com")
+	require.NoError(t, err)
+	user.AccessToken = "token"
+	login := &UserLogin{
+		UserLogin: &database.UserLogin{ID: "login", UserMXID: user.MXID},
+		Bridge:    br,
+		User:      user,
+		Log:       zerolog.Nop(),
+		Client:    &messageRequestTestClient{state: state},
+	}
+	return portal, login, state
+}
+
+func TestPortal_HandleMessageRequestResponse_Accept(t *testing.T) {
+	ctx := context.Background()
+	portal, login, state := newMessageRequestTest(t)
+
+	require.NoError(t, portal.AcceptMessageRequest(ctx, login))
+	assert.Equal(t, []bool{true}, state.responses)
+	assert.Equal(t, []id.RoomID{"!request:example.com"}, state.joined)
+	assert.Empty(t, state.deleted)
+	dbPortal, err := portal.Bridge.DB.Portal.GetByKey(ctx, messageRequestPortalKey)
+	require.NoError(t, err)
+	require.NotNil(t, dbPortal)
+	assert.False(t, dbPortal.MessageRequest)
+
+	// The request can only be responded to once
+	assert.ErrorIs(t, portal.AcceptMessageRequest(ctx, login), ErrNotMessageRequest)
+	assert.Equal(t, []bool{true}, state.responses)
+}
+
+func TestPortal_HandleMessageRequestResponse_Reject(t *testing.T) {
+	ctx := context.Background()
+	portal, login, state := newMessageRequestTest(t)
+
+	require.NoError(t, portal.handleMessageRequestResponse(ctx, login, false))
+	assert.Equal(t, []bool{false}, state.responses)
+	assert.Empty(t, state.joined)
+	assert.Equal(t, []id.RoomID{"!request:example.com"}, state.deleted)
+	dbPortal, err := portal.Bridge.DB.Portal.GetByKey(ctx, messageRequestPortalKey)
+	require.NoError(t, err)
+	assert.Nil(t, dbPortal)
+}
+
+func TestPortal_HandleMessageRequestResponse_Errors(t *testing.T) {
+	ctx := context.Background()
+	portal, login, state := newMessageRequestTest(t)
+
+	// If the remote network fails, the request stays pending
+	state.err = errors.New("network error")
+	assert.ErrorIs(t, portal.handleMessageRequestResponse(ctx, login, false), state.err)
+	assert.True(t, portal.MessageRequest)
+	assert.Empty(t, state.deleted)
+	dbPortal, err := portal.Bridge.DB.Portal.GetByKey(ctx, messageRequestPortalKey)
+	require.NoError(t, err)
+	require.NotNil(t, dbPortal)
+	assert.True(t, dbPortal.MessageRequest)
+
+	login.Client = &testNetworkAPI{}
+	assert.ErrorIs(t, portal.handleMessageRequestResponse(ctx, login, true), ErrMessageRequestsNotSupported)
+}
+
+type testNetworkAPI struct {
+	NetworkAPI
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/database"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/event"
+)
+
+// PollOption is a single answer option in a remote poll.
+type PollOption struct {
+	// The ID of the option. Votes refer to options using this ID.
+	ID   string
+	Text string
+}
+
+// Poll is a poll on the remote network.
+type Poll struct {
+	Question string
+	Options  []PollOption
+	// The maximum number of options a user can select. Defaults to 1 if unset.
+	MaxSelections int
+	// If true, votes are hidden until the poll ends.
+	Undisclosed bool
+}
+
+func (poll *Poll) fallbackText() string {
+	var buf strings.Builder
+	buf.WriteString(poll.Question)
+	for i, opt := range poll.Options {
+		_, _ = fmt.Fprintf(&buf, "\n%d. %s", i+1, opt.Text)
+	}
+	return buf.String()
+}
+
+// ToConvertedPart converts the poll into a plain text m.room.message part that lists the options.
+// The MSC3381 poll start content is included as extra fields, so clients that support polls can render it as one.
+//
+// If dbMeta implements [database.MetaWithPollResults], the options are stored in it,
+// so that the bridge can aggregate votes and summarize the results when the poll ends.
+func (poll *Poll) ToConvertedPart(partID networkid.PartID, dbMeta any) *ConvertedMessagePart {
+	fallback := poll.fallbackText()
+	pollStart := &event.PollStart{
+		Kind:          event.PollKindDisclosed,
+		MaxSelections: max(poll.MaxSelections, 1),
+		Question:      event.MSC1767Message{Text: poll.Question},
+		Answers:       make([]event.PollAnswer, len(poll.Options)),
+	}
+	if poll.Undisclosed {
+		pollStart.Kind = event.PollKindUndisclosed
+	}
+	results := &database.PollResults{
+		Options:     make(map[string]string, len(poll.Options)),
+		OptionOrder: make([]string, len(poll.Options)),
+	}
+	for i, opt := range poll.Options {
+		pollStart.Answers[i] = event.PollAnswer{ID: opt.ID, MSC1767Message: event.MSC1767Message{Text: opt.Text}}
+		results.Options[opt.ID] = opt.Text
+		results.OptionOrder[i] = opt.ID
+	}
+	if meta, ok := dbMeta.(database.MetaWithPollResults); ok {
+		meta.SetPollResults(results)
+	}
+	return &ConvertedMessagePart{
+		ID:   partID,
+		Type: event.EventMessage,
+		Content: &event.MessageEventContent{
+			MsgType: event.MsgText,
+			Body:    fallback,
+		},
+		Extra: map[string]any{
+			"org.matrix.msc1767.text":       fallback,
+			"org.matrix.msc3381.poll.start": pollStart,
+		},
+		DBMetadata: dbMeta,
+	}
+}
+
+func (portal *Portal) getRemotePollTarget(ctx context.Context, evt RemoteEventWithTargetMessage) (*database.Message, EventHandlingResult) {
+	log := zerolog.Ctx(ctx)
+	pollMsg, err := portal.getTargetMessagePart(ctx, evt)
+	if err != nil {
+		log.Err(err).Msg("Failed to get target poll message")
+		return nil, EventHandlingResultFailed.WithError(err)
+	} else if pollMsg == nil {
+		log.Warn().Msg("Target poll message not found")
+		return nil, EventHandlingResultIgnored
+	} else if pollMsg.HasFakeMXID() {
+		log.Debug().Msg("Ignoring poll event targeting message that wasn't bridged")
+		return nil, EventHandlingResultIgnored
+	}
+	return pollMsg, EventHandlingResultSuccess
+}
+
+// updatePollResults updates the aggregated results of the given poll message, if the message metadata supports it.
+func (portal *Portal) updatePollResults(ctx context.Context, pollMsg *database.Message, update func(results *database.PollResults)) *database.PollResults {
+	meta, ok := pollMsg.Metadata.(database.MetaWithPollResults)
+	if !ok {
+		return nil
+	}
+	results := meta.GetPollResults()
+	if results == nil {
+		results = &database.PollResults{}
+	}
+	update(results)
+	meta.SetPollResults(results)
+	err := portal.Bridge.DB.Message.Update(ctx, pollMsg)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to save poll results to database")
+	}
+	return results
+}
+
+func (portal *Portal) handleRemotePollVote(ctx context.Context, source *UserLogin, evt RemotePollVote) EventHandlingResult {
+	log := zerolog.Ctx(ctx)
+	pollMsg, res := portal.getRemotePollTarget(ctx, evt)
+	if pollMsg == nil {
+		return res
+	}
+	intent, ok := portal.GetIntentFor(ctx, evt.GetSender(), source, RemoteEventPollVote)
+	if !ok {
+		return EventHandlingResultFailed
+	}
+	votes := evt.GetVotes()
+	if votes == nil {
+		votes = []string{}
+	}
+	resp, err := intent.SendMessage(ctx, portal.MXID, event.EventUnstablePollResponse, &event.Content{
+		Parsed: &event.PollResponseEventContent{
+			RelatesTo: event.RelatesTo{
+				Type:    event.RelReference,
+				EventID: pollMsg.MXID,
+			},
+			Response: event.PollResponse{Answers: votes},
+		},
+	}, &MatrixSendExtra{Timestamp: getEventTS(evt)})
+	if err != nil {
+		log.Err(err).Msg("Failed to send poll vote to Matrix")
+		return EventHandlingResultFailed.WithError(err)
+	}
+	log.Debug().Stringer("event_id", resp.EventID).Strs("votes", votes).Msg("Sent poll vote to Matrix")
+	if senderID := evt.GetSender().Sender; senderID != "" {
+		portal.updatePollResults(ctx, pollMsg, func(results *database.PollResults) {
+			results.SetVote(senderID, votes)
+		})
+	}
+	return EventHandlingResultSuccess
+}
+
+func (portal *Portal) handleRemotePollEnd(ctx context.Context, source *UserLogin, evt RemotePollEnd) EventHandlingResult {
+	log := zerolog.Ctx(ctx)
+	pollMsg, res := portal.getRemotePollTarget(ctx, evt)
+	if pollMsg == nil {
+		return res
+	}
+	intent, ok := portal.GetIntentFor(ctx, evt.GetSender(), source, RemoteEventPollEnd)
+	if !ok {
+		return EventHandlingResultFailed
+	}
+	results := portal.updatePollResults(ctx, pollMsg, func(results *database.PollResults) {
+		results.Ended = true
+	})
+	resp, err := intent.SendMessage(ctx, portal.MXID, event.EventUnstablePollEnd, &event.Content{
+		Parsed: &event.PollEndEventContent{
+			RelatesTo: event.RelatesTo{
+				Type:    event.RelReference,
+				EventID: pollMsg.MXID,
+			},
+			Text: pollEndText(results),
+		},
+	}, &MatrixSendExtra{Timestamp: getEventTS(evt)})
+	if err != nil {
+		log.Err(err).Msg("Failed to send poll end to Matrix")
+		return EventHandlingResultFailed.WithError(err)
+	}
+	log.Debug().Stringer("event_id", resp.EventID).Msg("Sent poll end to Matrix")
+	return EventHandlingResultSuccess
+}
+
+func pollEndText(results *database.PollResults) string {
+	if results == nil {
+		return "The poll has ended."
+	}
+	top, count := results.TopOptions()
+	if len(top) == 0 {
+		return "The poll has ended. Nobody voted."
+	}
+	names := make([]string, len(top))
+	for i, optionID := range top {
+		names[i] = results.Options[optionID]
+		if names[i] == "" {
+			names[i] = optionID
+		}
+	}
+	votesWord := "votes"
+	if count == 1 {
+		votesWord = "vote"
+	}
+	if len(names) == 1 {
+		return fmt.Sprintf("The poll has ended. Top answer: %s (%d %s)", names[0], count, votesWord)
+	}
+	return fmt.Sprintf("The poll has ended. Top answers: %s (%d %s each)", strings.Join(names, ", "), count, votesWord)
+}
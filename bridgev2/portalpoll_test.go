@@ -0,0 +1,143 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/database"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/event"
+)
+
+type pollTestMessageMeta struct {
+	database.PollMetadata
+}
+
+type pollTestNetwork struct {
+	testNetworkConnector
+}
+
+func (n *pollTestNetwork) GetDBMetaTypes() database.MetaTypes {
+	return database.MetaTypes{Message: func() any { return &pollTestMessageMeta{} }}
+}
+
+var testPoll = &Poll{
+	Question:      "Lunch?",
+	Options:       []PollOption{{ID: "pizza", Text: "Pizza"}, {ID: "sushi", Text: "Sushi"}, {ID: "salad", Text: "Salad"}},
+	MaxSelections: 2,
+}
+
+func TestPollEndText(t *testing.T) {
+	options := map[string]string{"pizza": "Pizza", "sushi": "Sushi"}
+	for _, tc := range []struct {
+		name     string
+		results  *database.PollResults
+		expected string
+	}{
+		{"NoResults", nil, "The poll has ended."},
+		{"NoVotes", &database.PollResults{Options: options}, "The poll has ended. Nobody voted."},
+		{"OneVote", &database.PollResults{
+			Options:     options,
+			OptionOrder: []string{"pizza", "sushi"},
+			Votes:       map[networkid.UserID][]string{"alice": {"sushi"}},
+		}, "The poll has ended. Top answer: Sushi (1 vote)"},
+		{"Tie", &database.PollResults{
+			Options:     options,
+			OptionOrder: []string{"pizza", "sushi"},
+			Votes:       map[networkid.UserID][]string{"alice": {"sushi"}, "bob": {"pizza"}},
+		}, "The poll has ended. Top answers: Pizza, Sushi (1 vote each)"},
+		{"UnknownOption", &database.PollResults{
+			Options: options,
+			Votes:   map[networkid.UserID][]string{"alice": {"other"}, "bob": {"other"}},
+		}, "The poll has ended. Top answer: other (2 votes)"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, pollEndText(tc.results))
+		})
+	}
+}
+
+func TestPoll_ToConvertedPart(t *testing.T) {
+	meta := &pollTestMessageMeta{}
+	part := testPoll.ToConvertedPart("", meta)
+	assert.Equal(t, event.EventMessage, part.Type)
+	assert.Equal(t, event.MsgText, part.Content.MsgType)
+	assert.Equal(t, "Lunch?\n1. Pizza\n2. Sushi\n3. Salad", part.Content.Body)
+	assert.Equal(t, part.Content.Body, part.Extra["org.matrix.msc1767.text"])
+
+	pollStart, ok := part.Extra["org.matrix.msc3381.poll.start"].(*event.PollStart)
+	require.True(t, ok)
+	assert.Equal(t, event.PollKindDisclosed, pollStart.Kind)
+	assert.Equal(t, 2, pollStart.MaxSelections)
+	assert.Equal(t, "Lunch?", pollStart.Question.Text)
+	require.Len(t, pollStart.Answers, 3)
+	assert.Equal(t, "sushi", pollStart.Answers[1].ID)
+	assert.Equal(t, "Sushi", pollStart.Answers[1].Text)
+
+	require.NotNil(t, meta.Poll)
+	assert.Equal(t, []string{"pizza", "sushi", "salad"}, meta.Poll.OptionOrder)
+	assert.Equal(t, "Salad", meta.Poll.Options["salad"])
+
+	undisclosed := (&Poll{Question: "Secret", Undisclosed: true}).ToConvertedPart("", nil)
+	pollStart = undisclosed.Extra["org.matrix.msc3381.poll.start"].(*event.PollStart)
+	assert.Equal(t, event.PollKindUndisclosed, pollStart.Kind)
+	assert.Equal(t, 1, pollStart.MaxSelections)
+}
+
+func TestPortal_UpdatePollResults(t *testing.T) {
+	ctx := context.Background()
+	br := newTestBridge(t, newTestDB(t), nil, nil, &pollTestNetwork{})
+	portalKey := networkid.PortalKey{ID: "chat"}
+	require.NoError(t, br.DB.Portal.Insert(ctx, &database.Portal{PortalKey: portalKey, MXID: "!chat:example.com"}))
+	portal, err := br.GetExistingPortalByKey(ctx, portalKey)
+	require.NoError(t, err)
+	part := testPoll.ToConvertedPart("", &pollTestMessageMeta{})
+	require.NoError(t, br.DB.Message.Insert(ctx, &database.Message{
+		ID:        "poll",
+		MXID:      "$poll",
+		Room:      portalKey,
+		SenderID:  "alice",
+		Timestamp: time.Now(),
+		Metadata:  part.DBMetadata,
+	}))
+
+	for _, vote := range []struct {
+		sender networkid.UserID
+		votes  []string
+	}{
+		{"alice", []string{"pizza"}},
+		{"bob", []string{"pizza", "sushi"}},
+		{"carol", []string{"salad"}},
+		{"carol", []string{}},
+	} {
+		pollMsg, err := br.DB.Message.GetFirstPartByID(ctx, "", "poll")
+		require.NoError(t, err)
+		portal.updatePollResults(ctx, pollMsg, func(results *database.PollResults) {
+			results.SetVote(vote.sender, vote.votes)
+		})
+	}
+
+	pollMsg, err := br.DB.Message.GetFirstPartByID(ctx, "", "poll")
+	require.NoError(t, err)
+	results := portal.updatePollResults(ctx, pollMsg, func(results *database.PollResults) {
+		results.Ended = true
+	})
+	assert.Equal(t, "The poll has ended. Top answer: Pizza (2 votes)", pollEndText(results))
+
+	pollMsg, err = br.DB.Message.GetFirstPartByID(ctx, "", "poll")
+	require.NoError(t, err)
+	saved := pollMsg.Metadata.(*pollTestMessageMeta).Poll
+	require.NotNil(t, saved)
+	assert.True(t, saved.Ended)
+	assert.Equal(t, map[string]int{"pizza": 2, "sushi": 1}, saved.Counts())
+}
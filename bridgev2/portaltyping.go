@@ -0,0 +1,160 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// DefaultMaxTypingDuration is the default value for [TypingCapabilities.MaxDuration].
+const DefaultMaxTypingDuration = 2 * time.Minute
+
+const typingLoopInterval = 1 * time.Second
+
+type typingMatrixUser struct {
+	login    *UserLogin
+	started  time.Time
+	lastSent time.Time
+}
+
+type typingGhost struct {
+	intent     MatrixAPI
+	typingType TypingType
+	timeout    time.Duration
+	started    time.Time
+	lastSent   time.Time
+}
+
+// startTypingLoop starts the typing refresh loop if it's not already running and the network connector wants
+// typing notifications to be refreshed. The caller must hold currentlyTypingLock.
+func (portal *Portal) startTypingLoop() {
+	if portal.typingLoopRunning {
+		return
+	}
+	caps := portal.Bridge.Network.GetCapabilities().Typing
+	if caps.MatrixRefreshInterval <= 0 && !caps.RefreshRemote {
+		return
+	}
+	if caps.MaxDuration <= 0 {
+		caps.MaxDuration = DefaultMaxTypingDuration
+	}
+	portal.typingLoopRunning = true
+	go portal.typingLoop(caps)
+}
+
+func (portal *Portal) typingLoop(caps TypingCapabilities) {
+	log := portal.Log.With().Str("component", "typing loop").Logger()
+	ctx := log.WithContext(portal.Bridge.BackgroundCtx)
+	ticker := time.NewTicker(typingLoopInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			portal.currentlyTypingLock.Lock()
+			portal.typingLoopRunning = false
+			portal.currentlyTypingLock.Unlock()
+			return
+		case <-ticker.C:
+		}
+		if !portal.refreshTypings(ctx, caps) {
+			return
+		}
+	}
+}
+
+type matrixTypingRefresh struct {
+	userID     id.UserID
+	typingUser *typingMatrixUser
+	typing     bool
+}
+
+type ghostTypingRefresh struct {
+	ghost   *typingGhost
+	timeout time.Duration
+}
+
+// refreshTypings refreshes or stops typing notifications that need it and returns false if the loop should stop.
+// The lock is only held while deciding what to send, so that slow requests don't block the portal event loop.
+func (portal *Portal) refreshTypings(ctx context.Context, caps TypingCapabilities) bool {
+	matrixRefreshes, ghostRefreshes, keepRunning := portal.collectTypingRefreshes(ctx, caps)
+	for _, refresh := range matrixRefreshes {
+		portal.sendTyping(ctx, refresh.userID, refresh.typingUser, refresh.typing)
+	}
+	for _, refresh := range ghostRefreshes {
+		err := refresh.ghost.intent.MarkTyping(ctx, portal.MXID, refresh.ghost.typingType, refresh.timeout)
+		if err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).
+				Stringer("ghost_mxid", refresh.ghost.intent.GetMXID()).
+				Bool("typing", refresh.timeout > 0).
+				Msg("Failed to refresh typing")
+		}
+	}
+	return keepRunning
+}
+
+func (portal *Portal) collectTypingRefreshes(ctx context.Context, caps TypingCapabilities) (matrixRefreshes []matrixTypingRefresh, ghostRefreshes []ghostTypingRefresh, keepRunning bool) {
+	portal.currentlyTypingLock.Lock()
+	defer portal.currentlyTypingLock.Unlock()
+	now := time.Now()
+	if caps.MatrixRefreshInterval > 0 {
+		for userID, typingUser := range portal.currentlyTypingUsers {
+			if now.Sub(typingUser.started) > caps.MaxDuration {
+				zerolog.Ctx(ctx).Debug().Stringer("user_id", userID).Msg("Matrix user has been typing too long, stopping typing")
+				delete(portal.currentlyTypingUsers, userID)
+				portal.currentlyTyping = slices.DeleteFunc(portal.currentlyTyping, func(typingUserID id.UserID) bool {
+					return typingUserID == userID
+				})
+				matrixRefreshes = append(matrixRefreshes, matrixTypingRefresh{userID: userID, typingUser: typingUser, typing: false})
+			} else if now.Sub(typingUser.lastSent) >= caps.MatrixRefreshInterval {
+				typingUser.lastSent = now
+				matrixRefreshes = append(matrixRefreshes, matrixTypingRefresh{userID: userID, typingUser: typingUser, typing: true})
+			}
+		}
+	}
+	for ghostID, ghost := range portal.currentlyTypingGhosts {
+		if !caps.RefreshRemote {
+			// Typing notifications that aren't refreshed expire on their own, so just forget about them
+			if now.Sub(ghost.lastSent) > ghost.timeout {
+				delete(portal.currentlyTypingGhosts, ghostID)
+			}
+		} else if now.Sub(ghost.started) > caps.MaxDuration {
+			zerolog.Ctx(ctx).Debug().Stringer("ghost_mxid", ghostID).Msg("Ghost has been typing too long, stopping typing")
+			delete(portal.currentlyTypingGhosts, ghostID)
+			ghostRefreshes = append(ghostRefreshes, ghostTypingRefresh{ghost: ghost, timeout: 0})
+		} else if now.Sub(ghost.lastSent) >= ghost.timeout/2 {
+			ghost.lastSent = now
+			ghostRefreshes = append(ghostRefreshes, ghostTypingRefresh{ghost: ghost, timeout: ghost.timeout})
+		}
+	}
+	hasMatrixTypings := caps.MatrixRefreshInterval > 0 && len(portal.currentlyTypingUsers) > 0
+	hasRemoteTypings := caps.RefreshRemote && len(portal.currentlyTypingGhosts) > 0
+	keepRunning = hasMatrixTypings || hasRemoteTypings
+	if !keepRunning {
+		portal.typingLoopRunning = false
+	}
+	return
+}
+
+// stopGhostTyping clears the typing notification of the given ghost, e.g. because a message from them was bridged.
+func (portal *Portal) stopGhostTyping(ctx context.Context, intent MatrixAPI) {
+	portal.currentlyTypingLock.Lock()
+	_, wasTyping := portal.currentlyTypingGhosts[intent.GetMXID()]
+	delete(portal.currentlyTypingGhosts, intent.GetMXID())
+	portal.currentlyTypingLock.Unlock()
+	if wasTyping {
+		err := intent.MarkTyping(ctx, portal.MXID, TypingTypeText, 0)
+		if err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Msg("Failed to send stop typing event after bridging message")
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/database"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+type typingCall struct {
+	typing       bool
+	lockHeld     bool
+	ghostMXID    id.UserID
+	ghostTimeout time.Duration
+}
+
+type typingTestRecorder struct {
+	portal *Portal
+	lock   sync.Mutex
+	calls  []typingCall
+}
+
+func (r *typingTestRecorder) record(call typingCall) {
+	// The typing lock must not be held while doing network requests
+	if r.portal.currentlyTypingLock.TryLock() {
+		r.portal.currentlyTypingLock.Unlock()
+	} else {
+		call.lockHeld = true
+	}
+	r.lock.Lock()
+	r.calls = append(r.calls, call)
+	r.lock.Unlock()
+}
+
+func (r *typingTestRecorder) popCalls() []typingCall {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	calls := r.calls
+	r.calls = nil
+	return calls
+}
+
+type typingTestClient struct {
+	NetworkAPI
+	rec *typingTestRecorder
+}
+
+func (c *typingTestClient) HandleMatrixTyping(_ context.Context, msg *MatrixTyping) error {
+	c.rec.record(typingCall{typing: msg.IsTyping})
+	return nil
+}
+
+type typingTestIntent struct {
+	MatrixAPI
+	mxid id.UserID
+	rec  *typingTestRecorder
+}
+
+func (i *typingTestIntent) GetMXID() id.UserID { return i.mxid }
+
+func (i *typingTestIntent) MarkTyping(_ context.Context, _ id.RoomID, _ TypingType, timeout time.Duration) error {
+	i.rec.record(typingCall{typing: timeout > 0, ghostMXID: i.mxid, ghostTimeout: timeout})
+	return nil
+}
+
+func newTypingTestPortal() (*Portal, *typingTestRecorder) {
+	portal := &Portal{
+		Portal:                &database.Portal{MXID: "!room:example.com"},
+		currentlyTypingUsers:  make(map[id.UserID]*typingMatrixUser),
+		currentlyTypingGhosts: make(map[id.UserID]*typingGhost),
+	}
+	return portal, &typingTestRecorder{portal: portal}
+}
+
+func TestPortal_RefreshTypings(t *testing.T) {
+	ctx := context.Background()
+	caps := TypingCapabilities{MatrixRefreshInterval: 5 * time.Second, RefreshRemote: true, MaxDuration: time.Minute}
+	portal, rec := newTypingTestPortal()
+	login := &UserLogin{Client: &typingTestClient{rec: rec}}
+	now := time.Now()
+	portal.currentlyTyping = []id.UserID{"@fresh:example.com", "@refresh:example.com", "@expired:example.com"}
+	portal.currentlyTypingUsers["@fresh:example.com"] = &typingMatrixUser{login: login, started: now, lastSent: now}
+	portal.currentlyTypingUsers["@refresh:example.com"] = &typingMatrixUser{login: login, started: now.Add(-10 * time.Second), lastSent: now.Add(-6 * time.Second)}
+	portal.currentlyTypingUsers["@expired:example.com"] = &typingMatrixUser{login: login, started: now.Add(-2 * time.Minute), lastSent: now.Add(-time.Second)}
+	refreshGhost := &typingTestIntent{mxid: "@ghost_refresh:example.com", rec: rec}
+	expiredGhost := &typingTestIntent{mxid: "@ghost_expired:example.com", rec: rec}
+	portal.currentlyTypingGhosts[refreshGhost.mxid] = &typingGhost{
+		intent: refreshGhost, timeout: 10 * time.Second, started: now.Add(-20 * time.Second), lastSent: now.Add(-6 * time.Second),
+	}
+	portal.currentlyTypingGhosts[expiredGhost.mxid] = &typingGhost{
+		intent: expiredGhost, timeout: 10 * time.Second, started: now.Add(-2 * time.Minute), lastSent: now.Add(-time.Second),
+	}
+
+	assert.True(t, portal.refreshTypings(ctx, caps))
+	assert.ElementsMatch(t, []typingCall{
+		{typing: true},
+		{typing: false},
+		{typing: true, ghostMXID: refreshGhost.mxid, ghostTimeout: 10 * time.Second},
+		{typing: false, ghostMXID: expiredGhost.mxid},
+	}, rec.popCalls())
+	assert.Equal(t, []id.UserID{"@fresh:example.com", "@refresh:example.com"}, portal.currentlyTyping)
+	assert.Len(t, portal.currentlyTypingGhosts, 1)
+
+	// Nothing is due yet right after a refresh
+	assert.True(t, portal.refreshTypings(ctx, caps))
+	assert.Empty(t, rec.popCalls())
+
+	// Sending a message stops typing on the remote network too
+	portal.stopMatrixTyping(ctx, "@fresh:example.com")
+	portal.stopMatrixTyping(ctx, "@refresh:example.com")
+	portal.stopMatrixTyping(ctx, "@unknown:example.com")
+	assert.Equal(t, []typingCall{{typing: false}, {typing: false}}, rec.popCalls())
+	assert.Empty(t, portal.currentlyTyping)
+
+	portal.stopGhostTyping(ctx, refreshGhost)
+	assert.Equal(t, []typingCall{{typing: false, ghostMXID: refreshGhost.mxid}}, rec.popCalls())
+
+	// The loop stops once nobody is typing
+	portal.typingLoopRunning = true
+	assert.False(t, portal.refreshTypings(ctx, caps))
+	assert.False(t, portal.typingLoopRunning)
+}
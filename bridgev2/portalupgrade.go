@@ -0,0 +1,61 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// UpgradeRoom upgrades the portal's Matrix room to the given room version using the bridge bot
+// and makes the portal point at the replacement room.
+//
+// Messages and other mappings are stored per portal rather than per room, so they stay valid after the upgrade.
+// The given user's logins are preferred for fetching the chat info used to fill the new room. The user may be nil,
+// in which case any login in the portal will be used.
+func (portal *Portal) UpgradeRoom(ctx context.Context, newVersion id.RoomVersion, user *User) (id.RoomID, error) {
+	upgrader, ok := portal.Bridge.Bot.(RoomUpgradingMatrixAPI)
+	if !ok {
+		return "", ErrRoomUpgradeNotSupported
+	}
+	// Hold the room create lock for the whole upgrade, so that the tombstone sent by the server
+	// won't be processed as a normal tombstone (which would delete the old room) before MXID is updated.
+	portal.roomCreateLock.Lock()
+	defer portal.roomCreateLock.Unlock()
+	if portal.MXID == "" {
+		return "", ErrPortalHasNoRoom
+	}
+	log := zerolog.Ctx(ctx).With().
+		Str("action", "upgrade room").
+		Stringer("old_room_id", portal.MXID).
+		Str("new_room_version", string(newVersion)).
+		Logger()
+	ctx = log.WithContext(ctx)
+	log.Info().Msg("Upgrading portal room")
+	newRoomID, err := upgrader.UpgradeRoom(ctx, portal.MXID, newVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to upgrade room: %w", err)
+	}
+	log.Info().Stringer("new_room_id", newRoomID).Msg("Room upgraded, updating portal MXID")
+	// The old room is already tombstoned at this point, so switching the portal over must not be cancelled.
+	ctx = context.WithoutCancel(ctx)
+	err = portal.UpdateMatrixRoomID(ctx, newRoomID, UpdateMatrixRoomIDParams{
+		RoomCreateAlreadyLocked: true,
+		FetchInfoVia:            user,
+	})
+	if err != nil {
+		return newRoomID, fmt.Errorf("failed to update portal MXID: %w", err)
+	}
+	if user == nil {
+		go portal.updateInfoAfterTombstone(ctx, nil)
+	}
+	return newRoomID, nil
+}
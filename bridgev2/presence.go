@@ -0,0 +1,84 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+
+	"github.com/iKonoTelecomunicaciones/go/event"
+)
+
+func (br *Bridge) handleRemotePresence(ctx context.Context, source *UserLogin, evt RemotePresence) EventHandlingResult {
+	log := zerolog.Ctx(ctx)
+	if !br.Config.Presence.FromRemote {
+		return EventHandlingResultIgnored
+	}
+	sender := evt.GetSender()
+	if sender.IsFromMe || sender.Sender == "" {
+		// The user's own presence is managed by their Matrix clients
+		return EventHandlingResultIgnored
+	}
+	ghost, err := br.GetExistingGhostByID(ctx, sender.Sender)
+	if err != nil {
+		log.Err(err).Str("ghost_id", string(sender.Sender)).Msg("Failed to get ghost to bridge presence")
+		return EventHandlingResultFailed.WithError(err)
+	} else if ghost == nil {
+		log.Debug().Str("ghost_id", string(sender.Sender)).Msg("Ignoring presence of unknown ghost")
+		return EventHandlingResultIgnored
+	}
+	presenceAPI, ok := ghost.Intent.(PresenceMatrixAPI)
+	if !ok {
+		return EventHandlingResultIgnored
+	}
+	var statusMsg string
+	if withStatus, ok := evt.(RemotePresenceWithStatus); ok {
+		statusMsg = withStatus.GetStatusMessage()
+	}
+	err = presenceAPI.SetPresence(ctx, evt.GetPresence(), statusMsg)
+	if err != nil {
+		log.Err(err).Stringer("ghost_mxid", ghost.Intent.GetMXID()).Msg("Failed to bridge presence")
+		return EventHandlingResultFailed.WithError(err)
+	}
+	return EventHandlingResultSuccess
+}
+
+func (br *Bridge) handleMatrixPresence(ctx context.Context, evt *event.Event) EventHandlingResult {
+	if !br.Config.Presence.FromMatrix || evt.Sender == "" {
+		return EventHandlingResultIgnored
+	}
+	log := zerolog.Ctx(ctx)
+	// Only users who already use the bridge are interesting, so don't create new users here
+	user, err := br.GetExistingUserByMXID(ctx, evt.Sender)
+	if err != nil {
+		log.Err(err).Msg("Failed to get user to bridge presence")
+		return EventHandlingResultFailed.WithError(err)
+	} else if user == nil || !user.Permissions.SendEvents {
+		return EventHandlingResultIgnored
+	}
+	msg := &MatrixPresence{
+		Event:   evt,
+		Content: evt.Content.AsPresence(),
+		User:    user,
+	}
+	res := EventHandlingResultIgnored
+	for _, login := range user.GetUserLogins() {
+		presenceAPI, ok := login.Client.(PresenceHandlingNetworkAPI)
+		if !ok {
+			continue
+		}
+		err = presenceAPI.HandleMatrixPresence(ctx, msg)
+		if err != nil {
+			log.Err(err).Str("login_id", string(login.ID)).Msg("Failed to bridge presence to remote network")
+			res = EventHandlingResultFailed.WithError(err)
+		} else if res.Error == nil {
+			res = EventHandlingResultSuccess
+		}
+	}
+	return res
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package provisionutil
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/database"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+type RespMessageRequest struct {
+	RoomID         id.RoomID             `json:"room_id"`
+	PortalID       networkid.PortalID    `json:"portal_id"`
+	PortalReceiver networkid.UserLoginID `json:"portal_receiver,omitempty"`
+	RoomType       database.RoomType     `json:"room_type,omitempty"`
+	Name           string                `json:"name,omitempty"`
+	AvatarURL      id.ContentURIString   `json:"avatar_url,omitempty"`
+	OtherUserID    networkid.UserID      `json:"other_user_id,omitempty"`
+}
+
+type RespGetMessageRequests struct {
+	Requests []*RespMessageRequest `json:"requests"`
+}
+
+func GetMessageRequests(ctx context.Context, login *bridgev2.UserLogin) (*RespGetMessageRequests, error) {
+	dbPortals, err := login.Bridge.DB.Portal.GetMessageRequestsForLogin(ctx, login.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message request portals: %w", err)
+	}
+	resp := &RespGetMessageRequests{Requests: make([]*RespMessageRequest, 0, len(dbPortals))}
+	for _, dbPortal := range dbPortals {
+		portal, err := login.Bridge.GetExistingPortalByKey(ctx, dbPortal.PortalKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get portal %s: %w", dbPortal.PortalKey, err)
+		} else if portal == nil || !portal.MessageRequest || portal.MXID == "" {
+			continue
+		}
+		req := &RespMessageRequest{
+			RoomID:         portal.MXID,
+			PortalID:       portal.ID,
+			PortalReceiver: portal.Receiver,
+			RoomType:       portal.RoomType,
+			Name:           portal.Name,
+			AvatarURL:      portal.AvatarMXC,
+			OtherUserID:    portal.OtherUserID,
+		}
+		if portal.OtherUserID != "" && (req.Name == "" || req.AvatarURL == "") {
+			ghost, err := login.Bridge.GetExistingGhostByID(ctx, portal.OtherUserID)
+			if err != nil {
+				zerolog.Ctx(ctx).Err(err).Str("ghost_id", string(portal.OtherUserID)).
+					Msg("Failed to get other user of message request")
+			} else if ghost != nil {
+				if req.Name == "" {
+					req.Name = ghost.Name
+				}
+				if req.AvatarURL == "" {
+					req.AvatarURL = ghost.AvatarMXC
+				}
+			}
+		}
+		resp.Requests = append(resp.Requests, req)
+	}
+	return resp, nil
+}
+
+func RespondToMessageRequest(ctx context.Context, login *bridgev2.UserLogin, roomID id.RoomID, accept bool) error {
+	portal, err := getPortalForLogin(ctx, login, roomID)
+	if err != nil {
+		return err
+	}
+	if accept {
+		return portal.AcceptMessageRequest(ctx, login)
+	}
+	return portal.RejectMessageRequest(ctx, login)
+}
+
+// getPortalForLogin finds the portal with the given room ID, making sure the login is in the portal.
+func getPortalForLogin(ctx context.Context, login *bridgev2.UserLogin, roomID id.RoomID) (*bridgev2.Portal, error) {
+	portal, err := login.Bridge.GetPortalByMXID(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portal: %w", err)
+	} else if portal == nil || (portal.Receiver != "" && portal.Receiver != login.ID) {
+		return nil, bridgev2.RespError(mautrix.MNotFound.WithMessage("Portal not found"))
+	} else if portal.Receiver == "" {
+		up, err := login.Bridge.DB.UserPortal.Get(ctx, login.UserLogin, portal.PortalKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user portal: %w", err)
+		} else if up == nil {
+			return nil, bridgev2.RespError(mautrix.MNotFound.WithMessage("Portal not found"))
+		}
+	}
+	return portal, nil
+}
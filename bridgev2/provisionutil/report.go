@@ -0,0 +1,41 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package provisionutil
+
+import (
+	"context"
+	"fmt"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+func Report(ctx context.Context, login *bridgev2.UserLogin, roomID id.RoomID, eventID id.EventID, reason string) error {
+	if _, ok := login.Client.(bridgev2.ReportingNetworkAPI); !ok {
+		return bridgev2.ErrReportingNotSupported
+	}
+	portal, err := getPortalForLogin(ctx, login, roomID)
+	if err != nil {
+		return err
+	}
+	return portal.ReportEvent(ctx, login, eventID, reason)
+}
+
+func SetUserBlocked(ctx context.Context, login *bridgev2.UserLogin, userID networkid.UserID, blocked bool) error {
+	if _, ok := login.Client.(bridgev2.ReportingNetworkAPI); !ok {
+		return bridgev2.ErrReportingNotSupported
+	}
+	ghost, err := login.Bridge.GetExistingGhostByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get ghost: %w", err)
+	} else if ghost == nil {
+		return bridgev2.RespError(mautrix.MNotFound.WithMessage("User not found"))
+	}
+	return login.SetUserBlocked(ctx, ghost, blocked)
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package provisionutil
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"go.mau.fi/util/jsontime"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+const (
+	DefaultMessageSearchLimit = 20
+	MaxMessageSearchLimit     = 100
+)
+
+type RespSearchMessages struct {
+	Results    []*RespMessageSearchResult `json:"results"`
+	NextCursor networkid.PaginationCursor `json:"next_cursor,omitempty"`
+}
+
+type RespMessageSearchResult struct {
+	// The Matrix room and event IDs of the message. These are empty if the message hasn't been bridged.
+	RoomID  id.RoomID  `json:"room_id,omitempty"`
+	EventID id.EventID `json:"event_id,omitempty"`
+
+	PortalID   networkid.PortalID  `json:"portal_id"`
+	MessageID  networkid.MessageID `json:"message_id"`
+	SenderID   networkid.UserID    `json:"sender_id,omitempty"`
+	SenderMXID id.UserID           `json:"sender_mxid,omitempty"`
+	Timestamp  jsontime.UnixMilli  `json:"timestamp,omitempty"`
+	Snippet    string              `json:"snippet,omitempty"`
+}
+
+// SearchMessages searches the message history of the given login on the remote network.
+// If roomID is set, only the corresponding portal is searched.
+func SearchMessages(
+	ctx context.Context,
+	login *bridgev2.UserLogin,
+	query string,
+	roomID id.RoomID,
+	cursor networkid.PaginationCursor,
+	limit int,
+) (*RespSearchMessages, error) {
+	api, ok := login.Client.(bridgev2.MessageSearchingNetworkAPI)
+	if !ok {
+		return nil, bridgev2.RespError(mautrix.MUnrecognized.WithMessage("This bridge does not support searching messages"))
+	}
+	params := &bridgev2.MessageSearchParams{
+		Query:  query,
+		Cursor: cursor,
+		Limit:  limit,
+	}
+	if params.Limit <= 0 {
+		params.Limit = DefaultMessageSearchLimit
+	} else if params.Limit > MaxMessageSearchLimit {
+		params.Limit = MaxMessageSearchLimit
+	}
+	if roomID != "" {
+		var err error
+		params.Portal, err = getPortalForLogin(ctx, login, roomID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	resp, err := api.SearchMessages(ctx, params)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to search messages")
+		return nil, err
+	}
+	apiResp := &RespSearchMessages{
+		Results:    make([]*RespMessageSearchResult, len(resp.Results)),
+		NextCursor: resp.NextCursor,
+	}
+	portalRoomIDs := make(map[networkid.PortalKey]id.RoomID)
+	for i, result := range resp.Results {
+		apiResult := &RespMessageSearchResult{
+			PortalID:  result.PortalKey.ID,
+			MessageID: result.MessageID,
+			SenderID:  result.Sender.Sender,
+			Timestamp: jsontime.UM(result.Timestamp),
+			Snippet:   result.Snippet,
+		}
+		apiResp.Results[i] = apiResult
+		if result.Sender.IsFromMe {
+			apiResult.SenderMXID = login.UserMXID
+		} else if result.Sender.Sender != "" {
+			apiResult.SenderMXID = login.Bridge.Matrix.GhostIntent(result.Sender.Sender).GetMXID()
+		}
+		apiResult.RoomID, err = getSearchResultRoomID(ctx, login, result.PortalKey, portalRoomIDs)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := login.Bridge.DB.Message.GetFirstOrSpecificPartByID(ctx, login.ID, networkid.MessageOptionalPartID{
+			MessageID: result.MessageID,
+			PartID:    result.PartID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get message %s from database: %w", result.MessageID, err)
+		} else if msg != nil && !msg.HasFakeMXID() {
+			apiResult.EventID = msg.MXID
+		}
+	}
+	return apiResp, nil
+}
+
+func getSearchResultRoomID(ctx context.Context, login *bridgev2.UserLogin, key networkid.PortalKey, cache map[networkid.PortalKey]id.RoomID) (id.RoomID, error) {
+	roomID, ok := cache[key]
+	if ok {
+		return roomID, nil
+	}
+	portal, err := login.Bridge.GetExistingPortalByKey(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to get portal %s: %w", key, err)
+	} else if portal != nil {
+		roomID = portal.MXID
+	}
+	cache[key] = roomID
+	return roomID, nil
+}
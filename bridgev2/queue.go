@@ -73,6 +73,9 @@ func (br *Bridge) QueueMatrixEvent(ctx context.Context, evt *event.Event) EventH
 	// TODO maybe HandleMatrixEvent would be more appropriate as this also handles bot invites and commands
 
 	log := zerolog.Ctx(ctx)
+	if evt.Type == event.EphemeralEventPresence {
+		return br.handleMatrixPresence(ctx, evt)
+	}
 	var sender *User
 	if evt.Sender != "" {
 		var err error
@@ -99,6 +102,9 @@ func (br *Bridge) QueueMatrixEvent(ctx context.Context, evt *event.Event) EventH
 		br.Matrix.SendMessageStatus(ctx, &ErrEventSenderUserNotFound, StatusEventInfoFromEvent(evt))
 		return EventHandlingResultIgnored
 	}
+	if evt.Type == event.AccountDataIgnoredUserList && sender != nil {
+		return br.handleMatrixIgnoredUsers(ctx, sender)
+	}
 	if evt.Type == event.EventMessage && sender != nil {
 		msg := evt.Content.AsMessage()
 		msg.RemoveReplyFallback()
@@ -215,6 +221,15 @@ func (ul *UserLogin) QueueRemoteEvent(evt RemoteEvent) EventHandlingResult {
 func (br *Bridge) QueueRemoteEvent(login *UserLogin, evt RemoteEvent) (res EventHandlingResult) {
 	log := login.Log
 	ctx := log.WithContext(br.BackgroundCtx)
+	if evt.GetType() == RemoteEventPresence {
+		// Presence isn't handled in the portal event loop, so there's no panic recovery for bad casts here
+		presenceEvt, ok := evt.(RemotePresence)
+		if !ok {
+			log.Error().Type("event_struct", evt).Msg("Presence event doesn't implement RemotePresence")
+			return EventHandlingResultFailed
+		}
+		return br.handleRemotePresence(ctx, login, presenceEvt)
+	}
 	maybeUncertain, ok := evt.(RemoteEventWithUncertainPortalReceiver)
 	isUncertain := ok && maybeUncertain.PortalReceiverIsUncertain()
 	key := evt.GetPortalKey()
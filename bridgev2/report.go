@@ -0,0 +1,162 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/rs/zerolog"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/database"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// Report reports the given message, or the whole chat if the message is nil, to the remote network.
+func (portal *Portal) Report(ctx context.Context, login *UserLogin, targetMessage *database.Message, reason string) error {
+	api, ok := login.Client.(ReportingNetworkAPI)
+	if !ok {
+		return ErrReportingNotSupported
+	}
+	return api.HandleMatrixReport(ctx, &MatrixReport{
+		Portal:        portal,
+		TargetMessage: targetMessage,
+		Reason:        reason,
+	})
+}
+
+// ReportEvent finds the bridged message with the given Matrix event ID and reports it to the remote network.
+// If the event ID is empty, the whole chat is reported.
+func (portal *Portal) ReportEvent(ctx context.Context, login *UserLogin, eventID id.EventID, reason string) error {
+	var targetMessage *database.Message
+	if eventID != "" {
+		var err error
+		targetMessage, err = portal.Bridge.DB.Message.GetPartByMXID(ctx, eventID)
+		if err != nil {
+			return fmt.Errorf("failed to get target message: %w", err)
+		} else if targetMessage == nil || targetMessage.Room != portal.PortalKey {
+			return ErrReportTargetNotFound
+		}
+	}
+	return portal.Report(ctx, login, targetMessage, reason)
+}
+
+// SetUserBlocked blocks or unblocks the given remote user on the remote network.
+func (ul *UserLogin) SetUserBlocked(ctx context.Context, ghost *Ghost, blocked bool) error {
+	api, ok := ul.Client.(ReportingNetworkAPI)
+	if !ok {
+		return ErrReportingNotSupported
+	}
+	return api.HandleMatrixBlockUser(ctx, &MatrixBlockUser{
+		Ghost:   ghost,
+		Blocked: blocked,
+	})
+}
+
+// SyncIgnoredUsers fetches the user's Matrix ignored user list using their double puppet, then blocks or unblocks
+// the ghosts that were added to or removed from the list since the last sync on all logins that support it.
+func (user *User) SyncIgnoredUsers(ctx context.Context) error {
+	return user.syncIgnoredUsers(ctx, user.DoublePuppet(ctx))
+}
+
+func (br *Bridge) handleMatrixIgnoredUsers(ctx context.Context, user *User) EventHandlingResult {
+	// The list is refetched with the double puppet instead of using the event content,
+	// so that the event can't be used to block ghosts on behalf of another user.
+	err := user.SyncIgnoredUsers(ctx)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to sync ignored user list")
+		return EventHandlingResultFailed.WithError(err)
+	}
+	return EventHandlingResultSuccess
+}
+
+func (user *User) syncIgnoredUsers(ctx context.Context, intent MatrixAPI) error {
+	api, ok := intent.(IgnoredUserListMatrixAPI)
+	if !ok {
+		return nil
+	}
+	logins := slices.DeleteFunc(user.GetUserLogins(), func(login *UserLogin) bool {
+		_, ok := login.Client.(ReportingNetworkAPI)
+		return !ok
+	})
+	if len(logins) == 0 {
+		return nil
+	}
+	user.ignoredGhostsLock.Lock()
+	defer user.ignoredGhostsLock.Unlock()
+	ignoredUsers, err := api.GetIgnoredUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get ignored user list: %w", err)
+	}
+	newIgnored := make(map[networkid.UserID]struct{}, len(ignoredUsers))
+	for userID := range ignoredUsers {
+		if ghostID, isGhost := user.Bridge.Matrix.ParseGhostMXID(userID); isGhost {
+			newIgnored[ghostID] = struct{}{}
+		}
+	}
+	synced := make(map[networkid.UserID]struct{}, len(user.IgnoredGhosts))
+	changes := make(map[networkid.UserID]bool)
+	for _, ghostID := range user.IgnoredGhosts {
+		synced[ghostID] = struct{}{}
+		if _, stillIgnored := newIgnored[ghostID]; !stillIgnored {
+			changes[ghostID] = false
+		}
+	}
+	for ghostID := range newIgnored {
+		if _, alreadySynced := synced[ghostID]; !alreadySynced {
+			changes[ghostID] = true
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	for ghostID, blocked := range changes {
+		if user.setGhostBlocked(ctx, logins, ghostID, blocked) {
+			if blocked {
+				synced[ghostID] = struct{}{}
+			} else {
+				delete(synced, ghostID)
+			}
+		}
+	}
+	user.IgnoredGhosts = slices.Sorted(maps.Keys(synced))
+	err = user.Save(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save synced ignored user list: %w", err)
+	}
+	zerolog.Ctx(ctx).Debug().Int("change_count", len(changes)).Msg("Synced ignored user list")
+	return nil
+}
+
+func (user *User) setGhostBlocked(ctx context.Context, logins []*UserLogin, ghostID networkid.UserID, blocked bool) bool {
+	log := zerolog.Ctx(ctx).With().
+		Str("ghost_id", string(ghostID)).
+		Bool("blocked", blocked).
+		Logger()
+	ghost, err := user.Bridge.GetExistingGhostByID(ctx, ghostID)
+	if err != nil {
+		log.Err(err).Msg("Failed to get ghost to update block status")
+		return false
+	} else if ghost == nil {
+		// The ghost hasn't been bridged, so there's nothing to block on the remote network
+		return true
+	}
+	success := true
+	for _, login := range logins {
+		err = login.SetUserBlocked(ctx, ghost, blocked)
+		if err != nil {
+			log.Err(err).Str("login_id", string(login.ID)).Msg("Failed to bridge ignored user list change")
+			success = false
+		} else {
+			log.Debug().Str("login_id", string(login.ID)).Msg("Bridged ignored user list change")
+		}
+	}
+	return success
+}
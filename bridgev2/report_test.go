@@ -0,0 +1,179 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/util/dbutil"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/bridgeconfig"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/database"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+type blockChange struct {
+	ghost   networkid.UserID
+	blocked bool
+}
+
+type ignoreTestState struct {
+	lock    sync.Mutex
+	ignored map[id.UserID]event.IgnoredUser
+	changes []blockChange
+}
+
+func (s *ignoreTestState) setIgnored(userIDs ...id.UserID) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.ignored = make(map[id.UserID]event.IgnoredUser, len(userIDs))
+	for _, userID := range userIDs {
+		s.ignored[userID] = event.IgnoredUser{}
+	}
+}
+
+func (s *ignoreTestState) popChanges() []blockChange {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	changes := s.changes
+	s.changes = nil
+	return changes
+}
+
+type ignoreTestMatrix struct {
+	MatrixConnector
+	state *ignoreTestState
+}
+
+func (m *ignoreTestMatrix) Init(*Bridge)                           {}
+func (m *ignoreTestMatrix) BotIntent() MatrixAPI                   { return nil }
+func (m *ignoreTestMatrix) GhostIntent(networkid.UserID) MatrixAPI { return nil }
+func (m *ignoreTestMatrix) ParseGhostMXID(userID id.UserID) (networkid.UserID, bool) {
+	localpart, server, _ := userID.Parse()
+	if server != "example.com" || !strings.HasPrefix(localpart, "ghost_") {
+		return "", false
+	}
+	return networkid.UserID(strings.TrimPrefix(localpart, "ghost_")), true
+}
+
+func (m *ignoreTestMatrix) NewUserIntent(_ context.Context, userID id.UserID, token string) (MatrixAPI, string, error) {
+	if token == "" {
+		return nil, token, nil
+	}
+	return &ignoreTestIntent{userID: userID, state: m.state}, token, nil
+}
+
+type ignoreTestIntent struct {
+	MatrixAPI
+	userID id.UserID
+	state  *ignoreTestState
+}
+
+func (i *ignoreTestIntent) GetMXID() id.UserID { return i.userID }
+
+func (i *ignoreTestIntent) GetIgnoredUsers(context.Context) (map[id.UserID]event.IgnoredUser, error) {
+	i.state.lock.Lock()
+	defer i.state.lock.Unlock()
+	return i.state.ignored, nil
+}
+
+type ignoreTestNetwork struct {
+	NetworkConnector
+	state *ignoreTestState
+}
+
+func (n *ignoreTestNetwork) Init(*Bridge)                       {}
+func (n *ignoreTestNetwork) GetDBMetaTypes() database.MetaTypes { return database.MetaTypes{} }
+func (n *ignoreTestNetwork) LoadUserLogin(_ context.Context, login *UserLogin) error {
+	login.Client = &ignoreTestClient{state: n.state}
+	return nil
+}
+
+type ignoreTestClient struct {
+	NetworkAPI
+	state *ignoreTestState
+}
+
+func (c *ignoreTestClient) Connect(context.Context) {}
+
+func (c *ignoreTestClient) HandleMatrixReport(context.Context, *MatrixReport) error { return nil }
+
+func (c *ignoreTestClient) HandleMatrixBlockUser(_ context.Context, msg *MatrixBlockUser) error {
+	c.state.lock.Lock()
+	defer c.state.lock.Unlock()
+	c.state.changes = append(c.state.changes, blockChange{ghost: msg.Ghost.ID, blocked: msg.Blocked})
+	return nil
+}
+
+func newIgnoreTestBridge(t *testing.T, db *dbutil.Database, state *ignoreTestState) *Bridge {
+	return newTestBridge(t, db, nil, &ignoreTestMatrix{state: state}, &ignoreTestNetwork{state: state})
+}
+
+func TestUser_SyncIgnoredUsers(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	state := &ignoreTestState{}
+
+	br := newIgnoreTestBridge(t, db, state)
+	for _, ghostID := range []networkid.UserID{"alice", "bob"} {
+		_, err := br.GetGhostByID(ctx, ghostID)
+		require.NoError(t, err)
+	}
+	user, err := br.GetUserByMXID(ctx, "@user:example.com")
+	require.NoError(t, err)
+	_, err = user.NewLogin(ctx, &database.UserLogin{ID: "login"}, nil)
+	require.NoError(t, err)
+
+	// Logging in with a double puppet fetches the list and blocks the ignored ghosts
+	state.setIgnored("@ghost_alice:example.com", "@ghost_bob:example.com", "@someone:example.com")
+	require.NoError(t, user.LoginDoublePuppet(ctx, "token"))
+	assert.ElementsMatch(t, []blockChange{{"alice", true}, {"bob", true}}, state.popChanges())
+	dbUser, err := br.DB.User.GetByMXID(ctx, user.MXID)
+	require.NoError(t, err)
+	assert.Equal(t, []networkid.UserID{"alice", "bob"}, dbUser.IgnoredGhosts)
+
+	// The list is changed while the bridge is down, so the next start
+	// must only unblock the removed ghost instead of re-blocking everyone.
+	state.setIgnored("@ghost_alice:example.com")
+	br2 := newIgnoreTestBridge(t, db, state)
+	require.NoError(t, br2.StartLogins(ctx))
+	var changes []blockChange
+	require.Eventually(t, func() bool {
+		changes = append(changes, state.popChanges()...)
+		dbUser, err = br2.DB.User.GetByMXID(ctx, user.MXID)
+		return err == nil && len(dbUser.IgnoredGhosts) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []blockChange{{"bob", false}}, changes)
+	assert.Equal(t, []networkid.UserID{"alice"}, dbUser.IgnoredGhosts)
+
+	// Syncing again without changes doesn't touch the remote network
+	br2User, err := br2.GetExistingUserByMXID(ctx, user.MXID)
+	require.NoError(t, err)
+	require.NoError(t, br2User.SyncIgnoredUsers(ctx))
+	assert.Empty(t, state.popChanges())
+
+	// Changes pushed while the bridge is running are synced immediately
+	br2User.Permissions = bridgeconfig.PermissionLevelUser
+	state.setIgnored("@ghost_alice:example.com", "@ghost_bob:example.com")
+	res := br2.QueueMatrixEvent(ctx, &event.Event{
+		Sender: user.MXID,
+		Type:   event.AccountDataIgnoredUserList,
+		Content: event.Content{Parsed: &event.IgnoredUserListEventContent{
+			IgnoredUsers: map[id.UserID]event.IgnoredUser{"@ghost_alice:example.com": {}, "@ghost_bob:example.com": {}},
+		}},
+	})
+	assert.True(t, res.Success)
+	assert.Equal(t, []blockChange{{"bob", true}}, state.popChanges())
+}
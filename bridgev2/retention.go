@@ -0,0 +1,241 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/database"
+	"github.com/iKonoTelecomunicaciones/go/event"
+)
+
+// RetentionLoop periodically deletes old message and reaction mappings from the database
+// according to the retention policy in the bridge config.
+//
+// Disappearing message timers are never pruned: started timers are deleted by the [DisappearLoop] after
+// the message is redacted, and unstarted (after read) timers must stay until the chat is read,
+// as deleting them would leave the message on Matrix forever.
+type RetentionLoop struct {
+	br   *Bridge
+	stop atomic.Pointer[context.CancelFunc]
+}
+
+const (
+	DefaultRetentionCheckInterval = 1 * time.Hour
+	DefaultRetentionBatchSize     = 1000
+	// RetentionBatchDelay is the time to sleep between deletion batches to avoid hogging the database.
+	RetentionBatchDelay = 100 * time.Millisecond
+)
+
+func (rl *RetentionLoop) Start() {
+	log := rl.br.Log.With().Str("component", "retention loop").Logger()
+	ctx, stop := context.WithCancel(log.WithContext(context.Background()))
+	if oldStop := rl.stop.Swap(&stop); oldStop != nil {
+		(*oldStop)()
+	}
+	interval := rl.br.Config.Retention.CheckInterval
+	if interval <= 0 {
+		interval = DefaultRetentionCheckInterval
+	}
+	log.Debug().Stringer("check_interval", interval).Msg("Retention loop starting")
+	if rl.br.Config.Retention.UseRoomRetention {
+		rl.loadRoomRetention(ctx)
+	}
+	for {
+		rl.prune(ctx)
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			log.Debug().Msg("Retention loop stopping")
+			return
+		}
+	}
+}
+
+func (rl *RetentionLoop) Stop() {
+	if rl == nil {
+		return
+	}
+	if stop := rl.stop.Load(); stop != nil {
+		(*stop)()
+	}
+}
+
+// GetMaxAge returns the effective maximum age of message mappings in the given portal,
+// or zero if mappings in the portal don't expire based on age.
+func (rl *RetentionLoop) GetMaxAge(portal *database.Portal) time.Duration {
+	cfg := &rl.br.Config.Retention
+	maxAge := cfg.GetPolicy(string(portal.RoomType)).MaxAge
+	if cfg.UseRoomRetention && portal.RetentionMaxAge > 0 && (maxAge <= 0 || portal.RetentionMaxAge < maxAge) {
+		maxAge = portal.RetentionMaxAge
+	}
+	return maxAge
+}
+
+// loadRoomRetention fetches the current m.room.retention state of all portal rooms.
+// Only new retention events are received while the bridge is running,
+// so rooms that changed while it was offline or before it was enabled would be missed otherwise.
+func (rl *RetentionLoop) loadRoomRetention(ctx context.Context) {
+	log := zerolog.Ctx(ctx)
+	stateGetter, ok := rl.br.Matrix.(MatrixConnectorWithArbitraryRoomState)
+	if !ok {
+		return
+	}
+	portals, err := rl.br.GetAllPortalsWithMXID(ctx)
+	if err != nil {
+		log.Err(err).Msg("Failed to get portals to load room retention policies")
+		return
+	}
+	for _, portal := range portals {
+		if ctx.Err() != nil {
+			return
+		}
+		var maxAge time.Duration
+		evt, err := stateGetter.GetStateEvent(ctx, portal.MXID, event.StateRetention, "")
+		if err != nil && !errors.Is(err, mautrix.MNotFound) {
+			log.Warn().Err(err).Stringer("room_id", portal.MXID).Msg("Failed to get room retention policy")
+			continue
+		} else if evt != nil {
+			content, ok := evt.Content.Parsed.(*event.RetentionEventContent)
+			if ok && content.MaxLifetime != nil {
+				maxAge = content.MaxLifetime.Duration
+			}
+		}
+		err = portal.updateRetentionMaxAge(ctx, maxAge)
+		if err != nil {
+			log.Err(err).Stringer("room_id", portal.MXID).Msg("Failed to save room retention policy")
+		}
+	}
+}
+
+func (rl *RetentionLoop) prune(ctx context.Context) {
+	log := zerolog.Ctx(ctx)
+	portals, err := rl.br.DB.Portal.GetAll(ctx)
+	if err != nil {
+		log.Err(err).Msg("Failed to get portals to prune")
+		return
+	}
+	var totalMessages, totalReactions int64
+	for _, portal := range portals {
+		if portal.RoomType == database.RoomTypeSpace {
+			continue
+		}
+		messages, reactions, err := rl.prunePortal(ctx, portal)
+		totalMessages += messages
+		totalReactions += reactions
+		if ctx.Err() != nil {
+			return
+		} else if err != nil {
+			log.Err(err).Object("portal_key", portal.PortalKey).Msg("Failed to prune old rows in portal")
+		}
+	}
+	if totalMessages > 0 || totalReactions > 0 {
+		log.Info().
+			Int64("messages", totalMessages).
+			Int64("reactions", totalReactions).
+			Msg("Pruned old rows from database")
+	}
+}
+
+func (rl *RetentionLoop) prunePortal(ctx context.Context, portal *database.Portal) (messages, reactions int64, err error) {
+	batchSize := rl.br.Config.Retention.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultRetentionBatchSize
+	}
+	if maxAge := rl.GetMaxAge(portal); maxAge > 0 {
+		cutoff := time.Now().Add(-maxAge)
+		messages, err = pruneInBatches(ctx, batchSize, func() (int64, error) {
+			return rl.br.DB.Message.PruneOlderThan(ctx, portal.PortalKey, cutoff, batchSize)
+		})
+		if err != nil {
+			return
+		}
+		reactions, err = pruneInBatches(ctx, batchSize, func() (int64, error) {
+			return rl.br.DB.Reaction.PruneOlderThan(ctx, portal.PortalKey, cutoff, batchSize)
+		})
+		if err != nil {
+			return
+		}
+	}
+	if maxRows := rl.br.Config.Retention.GetPolicy(string(portal.RoomType)).MaxRows; maxRows > 0 {
+		var excessMessages int64
+		excessMessages, err = pruneInBatches(ctx, batchSize, func() (int64, error) {
+			return rl.br.DB.Message.PruneExcess(ctx, portal.PortalKey, maxRows, batchSize)
+		})
+		messages += excessMessages
+	}
+	return
+}
+
+func pruneInBatches(ctx context.Context, batchSize int, fn func() (int64, error)) (total int64, err error) {
+	for {
+		var deleted int64
+		deleted, err = fn()
+		total += deleted
+		if err != nil || deleted < int64(batchSize) {
+			return
+		}
+		select {
+		case <-time.After(RetentionBatchDelay):
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		}
+	}
+}
+
+func (portal *Portal) handleMatrixRetention(ctx context.Context, evt *event.Event) EventHandlingResult {
+	if evt.StateKey == nil || *evt.StateKey != "" {
+		return EventHandlingResultIgnored
+	}
+	content, ok := evt.Content.Parsed.(*event.RetentionEventContent)
+	if !ok {
+		return EventHandlingResultFailed.WithMSSError(ErrUnexpectedParsedContentType)
+	}
+	var maxAge time.Duration
+	if content.MaxLifetime != nil {
+		maxAge = content.MaxLifetime.Duration
+	}
+	if portal.RetentionMaxAge == maxAge {
+		return EventHandlingResultIgnored
+	}
+	err := portal.updateRetentionMaxAge(ctx, maxAge)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to save portal after retention policy change")
+		return EventHandlingResultFailed.WithError(err)
+	}
+	return EventHandlingResultSuccess
+}
+
+func (portal *Portal) updateRetentionMaxAge(ctx context.Context, maxAge time.Duration) error {
+	if portal.RetentionMaxAge == maxAge {
+		return nil
+	}
+	zerolog.Ctx(ctx).Debug().
+		Object("portal_key", portal.PortalKey).
+		Stringer("old_max_age", portal.RetentionMaxAge).
+		Stringer("new_max_age", maxAge).
+		Msg("Room retention policy changed")
+	portal.RetentionMaxAge = maxAge
+	return portal.Save(ctx)
+}
+
+// matrixTargetNotFound returns the result for a Matrix edit or reaction whose target isn't in the database.
+// If retention is enabled, the target was most likely pruned, so the event is ignored instead of failing.
+func (portal *Portal) matrixTargetNotFound(action string) EventHandlingResult {
+	if portal.Bridge.Config.Retention.Enabled {
+		return EventHandlingResultIgnored.WithMSSError(fmt.Errorf("%s %w", action, ErrTargetMessagePruned))
+	}
+	return EventHandlingResultFailed.WithMSSError(fmt.Errorf("%s %w", action, ErrTargetMessageNotFound))
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/bridgeconfig"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/database"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+func TestPruneInBatches(t *testing.T) {
+	ctx := context.Background()
+	results := []int64{2, 2, 1, 2}
+	var calls int
+	total, err := pruneInBatches(ctx, 2, func() (int64, error) {
+		calls++
+		return results[calls-1], nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	assert.Equal(t, 3, calls, "pruning should stop after the first partial batch")
+
+	calls = 0
+	total, err = pruneInBatches(ctx, 2, func() (int64, error) {
+		calls++
+		if calls == 2 {
+			return 1, errors.New("meow")
+		}
+		return 2, nil
+	})
+	assert.EqualError(t, err, "meow")
+	assert.Equal(t, int64(3), total)
+
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	calls = 0
+	total, err = pruneInBatches(cancelledCtx, 2, func() (int64, error) {
+		calls++
+		return 2, nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetentionLoop_PrunePortal(t *testing.T) {
+	ctx := context.Background()
+	cfg := &bridgeconfig.BridgeConfig{}
+	cfg.Retention.Enabled = true
+	cfg.Retention.BatchSize = 2
+	cfg.Retention.Default.MaxAge = time.Hour
+	br := newTestBridge(t, newTestDB(t), cfg, nil, nil)
+
+	insertPortal := func(key networkid.PortalKey, ages ...time.Duration) {
+		require.NoError(t, br.DB.Portal.Insert(ctx, &database.Portal{PortalKey: key}))
+		for i, age := range ages {
+			require.NoError(t, br.DB.Message.Insert(ctx, &database.Message{
+				ID:        networkid.MessageID(fmt.Sprintf("%s-%d", key.ID, i)),
+				MXID:      id.EventID(fmt.Sprintf("$%s-%d", key.ID, i)),
+				Room:      key,
+				SenderID:  "sender",
+				Timestamp: time.Now().Add(-age),
+			}))
+		}
+	}
+	idle := networkid.PortalKey{ID: "idle"}
+	active := networkid.PortalKey{ID: "active"}
+	insertPortal(idle, 5*time.Hour, 4*time.Hour, 3*time.Hour, 2*time.Hour, 90*time.Minute)
+	insertPortal(active, 3*time.Hour, 2*time.Hour, time.Minute, time.Second)
+
+	rl := &RetentionLoop{br: br}
+	// All messages in the idle portal are too old, but the newest one must be kept for backfilling
+	portal, err := br.DB.Portal.GetByKey(ctx, idle)
+	require.NoError(t, err)
+	messages, _, err := rl.prunePortal(ctx, portal)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), messages)
+	count, err := br.DB.Message.CountMessagesInPortal(ctx, idle)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	last, err := br.DB.Message.GetLastPartAtOrBeforeTime(ctx, idle, time.Now())
+	require.NoError(t, err)
+	require.NotNil(t, last)
+	assert.Equal(t, networkid.MessageID("idle-4"), last.ID)
+
+	// Recent messages aren't touched
+	portal, err = br.DB.Portal.GetByKey(ctx, active)
+	require.NoError(t, err)
+	messages, _, err = rl.prunePortal(ctx, portal)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), messages)
+	count, err = br.DB.Message.CountMessagesInPortal(ctx, active)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
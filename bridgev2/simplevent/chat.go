@@ -22,6 +22,9 @@ import (
 // Otherwise, the latest database message timestamp is compared to LatestMessageTS.
 //
 // All four fields are optional.
+//
+// ChatResync can also be used as a [bridgev2.RemoteMessageRequest] by setting the event type to
+// [bridgev2.RemoteEventMessageRequest].
 type ChatResync struct {
 	EventMeta
 
@@ -38,6 +41,7 @@ var (
 	_ bridgev2.RemoteChatResyncWithInfo       = (*ChatResync)(nil)
 	_ bridgev2.RemoteChatResyncBackfill       = (*ChatResync)(nil)
 	_ bridgev2.RemoteChatResyncBackfillBundle = (*ChatResync)(nil)
+	_ bridgev2.RemoteMessageRequest           = (*ChatResync)(nil)
 )
 
 func (evt *ChatResync) CheckNeedsBackfill(ctx context.Context, latestMessage *database.Message) (bool, error) {
@@ -0,0 +1,41 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package simplevent
+
+import (
+	"github.com/iKonoTelecomunicaciones/go/bridgev2"
+	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+)
+
+// PollVote is a simple implementation of [bridgev2.RemotePollVote].
+type PollVote struct {
+	EventMeta
+	TargetMessage networkid.MessageID
+	Votes         []string
+}
+
+var _ bridgev2.RemotePollVote = (*PollVote)(nil)
+
+func (evt *PollVote) GetTargetMessage() networkid.MessageID {
+	return evt.TargetMessage
+}
+
+func (evt *PollVote) GetVotes() []string {
+	return evt.Votes
+}
+
+// PollEnd is a simple implementation of [bridgev2.RemotePollEnd].
+type PollEnd struct {
+	EventMeta
+	TargetMessage networkid.MessageID
+}
+
+var _ bridgev2.RemotePollEnd = (*PollEnd)(nil)
+
+func (evt *PollEnd) GetTargetMessage() networkid.MessageID {
+	return evt.TargetMessage
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/iKonoTelecomunicaciones/go/bridgev2"
 	"github.com/iKonoTelecomunicaciones/go/bridgev2/networkid"
+	"github.com/iKonoTelecomunicaciones/go/event"
 )
 
 type Receipt struct {
@@ -75,3 +76,22 @@ func (evt *Typing) GetTimeout() time.Duration {
 func (evt *Typing) GetTypingType() bridgev2.TypingType {
 	return evt.Type
 }
+
+type Presence struct {
+	EventMeta
+	Presence      event.Presence
+	StatusMessage string
+}
+
+var (
+	_ bridgev2.RemotePresence           = (*Presence)(nil)
+	_ bridgev2.RemotePresenceWithStatus = (*Presence)(nil)
+)
+
+func (evt *Presence) GetPresence() event.Presence {
+	return evt.Presence
+}
+
+func (evt *Presence) GetStatusMessage() string {
+	return evt.StatusMessage
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package thumbnail
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// Blurhash encodes the given image as a [BlurHash] with the given number of components on each axis (1-9).
+//
+// The hash is computed from every pixel of the image, so large images should be scaled down with [Scale] first.
+//
+// [BlurHash]: https://blurha.sh
+func Blurhash(img image.Image, xComponents, yComponents int) (string, error) {
+	if xComponents < 1 || xComponents > 9 || yComponents < 1 || yComponents > 9 {
+		return "", fmt.Errorf("blurhash components must be between 1 and 9, got %dx%d", xComponents, yComponents)
+	}
+	rgba := toRGBA(img)
+	width, height := rgba.Rect.Dx(), rgba.Rect.Dy()
+	if width == 0 || height == 0 {
+		return "", fmt.Errorf("can't compute blurhash of empty image")
+	}
+
+	var linear [256]float64
+	for i := range linear {
+		linear[i] = sRGBToLinear(i)
+	}
+	factors := make([][3]float64, xComponents*yComponents)
+	cosX := make([]float64, width)
+	cosY := make([]float64, height)
+	for j := 0; j < yComponents; j++ {
+		for y := range cosY {
+			cosY[y] = math.Cos(math.Pi * float64(j) * float64(y) / float64(height))
+		}
+		for i := 0; i < xComponents; i++ {
+			for x := range cosX {
+				cosX[x] = math.Cos(math.Pi * float64(i) * float64(x) / float64(width))
+			}
+			var r, g, b float64
+			for y := 0; y < height; y++ {
+				row := rgba.Pix[y*rgba.Stride:]
+				for x := 0; x < width; x++ {
+					basis := cosX[x] * cosY[y]
+					r += basis * linear[row[x*4]]
+					g += basis * linear[row[x*4+1]]
+					b += basis * linear[row[x*4+2]]
+				}
+			}
+			normalization := 2.0
+			if i == 0 && j == 0 {
+				normalization = 1
+			}
+			scale := normalization / float64(width*height)
+			factors[j*xComponents+i] = [3]float64{r * scale, g * scale, b * scale}
+		}
+	}
+
+	var hash strings.Builder
+	encodeBase83(&hash, (xComponents-1)+(yComponents-1)*9, 1)
+	maxValue := 1.0
+	if len(factors) > 1 {
+		var actualMax float64
+		for _, factor := range factors[1:] {
+			actualMax = max(actualMax, math.Abs(factor[0]), math.Abs(factor[1]), math.Abs(factor[2]))
+		}
+		quantizedMax := clampInt(int(math.Floor(actualMax*166-0.5)), 0, 82)
+		maxValue = float64(quantizedMax+1) / 166
+		encodeBase83(&hash, quantizedMax, 1)
+	} else {
+		encodeBase83(&hash, 0, 1)
+	}
+	dc := factors[0]
+	encodeBase83(&hash, linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4)
+	for _, factor := range factors[1:] {
+		quantR := quantizeAC(factor[0], maxValue)
+		quantG := quantizeAC(factor[1], maxValue)
+		quantB := quantizeAC(factor[2], maxValue)
+		encodeBase83(&hash, quantR*19*19+quantG*19+quantB, 2)
+	}
+	return hash.String(), nil
+}
+
+func encodeBase83(into *strings.Builder, value, length int) {
+	divisor := 1
+	for i := 1; i < length; i++ {
+		divisor *= 83
+	}
+	for ; divisor > 0; divisor /= 83 {
+		into.WriteByte(base83Chars[(value/divisor)%83])
+	}
+}
+
+func quantizeAC(value, maxValue float64) int {
+	return clampInt(int(math.Floor(signPow(value/maxValue, 0.5)*9+9.5)), 0, 18)
+}
+
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
+
+func sRGBToLinear(value int) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := max(0, min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func clampInt(value, minValue, maxValue int) int {
+	return max(minValue, min(maxValue, value))
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exifSegment(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], exifOrientationTag)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func jpegWithOrientation(t *testing.T, img image.Image, order binary.ByteOrder, orientation uint16) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	data := buf.Bytes()
+	return append(append(data[:2:2], exifSegment(order, orientation)...), data[2:]...)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	img := solidImage(4, 2, color.RGBA{A: 255})
+	assert.Equal(t, 6, jpegOrientation(jpegWithOrientation(t, img, binary.BigEndian, 6)))
+	assert.Equal(t, 3, jpegOrientation(jpegWithOrientation(t, img, binary.LittleEndian, 3)))
+	assert.Equal(t, 1, jpegOrientation(jpegWithOrientation(t, img, binary.BigEndian, 9)))
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	assert.Equal(t, 1, jpegOrientation(buf.Bytes()))
+	assert.Equal(t, 1, jpegOrientation([]byte("not a jpeg")))
+	// Truncated segments must not cause out of bounds reads
+	data := jpegWithOrientation(t, img, binary.BigEndian, 6)
+	for i := range 40 {
+		assert.NotPanics(t, func() { jpegOrientation(data[:i]) })
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// A 3x2 image with red in the top left and green in the top right corner
+	src := solidImage(3, 2, color.RGBA{A: 255})
+	red, green := color.RGBA{R: 255, A: 255}, color.RGBA{G: 255, A: 255}
+	src.SetRGBA(0, 0, red)
+	src.SetRGBA(2, 0, green)
+	for orientation, expected := range map[int]struct {
+		width, height  int
+		redX, redY     int
+		greenX, greenY int
+	}{
+		1: {3, 2, 0, 0, 2, 0},
+		2: {3, 2, 2, 0, 0, 0},
+		3: {3, 2, 2, 1, 0, 1},
+		4: {3, 2, 0, 1, 2, 1},
+		5: {2, 3, 0, 0, 0, 2},
+		6: {2, 3, 1, 0, 1, 2},
+		7: {2, 3, 1, 2, 1, 0},
+		8: {2, 3, 0, 2, 0, 0},
+	} {
+		dst := applyOrientation(src, orientation)
+		assert.Equal(t, expected.width, dst.Rect.Dx(), "orientation %d", orientation)
+		assert.Equal(t, expected.height, dst.Rect.Dy(), "orientation %d", orientation)
+		assert.Equal(t, red, dst.RGBAAt(expected.redX, expected.redY), "orientation %d", orientation)
+		assert.Equal(t, green, dst.RGBAAt(expected.greenX, expected.greenY), "orientation %d", orientation)
+	}
+}
+
+func TestProcess_Orientation(t *testing.T) {
+	data := jpegWithOrientation(t, solidImage(200, 100, color.RGBA{R: 10, G: 20, B: 30, A: 255}), binary.BigEndian, 6)
+	res, err := Process(bytes.NewReader(data), 50, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 100, res.Width)
+	assert.Equal(t, 200, res.Height)
+	assert.Equal(t, 25, res.ThumbnailWidth)
+	assert.Equal(t, 50, res.ThumbnailHeight)
+	thumb, err := jpeg.DecodeConfig(bytes.NewReader(res.Thumbnail))
+	require.NoError(t, err)
+	assert.Equal(t, 25, thumb.Width)
+	assert.Equal(t, 50, thumb.Height)
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package thumbnail extracts dimensions, blurhashes and thumbnails from images using only the Go standard library.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+const (
+	// DefaultMaxSize is the default maximum width and height of generated thumbnails.
+	DefaultMaxSize = 800
+	// DefaultMaxPixels is the default maximum number of pixels in images that are decoded.
+	// Decoding an image takes 4 bytes of memory per pixel, so this is about 160 MB.
+	DefaultMaxPixels = 40_000_000
+	// BlurhashInputSize is the size that images are scaled down to before computing the blurhash.
+	BlurhashInputSize = 64
+	JPEGQuality       = 80
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrImageTooLarge     = errors.New("image has too many pixels")
+)
+
+var supportedMimeTypes = map[string]struct{}{
+	"image/jpeg": {},
+	"image/png":  {},
+	"image/gif":  {},
+}
+
+// IsSupported returns true if images with the given mime type can be decoded by [Process].
+func IsSupported(mimeType string) bool {
+	_, ok := supportedMimeTypes[mimeType]
+	return ok
+}
+
+// Result contains the metadata extracted from an image by [Process].
+type Result struct {
+	Width    int
+	Height   int
+	Blurhash string
+
+	// The encoded thumbnail. This is only set if the image is larger than the maximum thumbnail size.
+	Thumbnail         []byte
+	ThumbnailMimeType string
+	ThumbnailWidth    int
+	ThumbnailHeight   int
+}
+
+// Process decodes a JPEG, PNG or GIF image and returns its dimensions and blurhash.
+//
+// If the image is larger than maxSize in either dimension, a thumbnail that fits in a maxSize×maxSize box is
+// also generated. Thumbnails are encoded as JPEG, unless the image has transparency, in which case PNG is used.
+//
+// The EXIF orientation of JPEG images is applied, so the dimensions, thumbnail and blurhash match the way the image
+// is displayed.
+//
+// The dimensions are read from the header before decoding, and images with more than maxPixels pixels are
+// rejected with [ErrImageTooLarge], as a small file can otherwise decode into gigabytes of pixel data.
+func Process(r io.Reader, maxSize, maxPixels int) (*Result, error) {
+	if maxPixels <= 0 {
+		maxPixels = DefaultMaxPixels
+	}
+	var header bytes.Buffer
+	cfg, format, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if errors.Is(err, image.ErrFormat) {
+		return nil, ErrUnsupportedFormat
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to decode image config: %w", err)
+	} else if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, fmt.Errorf("image is empty")
+	} else if cfg.Width > maxPixels/cfg.Height {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+	orientation := 1
+	if format == "jpeg" {
+		// The header contains all segments before the frame header, which is where the EXIF data is
+		orientation = jpegOrientation(header.Bytes())
+	}
+	img, _, err := image.Decode(io.MultiReader(&header, r))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return nil, fmt.Errorf("image is empty")
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	rgba := applyOrientation(toRGBA(img), orientation)
+	res := &Result{Width: rgba.Rect.Dx(), Height: rgba.Rect.Dy()}
+	if res.Width > maxSize || res.Height > maxSize {
+		res.ThumbnailWidth, res.ThumbnailHeight = Fit(res.Width, res.Height, maxSize)
+		res.Thumbnail, res.ThumbnailMimeType, err = encode(Scale(rgba, res.ThumbnailWidth, res.ThumbnailHeight), isOpaque(img))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+		}
+	}
+	xComponents, yComponents := 4, 3
+	if res.Height > res.Width {
+		xComponents, yComponents = 3, 4
+	}
+	blurhashWidth, blurhashHeight := Fit(res.Width, res.Height, BlurhashInputSize)
+	res.Blurhash, err = Blurhash(Scale(rgba, blurhashWidth, blurhashHeight), xComponents, yComponents)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Fit returns the largest dimensions that fit in a maxSize×maxSize box while keeping the aspect ratio.
+// Dimensions that already fit are returned as-is.
+func Fit(width, height, maxSize int) (int, int) {
+	if width <= maxSize && height <= maxSize {
+		return width, height
+	} else if width >= height {
+		return maxSize, max(1, height*maxSize/width)
+	} else {
+		return max(1, width*maxSize/height), maxSize
+	}
+}
+
+// Scale resizes the image to the given dimensions by averaging the source pixels covered by each output pixel.
+func Scale(img image.Image, width, height int) *image.RGBA {
+	src := toRGBA(img)
+	srcWidth, srcHeight := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for dy := 0; dy < height; dy++ {
+		y0 := dy * srcHeight / height
+		y1 := max((dy+1)*srcHeight/height, y0+1)
+		for dx := 0; dx < width; dx++ {
+			x0 := dx * srcWidth / width
+			x1 := max((dx+1)*srcWidth/width, x0+1)
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride:]
+				for x := x0; x < x1; x++ {
+					r += uint64(row[x*4])
+					g += uint64(row[x*4+1])
+					b += uint64(row[x*4+2])
+					a += uint64(row[x*4+3])
+					n++
+				}
+			}
+			out := dst.Pix[dst.PixOffset(dx, dy):]
+			out[0], out[1], out[2], out[3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return dst
+}
+
+// toRGBA converts the image to RGBA with the origin at (0, 0), which the pixel loops in this package expect.
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, bounds.Min, draw.Src)
+	return rgba
+}
+
+func isOpaque(img image.Image) bool {
+	opaque, ok := img.(interface{ Opaque() bool })
+	return ok && opaque.Opaque()
+}
+
+func encode(img image.Image, opaque bool) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if opaque {
+		err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: JPEGQuality})
+		return buf.Bytes(), "image/jpeg", err
+	}
+	err := png.Encode(&buf, img)
+	return buf.Bytes(), "image/png", err
+}
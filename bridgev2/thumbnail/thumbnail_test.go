@@ -0,0 +1,149 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func solidImage(width, height int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func decodeBase83(str string) (value int) {
+	for _, char := range str {
+		value = value*83 + strings.IndexRune(base83Chars, char)
+	}
+	return
+}
+
+func TestFit(t *testing.T) {
+	for _, tc := range []struct{ width, height, maxSize, expectedWidth, expectedHeight int }{
+		{100, 50, 800, 100, 50},
+		{1600, 800, 800, 800, 400},
+		{800, 1600, 800, 400, 800},
+		{10000, 1, 800, 800, 1},
+	} {
+		width, height := Fit(tc.width, tc.height, tc.maxSize)
+		assert.Equal(t, tc.expectedWidth, width, "%dx%d", tc.width, tc.height)
+		assert.Equal(t, tc.expectedHeight, height, "%dx%d", tc.width, tc.height)
+	}
+}
+
+func TestScale(t *testing.T) {
+	// Left half black, right half white
+	img := solidImage(4, 2, color.RGBA{A: 255})
+	for y := 0; y < 2; y++ {
+		for x := 2; x < 4; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+		}
+	}
+
+	halved := Scale(img, 2, 1)
+	assert.Equal(t, image.Rect(0, 0, 2, 1), halved.Rect)
+	assert.Equal(t, color.RGBA{A: 255}, halved.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, halved.RGBAAt(1, 0))
+
+	averaged := Scale(img, 1, 1)
+	assert.Equal(t, color.RGBA{R: 127, G: 127, B: 127, A: 255}, averaged.RGBAAt(0, 0))
+
+	// Images with a non-zero origin are handled too
+	sub := img.SubImage(image.Rect(2, 0, 4, 2))
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, Scale(sub, 1, 1).RGBAAt(0, 0))
+
+	upscaled := Scale(img, 8, 4)
+	assert.Equal(t, color.RGBA{A: 255}, upscaled.RGBAAt(3, 3))
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, upscaled.RGBAAt(4, 0))
+}
+
+func TestBlurhash(t *testing.T) {
+	hash, err := Blurhash(solidImage(32, 32, color.RGBA{A: 255}), 4, 3)
+	require.NoError(t, err)
+	// A solid image only has the DC component, every AC component is encoded as zero ("fQ")
+	assert.Equal(t, "L00000"+strings.Repeat("fQ", 11), hash)
+
+	hash, err = Blurhash(solidImage(32, 32, color.RGBA{R: 255, G: 255, B: 255, A: 255}), 3, 4)
+	require.NoError(t, err)
+	require.Len(t, hash, 4+2*3*4)
+	assert.Equal(t, (3-1)+(4-1)*9, decodeBase83(hash[:1]))
+	assert.Equal(t, 0xffffff, decodeBase83(hash[2:6]))
+
+	gradient := image.NewRGBA(image.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			gradient.SetRGBA(x, y, color.RGBA{R: uint8(x * 8), B: uint8(y * 16), A: 255})
+		}
+	}
+	hash, err = Blurhash(gradient, 4, 3)
+	require.NoError(t, err)
+	require.Len(t, hash, 4+2*4*3)
+	assert.NotEqual(t, strings.Repeat("fQ", 11), hash[6:], "gradient should have non-zero AC components")
+
+	_, err = Blurhash(gradient, 0, 3)
+	assert.Error(t, err)
+	_, err = Blurhash(gradient, 4, 10)
+	assert.Error(t, err)
+}
+
+func TestProcess(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, solidImage(200, 100, color.RGBA{R: 10, G: 20, B: 30, A: 255})))
+	data := buf.Bytes()
+
+	res, err := Process(bytes.NewReader(data), 50, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 200, res.Width)
+	assert.Equal(t, 100, res.Height)
+	assert.Equal(t, 50, res.ThumbnailWidth)
+	assert.Equal(t, 25, res.ThumbnailHeight)
+	assert.Equal(t, "image/jpeg", res.ThumbnailMimeType)
+	assert.NotEmpty(t, res.Blurhash)
+
+	res, err = Process(bytes.NewReader(data), 800, 0)
+	require.NoError(t, err)
+	assert.Nil(t, res.Thumbnail)
+
+	_, err = Process(bytes.NewReader(data), 800, 200*100-1)
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+
+	_, err = Process(strings.NewReader("not an image"), 800, 0)
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestProcess_DecompressionBomb(t *testing.T) {
+	// A PNG that only has a header claiming to be 100000x100000 pixels. It must be rejected
+	// based on the header alone, without trying to allocate memory for the pixels.
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], 100000)
+	binary.BigEndian.PutUint32(ihdr[4:8], 100000)
+	ihdr[8], ihdr[9] = 8, 6 // 8-bit RGBA
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	_ = binary.Write(&buf, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	buf.Write(chunk)
+	_ = binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+
+	_, err := Process(&buf, 800, 0)
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+}
@@ -0,0 +1,26 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bridgev2
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/iKonoTelecomunicaciones/go/internal/tracing"
+)
+
+var tracer = otel.Tracer("github.com/iKonoTelecomunicaciones/go/bridgev2")
+
+func endEventSpan(span trace.Span, res EventHandlingResult) {
+	span.SetAttributes(
+		attribute.Bool("success", res.Success),
+		attribute.Bool("ignored", res.Ignored),
+	)
+	tracing.RecordError(span, res.Error)
+	span.End()
+}
@@ -39,6 +39,8 @@ type User struct {
 
 	managementCreateLock sync.Mutex
 
+	ignoredGhostsLock sync.Mutex
+
 	logins map[networkid.UserLoginID]*UserLogin
 }
 
@@ -116,21 +118,26 @@ func (user *User) LoginDoublePuppet(ctx context.Context, token string) error {
 		return fmt.Errorf("no token provided")
 	}
 	user.doublePuppetLock.Lock()
-	defer user.doublePuppetLock.Unlock()
 	intent, newToken, err := user.Bridge.Matrix.NewUserIntent(ctx, user.MXID, token)
 	if err != nil {
+		user.doublePuppetLock.Unlock()
 		return err
 	}
 	user.AccessToken = newToken
 	user.doublePuppetIntent = intent
 	user.doublePuppetInitialized = true
 	err = user.Save(ctx)
+	user.doublePuppetLock.Unlock()
 	if err != nil {
 		zerolog.Ctx(ctx).Err(err).Msg("Failed to save new access token")
 	}
 	if newToken != token {
 		return fmt.Errorf("logging in manually is not supported when automatic double puppeting is enabled")
 	}
+	err = user.syncIgnoredUsers(ctx, intent)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Failed to sync ignored user list")
+	}
 	return nil
 }
 
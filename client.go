@@ -17,6 +17,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -24,11 +25,16 @@ import (
 	"go.mau.fi/util/ptr"
 	"go.mau.fi/util/random"
 	"go.mau.fi/util/retryafter"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/exp/maps"
 
 	"github.com/iKonoTelecomunicaciones/go/crypto/backup"
 	"github.com/iKonoTelecomunicaciones/go/event"
 	"github.com/iKonoTelecomunicaciones/go/id"
+	"github.com/iKonoTelecomunicaciones/go/internal/tracing"
 	"github.com/iKonoTelecomunicaciones/go/pushrules"
 )
 
@@ -80,7 +86,8 @@ type Client struct {
 	HomeserverURL  *url.URL     // The base homeserver URL
 	UserID         id.UserID    // The user ID of the client. Used for forming HTTP paths which use the client's user ID.
 	DeviceID       id.DeviceID  // The device ID of the client.
-	AccessToken    string       // The access_token for the client.
+	AccessToken    string       // The access_token for the client. Use SetCredentials to change it while requests may be in flight.
+	RefreshToken   string       // The refresh_token for the client, used to get a new access token when the current one expires.
 	UserAgent      string       // The value for the User-Agent header
 	Client         *http.Client // The underlying HTTP client which will be used to make HTTP requests.
 	Syncer         Syncer       // The thing which can process /sync responses
@@ -123,6 +130,20 @@ type Client struct {
 	SetAppServiceDeviceID bool
 
 	syncingID uint32 // Identifies the current Sync. Only one Sync can be active at any given time.
+
+	slidingSync slidingSyncState
+
+	// The time when the current access token expires. If set, the token will be refreshed slightly before expiry.
+	AccessTokenExpiresAt time.Time
+	// OAuth contains the authorization server and client ID used for next-gen auth.
+	// If set, access tokens are refreshed using the OAuth token endpoint instead of /refresh.
+	OAuth *OAuthClientInfo
+	// TokenStore is called whenever the access or refresh token changes.
+	TokenStore TokenStore
+
+	tokenRefreshLock sync.Mutex
+	// tokenLock guards AccessToken, RefreshToken and AccessTokenExpiresAt, which are updated when the token is refreshed.
+	tokenLock sync.RWMutex
 }
 
 type ClientWellKnown struct {
@@ -196,13 +217,13 @@ func DiscoverClientAPIWithClient(ctx context.Context, client *http.Client, serve
 //
 // Deprecated: use the StoreCredentials field in ReqLogin instead.
 func (cli *Client) SetCredentials(userID id.UserID, accessToken string) {
-	cli.AccessToken = accessToken
+	cli.setAccessToken(accessToken)
 	cli.UserID = userID
 }
 
 // ClearCredentials removes the user ID and access token on this client instance.
 func (cli *Client) ClearCredentials() {
-	cli.AccessToken = ""
+	cli.setAccessToken("")
 	cli.UserID = ""
 	cli.DeviceID = ""
 }
@@ -422,6 +443,9 @@ type FullRequest struct {
 	ResponseSizeLimit int64
 	Logger            *zerolog.Logger
 	Client            *http.Client
+
+	// Used for requests to the OAuth issuer and /refresh, which must not include the current access token.
+	omitAccessToken bool
 }
 
 var requestID int32
@@ -505,6 +529,38 @@ func (cli *Client) MakeFullRequest(ctx context.Context, params FullRequest) ([]b
 }
 
 func (cli *Client) MakeFullRequestWithResp(ctx context.Context, params FullRequest) ([]byte, *http.Response, error) {
+	if cli == nil {
+		return nil, nil, ErrClientIsNil
+	}
+	ctx, span := tracer.Start(ctx, "Client.MakeFullRequest", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("http.request.method", params.Method),
+		attribute.String("url.path", urlPathForSpan(params.URL)),
+	))
+	defer span.End()
+	if cli.shouldRefreshAccessToken() {
+		err := cli.refreshAccessToken(ctx, cli.getAccessToken())
+		if err != nil {
+			cli.cliOrContextLog(ctx).Err(err).Msg("Failed to refresh access token before expiry")
+		}
+	}
+	usedToken := cli.getAccessToken()
+	data, resp, err := cli.makeFullRequestWithResp(ctx, params)
+	if usedToken != "" && cli.getRefreshToken() != "" && errors.Is(err, MUnknownToken) && canRetryRequestBody(params.RequestBody) {
+		refreshErr := cli.refreshAccessToken(ctx, usedToken)
+		if refreshErr != nil {
+			cli.cliOrContextLog(ctx).Err(refreshErr).Msg("Failed to refresh access token after M_UNKNOWN_TOKEN error")
+			return data, resp, err
+		}
+		data, resp, err = cli.makeFullRequestWithResp(ctx, params)
+	}
+	if resp != nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	}
+	tracing.RecordError(span, err)
+	return data, resp, err
+}
+
+func (cli *Client) makeFullRequestWithResp(ctx context.Context, params FullRequest) ([]byte, *http.Response, error) {
 	if cli == nil {
 		return nil, nil, ErrClientIsNil
 	}
@@ -543,8 +599,12 @@ func (cli *Client) MakeFullRequestWithResp(ctx context.Context, params FullReque
 	if cli.UserAgent != "" {
 		req.Header.Set("User-Agent", cli.UserAgent)
 	}
-	if len(cli.AccessToken) > 0 {
-		req.Header.Set("Authorization", "Bearer "+cli.AccessToken)
+	if cli.isHomeserverURL(req.URL) {
+		// Don't leak trace IDs to third parties like OAuth issuers
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	}
+	if accessToken := cli.getAccessToken(); len(accessToken) > 0 && !params.omitAccessToken {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
 	}
 	if params.ResponseSizeLimit == 0 {
 		params.ResponseSizeLimit = cli.ResponseSizeLimit
@@ -995,8 +1055,11 @@ func (cli *Client) Login(ctx context.Context, req *ReqLogin) (resp *RespLogin, e
 	})
 	if req.StoreCredentials && err == nil {
 		cli.DeviceID = resp.DeviceID
-		cli.AccessToken = resp.AccessToken
 		cli.UserID = resp.UserID
+		err = cli.updateTokens(ctx, resp.AccessToken, resp.RefreshToken, time.Duration(resp.ExpiresInMS)*time.Millisecond)
+		if err != nil {
+			return resp, err
+		}
 
 		cli.Log.Debug().
 			Str("user_id", cli.UserID.String()).
@@ -1518,6 +1581,25 @@ func (cli *Client) CreateRoom(ctx context.Context, req *ReqCreateRoom) (resp *Re
 	return
 }
 
+// UpgradeRoom replaces the given room with a new room using the given room version.
+// See https://spec.matrix.org/v1.16/client-server-api/#post_matrixclientv3roomsroomidupgrade
+//
+// The server will copy the important state events to the new room and send a tombstone in the old room.
+// Use [Client.CopyRoomState] to copy any other state events that the server doesn't handle.
+func (cli *Client) UpgradeRoom(ctx context.Context, roomID id.RoomID, req *ReqUpgradeRoom) (resp *RespUpgradeRoom, err error) {
+	urlPath := cli.BuildClientURL("v3", "rooms", roomID, "upgrade")
+	_, err = cli.MakeRequest(ctx, http.MethodPost, urlPath, req, &resp)
+	if err == nil && cli.StateStore != nil {
+		storeErr := cli.StateStore.SetMembership(ctx, resp.ReplacementRoom, cli.UserID, event.MembershipJoin)
+		if storeErr != nil {
+			cli.cliOrContextLog(ctx).Warn().Err(storeErr).
+				Stringer("creator_user_id", cli.UserID).
+				Msg("Failed to update creator membership in state store after upgrading room")
+		}
+	}
+	return
+}
+
 // LeaveRoom leaves the given room. See https://spec.matrix.org/v1.2/client-server-api/#post_matrixclientv3roomsroomidleave
 func (cli *Client) LeaveRoom(ctx context.Context, roomID id.RoomID, optionalReq ...*ReqLeave) (resp *RespLeaveRoom, err error) {
 	req := &ReqLeave{}
@@ -2048,13 +2130,22 @@ func (nopCloseSeeker) Close() error {
 
 // UploadMedia uploads the given data to the content repository and returns an MXC URI.
 // See https://spec.matrix.org/v1.7/client-server-api/#post_matrixmediav3upload
-func (cli *Client) UploadMedia(ctx context.Context, data ReqUploadMedia) (*RespMediaUpload, error) {
+func (cli *Client) UploadMedia(ctx context.Context, data ReqUploadMedia) (resp *RespMediaUpload, err error) {
 	if data.DoneCallback != nil {
 		defer data.DoneCallback()
 	}
 	if cli == nil {
 		return nil, ErrClientIsNil
 	}
+	ctx, span := tracer.Start(ctx, "Client.UploadMedia", trace.WithAttributes(
+		attribute.String("mime_type", data.ContentType),
+		attribute.Int64("size", max(data.ContentLength, int64(len(data.ContentBytes)))),
+		attribute.Bool("async", !data.MXC.IsEmpty()),
+	))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
 	if data.UnstableUploadURL != "" {
 		if data.MXC.IsEmpty() {
 			return nil, errors.New("MXC must also be set when uploading to external URL")
@@ -2079,7 +2170,7 @@ func (cli *Client) UploadMedia(ctx context.Context, data ReqUploadMedia) (*RespM
 	}
 
 	var m RespMediaUpload
-	_, err := cli.MakeFullRequest(ctx, FullRequest{
+	_, err = cli.MakeFullRequest(ctx, FullRequest{
 		Method:        method,
 		URL:           u.String(),
 		Headers:       headers,
@@ -2206,6 +2297,29 @@ func (cli *Client) Hierarchy(ctx context.Context, roomID id.RoomID, req *ReqHier
 	return
 }
 
+// Notifications returns the events that the user has been notified about, most recent first.
+//
+// See https://spec.matrix.org/v1.12/client-server-api/#get_matrixclientv3notifications
+func (cli *Client) Notifications(ctx context.Context, req *ReqNotifications) (resp *RespNotifications, err error) {
+	urlPath := cli.BuildURLWithQuery(ClientURLPath{"v3", "notifications"}, req.Query())
+	_, err = cli.MakeRequest(ctx, http.MethodGet, urlPath, nil, &resp)
+	return
+}
+
+// Search performs a server-side search. The next batch parameter can be used to fetch more results
+// using the token from a previous response. Only the room events category is currently defined in the spec.
+//
+// See https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3search
+func (cli *Client) Search(ctx context.Context, req *ReqSearch, nextBatch string) (resp *RespSearch, err error) {
+	query := map[string]string{}
+	if nextBatch != "" {
+		query["next_batch"] = nextBatch
+	}
+	urlPath := cli.BuildURLWithQuery(ClientURLPath{"v3", "search"}, query)
+	_, err = cli.MakeRequest(ctx, http.MethodPost, urlPath, req, &resp)
+	return
+}
+
 // Messages returns a list of message and state events for a room. It uses
 // pagination query parameters to paginate history in the room.
 // See https://spec.matrix.org/v1.12/client-server-api/#get_matrixclientv3roomsroomidmessages
@@ -2418,6 +2532,37 @@ func (cli *Client) GetKeyChanges(ctx context.Context, from, to string) (resp *Re
 	return
 }
 
+// PutDehydratedDevice uploads a new dehydrated device using MSC3814, replacing any previous one.
+//
+// See https://github.com/matrix-org/matrix-spec-proposals/pull/3814
+func (cli *Client) PutDehydratedDevice(ctx context.Context, req *ReqPutDehydratedDevice) (resp *RespPutDehydratedDevice, err error) {
+	urlPath := cli.BuildClientURL("unstable", "org.matrix.msc3814.v1", "dehydrated_device")
+	_, err = cli.MakeRequest(ctx, http.MethodPut, urlPath, req, &resp)
+	return
+}
+
+// GetDehydratedDevice gets the current dehydrated device using MSC3814.
+func (cli *Client) GetDehydratedDevice(ctx context.Context) (resp *RespGetDehydratedDevice, err error) {
+	urlPath := cli.BuildClientURL("unstable", "org.matrix.msc3814.v1", "dehydrated_device")
+	_, err = cli.MakeRequest(ctx, http.MethodGet, urlPath, nil, &resp)
+	return
+}
+
+// DeleteDehydratedDevice deletes the current dehydrated device using MSC3814.
+func (cli *Client) DeleteDehydratedDevice(ctx context.Context) (resp *RespDeleteDehydratedDevice, err error) {
+	urlPath := cli.BuildClientURL("unstable", "org.matrix.msc3814.v1", "dehydrated_device")
+	_, err = cli.MakeRequest(ctx, http.MethodDelete, urlPath, nil, &resp)
+	return
+}
+
+// GetDehydratedDeviceEvents fetches a batch of to-device events that were sent to a dehydrated device using MSC3814.
+// The next batch token from the previous response should be passed to get the next batch.
+func (cli *Client) GetDehydratedDeviceEvents(ctx context.Context, deviceID id.DeviceID, nextBatch string) (resp *RespDehydratedDeviceEvents, err error) {
+	urlPath := cli.BuildClientURL("unstable", "org.matrix.msc3814.v1", "dehydrated_device", deviceID, "events")
+	_, err = cli.MakeRequest(ctx, http.MethodPost, urlPath, &ReqDehydratedDeviceEvents{NextBatch: nextBatch}, &resp)
+	return
+}
+
 // GetKeyBackup retrieves the keys from the backup.
 //
 // See: https://spec.matrix.org/v1.9/client-server-api/#get_matrixclientv3room_keyskeys
@@ -8,6 +8,7 @@ package crypto
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/tidwall/sjson"
 
@@ -121,3 +122,32 @@ func (account *OlmAccount) getOneTimeKeys(userID id.UserID, deviceID id.DeviceID
 	}
 	return oneTimeKeys
 }
+
+// getFallbackKeys generates a new fallback key and returns it signed and ready for uploading.
+// If the olm implementation doesn't support fallback keys, nil is returned.
+func (account *OlmAccount) getFallbackKeys(userID id.UserID, deviceID id.DeviceID) (map[id.KeyID]mautrix.OneTimeKey, error) {
+	internal, ok := account.Internal.(olm.AccountWithFallbackKeys)
+	if !ok {
+		return nil, nil
+	}
+	err := internal.GenFallbackKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate fallback key: %w", err)
+	}
+	internalKeys, err := internal.UnpublishedFallbackKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fallback key: %w", err)
+	}
+	fallbackKeys := make(map[id.KeyID]mautrix.OneTimeKey, len(internalKeys))
+	for keyID, key := range internalKeys {
+		key := mautrix.OneTimeKey{Key: key, Fallback: true}
+		signature, err := account.SignJSON(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign fallback key: %w", err)
+		}
+		key.Signatures = signatures.NewSingleSignature(userID, id.KeyAlgorithmEd25519, deviceID.String(), signature)
+		key.IsSigned = true
+		fallbackKeys[id.NewKeyID(id.KeyAlgorithmSignedCurve25519, keyID)] = key
+	}
+	return fallbackKeys, nil
+}
@@ -19,6 +19,7 @@ import (
 
 	mautrix "github.com/iKonoTelecomunicaciones/go"
 	"github.com/iKonoTelecomunicaciones/go/crypto"
+	"github.com/iKonoTelecomunicaciones/go/crypto/ssss"
 	"github.com/iKonoTelecomunicaciones/go/event"
 	"github.com/iKonoTelecomunicaciones/go/id"
 	"github.com/iKonoTelecomunicaciones/go/sqlstatestore"
@@ -40,6 +41,16 @@ type CryptoHelper struct {
 	MSC4190 bool
 	LoginAs *mautrix.ReqLogin
 
+	// DehydratedDevice enables MSC3814 dehydrated devices. When enabled, Init will process room keys sent to
+	// the previous dehydrated device while the client was offline and upload a new one, which is then rotated
+	// periodically in the background. Errors setting up the dehydrated device are logged, but don't fail Init.
+	DehydratedDevice bool
+	// DehydrationSSSSKey is used to fetch the dehydrated device pickle key from SSSS, or to store a new one
+	// if it doesn't exist yet. It's only needed if the pickle key isn't already in the crypto store.
+	DehydrationSSSSKey *ssss.Key
+
+	stopDehydratedDeviceLoop context.CancelFunc
+
 	ASEventProcessor  crypto.ASEventProcessor
 	CustomPostDecrypt func(context.Context, *event.Event)
 
@@ -207,6 +218,11 @@ func (helper *CryptoHelper) Init(ctx context.Context) error {
 		return fmt.Errorf("failed to load olm account: %w", err)
 	} else if err = helper.verifyDeviceKeysOnServer(ctx); err != nil {
 		return err
+	} else if helper.DehydratedDevice {
+		// Dehydrated devices are optional, so failing to set one up shouldn't prevent using encryption
+		if err = helper.initDehydratedDevice(ctx); err != nil {
+			helper.log.Warn().Err(err).Msg("Failed to set up dehydrated device")
+		}
 	}
 
 	if syncer != nil {
@@ -229,6 +245,9 @@ func (helper *CryptoHelper) Init(ctx context.Context) error {
 }
 
 func (helper *CryptoHelper) Close() error {
+	if helper != nil && helper.stopDehydratedDeviceLoop != nil {
+		helper.stopDehydratedDeviceLoop()
+	}
 	if helper != nil && helper.dbForManagedStores != nil {
 		err := helper.dbForManagedStores.Close()
 		if err != nil {
@@ -278,6 +297,22 @@ func (helper *CryptoHelper) verifyDeviceKeysOnServer(ctx context.Context) error
 	}
 }
 
+func (helper *CryptoHelper) initDehydratedDevice(ctx context.Context) error {
+	dehydrationKey, err := helper.mach.GetOrCreateDehydrationKey(ctx, helper.DehydrationSSSSKey)
+	if err != nil {
+		return fmt.Errorf("failed to get dehydrated device key: %w", err)
+	}
+	deviceID, err := helper.mach.RotateDehydratedDevice(ctx, dehydrationKey)
+	if err != nil {
+		return fmt.Errorf("failed to rotate dehydrated device: %w", err)
+	}
+	helper.log.Debug().Stringer("dehydrated_device_id", deviceID).Msg("Dehydrated device set up")
+	var loopCtx context.Context
+	loopCtx, helper.stopDehydratedDeviceLoop = context.WithCancel(context.WithoutCancel(ctx))
+	go helper.mach.DehydratedDeviceLoop(loopCtx, dehydrationKey)
+	return nil
+}
+
 var NoSessionFound = crypto.NoSessionFound
 
 const initialSessionWaitTimeout = 3 * time.Second
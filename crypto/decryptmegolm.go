@@ -17,10 +17,13 @@ import (
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 	"go.mau.fi/util/exgjson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/iKonoTelecomunicaciones/go/crypto/olm"
 	"github.com/iKonoTelecomunicaciones/go/event"
 	"github.com/iKonoTelecomunicaciones/go/id"
+	"github.com/iKonoTelecomunicaciones/go/internal/tracing"
 )
 
 var (
@@ -46,7 +49,19 @@ var (
 )
 
 // DecryptMegolmEvent decrypts an m.room.encrypted event where the algorithm is m.megolm.v1.aes-sha2
-func (mach *OlmMachine) DecryptMegolmEvent(ctx context.Context, evt *event.Event) (*event.Event, error) {
+func (mach *OlmMachine) DecryptMegolmEvent(ctx context.Context, evt *event.Event) (decrypted *event.Event, err error) {
+	ctx, span := tracer.Start(ctx, "OlmMachine.DecryptMegolmEvent", trace.WithAttributes(
+		attribute.String("room_id", evt.RoomID.String()),
+		attribute.String("event_id", evt.ID.String()),
+	))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+	return mach.decryptMegolmEvent(ctx, evt)
+}
+
+func (mach *OlmMachine) decryptMegolmEvent(ctx context.Context, evt *event.Event) (*event.Event, error) {
 	content, ok := evt.Content.Parsed.(*event.EncryptedEventContent)
 	if !ok {
 		return nil, IncorrectEncryptedContentType
@@ -341,6 +341,10 @@ func (mach *OlmMachine) createInboundSession(ctx context.Context, senderKey id.S
 const MinUnwedgeInterval = 1 * time.Hour
 
 func (mach *OlmMachine) unwedgeDevice(log zerolog.Logger, sender id.UserID, senderKey id.SenderKey) {
+	if mach.isDehydratedDevice {
+		// Dehydrated devices are replaced right after processing their events, so there's no point in unwedging.
+		return
+	}
 	log = log.With().Str("action", "unwedge olm session").Logger()
 	ctx := log.WithContext(mach.backgroundCtx)
 	mach.recentlyUnwedgedLock.Lock()
@@ -0,0 +1,309 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package crypto
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mau.fi/util/random"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/crypto/olm"
+	"github.com/iKonoTelecomunicaciones/go/crypto/signatures"
+	"github.com/iKonoTelecomunicaciones/go/crypto/ssss"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// DehydratedDeviceAlgorithm is the device data algorithm used for dehydrated devices.
+// The device data contains the Olm account pickled with the dehydration key.
+const DehydratedDeviceAlgorithm = "org.matrix.msc3814.v1.olm"
+
+// DehydratedDeviceRotationInterval is how often DehydratedDeviceLoop replaces the dehydrated device.
+const DehydratedDeviceRotationInterval = 7 * 24 * time.Hour
+
+var (
+	ErrNoDehydrationKey                 = errors.New("dehydration key not found")
+	ErrUnsupportedDehydratedDeviceData  = errors.New("unsupported dehydrated device algorithm")
+	ErrDehydratedDeviceIdentityMismatch = errors.New("dehydrated device keys don't match pickled account")
+)
+
+// DehydratedDeviceData is the content of the device_data field of dehydrated devices.
+type DehydratedDeviceData struct {
+	Algorithm string `json:"algorithm"`
+	Account   string `json:"account"`
+}
+
+// GetDehydrationKey returns the key used to pickle dehydrated devices from the crypto store.
+// If the key isn't stored locally, [ErrNoDehydrationKey] is returned.
+func (mach *OlmMachine) GetDehydrationKey(ctx context.Context) ([]byte, error) {
+	secret, err := mach.CryptoStore.GetSecret(ctx, id.SecretDehydratedDevice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dehydration key from store: %w", err)
+	} else if secret == "" {
+		return nil, ErrNoDehydrationKey
+	}
+	key, err := base64.RawStdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode dehydration key: %w", err)
+	}
+	return key, nil
+}
+
+func (mach *OlmMachine) storeDehydrationKey(ctx context.Context, key []byte) error {
+	err := mach.CryptoStore.PutSecret(ctx, id.SecretDehydratedDevice, base64.RawStdEncoding.EncodeToString(key))
+	if err != nil {
+		return fmt.Errorf("failed to save dehydration key: %w", err)
+	}
+	return nil
+}
+
+// FetchDehydrationKeyFromSSSS fetches the dehydration key from SSSS, decrypts it using the given key
+// and stores it in the crypto store.
+func (mach *OlmMachine) FetchDehydrationKeyFromSSSS(ctx context.Context, key *ssss.Key) ([]byte, error) {
+	dehydrationKey, err := mach.SSSS.GetDecryptedAccountData(ctx, event.AccountDataDehydratedDeviceKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return dehydrationKey, mach.storeDehydrationKey(ctx, dehydrationKey)
+}
+
+// GenerateAndUploadDehydrationKey generates a new dehydration key, stores it in SSSS encrypted with the given key
+// and saves it in the crypto store.
+func (mach *OlmMachine) GenerateAndUploadDehydrationKey(ctx context.Context, key *ssss.Key) ([]byte, error) {
+	dehydrationKey := random.Bytes(32)
+	err := mach.SSSS.SetEncryptedAccountData(ctx, event.AccountDataDehydratedDeviceKey, dehydrationKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload dehydration key to SSSS: %w", err)
+	}
+	return dehydrationKey, mach.storeDehydrationKey(ctx, dehydrationKey)
+}
+
+// GetOrCreateDehydrationKey returns the dehydration key from the crypto store. If it's not stored locally,
+// it will be fetched from SSSS, or generated and uploaded to SSSS if it doesn't exist there either.
+func (mach *OlmMachine) GetOrCreateDehydrationKey(ctx context.Context, key *ssss.Key) ([]byte, error) {
+	dehydrationKey, err := mach.GetDehydrationKey(ctx)
+	if !errors.Is(err, ErrNoDehydrationKey) {
+		return dehydrationKey, err
+	} else if key == nil {
+		return nil, fmt.Errorf("%w and no SSSS key was provided", ErrNoDehydrationKey)
+	}
+	dehydrationKey, err = mach.FetchDehydrationKeyFromSSSS(ctx, key)
+	if errors.Is(err, mautrix.MNotFound) {
+		mach.machOrContextLog(ctx).Debug().Msg("Dehydration key not found in SSSS, generating new one")
+		return mach.GenerateAndUploadDehydrationKey(ctx, key)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch dehydration key from SSSS: %w", err)
+	}
+	return dehydrationKey, nil
+}
+
+// CreateDehydratedDevice creates a new dehydrated device pickled with the given key and uploads it to the server,
+// replacing the previous dehydrated device if there is one.
+//
+// If the self-signing key is cached, the device keys will also be cross-signed.
+func (mach *OlmMachine) CreateDehydratedDevice(ctx context.Context, dehydrationKey []byte) (id.DeviceID, error) {
+	account := NewOlmAccount()
+	deviceID := id.DeviceID(strings.ToUpper(random.String(10)))
+	userID := mach.Client.UserID
+
+	deviceKeys := &mautrix.DeviceKeys{
+		UserID:     userID,
+		DeviceID:   deviceID,
+		Algorithms: []id.Algorithm{id.AlgorithmMegolmV1, id.AlgorithmOlmV1},
+		Keys: map[id.DeviceKeyID]string{
+			id.NewDeviceKeyID(id.KeyAlgorithmCurve25519, deviceID): string(account.IdentityKey()),
+			id.NewDeviceKeyID(id.KeyAlgorithmEd25519, deviceID):    string(account.SigningKey()),
+		},
+		Dehydrated: true,
+	}
+	signature, err := account.SignJSON(deviceKeys)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign device keys: %w", err)
+	}
+	deviceKeys.Signatures = signatures.NewSingleSignature(userID, id.KeyAlgorithmEd25519, deviceID.String(), signature)
+	if mach.CrossSigningKeys != nil && mach.CrossSigningKeys.SelfSigningKey != nil {
+		selfSigningKey := mach.CrossSigningKeys.SelfSigningKey
+		signature, err = selfSigningKey.SignJSON(deviceKeys)
+		if err != nil {
+			return "", fmt.Errorf("failed to cross-sign device keys: %w", err)
+		}
+		deviceKeys.Signatures[userID][id.NewKeyID(id.KeyAlgorithmEd25519, selfSigningKey.PublicKey().String())] = signature
+	}
+	oneTimeKeys := account.getOneTimeKeys(userID, deviceID, 0)
+	// The dehydrated device can't upload new one-time keys while it's offline,
+	// so a fallback key is needed for sessions to be created after the one-time keys run out.
+	fallbackKeys, err := account.getFallbackKeys(userID, deviceID)
+	if err != nil {
+		return "", err
+	}
+	account.Internal.MarkKeysAsPublished()
+
+	pickled, err := account.Internal.Pickle(dehydrationKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to pickle account: %w", err)
+	}
+	deviceData, err := json.Marshal(&DehydratedDeviceData{
+		Algorithm: DehydratedDeviceAlgorithm,
+		Account:   string(pickled),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal device data: %w", err)
+	}
+	resp, err := mach.Client.PutDehydratedDevice(ctx, &mautrix.ReqPutDehydratedDevice{
+		DeviceID:                 deviceID,
+		DeviceData:               deviceData,
+		InitialDeviceDisplayName: "Dehydrated device",
+		DeviceKeys:               deviceKeys,
+		OneTimeKeys:              oneTimeKeys,
+		FallbackKeys:             fallbackKeys,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload dehydrated device: %w", err)
+	}
+	mach.machOrContextLog(ctx).Debug().
+		Stringer("device_id", resp.DeviceID).
+		Int("one_time_key_count", len(oneTimeKeys)).
+		Msg("Uploaded new dehydrated device")
+	return resp.DeviceID, nil
+}
+
+// RehydrateDevice fetches the current dehydrated device from the server, unpickles it with the given key
+// and processes all the to-device events that were sent to it. Room keys received by the dehydrated device
+// are stored in this machine's crypto store.
+//
+// If there is no dehydrated device on the server, this returns an empty device ID and no error.
+func (mach *OlmMachine) RehydrateDevice(ctx context.Context, dehydrationKey []byte) (id.DeviceID, error) {
+	log := mach.machOrContextLog(ctx).With().Str("action", "rehydrate device").Logger()
+	ctx = log.WithContext(ctx)
+	resp, err := mach.Client.GetDehydratedDevice(ctx)
+	if errors.Is(err, mautrix.MNotFound) {
+		log.Debug().Msg("No dehydrated device found")
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to get dehydrated device: %w", err)
+	}
+	var deviceData DehydratedDeviceData
+	if err = json.Unmarshal(resp.DeviceData, &deviceData); err != nil {
+		return "", fmt.Errorf("failed to parse dehydrated device data: %w", err)
+	} else if deviceData.Algorithm != DehydratedDeviceAlgorithm {
+		return "", fmt.Errorf("%w %q", ErrUnsupportedDehydratedDeviceData, deviceData.Algorithm)
+	}
+	internalAccount, err := olm.AccountFromPickled([]byte(deviceData.Account), dehydrationKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to unpickle dehydrated device: %w", err)
+	}
+	account := &OlmAccount{Internal: internalAccount, Shared: true}
+	device, err := mach.GetOrFetchDevice(ctx, mach.Client.UserID, resp.DeviceID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get dehydrated device keys: %w", err)
+	} else if device.IdentityKey != account.IdentityKey() || device.SigningKey != account.SigningKey() {
+		return "", ErrDehydratedDeviceIdentityMismatch
+	}
+
+	// Use a temporary machine with an in-memory store for the Olm sessions of the dehydrated device,
+	// but store all received room keys using the real machine.
+	dehydratedMach := NewOlmMachine(mach.Client, mach.Log, NewMemoryStore(nil), mach.StateStore)
+	dehydratedMach.account = account
+	dehydratedMach.isDehydratedDevice = true
+	defer dehydratedMach.Destroy()
+
+	var nextBatch string
+	var eventCount, keyCount int
+	for {
+		var events *mautrix.RespDehydratedDeviceEvents
+		events, err = mach.Client.GetDehydratedDeviceEvents(ctx, resp.DeviceID, nextBatch)
+		if err != nil {
+			return "", fmt.Errorf("failed to get dehydrated device events: %w", err)
+		} else if len(events.Events) == 0 {
+			break
+		}
+		for _, evt := range events.Events {
+			if mach.handleDehydratedDeviceEvent(ctx, dehydratedMach, evt) {
+				keyCount++
+			}
+		}
+		eventCount += len(events.Events)
+		nextBatch = events.NextBatch
+	}
+	log.Info().
+		Stringer("device_id", resp.DeviceID).
+		Int("event_count", eventCount).
+		Int("room_key_count", keyCount).
+		Msg("Processed events sent to dehydrated device")
+	return resp.DeviceID, nil
+}
+
+func (mach *OlmMachine) handleDehydratedDeviceEvent(ctx context.Context, dehydratedMach *OlmMachine, evt *event.Event) bool {
+	log := mach.machOrContextLog(ctx).With().
+		Stringer("sender", evt.Sender).
+		Str("type", evt.Type.Type).
+		Logger()
+	evt.Type.Class = event.ToDeviceEventType
+	err := evt.Content.ParseRaw(evt.Type)
+	if err != nil && !errors.Is(err, event.ErrUnsupportedContentType) {
+		log.Warn().Err(err).Msg("Failed to parse dehydrated device event content")
+		return false
+	}
+	switch content := evt.Content.Parsed.(type) {
+	case *event.EncryptedEventContent:
+		decryptedEvt, err := dehydratedMach.decryptOlmEvent(log.WithContext(ctx), evt)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to decrypt dehydrated device event")
+			return false
+		}
+		switch decryptedContent := decryptedEvt.Content.Parsed.(type) {
+		case *event.RoomKeyEventContent:
+			mach.receiveRoomKey(log.WithContext(ctx), decryptedEvt, decryptedContent)
+			return true
+		case *event.ForwardedRoomKeyEventContent:
+			return mach.importForwardedRoomKey(log.WithContext(ctx), decryptedEvt, decryptedContent)
+		default:
+			log.Debug().Str("decrypted_type", decryptedEvt.Type.Type).Msg("Ignoring decrypted dehydrated device event")
+		}
+	case *event.RoomKeyWithheldEventContent:
+		mach.HandleRoomKeyWithheld(log.WithContext(ctx), content)
+	default:
+		log.Debug().Msg("Ignoring dehydrated device event")
+	}
+	return false
+}
+
+// RotateDehydratedDevice processes the events sent to the current dehydrated device (if any)
+// and then replaces it with a new one.
+func (mach *OlmMachine) RotateDehydratedDevice(ctx context.Context, dehydrationKey []byte) (id.DeviceID, error) {
+	_, err := mach.RehydrateDevice(ctx, dehydrationKey)
+	if err != nil {
+		return "", err
+	}
+	return mach.CreateDehydratedDevice(ctx, dehydrationKey)
+}
+
+// DehydratedDeviceLoop rotates the dehydrated device every [DehydratedDeviceRotationInterval] until the context is canceled.
+func (mach *OlmMachine) DehydratedDeviceLoop(ctx context.Context, dehydrationKey []byte) {
+	log := mach.Log.With().Str("action", "rotate dehydrated device").Logger()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debug().Msg("Loop stopped")
+			return
+		case <-time.After(DehydratedDeviceRotationInterval):
+		}
+		deviceID, err := mach.RotateDehydratedDevice(log.WithContext(ctx), dehydrationKey)
+		if err != nil {
+			log.Err(err).Msg("Failed to rotate dehydrated device")
+		} else {
+			log.Info().Stringer("device_id", deviceID).Msg("Rotated dehydrated device")
+		}
+	}
+}
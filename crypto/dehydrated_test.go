@@ -0,0 +1,68 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package crypto_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iKonoTelecomunicaciones/go/crypto/cryptohelper"
+	"github.com/iKonoTelecomunicaciones/go/id"
+	"github.com/iKonoTelecomunicaciones/go/mockserver"
+)
+
+func TestOlmMachine_RehydrateDevice(t *testing.T) {
+	ctx := context.Background()
+	server := mockserver.Create(t)
+	roomID := id.RoomID("!room:localhost")
+	aliceID := id.UserID("@alice:localhost")
+
+	aliceClient, _ := server.Login(t, ctx, aliceID, "ALICE1")
+	aliceMach := aliceClient.Crypto.(*cryptohelper.CryptoHelper).Machine()
+	ssssKey, err := aliceMach.SSSS.GenerateAndUploadKey(ctx, "")
+	require.NoError(t, err)
+	dehydrationKey, err := aliceMach.GetOrCreateDehydrationKey(ctx, ssssKey)
+	require.NoError(t, err)
+	require.Len(t, dehydrationKey, 32)
+
+	deviceID, err := aliceMach.RotateDehydratedDevice(ctx, dehydrationKey)
+	require.NoError(t, err)
+	require.NotEmpty(t, deviceID)
+	assert.True(t, server.DeviceKeys[aliceID][deviceID].Dehydrated)
+	require.Len(t, server.FallbackKeys[aliceID][deviceID], 1)
+	// The dehydrated device can't replenish its one-time keys, so sessions must still work after they run out
+	clear(server.OneTimeKeys[aliceID][deviceID])
+
+	bobClient, _ := server.Login(t, ctx, "@bob:localhost", "BOB1")
+	bobMach := bobClient.Crypto.(*cryptohelper.CryptoHelper).Machine()
+	require.NoError(t, bobMach.ShareGroupSession(ctx, roomID, []id.UserID{aliceID}))
+	outbound, err := bobMach.CryptoStore.GetOutboundGroupSession(ctx, roomID)
+	require.NoError(t, err)
+	require.Len(t, server.DeviceInbox[aliceID][deviceID], 1)
+
+	// A new device that only has access to SSSS should be able to get the keys sent while it was offline.
+	newClient, _ := server.Login(t, ctx, aliceID, "ALICE2")
+	newMach := newClient.Crypto.(*cryptohelper.CryptoHelper).Machine()
+	_, err = newMach.GetDehydrationKey(ctx)
+	require.Error(t, err)
+	newDehydrationKey, err := newMach.GetOrCreateDehydrationKey(ctx, ssssKey)
+	require.NoError(t, err)
+	require.Equal(t, dehydrationKey, newDehydrationKey)
+
+	rehydratedID, err := newMach.RehydrateDevice(ctx, newDehydrationKey)
+	require.NoError(t, err)
+	assert.Equal(t, deviceID, rehydratedID)
+	session, err := newMach.CryptoStore.GetGroupSession(ctx, roomID, outbound.ID())
+	require.NoError(t, err)
+	assert.NotNil(t, session)
+
+	_, err = newMach.RehydrateDevice(ctx, []byte("wrong key"))
+	assert.Error(t, err)
+}
@@ -18,10 +18,13 @@ import (
 	"github.com/tidwall/gjson"
 	"go.mau.fi/util/exgjson"
 	"go.mau.fi/util/exzerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	mautrix "github.com/iKonoTelecomunicaciones/go"
 	"github.com/iKonoTelecomunicaciones/go/event"
 	"github.com/iKonoTelecomunicaciones/go/id"
+	"github.com/iKonoTelecomunicaciones/go/internal/tracing"
 )
 
 var (
@@ -113,7 +116,19 @@ func (mach *OlmMachine) EncryptMegolmEvent(ctx context.Context, roomID id.RoomID
 //
 // If you use the event.Content struct, make sure you pass a pointer to the struct,
 // as JSON serialization will not work correctly otherwise.
-func (mach *OlmMachine) EncryptMegolmEventWithStateKey(ctx context.Context, roomID id.RoomID, evtType event.Type, stateKey *string, content interface{}) (*event.EncryptedEventContent, error) {
+func (mach *OlmMachine) EncryptMegolmEventWithStateKey(ctx context.Context, roomID id.RoomID, evtType event.Type, stateKey *string, content interface{}) (encrypted *event.EncryptedEventContent, err error) {
+	ctx, span := tracer.Start(ctx, "OlmMachine.EncryptMegolmEvent", trace.WithAttributes(
+		attribute.String("room_id", roomID.String()),
+		attribute.String("event_type", evtType.Type),
+	))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+	return mach.encryptMegolmEvent(ctx, roomID, evtType, stateKey, content)
+}
+
+func (mach *OlmMachine) encryptMegolmEvent(ctx context.Context, roomID id.RoomID, evtType event.Type, stateKey *string, content interface{}) (*event.EncryptedEventContent, error) {
 	mach.megolmEncryptLock.Lock()
 	defer mach.megolmEncryptLock.Unlock()
 	session, err := mach.CryptoStore.GetOutboundGroupSession(ctx, roomID)
@@ -35,8 +35,8 @@ type Account struct {
 	NumFallbackKeys    uint8               `json:"number_fallback_keys"`
 }
 
-// Ensure that Account adheres to the olm.Account interface.
-var _ olm.Account = (*Account)(nil)
+// Ensure that Account adheres to the olm.Account interface and supports fallback keys.
+var _ olm.AccountWithFallbackKeys = (*Account)(nil)
 
 // AccountFromJSONPickled loads the Account details from a pickled base64 string. The input is decrypted with the supplied key.
 func AccountFromJSONPickled(pickled, key []byte) (*Account, error) {
@@ -292,6 +292,11 @@ func (a *Account) FallbackKeyUnpublished() map[string]id.Curve25519 {
 	return keys
 }
 
+// UnpublishedFallbackKeys implements [olm.AccountWithFallbackKeys]. It's the same as [Account.FallbackKeyUnpublished].
+func (a *Account) UnpublishedFallbackKeys() (map[string]id.Curve25519, error) {
+	return a.FallbackKeyUnpublished(), nil
+}
+
 //FallbackKeyUnpublishedJSON returns the public part of the current fallback key, only if it is unpublished, of the Account as a JSON string.
 //
 //The returned JSON is of format:
@@ -0,0 +1,265 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package crypto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/crypto/backup"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+var (
+	ErrKeyBackupUploadNotEnabled = errors.New("key backup upload is not enabled")
+	ErrNoKeyBackupVersion        = errors.New("no trusted key backup version found on server")
+)
+
+const (
+	defaultKeyBackupUploadBatchSize = 100
+	defaultKeyBackupUploadDelay     = 5 * time.Second
+	maxKeyBackupUploadRetryDelay    = 30 * time.Minute
+)
+
+// EnableKeyBackupUpload makes the machine automatically upload new inbound Megolm sessions to server-side key backup.
+//
+// The given key must match the latest key backup version on the server (as checked by
+// GetAndVerifyLatestKeyBackupVersion). Sessions that haven't been uploaded to that version yet are uploaded
+// immediately in the background, and any sessions received afterwards are uploaded in batches.
+func (mach *OlmMachine) EnableKeyBackupUpload(ctx context.Context, megolmBackupKey *backup.MegolmBackupKey) error {
+	versionInfo, err := mach.GetAndVerifyLatestKeyBackupVersion(ctx, megolmBackupKey)
+	if err != nil {
+		return fmt.Errorf("failed to verify latest key backup version: %w", err)
+	} else if versionInfo == nil {
+		return ErrNoKeyBackupVersion
+	}
+	if mach.KeyBackupVersion() != versionInfo.Version {
+		err = mach.SetKeyBackupVersion(ctx, versionInfo.Version)
+		if err != nil {
+			return fmt.Errorf("failed to save key backup version: %w", err)
+		}
+	}
+	mach.keyBackupLock.Lock()
+	mach.keyBackupKey = megolmBackupKey
+	startLoop := !mach.keyBackupLoopStarted
+	mach.keyBackupLoopStarted = true
+	mach.keyBackupLock.Unlock()
+	if startLoop {
+		go mach.keyBackupUploadLoop(mach.backgroundCtx)
+	}
+	mach.requestKeyBackupUpload()
+	return nil
+}
+
+// DisableKeyBackupUpload stops automatically uploading new sessions to server-side key backup.
+func (mach *OlmMachine) DisableKeyBackupUpload() {
+	mach.keyBackupLock.Lock()
+	mach.keyBackupKey = nil
+	mach.keyBackupLock.Unlock()
+}
+
+func (mach *OlmMachine) requestKeyBackupUpload() {
+	select {
+	case mach.keyBackupUploadRequested <- struct{}{}:
+	default:
+	}
+}
+
+func (mach *OlmMachine) keyBackupUploadLoop(ctx context.Context) {
+	log := mach.Log.With().Str("action", "key backup upload loop").Logger()
+	ctx = log.WithContext(ctx)
+	delay := mach.KeyBackupUploadDelay
+	if delay <= 0 {
+		delay = defaultKeyBackupUploadDelay
+	}
+	retryDelay := delay
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-mach.keyBackupUploadRequested:
+		}
+		// Wait a bit so that sessions received in quick succession get uploaded in the same batch
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		count, err := mach.UploadKeysToBackup(ctx)
+		if errors.Is(err, ErrKeyBackupUploadNotEnabled) {
+			continue
+		} else if err != nil {
+			log.Err(err).
+				Int("uploaded_count", count).
+				Dur("retry_in", retryDelay).
+				Msg("Failed to upload keys to backup")
+			go func(retryIn time.Duration) {
+				select {
+				case <-ctx.Done():
+				case <-time.After(retryIn):
+					mach.requestKeyBackupUpload()
+				}
+			}(retryDelay)
+			retryDelay = min(retryDelay*2, maxKeyBackupUploadRetryDelay)
+		} else {
+			retryDelay = delay
+			if count > 0 {
+				log.Debug().Int("uploaded_count", count).Msg("Uploaded keys to backup")
+			}
+		}
+	}
+}
+
+// UploadKeysToBackup uploads all inbound Megolm sessions that aren't in the current key backup version.
+//
+// If the server reports that the backup version has changed, the new version is verified with the same key and
+// sessions are uploaded there instead. If the new version isn't trusted, uploading is disabled.
+func (mach *OlmMachine) UploadKeysToBackup(ctx context.Context) (count int, err error) {
+	mach.keyBackupUploadLock.Lock()
+	defer mach.keyBackupUploadLock.Unlock()
+	mach.keyBackupLock.Lock()
+	megolmBackupKey := mach.keyBackupKey
+	mach.keyBackupLock.Unlock()
+	if megolmBackupKey == nil {
+		return 0, ErrKeyBackupUploadNotEnabled
+	}
+	log := mach.machOrContextLog(ctx)
+	batchSize := mach.KeyBackupUploadBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultKeyBackupUploadBatchSize
+	}
+	version := mach.KeyBackupVersion()
+	// Sessions that can't be exported would otherwise be returned by the store forever
+	failed := make(map[id.SessionID]struct{})
+	for {
+		var sessions []*InboundGroupSession
+		err = mach.CryptoStore.GetGroupSessionsWithoutKeyBackupVersion(ctx, version).Iter(func(session *InboundGroupSession) (bool, error) {
+			if _, isFailed := failed[session.ID()]; !isFailed {
+				sessions = append(sessions, session)
+			}
+			return len(sessions) < batchSize, nil
+		})
+		if err != nil {
+			return count, fmt.Errorf("failed to get sessions to back up: %w", err)
+		} else if len(sessions) == 0 {
+			return count, nil
+		}
+		req, sessionIDs := mach.makeKeyBackupRequest(ctx, megolmBackupKey, sessions, failed)
+		if len(sessionIDs) == 0 {
+			continue
+		}
+		_, err = mach.Client.PutKeysInBackup(ctx, version, req)
+		if errors.Is(err, mautrix.MWrongRoomKeysVersion) {
+			log.Info().Stringer("old_version", version).Msg("Key backup version changed, checking new version")
+			oldVersion := version
+			version, err = mach.switchKeyBackupVersion(ctx, megolmBackupKey)
+			if err != nil {
+				return count, err
+			} else if version == oldVersion {
+				return count, fmt.Errorf("server rejected key backup version %s, but it's still the latest version", version)
+			}
+			continue
+		} else if err != nil {
+			return count, fmt.Errorf("failed to upload keys to backup: %w", err)
+		}
+		err = mach.CryptoStore.SetGroupSessionKeyBackupVersion(ctx, version, sessionIDs...)
+		if err != nil {
+			return count, fmt.Errorf("failed to mark sessions as backed up: %w", err)
+		}
+		count += len(sessionIDs)
+	}
+}
+
+func (mach *OlmMachine) makeKeyBackupRequest(
+	ctx context.Context,
+	megolmBackupKey *backup.MegolmBackupKey,
+	sessions []*InboundGroupSession,
+	failed map[id.SessionID]struct{},
+) (*mautrix.ReqKeyBackup, []id.SessionID) {
+	log := zerolog.Ctx(ctx)
+	req := &mautrix.ReqKeyBackup{Rooms: make(map[id.RoomID]mautrix.ReqRoomKeyBackup)}
+	sessionIDs := make([]id.SessionID, 0, len(sessions))
+	for _, session := range sessions {
+		data, err := mach.encryptSessionForBackup(megolmBackupKey, session)
+		if err != nil {
+			log.Warn().Err(err).
+				Stringer("room_id", session.RoomID).
+				Stringer("session_id", session.ID()).
+				Msg("Failed to encrypt session for key backup")
+			failed[session.ID()] = struct{}{}
+			continue
+		}
+		room, ok := req.Rooms[session.RoomID]
+		if !ok {
+			room = mautrix.ReqRoomKeyBackup{Sessions: make(map[id.SessionID]mautrix.ReqKeyBackupData)}
+			req.Rooms[session.RoomID] = room
+		}
+		room.Sessions[session.ID()] = *data
+		sessionIDs = append(sessionIDs, session.ID())
+	}
+	return req, sessionIDs
+}
+
+func (mach *OlmMachine) encryptSessionForBackup(megolmBackupKey *backup.MegolmBackupKey, session *InboundGroupSession) (*mautrix.ReqKeyBackupData, error) {
+	firstKnownIndex := session.Internal.FirstKnownIndex()
+	sessionKey, err := session.Internal.Export(firstKnownIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export session: %w", err)
+	}
+	encrypted, err := backup.EncryptSessionData(megolmBackupKey, &backup.MegolmSessionData{
+		Algorithm:          id.AlgorithmMegolmV1,
+		ForwardingKeyChain: session.ForwardingChains,
+		SenderClaimedKeys:  backup.SenderClaimedKeys{Ed25519: session.SigningKey},
+		SenderKey:          session.SenderKey,
+		SessionKey:         string(sessionKey),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt session data: %w", err)
+	}
+	encryptedJSON, err := json.Marshal(encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal encrypted session data: %w", err)
+	}
+	return &mautrix.ReqKeyBackupData{
+		FirstMessageIndex: int(firstKnownIndex),
+		ForwardedCount:    len(session.ForwardingChains),
+		// Only sessions created by this device are known to be authentic without further checks
+		IsVerified:  session.SenderKey == mach.account.IdentityKey() && len(session.ForwardingChains) == 0,
+		SessionData: encryptedJSON,
+	}, nil
+}
+
+func (mach *OlmMachine) switchKeyBackupVersion(ctx context.Context, megolmBackupKey *backup.MegolmBackupKey) (id.KeyBackupVersion, error) {
+	versionInfo, err := mach.GetAndVerifyLatestKeyBackupVersion(ctx, megolmBackupKey)
+	if err != nil || versionInfo == nil {
+		// The backup was replaced with one we can't verify (e.g. the user reset their recovery key),
+		// so stop uploading to it until a new key is provided.
+		mach.keyBackupLock.Lock()
+		if mach.keyBackupKey == megolmBackupKey {
+			mach.keyBackupKey = nil
+		}
+		mach.keyBackupLock.Unlock()
+		if err == nil {
+			err = ErrNoKeyBackupVersion
+		}
+		return "", fmt.Errorf("disabled key backup upload as new version couldn't be verified: %w", err)
+	}
+	err = mach.SetKeyBackupVersion(ctx, versionInfo.Version)
+	if err != nil {
+		return "", fmt.Errorf("failed to save new key backup version: %w", err)
+	}
+	mach.machOrContextLog(ctx).Info().
+		Stringer("new_version", versionInfo.Version).
+		Msg("Switched to new key backup version")
+	return versionInfo.Version, nil
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package crypto
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/crypto/backup"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+type mockKeyBackupServer struct {
+	lock    sync.Mutex
+	key     *backup.MegolmBackupKey
+	version id.KeyBackupVersion
+	stored  map[id.KeyBackupVersion]map[id.SessionID]mautrix.ReqKeyBackupData
+}
+
+func (mkbs *mockKeyBackupServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mkbs.lock.Lock()
+	defer mkbs.lock.Unlock()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/_matrix/client/v3/room_keys/version":
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&mautrix.RespRoomKeysVersion[backup.MegolmAuthData]{
+			Algorithm: id.KeyBackupAlgorithmMegolmBackupV1,
+			AuthData: backup.MegolmAuthData{
+				PublicKey: id.Ed25519(base64.RawStdEncoding.EncodeToString(mkbs.key.PublicKey().Bytes())),
+			},
+			Version: mkbs.version,
+		})
+	case r.Method == http.MethodPut && r.URL.Path == "/_matrix/client/v3/room_keys/keys":
+		if id.KeyBackupVersion(r.URL.Query().Get("version")) != mkbs.version {
+			mautrix.MWrongRoomKeysVersion.WithMessage("Wrong backup version").Write(w)
+			return
+		}
+		var req mautrix.ReqKeyBackup
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			mautrix.MNotJSON.WithMessage(err.Error()).Write(w)
+			return
+		}
+		if mkbs.stored[mkbs.version] == nil {
+			mkbs.stored[mkbs.version] = make(map[id.SessionID]mautrix.ReqKeyBackupData)
+		}
+		for _, room := range req.Rooms {
+			for sessionID, data := range room.Sessions {
+				mkbs.stored[mkbs.version][sessionID] = data
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&mautrix.RespRoomKeysUpdate{Count: len(mkbs.stored[mkbs.version])})
+	default:
+		mautrix.MUnrecognized.WithMessage("Unrecognized endpoint").Write(w)
+	}
+}
+
+func newKeyBackupTestMachine(t *testing.T) (*OlmMachine, *mockKeyBackupServer) {
+	key, err := backup.NewMegolmBackupKey()
+	require.NoError(t, err)
+	mkbs := &mockKeyBackupServer{
+		key:     key,
+		version: "1",
+		stored:  make(map[id.KeyBackupVersion]map[id.SessionID]mautrix.ReqKeyBackupData),
+	}
+	server := httptest.NewServer(mkbs)
+	t.Cleanup(server.Close)
+	mach := newMachine(t, "@user1:example.com")
+	mach.Client.HomeserverURL, err = mautrix.ParseAndNormalizeBaseURL(server.URL)
+	require.NoError(t, err)
+	mach.KeyBackupUploadBatchSize = 2
+	return mach, mkbs
+}
+
+func TestOlmMachine_UploadKeysToBackup(t *testing.T) {
+	ctx := context.TODO()
+	mach, mkbs := newKeyBackupTestMachine(t)
+
+	_, err := mach.UploadKeysToBackup(ctx)
+	assert.ErrorIs(t, err, ErrKeyBackupUploadNotEnabled)
+
+	var sessionIDs []id.SessionID
+	for _, roomID := range []id.RoomID{"!a:example.com", "!b:example.com", "!c:example.com"} {
+		sess, err := mach.newOutboundGroupSession(ctx, roomID)
+		require.NoError(t, err)
+		sessionIDs = append(sessionIDs, sess.ID())
+	}
+
+	mach.keyBackupKey = mkbs.key
+	mach.account.KeyBackupVersion = mkbs.version
+	count, err := mach.UploadKeysToBackup(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	require.Len(t, mkbs.stored["1"], 3)
+
+	for _, sessionID := range sessionIDs {
+		uploaded, ok := mkbs.stored["1"][sessionID]
+		require.True(t, ok)
+		assert.True(t, uploaded.IsVerified)
+		var encrypted backup.EncryptedSessionData[backup.MegolmSessionData]
+		require.NoError(t, json.Unmarshal(uploaded.SessionData, &encrypted))
+		decrypted, err := encrypted.Decrypt(mkbs.key)
+		require.NoError(t, err)
+		assert.Equal(t, id.AlgorithmMegolmV1, decrypted.Algorithm)
+		assert.Equal(t, mach.account.IdentityKey(), decrypted.SenderKey)
+	}
+
+	count, err = mach.UploadKeysToBackup(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count, "sessions shouldn't be uploaded twice")
+}
+
+func TestOlmMachine_UploadKeysToBackup_VersionChange(t *testing.T) {
+	ctx := context.TODO()
+	mach, mkbs := newKeyBackupTestMachine(t)
+	mach.keyBackupKey = mkbs.key
+	mach.account.KeyBackupVersion = mkbs.version
+
+	_, err := mach.newOutboundGroupSession(ctx, "!a:example.com")
+	require.NoError(t, err)
+	count, err := mach.UploadKeysToBackup(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	mkbs.version = "2"
+	_, err = mach.newOutboundGroupSession(ctx, "!b:example.com")
+	require.NoError(t, err)
+	count, err = mach.UploadKeysToBackup(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "all sessions should be reuploaded to the new version")
+	assert.Equal(t, id.KeyBackupVersion("2"), mach.KeyBackupVersion())
+	assert.Len(t, mkbs.stored["2"], 2)
+
+	// A new backup with a different key must not be trusted
+	mkbs.key, err = backup.NewMegolmBackupKey()
+	require.NoError(t, err)
+	mkbs.version = "3"
+	_, err = mach.newOutboundGroupSession(ctx, "!c:example.com")
+	require.NoError(t, err)
+	_, err = mach.UploadKeysToBackup(ctx)
+	assert.Error(t, err)
+	assert.Empty(t, mkbs.stored["3"])
+	_, err = mach.UploadKeysToBackup(ctx)
+	assert.ErrorIs(t, err, ErrKeyBackupUploadNotEnabled)
+}
@@ -23,8 +23,8 @@ type Account struct {
 	mem []byte
 }
 
-// Ensure that [Account] implements [olm.Account].
-var _ olm.Account = (*Account)(nil)
+// Ensure that [Account] implements [olm.Account] and [olm.AccountWithFallbackKeys].
+var _ olm.AccountWithFallbackKeys = (*Account)(nil)
 
 // AccountFromPickled loads an Account from a pickled base64 string.  Decrypts
 // the Account using the supplied key.  Returns error on failure.  If the key
@@ -314,6 +314,48 @@ func (a *Account) GenOneTimeKeys(num uint) error {
 	return nil
 }
 
+// GenFallbackKey generates a new fallback key. The previous fallback key is
+// kept so that sessions that were started with it can still be established.
+func (a *Account) GenFallbackKey() error {
+	random := make([]byte, C.olm_account_generate_fallback_key_random_length((*C.OlmAccount)(a.int))+1)
+	_, err := rand.Read(random)
+	if err != nil {
+		return olm.NotEnoughGoRandom
+	}
+	r := C.olm_account_generate_fallback_key(
+		(*C.OlmAccount)(a.int),
+		unsafe.Pointer(unsafe.SliceData(random)),
+		C.size_t(len(random)),
+	)
+	runtime.KeepAlive(random)
+	if r == errorVal() {
+		return a.lastError()
+	}
+	return nil
+}
+
+// UnpublishedFallbackKeys returns the public part of the current fallback key
+// if it hasn't been published yet.
+func (a *Account) UnpublishedFallbackKeys() (map[string]id.Curve25519, error) {
+	fallbackKeyJSON := make([]byte, C.olm_account_unpublished_fallback_key_length((*C.OlmAccount)(a.int)))
+	r := C.olm_account_unpublished_fallback_key(
+		(*C.OlmAccount)(a.int),
+		unsafe.Pointer(unsafe.SliceData(fallbackKeyJSON)),
+		C.size_t(len(fallbackKeyJSON)),
+	)
+	if r == errorVal() {
+		return nil, a.lastError()
+	}
+	var fallbackKeys struct {
+		Curve25519 map[string]id.Curve25519 `json:"curve25519"`
+	}
+	err := json.Unmarshal(fallbackKeyJSON[:r], &fallbackKeys)
+	if err != nil {
+		return nil, err
+	}
+	return fallbackKeys.Curve25519, nil
+}
+
 // NewOutboundSession creates a new out-bound session for sending messages to a
 // given curve25519 identityKey and oneTimeKey.  Returns error on failure.  If the
 // keys couldn't be decoded as base64 then the error will be "INVALID_BASE64"
@@ -20,6 +20,7 @@ import (
 	"go.mau.fi/util/exzerolog"
 
 	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/crypto/backup"
 	"github.com/iKonoTelecomunicaciones/go/crypto/olm"
 	"github.com/iKonoTelecomunicaciones/go/crypto/ssss"
 	"github.com/iKonoTelecomunicaciones/go/event"
@@ -55,6 +56,8 @@ type OlmMachine struct {
 	AllowKeyShare func(context.Context, *id.Device, event.RequestedKeyInfo) *KeyShareRejection
 
 	account *OlmAccount
+	// Set for the temporary machines used to process events sent to a dehydrated device.
+	isDehydratedDevice bool
 
 	roomKeyRequestFilled            *sync.Map
 	keyVerificationTransactionState *sync.Map
@@ -98,6 +101,17 @@ type OlmMachine struct {
 
 	secretLock      sync.Mutex
 	secretListeners map[string]chan<- string
+
+	// The maximum number of sessions to upload to key backup in one request.
+	KeyBackupUploadBatchSize int
+	// How long to wait for more sessions before uploading new sessions to key backup.
+	KeyBackupUploadDelay time.Duration
+
+	keyBackupKey             *backup.MegolmBackupKey
+	keyBackupLock            sync.Mutex
+	keyBackupUploadLock      sync.Mutex
+	keyBackupUploadRequested chan struct{}
+	keyBackupLoopStarted     bool
 }
 
 // StateStore is used by OlmMachine to get room state information that's needed for encryption.
@@ -134,6 +148,8 @@ func NewOlmMachine(client *mautrix.Client, log *zerolog.Logger, cryptoStore Stor
 		devicesToUnwedge: make(map[id.IdentityKey]bool),
 		recentlyUnwedged: make(map[id.IdentityKey]time.Time),
 		secretListeners:  make(map[string]chan<- string),
+
+		keyBackupUploadRequested: make(chan struct{}, 1),
 	}
 	mach.backgroundCtx, mach.cancelBackgroundCtx = context.WithCancel(context.Background())
 	mach.AllowKeyShare = mach.defaultAllowKeyShare
@@ -620,6 +636,7 @@ func (mach *OlmMachine) MarkSessionReceived(ctx context.Context, roomID id.RoomI
 	if mach.SessionReceived != nil {
 		mach.SessionReceived(ctx, roomID, id, firstKnownIndex)
 	}
+	mach.requestKeyBackupUpload()
 
 	mach.keyWaitersLock.Lock()
 	ch, ok := mach.keyWaiters[id]
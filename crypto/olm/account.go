@@ -87,6 +87,20 @@ type Account interface {
 	RemoveOneTimeKeys(s Session) error
 }
 
+// AccountWithFallbackKeys is an optional interface for [Account] implementations that support fallback keys.
+type AccountWithFallbackKeys interface {
+	Account
+
+	// GenFallbackKey generates a new fallback key. The previous fallback key is
+	// kept so that sessions that were started with it can still be established.
+	GenFallbackKey() error
+
+	// UnpublishedFallbackKeys returns the public part of the current fallback key
+	// if it hasn't been published yet. The returned map is from key id to
+	// base64-encoded Curve25519 key.
+	UnpublishedFallbackKeys() (map[string]id.Curve25519, error)
+}
+
 var Driver = "none"
 
 var InitBlankAccount func() Account
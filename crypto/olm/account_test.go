@@ -120,3 +120,30 @@ func FuzzAccount_Sign(f *testing.F) {
 		assert.True(t, goolmAccount.IdKeys.Ed25519.Verify(bytes.Clone(message), goolmSignatureBytes))
 	})
 }
+
+func TestAccount_FallbackKeys(t *testing.T) {
+	libolmAccount, err := libolm.NewAccount()
+	require.NoError(t, err)
+	goolmAccount, err := account.NewAccount()
+	require.NoError(t, err)
+	for name, acc := range map[string]olm.AccountWithFallbackKeys{"libolm": libolmAccount, "goolm": goolmAccount} {
+		t.Run(name, func(t *testing.T) {
+			keys, err := acc.UnpublishedFallbackKeys()
+			require.NoError(t, err)
+			assert.Empty(t, keys)
+
+			require.NoError(t, acc.GenFallbackKey())
+			keys, err = acc.UnpublishedFallbackKeys()
+			require.NoError(t, err)
+			require.Len(t, keys, 1)
+			for _, key := range keys {
+				assert.Len(t, key, 43)
+			}
+
+			acc.MarkKeysAsPublished()
+			keys, err = acc.UnpublishedFallbackKeys()
+			require.NoError(t, err)
+			assert.Empty(t, keys)
+		})
+	}
+}
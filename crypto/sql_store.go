@@ -584,6 +584,22 @@ func (store *SQLCryptoStore) GetGroupSessionsWithoutKeyBackupVersion(ctx context
 	return dbutil.NewRowIterWithError(rows, store.scanInboundGroupSession, err)
 }
 
+// SetGroupSessionKeyBackupVersion marks the given inbound Megolm sessions as uploaded to the given key backup version.
+func (store *SQLCryptoStore) SetGroupSessionKeyBackupVersion(ctx context.Context, version id.KeyBackupVersion, sessionIDs ...id.SessionID) error {
+	return store.DB.DoTxn(ctx, nil, func(ctx context.Context) error {
+		for _, sessionID := range sessionIDs {
+			_, err := store.DB.Exec(ctx,
+				"UPDATE crypto_megolm_inbound_session SET key_backup_version=$1 WHERE session_id=$2 AND account_id=$3",
+				version, sessionID, store.AccountID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 // AddOutboundGroupSession stores an outbound Megolm session, along with the information about the room and involved devices.
 func (store *SQLCryptoStore) AddOutboundGroupSession(ctx context.Context, session *OutboundGroupSession) error {
 	sessionBytes, err := session.Internal.Pickle(store.PickleKey)
@@ -91,6 +91,8 @@ type Store interface {
 	GetAllGroupSessions(context.Context) dbutil.RowIter[*InboundGroupSession]
 	// GetGroupSessionsWithoutKeyBackupVersion gets all the inbound Megolm sessions in the store that do not match given key backup version.
 	GetGroupSessionsWithoutKeyBackupVersion(context.Context, id.KeyBackupVersion) dbutil.RowIter[*InboundGroupSession]
+	// SetGroupSessionKeyBackupVersion marks the given inbound Megolm sessions as uploaded to the given key backup version.
+	SetGroupSessionKeyBackupVersion(context.Context, id.KeyBackupVersion, ...id.SessionID) error
 
 	// AddOutboundGroupSession inserts the given outbound Megolm session into the store.
 	//
@@ -447,6 +449,19 @@ func (gs *MemoryStore) GetGroupSessionsWithoutKeyBackupVersion(_ context.Context
 	return dbutil.NewSliceIter(result)
 }
 
+func (gs *MemoryStore) SetGroupSessionKeyBackupVersion(_ context.Context, version id.KeyBackupVersion, sessionIDs ...id.SessionID) error {
+	gs.lock.Lock()
+	defer gs.lock.Unlock()
+	for _, room := range gs.GroupSessions {
+		for _, sessionID := range sessionIDs {
+			if session, ok := room[sessionID]; ok {
+				session.KeyBackupVersion = version
+			}
+		}
+	}
+	return gs.save()
+}
+
 func (gs *MemoryStore) AddOutboundGroupSession(_ context.Context, session *OutboundGroupSession) error {
 	gs.lock.Lock()
 	defer gs.lock.Unlock()
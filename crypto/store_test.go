@@ -165,6 +165,38 @@ func TestStoreMegolmSession(t *testing.T) {
 	}
 }
 
+func TestStoreMegolmSessionKeyBackupVersion(t *testing.T) {
+	stores := getCryptoStores(t)
+	for storeName, store := range stores {
+		t.Run(storeName, func(t *testing.T) {
+			acc := NewOlmAccount()
+
+			internal, err := olm.InboundGroupSessionFromPickled([]byte(groupSession), []byte("test"))
+			require.NoError(t, err, "Error creating internal inbound group session")
+
+			igs := &InboundGroupSession{
+				Internal:   internal,
+				SigningKey: acc.SigningKey(),
+				SenderKey:  acc.IdentityKey(),
+				RoomID:     "room1",
+			}
+			err = store.PutGroupSession(context.TODO(), igs)
+			require.NoError(t, err, "Error storing inbound group session")
+
+			sessions, err := store.GetGroupSessionsWithoutKeyBackupVersion(context.TODO(), "1").AsList()
+			require.NoError(t, err, "Error getting sessions without key backup version")
+			assert.Len(t, sessions, 1)
+
+			err = store.SetGroupSessionKeyBackupVersion(context.TODO(), "1", igs.ID())
+			require.NoError(t, err, "Error setting key backup version")
+
+			sessions, err = store.GetGroupSessionsWithoutKeyBackupVersion(context.TODO(), "1").AsList()
+			require.NoError(t, err, "Error getting sessions without key backup version")
+			assert.Empty(t, sessions)
+		})
+	}
+}
+
 func TestStoreOutboundMegolmSession(t *testing.T) {
 	stores := getCryptoStores(t)
 	for storeName, store := range stores {
@@ -69,6 +69,12 @@ var (
 	MInvalidParam = RespError{ErrCode: "M_INVALID_PARAM", StatusCode: http.StatusBadRequest}
 	// The client specified a room key backup version that is not the current room key backup version for the user.
 	MWrongRoomKeysVersion = RespError{ErrCode: "M_WRONG_ROOM_KEYS_VERSION", StatusCode: http.StatusForbidden}
+	// The sliding sync position token is not valid anymore and the client must start a new connection.
+	MUnknownPos = RespError{ErrCode: "M_UNKNOWN_POS", StatusCode: http.StatusBadRequest}
+	// The media ID was created with /create, but the content hasn't been uploaded yet.
+	MNotYetUploaded = RespError{ErrCode: "M_NOT_YET_UPLOADED", StatusCode: http.StatusGatewayTimeout}
+	// The client tried to upload content to a media ID that already has content.
+	MCannotOverwriteMedia = RespError{ErrCode: "M_CANNOT_OVERWRITE_MEDIA", StatusCode: http.StatusConflict}
 
 	MURLNotSet         = RespError{ErrCode: "M_URL_NOT_SET"}
 	MBadStatus         = RespError{ErrCode: "M_BAD_STATUS"}
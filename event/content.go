@@ -26,6 +26,7 @@ var TypeMap = map[Type]reflect.Type{
 	StateServerACL:         reflect.TypeOf(ServerACLEventContent{}),
 	StateTopic:             reflect.TypeOf(TopicEventContent{}),
 	StateTombstone:         reflect.TypeOf(TombstoneEventContent{}),
+	StateRetention:         reflect.TypeOf(RetentionEventContent{}),
 	StateCreate:            reflect.TypeOf(CreateEventContent{}),
 	StateJoinRules:         reflect.TypeOf(JoinRulesEventContent{}),
 	StateHistoryVisibility: reflect.TypeOf(HistoryVisibilityEventContent{}),
@@ -51,6 +52,7 @@ var TypeMap = map[Type]reflect.Type{
 	StateBeeperRoomFeatures:       reflect.TypeOf(RoomFeatures{}),
 	StateBeeperDisappearingTimer:  reflect.TypeOf(BeeperDisappearingTimer{}),
 	StateBotCommands:              reflect.TypeOf(BotCommandsEventContent{}),
+	StateImagePack:                reflect.TypeOf(ImagePackEventContent{}),
 
 	EventMessage:   reflect.TypeOf(MessageEventContent{}),
 	EventSticker:   reflect.TypeOf(MessageEventContent{}),
@@ -60,6 +62,7 @@ var TypeMap = map[Type]reflect.Type{
 
 	EventUnstablePollStart:    reflect.TypeOf(PollStartEventContent{}),
 	EventUnstablePollResponse: reflect.TypeOf(PollResponseEventContent{}),
+	EventUnstablePollEnd:      reflect.TypeOf(PollEndEventContent{}),
 
 	BeeperMessageStatus: reflect.TypeOf(BeeperMessageStatusEventContent{}),
 	BeeperTranscription: reflect.TypeOf(BeeperTranscriptionEventContent{}),
@@ -71,6 +74,8 @@ var TypeMap = map[Type]reflect.Type{
 	AccountDataIgnoredUserList: reflect.TypeOf(IgnoredUserListEventContent{}),
 	AccountDataMarkedUnread:    reflect.TypeOf(MarkedUnreadEventContent{}),
 	AccountDataBeeperMute:      reflect.TypeOf(BeeperMuteEventContent{}),
+	AccountDataImagePack:       reflect.TypeOf(ImagePackEventContent{}),
+	AccountDataImagePackRooms:  reflect.TypeOf(ImagePackRoomsEventContent{}),
 
 	EphemeralEventTyping:   reflect.TypeOf(TypingEventContent{}),
 	EphemeralEventReceipt:  reflect.TypeOf(ReceiptEventContent{}),
@@ -242,6 +247,7 @@ func init() {
 	gob.Register(&RoomAvatarEventContent{})
 	gob.Register(&TopicEventContent{})
 	gob.Register(&TombstoneEventContent{})
+	gob.Register(&RetentionEventContent{})
 	gob.Register(&CreateEventContent{})
 	gob.Register(&JoinRulesEventContent{})
 	gob.Register(&HistoryVisibilityEventContent{})
@@ -256,6 +262,8 @@ func init() {
 	gob.Register(&DirectChatsEventContent{})
 	gob.Register(&FullyReadEventContent{})
 	gob.Register(&IgnoredUserListEventContent{})
+	gob.Register(&ImagePackEventContent{})
+	gob.Register(&ImagePackRoomsEventContent{})
 	gob.Register(&TypingEventContent{})
 	gob.Register(&ReceiptEventContent{})
 	gob.Register(&PresenceEventContent{})
@@ -325,6 +333,13 @@ func (content *Content) AsTombstone() *TombstoneEventContent {
 	}
 	return casted
 }
+func (content *Content) AsRetention() *RetentionEventContent {
+	casted, ok := content.Parsed.(*RetentionEventContent)
+	if !ok {
+		return &RetentionEventContent{}
+	}
+	return casted
+}
 func (content *Content) AsCreate() *CreateEventContent {
 	casted, ok := content.Parsed.(*CreateEventContent)
 	if !ok {
@@ -451,6 +466,20 @@ func (content *Content) AsIgnoredUserList() *IgnoredUserListEventContent {
 	}
 	return casted
 }
+func (content *Content) AsImagePack() *ImagePackEventContent {
+	casted, ok := content.Parsed.(*ImagePackEventContent)
+	if !ok {
+		return &ImagePackEventContent{}
+	}
+	return casted
+}
+func (content *Content) AsImagePackRooms() *ImagePackRoomsEventContent {
+	casted, ok := content.Parsed.(*ImagePackRoomsEventContent)
+	if !ok {
+		return &ImagePackRoomsEventContent{}
+	}
+	return casted
+}
 func (content *Content) AsMarkedUnread() *MarkedUnreadEventContent {
 	casted, ok := content.Parsed.(*MarkedUnreadEventContent)
 	if !ok {
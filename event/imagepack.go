@@ -0,0 +1,49 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package event
+
+import (
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// ImagePackUsage is the intended usage of an image pack or an individual image in a pack.
+type ImagePackUsage string
+
+const (
+	ImagePackUsageEmoticon ImagePackUsage = "emoticon"
+	ImagePackUsageSticker  ImagePackUsage = "sticker"
+)
+
+// ImagePackEventContent represents the content of an image pack (custom emojis and stickers),
+// either as a im.ponies.room_emotes state event or a im.ponies.user_emotes account data event.
+// https://github.com/matrix-org/matrix-spec-proposals/pull/2545
+type ImagePackEventContent struct {
+	Images map[string]*ImagePackImage `json:"images"`
+	Pack   *ImagePackMetadata         `json:"pack,omitempty"`
+}
+
+// ImagePackMetadata contains the optional metadata of an image pack.
+type ImagePackMetadata struct {
+	DisplayName string              `json:"display_name,omitempty"`
+	AvatarURL   id.ContentURIString `json:"avatar_url,omitempty"`
+	Usage       []ImagePackUsage    `json:"usage,omitempty"`
+	Attribution string              `json:"attribution,omitempty"`
+}
+
+// ImagePackImage is a single image in an image pack. The key in the images map is the shortcode of the image.
+type ImagePackImage struct {
+	URL   id.ContentURIString `json:"url"`
+	Body  string              `json:"body,omitempty"`
+	Info  *FileInfo           `json:"info,omitempty"`
+	Usage []ImagePackUsage    `json:"usage,omitempty"`
+}
+
+// ImagePackRoomsEventContent represents the content of a im.ponies.emote_rooms account data event,
+// which lists room image packs that the user has enabled globally.
+type ImagePackRoomsEventContent struct {
+	Rooms map[id.RoomID]map[string]struct{} `json:"rooms"`
+}
@@ -6,11 +6,16 @@
 
 package event
 
+// PollResponse is the content of the org.matrix.msc3381.poll.response field.
+//
+// This is an alias to an unnamed struct type so that code using the previously anonymous struct keeps compiling.
+type PollResponse = struct {
+	Answers []string `json:"answers"`
+}
+
 type PollResponseEventContent struct {
-	RelatesTo RelatesTo `json:"m.relates_to"`
-	Response  struct {
-		Answers []string `json:"answers"`
-	} `json:"org.matrix.msc3381.poll.response"`
+	RelatesTo RelatesTo    `json:"m.relates_to"`
+	Response  PollResponse `json:"org.matrix.msc3381.poll.response"`
 }
 
 func (content *PollResponseEventContent) GetRelatesTo() *RelatesTo {
@@ -34,18 +39,33 @@ type MSC1767Message struct {
 	Message []ExtensibleText `json:"org.matrix.msc1767.message,omitempty"`
 }
 
+const (
+	PollKindDisclosed   = "org.matrix.msc3381.poll.disclosed"
+	PollKindUndisclosed = "org.matrix.msc3381.poll.undisclosed"
+)
+
+// PollAnswer is a single answer in a poll start event.
+//
+// This is an alias to an unnamed struct type so that code using the previously anonymous struct keeps compiling.
+type PollAnswer = struct {
+	ID string `json:"id"`
+	MSC1767Message
+}
+
+// PollStart is the content of the org.matrix.msc3381.poll.start field.
+//
+// This is an alias to an unnamed struct type so that code using the previously anonymous struct keeps compiling.
+type PollStart = struct {
+	Kind          string         `json:"kind"`
+	MaxSelections int            `json:"max_selections"`
+	Question      MSC1767Message `json:"question"`
+	Answers       []PollAnswer   `json:"answers"`
+}
+
 type PollStartEventContent struct {
 	RelatesTo *RelatesTo `json:"m.relates_to,omitempty"`
 	Mentions  *Mentions  `json:"m.mentions,omitempty"`
-	PollStart struct {
-		Kind          string         `json:"kind"`
-		MaxSelections int            `json:"max_selections"`
-		Question      MSC1767Message `json:"question"`
-		Answers       []struct {
-			ID string `json:"id"`
-			MSC1767Message
-		} `json:"answers"`
-	} `json:"org.matrix.msc3381.poll.start"`
+	PollStart PollStart  `json:"org.matrix.msc3381.poll.start"`
 }
 
 func (content *PollStartEventContent) GetRelatesTo() *RelatesTo {
@@ -62,3 +82,25 @@ func (content *PollStartEventContent) OptionalGetRelatesTo() *RelatesTo {
 func (content *PollStartEventContent) SetRelatesTo(rel *RelatesTo) {
 	content.RelatesTo = rel
 }
+
+type PollEndEventContent struct {
+	RelatesTo RelatesTo `json:"m.relates_to"`
+	PollEnd   struct{}  `json:"org.matrix.msc3381.poll.end"`
+	// Fallback text describing the results of the poll
+	Text string `json:"org.matrix.msc1767.text,omitempty"`
+}
+
+func (content *PollEndEventContent) GetRelatesTo() *RelatesTo {
+	return &content.RelatesTo
+}
+
+func (content *PollEndEventContent) OptionalGetRelatesTo() *RelatesTo {
+	if content.RelatesTo.Type == "" {
+		return nil
+	}
+	return &content.RelatesTo
+}
+
+func (content *PollEndEventContent) SetRelatesTo(rel *RelatesTo) {
+	content.RelatesTo = *rel
+}
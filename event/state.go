@@ -103,6 +103,13 @@ func (tec *TombstoneEventContent) GetReplacementRoom() id.RoomID {
 	return tec.ReplacementRoom
 }
 
+// RetentionEventContent represents the content of a m.room.retention state event.
+// https://github.com/matrix-org/matrix-spec-proposals/pull/1763
+type RetentionEventContent struct {
+	MinLifetime *jsontime.Milliseconds `json:"min_lifetime,omitempty"`
+	MaxLifetime *jsontime.Milliseconds `json:"max_lifetime,omitempty"`
+}
+
 type Predecessor struct {
 	RoomID  id.RoomID  `json:"room_id"`
 	EventID id.EventID `json:"event_id"`
@@ -110,10 +110,10 @@ func (et *Type) GuessClass() TypeClass {
 	switch et.Type {
 	case StateAliases.Type, StateCanonicalAlias.Type, StateCreate.Type, StateJoinRules.Type, StateMember.Type, StateThirdPartyInvite.Type,
 		StatePowerLevels.Type, StateRoomName.Type, StateRoomAvatar.Type, StateServerACL.Type, StateTopic.Type,
-		StatePinnedEvents.Type, StateTombstone.Type, StateEncryption.Type, StateBridge.Type, StateHalfShotBridge.Type,
+		StatePinnedEvents.Type, StateTombstone.Type, StateRetention.Type, StateEncryption.Type, StateBridge.Type, StateHalfShotBridge.Type,
 		StateSpaceParent.Type, StateSpaceChild.Type, StatePolicyRoom.Type, StatePolicyServer.Type, StatePolicyUser.Type,
 		StateElementFunctionalMembers.Type, StateBeeperRoomFeatures.Type, StateBeeperDisappearingTimer.Type,
-		StateBotCommands.Type:
+		StateBotCommands.Type, StateImagePack.Type:
 		return StateEventType
 	case EphemeralEventReceipt.Type, EphemeralEventTyping.Type, EphemeralEventPresence.Type:
 		return EphemeralEventType
@@ -121,7 +121,8 @@ func (et *Type) GuessClass() TypeClass {
 		AccountDataFullyRead.Type, AccountDataIgnoredUserList.Type, AccountDataMarkedUnread.Type,
 		AccountDataSecretStorageKey.Type, AccountDataSecretStorageDefaultKey.Type,
 		AccountDataCrossSigningMaster.Type, AccountDataCrossSigningSelf.Type, AccountDataCrossSigningUser.Type,
-		AccountDataFullyRead.Type, AccountDataMegolmBackupKey.Type:
+		AccountDataFullyRead.Type, AccountDataMegolmBackupKey.Type, AccountDataDehydratedDeviceKey.Type,
+		AccountDataImagePack.Type, AccountDataImagePackRooms.Type:
 		return AccountDataEventType
 	case EventRedaction.Type, EventMessage.Type, EventEncrypted.Type, EventReaction.Type, EventSticker.Type,
 		InRoomVerificationStart.Type, InRoomVerificationReady.Type, InRoomVerificationAccept.Type,
@@ -186,6 +187,7 @@ var (
 	StatePinnedEvents      = Type{"m.room.pinned_events", StateEventType}
 	StateServerACL         = Type{"m.room.server_acl", StateEventType}
 	StateTombstone         = Type{"m.room.tombstone", StateEventType}
+	StateRetention         = Type{"m.room.retention", StateEventType}
 	StatePolicyRoom        = Type{"m.policy.rule.room", StateEventType}
 	StatePolicyServer      = Type{"m.policy.rule.server", StateEventType}
 	StatePolicyUser        = Type{"m.policy.rule.user", StateEventType}
@@ -206,6 +208,7 @@ var (
 	StateBeeperRoomFeatures       = Type{"com.beeper.room_features", StateEventType}
 	StateBeeperDisappearingTimer  = Type{"com.beeper.disappearing_timer", StateEventType}
 	StateBotCommands              = Type{"org.matrix.msc4332.commands", StateEventType}
+	StateImagePack                = Type{"im.ponies.room_emotes", StateEventType}
 )
 
 // Message events
@@ -259,6 +262,8 @@ var (
 	AccountDataIgnoredUserList = Type{"m.ignored_user_list", AccountDataEventType}
 	AccountDataMarkedUnread    = Type{"m.marked_unread", AccountDataEventType}
 	AccountDataBeeperMute      = Type{"com.beeper.mute", AccountDataEventType}
+	AccountDataImagePack       = Type{"im.ponies.user_emotes", AccountDataEventType}
+	AccountDataImagePackRooms  = Type{"im.ponies.emote_rooms", AccountDataEventType}
 
 	AccountDataSecretStorageDefaultKey = Type{"m.secret_storage.default_key", AccountDataEventType}
 	AccountDataSecretStorageKey        = Type{"m.secret_storage.key", AccountDataEventType}
@@ -266,6 +271,7 @@ var (
 	AccountDataCrossSigningUser        = Type{string(id.SecretXSUserSigning), AccountDataEventType}
 	AccountDataCrossSigningSelf        = Type{string(id.SecretXSSelfSigning), AccountDataEventType}
 	AccountDataMegolmBackupKey         = Type{string(id.SecretMegolmBackupV1), AccountDataEventType}
+	AccountDataDehydratedDeviceKey     = Type{string(id.SecretDehydratedDevice), AccountDataEventType}
 )
 
 // Device-to-device events
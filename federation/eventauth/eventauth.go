@@ -207,7 +207,7 @@ func Authorize(roomVersion id.RoomVersion, evt *pdu.PDU, getEvents GetEventsFunc
 		// 6. If the sender’s current membership state is not join, reject.
 		return ErrNotInRoom
 	}
-	powerLevels, err := getPowerLevels(roomVersion, authEvents, createEvt)
+	powerLevels, err := GetPowerLevels(roomVersion, authEvents, createEvt)
 	if err != nil {
 		return err
 	}
@@ -377,7 +377,7 @@ func authorizeMember(roomVersion id.RoomVersion, evt, createEvt *pdu.PDU, authEv
 				// 5.3.5.1. If membership state is join or invite, allow.
 				return nil
 			}
-			powerLevels, err := getPowerLevels(roomVersion, authEvents, createEvt)
+			powerLevels, err := GetPowerLevels(roomVersion, authEvents, createEvt)
 			if err != nil {
 				return err
 			}
@@ -464,7 +464,7 @@ func authorizeMember(roomVersion id.RoomVersion, evt, createEvt *pdu.PDU, authEv
 		} else if targetPrevMembership == event.MembershipBan {
 			return ErrInviteTargetBanned
 		}
-		powerLevels, err := getPowerLevels(roomVersion, authEvents, createEvt)
+		powerLevels, err := GetPowerLevels(roomVersion, authEvents, createEvt)
 		if err != nil {
 			return err
 		}
@@ -488,7 +488,7 @@ func authorizeMember(roomVersion id.RoomVersion, evt, createEvt *pdu.PDU, authEv
 			// 5.5.2. If the sender’s current membership state is not join, reject.
 			return ErrCantKickWithoutBeingInRoom
 		}
-		powerLevels, err := getPowerLevels(roomVersion, authEvents, createEvt)
+		powerLevels, err := GetPowerLevels(roomVersion, authEvents, createEvt)
 		if err != nil {
 			return err
 		}
@@ -505,11 +505,11 @@ func authorizeMember(roomVersion id.RoomVersion, evt, createEvt *pdu.PDU, authEv
 		// 5.5.5. Otherwise, reject.
 		return ErrInsufficientPermissionForKick
 	case event.MembershipBan:
-		if senderMembership != event.MembershipLeave {
+		if senderMembership != event.MembershipJoin {
 			// 5.6.1. If the sender’s current membership state is not join, reject.
 			return ErrCantBanWithoutBeingInRoom
 		}
-		powerLevels, err := getPowerLevels(roomVersion, authEvents, createEvt)
+		powerLevels, err := GetPowerLevels(roomVersion, authEvents, createEvt)
 		if err != nil {
 			return err
 		}
@@ -738,7 +738,9 @@ func findEventAndReadString(events []*pdu.PDU, evtType, stateKey, fieldPath, def
 	})
 }
 
-func getPowerLevels(roomVersion id.RoomVersion, authEvents []*pdu.PDU, createEvt *pdu.PDU) (*event.PowerLevelsEventContent, error) {
+// GetPowerLevels returns the power levels defined by the m.room.power_levels event in the given auth events.
+// If there is no power level event, the room creator has power level 100 (or infinite in room v12+).
+func GetPowerLevels(roomVersion id.RoomVersion, authEvents []*pdu.PDU, createEvt *pdu.PDU) (*event.PowerLevelsEventContent, error) {
 	var err error
 	powerLevels := findEventAndReadData(authEvents, event.StatePowerLevels.Type, "", func(evt *pdu.PDU) *event.PowerLevelsEventContent {
 		if evt == nil {
@@ -83,3 +83,43 @@ func TestAuthorize(t *testing.T) {
 	}
 
 }
+
+func parseEvents(t *testing.T, lines ...string) eventMap {
+	events := make(eventMap, len(lines))
+	for _, line := range lines {
+		var evt *pdu.PDU
+		require.NoError(t, json.Unmarshal([]byte(line), &evt))
+		events[id.EventID(gjson.GetBytes(evt.Unsigned, "event_id").Str)] = evt
+	}
+	return events
+}
+
+func TestAuthorize_Ban(t *testing.T) {
+	events := parseEvents(t,
+		`{"auth_events":[],"content":{"room_version":"11"},"depth":1,"origin_server_ts":1,"prev_events":[],"sender":"@alice:example.com","type":"m.room.create","room_id":"!room:example.com","state_key":"","unsigned":{"event_id":"$CREATE"}}`,
+		`{"auth_events":["$CREATE"],"content":{"users":{"@alice:example.com":100,"@bob:example.com":50}},"depth":2,"origin_server_ts":2,"prev_events":["$CREATE"],"sender":"@alice:example.com","type":"m.room.power_levels","room_id":"!room:example.com","state_key":"","unsigned":{"event_id":"$POWER"}}`,
+		`{"auth_events":["$CREATE","$POWER"],"content":{"membership":"join"},"depth":3,"origin_server_ts":3,"prev_events":["$POWER"],"sender":"@bob:example.com","type":"m.room.member","room_id":"!room:example.com","state_key":"@bob:example.com","unsigned":{"event_id":"$BOBJOIN"}}`,
+		`{"auth_events":["$CREATE","$POWER","$BOBJOIN"],"content":{"membership":"leave"},"depth":4,"origin_server_ts":4,"prev_events":["$BOBJOIN"],"sender":"@bob:example.com","type":"m.room.member","room_id":"!room:example.com","state_key":"@bob:example.com","unsigned":{"event_id":"$BOBLEAVE"}}`,
+		`{"auth_events":["$CREATE","$POWER"],"content":{"membership":"join"},"depth":5,"origin_server_ts":5,"prev_events":["$BOBLEAVE"],"sender":"@carol:example.com","type":"m.room.member","room_id":"!room:example.com","state_key":"@carol:example.com","unsigned":{"event_id":"$CAROL"}}`,
+	)
+	banEvent := func(senderMember id.EventID) *pdu.PDU {
+		return &pdu.PDU{
+			AuthEvents:     []id.EventID{"$CREATE", "$POWER", senderMember, "$CAROL"},
+			Content:        jsontext.Value(`{"membership":"ban"}`),
+			Depth:          6,
+			OriginServerTS: 6,
+			PrevEvents:     []id.EventID{"$CAROL"},
+			RoomID:         "!room:example.com",
+			Sender:         "@bob:example.com",
+			StateKey:       ptr.Ptr("@carol:example.com"),
+			Type:           "m.room.member",
+		}
+	}
+	t.Run("Joined", func(t *testing.T) {
+		assert.NoError(t, eventauth.Authorize(id.RoomV11, banEvent("$BOBJOIN"), events.Get, GetKey))
+	})
+	t.Run("Left", func(t *testing.T) {
+		err := eventauth.Authorize(id.RoomV11, banEvent("$BOBLEAVE"), events.Get, GetKey)
+		assert.ErrorIs(t, err, eventauth.ErrCantBanWithoutBeingInRoom)
+	})
+}
@@ -0,0 +1,16 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package federation
+
+import (
+	"context"
+)
+
+// ContextWithOriginServerName is used by tests to bypass [ServerAuth.AuthenticateMiddleware].
+func ContextWithOriginServerName(ctx context.Context, origin string) context.Context {
+	return context.WithValue(ctx, contextKeyOriginServer, origin)
+}
@@ -18,6 +18,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
 	"go.mau.fi/util/ptr"
@@ -143,6 +144,26 @@ func (sa *ServerAuth) GetKeysWithCache(ctx context.Context, serverName string, k
 	return res, nil
 }
 
+// GetKeyFunc returns a function that fetches server keys using GetKeysWithCache.
+// The returned function is compatible with pdu.GetKeyFunc and can be used to verify event signatures.
+func (sa *ServerAuth) GetKeyFunc(ctx context.Context) func(serverName string, keyID id.KeyID, minValidUntil time.Time) (id.SigningKey, time.Time, error) {
+	return func(serverName string, keyID id.KeyID, minValidUntil time.Time) (id.SigningKey, time.Time, error) {
+		res, err := sa.GetKeysWithCache(ctx, serverName, keyID)
+		if err != nil {
+			return "", time.Time{}, err
+		} else if res == nil {
+			return "", time.Time{}, nil
+		} else if err = res.VerifySelfSignature(); err != nil {
+			return "", time.Time{}, err
+		} else if key, ok := res.VerifyKeys[keyID]; ok {
+			return key.Key, res.ValidUntilTS.Time, nil
+		} else if oldKey, ok := res.OldVerifyKeys[keyID]; ok {
+			return oldKey.Key, oldKey.ExpiredTS.Time, nil
+		}
+		return "", time.Time{}, nil
+	}
+}
+
 type fixedLimitedReader struct {
 	R   io.Reader
 	N   int64
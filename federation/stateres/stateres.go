@@ -0,0 +1,503 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build goexperiment.jsonv2
+
+// Package stateres implements the state resolution v2 algorithm used by room versions 2 and above,
+// including the v2.1 changes used by room version 12.
+//
+// https://spec.matrix.org/v1.16/rooms/v2/#state-resolution
+package stateres
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/federation/eventauth"
+	"github.com/iKonoTelecomunicaciones/go/federation/pdu"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// StateMap is a map from state keys to the event IDs of the corresponding state events.
+type StateMap = map[pdu.StateKey]id.EventID
+
+var (
+	ErrUnsupportedStateResVersion = errors.New("unsupported state resolution version")
+	ErrEventNotFound              = errors.New("event not found")
+)
+
+type eventSet = map[id.EventID]struct{}
+
+type resolver struct {
+	roomVersion id.RoomVersion
+	getEvents   eventauth.GetEventsFunc
+	getKey      pdu.GetKeyFunc
+
+	events     map[id.EventID]*pdu.PDU
+	authChains map[id.EventID]eventSet
+}
+
+// Resolve resolves the given state sets into a single state map.
+//
+// The getEvents function must be able to return all events in the state sets and their auth chains.
+// The getKey function is passed through to [eventauth.Authorize].
+func Resolve(roomVersion id.RoomVersion, stateSets []StateMap, getEvents eventauth.GetEventsFunc, getKey pdu.GetKeyFunc) (StateMap, error) {
+	stateResVersion := roomVersion.StateResVersion()
+	if stateResVersion != id.StateResV2 && stateResVersion != id.StateResV2_1 {
+		return nil, fmt.Errorf("%w %d for room version %s", ErrUnsupportedStateResVersion, stateResVersion, roomVersion)
+	}
+	if len(stateSets) == 0 {
+		return StateMap{}, nil
+	} else if len(stateSets) == 1 {
+		output := make(StateMap, len(stateSets[0]))
+		for key, evtID := range stateSets[0] {
+			output[key] = evtID
+		}
+		return output, nil
+	}
+	r := &resolver{
+		roomVersion: roomVersion,
+		getEvents:   getEvents,
+		getKey:      getKey,
+		events:      make(map[id.EventID]*pdu.PDU),
+		authChains:  make(map[id.EventID]eventSet),
+	}
+	return r.resolve(stateSets)
+}
+
+func (r *resolver) resolve(stateSets []StateMap) (StateMap, error) {
+	unconflicted, conflicted := splitConflicted(stateSets)
+	fullConflictedSet, err := r.getFullConflictedSet(stateSets, conflicted)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. Select the set X of all power events that appear in the full conflicted set.
+	//    For each such power event P, enlarge X by adding the events in the auth chain of P
+	//    which also belong to the full conflicted set. Sort X into a list using the reverse topological power ordering.
+	powerEvents := make(eventSet)
+	for evtID := range fullConflictedSet {
+		if !isPowerEvent(r.events[evtID]) {
+			continue
+		}
+		powerEvents[evtID] = struct{}{}
+		for authEvtID := range r.authChains[evtID] {
+			if _, ok := fullConflictedSet[authEvtID]; ok {
+				powerEvents[authEvtID] = struct{}{}
+			}
+		}
+	}
+	sortedPowerEvents, err := r.reverseTopologicalPowerSort(powerEvents)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Apply the iterative auth checks algorithm, starting from the unconflicted state map, to the list of events
+	//    from the previous step to get a partially resolved state.
+	//    In v2.1, the iterative auth checks start from an empty state map instead.
+	partialState := make(StateMap)
+	if r.roomVersion.StateResVersion() == id.StateResV2 {
+		for key, evtID := range unconflicted {
+			partialState[key] = evtID
+		}
+	}
+	err = r.iterativeAuthChecks(sortedPowerEvents, partialState)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Take all remaining events that weren't picked in step 1 and order them by the mainline ordering
+	//    based on the power level in the partially resolved state obtained in step 2.
+	otherEvents := make([]id.EventID, 0, len(fullConflictedSet)-len(powerEvents))
+	for evtID := range fullConflictedSet {
+		if _, isPowerEvt := powerEvents[evtID]; !isPowerEvt {
+			otherEvents = append(otherEvents, evtID)
+		}
+	}
+	err = r.mainlineSort(otherEvents, partialState[pdu.StateKey{Type: event.StatePowerLevels.Type}])
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. Apply the iterative auth checks algorithm on the partial resolved state and the list of events from the previous step.
+	err = r.iterativeAuthChecks(otherEvents, partialState)
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. Update the result by replacing any event with the event with the same key from the unconflicted state map, if such an event exists.
+	for key, evtID := range unconflicted {
+		partialState[key] = evtID
+	}
+	return partialState, nil
+}
+
+func splitConflicted(stateSets []StateMap) (unconflicted StateMap, conflicted eventSet) {
+	unconflicted = make(StateMap)
+	conflicted = make(eventSet)
+	allKeys := make(map[pdu.StateKey]struct{})
+	for _, stateSet := range stateSets {
+		for key := range stateSet {
+			allKeys[key] = struct{}{}
+		}
+	}
+	for key := range allKeys {
+		firstEvtID, inFirst := stateSets[0][key]
+		isConflicted := !inFirst
+		for _, stateSet := range stateSets[1:] {
+			if evtID, ok := stateSet[key]; !ok || evtID != firstEvtID {
+				isConflicted = true
+				break
+			}
+		}
+		if !isConflicted {
+			unconflicted[key] = firstEvtID
+			continue
+		}
+		for _, stateSet := range stateSets {
+			if evtID, ok := stateSet[key]; ok {
+				conflicted[evtID] = struct{}{}
+			}
+		}
+	}
+	return
+}
+
+func (r *resolver) getFullConflictedSet(stateSets []StateMap, conflicted eventSet) (eventSet, error) {
+	fullSet := make(eventSet, len(conflicted))
+	for evtID := range conflicted {
+		if _, err := r.getAuthChain(evtID); err != nil {
+			return nil, err
+		}
+		fullSet[evtID] = struct{}{}
+	}
+	// The auth difference is calculated by first calculating the full auth chain for each state set
+	// and taking every event that doesn't appear in every auth chain.
+	authChainCounts := make(map[id.EventID]int)
+	for _, stateSet := range stateSets {
+		fullAuthChain := make(eventSet)
+		for _, evtID := range stateSet {
+			authChain, err := r.getAuthChain(evtID)
+			if err != nil {
+				return nil, err
+			}
+			for authEvtID := range authChain {
+				fullAuthChain[authEvtID] = struct{}{}
+			}
+		}
+		for authEvtID := range fullAuthChain {
+			authChainCounts[authEvtID]++
+		}
+	}
+	for evtID, count := range authChainCounts {
+		if count != len(stateSets) {
+			fullSet[evtID] = struct{}{}
+		}
+	}
+	if r.roomVersion.StateResVersion() == id.StateResV2_1 {
+		// In v2.1, the full conflicted set also includes the conflicted state subgraph,
+		// i.e. events that are both ancestors and descendants of conflicted state events.
+		reachesConflicted := make(map[id.EventID]bool)
+		var checkReaches func(evtID id.EventID) bool
+		checkReaches = func(evtID id.EventID) bool {
+			if reaches, ok := reachesConflicted[evtID]; ok {
+				return reaches
+			}
+			reachesConflicted[evtID] = false
+			reaches := false
+			for _, authEvtID := range r.events[evtID].AuthEvents {
+				if _, isConflicted := conflicted[authEvtID]; isConflicted || checkReaches(authEvtID) {
+					reaches = true
+					break
+				}
+			}
+			reachesConflicted[evtID] = reaches
+			return reaches
+		}
+		for evtID := range conflicted {
+			for ancestorID := range r.authChains[evtID] {
+				if checkReaches(ancestorID) {
+					fullSet[ancestorID] = struct{}{}
+				}
+			}
+		}
+	}
+	return fullSet, nil
+}
+
+func (r *resolver) getEvent(evtID id.EventID) (*pdu.PDU, error) {
+	if evt, ok := r.events[evtID]; ok {
+		return evt, nil
+	}
+	evts, err := r.getEvents([]id.EventID{evtID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event %s: %w", evtID, err)
+	} else if len(evts) != 1 || evts[0] == nil {
+		return nil, fmt.Errorf("%w: %s", ErrEventNotFound, evtID)
+	}
+	r.events[evtID] = evts[0]
+	return evts[0], nil
+}
+
+// getEventsForAuth is a [eventauth.GetEventsFunc] that uses the resolver's event cache.
+func (r *resolver) getEventsForAuth(ids []id.EventID) ([]*pdu.PDU, error) {
+	output := make([]*pdu.PDU, len(ids))
+	for i, evtID := range ids {
+		evt, err := r.getEvent(evtID)
+		if errors.Is(err, ErrEventNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		output[i] = evt
+	}
+	return output, nil
+}
+
+func (r *resolver) getAuthChain(evtID id.EventID) (eventSet, error) {
+	if chain, ok := r.authChains[evtID]; ok {
+		return chain, nil
+	}
+	evt, err := r.getEvent(evtID)
+	if err != nil {
+		return nil, err
+	}
+	chain := make(eventSet)
+	// Mark the event as visited before recursing to avoid infinite loops on malformed auth graphs
+	r.authChains[evtID] = chain
+	for _, authEvtID := range evt.AuthEvents {
+		chain[authEvtID] = struct{}{}
+		authChain, err := r.getAuthChain(authEvtID)
+		if err != nil {
+			return nil, err
+		}
+		for ancestorID := range authChain {
+			chain[ancestorID] = struct{}{}
+		}
+	}
+	return chain, nil
+}
+
+func isPowerEvent(evt *pdu.PDU) bool {
+	if evt.StateKey == nil {
+		return false
+	}
+	switch evt.Type {
+	case event.StateCreate.Type, event.StatePowerLevels.Type, event.StateJoinRules.Type:
+		return *evt.StateKey == ""
+	case event.StateMember.Type:
+		membership := event.Membership(gjson.GetBytes(evt.Content, "membership").Str)
+		return (membership == event.MembershipLeave || membership == event.MembershipBan) && *evt.StateKey != evt.Sender.String()
+	default:
+		return false
+	}
+}
+
+func (r *resolver) getCreateEvent(evt *pdu.PDU, authEvents []*pdu.PDU) *pdu.PDU {
+	if r.roomVersion.RoomIDIsCreateEventID() {
+		if len(evt.RoomID) < 2 {
+			return nil
+		}
+		createEvt, _ := r.getEvent(id.EventID("$" + evt.RoomID[1:]))
+		return createEvt
+	}
+	for _, authEvt := range authEvents {
+		if authEvt != nil && authEvt.Type == event.StateCreate.Type {
+			return authEvt
+		}
+	}
+	return nil
+}
+
+func (r *resolver) getSenderPowerLevel(evt *pdu.PDU) (int, error) {
+	if evt.Type == event.StateCreate.Type {
+		return math.MaxInt, nil
+	}
+	authEvents, err := r.getEventsForAuth(evt.AuthEvents)
+	if err != nil {
+		return 0, err
+	}
+	authEvents = slices.DeleteFunc(authEvents, func(authEvt *pdu.PDU) bool {
+		return authEvt == nil || authEvt.StateKey == nil
+	})
+	createEvt := r.getCreateEvent(evt, authEvents)
+	if createEvt == nil {
+		return 0, nil
+	}
+	powerLevels, err := eventauth.GetPowerLevels(r.roomVersion, authEvents, createEvt)
+	if err != nil {
+		// Invalid power levels would've been rejected by auth checks, so this shouldn't happen
+		return 0, nil
+	}
+	return powerLevels.GetUserLevel(evt.Sender), nil
+}
+
+type sortKey struct {
+	evtID      id.EventID
+	powerLevel int
+	timestamp  int64
+}
+
+// compare returns a negative number if a should be ordered before b.
+func (a sortKey) compare(b sortKey) int {
+	if a.powerLevel != b.powerLevel {
+		// Higher power level comes first
+		return cmp.Compare(b.powerLevel, a.powerLevel)
+	} else if a.timestamp != b.timestamp {
+		return cmp.Compare(a.timestamp, b.timestamp)
+	}
+	return cmp.Compare(a.evtID, b.evtID)
+}
+
+// reverseTopologicalPowerSort sorts the given events so that auth events come before the events that reference them.
+// Ties are broken by sender power level (higher first), then origin_server_ts, then event ID.
+func (r *resolver) reverseTopologicalPowerSort(events eventSet) ([]id.EventID, error) {
+	keys := make(map[id.EventID]sortKey, len(events))
+	remainingDeps := make(map[id.EventID]int, len(events))
+	dependents := make(map[id.EventID][]id.EventID, len(events))
+	for evtID := range events {
+		evt := r.events[evtID]
+		powerLevel, err := r.getSenderPowerLevel(evt)
+		if err != nil {
+			return nil, err
+		}
+		keys[evtID] = sortKey{evtID: evtID, powerLevel: powerLevel, timestamp: evt.OriginServerTS}
+		for authEvtID := range r.authChains[evtID] {
+			if _, ok := events[authEvtID]; ok {
+				remainingDeps[evtID]++
+				dependents[authEvtID] = append(dependents[authEvtID], evtID)
+			}
+		}
+	}
+	ready := make([]sortKey, 0, len(events))
+	for evtID, key := range keys {
+		if remainingDeps[evtID] == 0 {
+			ready = append(ready, key)
+		}
+	}
+	output := make([]id.EventID, 0, len(events))
+	for len(ready) > 0 {
+		minIdx := 0
+		for i := 1; i < len(ready); i++ {
+			if ready[i].compare(ready[minIdx]) < 0 {
+				minIdx = i
+			}
+		}
+		next := ready[minIdx]
+		ready = slices.Delete(ready, minIdx, minIdx+1)
+		output = append(output, next.evtID)
+		for _, dependentID := range dependents[next.evtID] {
+			remainingDeps[dependentID]--
+			if remainingDeps[dependentID] == 0 {
+				ready = append(ready, keys[dependentID])
+			}
+		}
+	}
+	if len(output) != len(events) {
+		return nil, fmt.Errorf("auth graph contains a cycle")
+	}
+	return output, nil
+}
+
+func (r *resolver) getPowerLevelAuthEvent(evt *pdu.PDU) (id.EventID, error) {
+	for _, authEvtID := range evt.AuthEvents {
+		authEvt, err := r.getEvent(authEvtID)
+		if err != nil {
+			return "", err
+		} else if authEvt.Type == event.StatePowerLevels.Type && authEvt.StateKey != nil && *authEvt.StateKey == "" {
+			return authEvtID, nil
+		}
+	}
+	return "", nil
+}
+
+// mainlineSort sorts the given events by their position relative to the mainline of the given power level event.
+// Ties are broken by origin_server_ts, then event ID.
+func (r *resolver) mainlineSort(events []id.EventID, powerLevelEvtID id.EventID) error {
+	var mainline []id.EventID
+	for powerLevelEvtID != "" {
+		mainline = append(mainline, powerLevelEvtID)
+		evt, err := r.getEvent(powerLevelEvtID)
+		if err != nil {
+			return err
+		}
+		powerLevelEvtID, err = r.getPowerLevelAuthEvent(evt)
+		if err != nil {
+			return err
+		}
+	}
+	// The oldest event in the mainline has the lowest position, events that don't reference the mainline get 0.
+	mainlinePositions := make(map[id.EventID]int, len(mainline))
+	for i, evtID := range mainline {
+		mainlinePositions[evtID] = len(mainline) - i
+	}
+	keys := make(map[id.EventID]sortKey, len(events))
+	for _, evtID := range events {
+		evt := r.events[evtID]
+		position := 0
+		for cur := evtID; cur != ""; {
+			if pos, ok := mainlinePositions[cur]; ok {
+				position = pos
+				break
+			}
+			curEvt, err := r.getEvent(cur)
+			if err != nil {
+				return err
+			}
+			cur, err = r.getPowerLevelAuthEvent(curEvt)
+			if err != nil {
+				return err
+			}
+		}
+		// The power level field is reused for the mainline position, but lower positions come first
+		keys[evtID] = sortKey{evtID: evtID, powerLevel: -position, timestamp: evt.OriginServerTS}
+	}
+	slices.SortFunc(events, func(a, b id.EventID) int {
+		return keys[a].compare(keys[b])
+	})
+	return nil
+}
+
+// iterativeAuthChecks authorizes each event in order against the given state and its own auth events,
+// and adds accepted state events to the state map.
+func (r *resolver) iterativeAuthChecks(events []id.EventID, state StateMap) error {
+	for _, evtID := range events {
+		evt := r.events[evtID]
+		authEvents := make(StateMap, len(evt.AuthEvents))
+		for _, authEvtID := range evt.AuthEvents {
+			authEvt, err := r.getEvent(authEvtID)
+			if errors.Is(err, ErrEventNotFound) {
+				continue
+			} else if err != nil {
+				return err
+			} else if authEvt.StateKey != nil {
+				authEvents[pdu.StateKey{Type: authEvt.Type, StateKey: *authEvt.StateKey}] = authEvtID
+			}
+		}
+		for _, key := range evt.AuthEventSelection(r.roomVersion) {
+			if stateEvtID, ok := state[key]; ok {
+				authEvents[key] = stateEvtID
+			}
+		}
+		evtClone := evt.Clone()
+		evtClone.AuthEvents = make([]id.EventID, 0, len(authEvents))
+		for _, authEvtID := range authEvents {
+			evtClone.AuthEvents = append(evtClone.AuthEvents, authEvtID)
+		}
+		slices.Sort(evtClone.AuthEvents)
+		err := eventauth.Authorize(r.roomVersion, evtClone, r.getEventsForAuth, r.getKey)
+		if err == nil && evt.StateKey != nil {
+			state[pdu.StateKey{Type: evt.Type, StateKey: *evt.StateKey}] = evtID
+		}
+	}
+	return nil
+}
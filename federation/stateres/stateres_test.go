@@ -0,0 +1,119 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build goexperiment.jsonv2
+
+package stateres_test
+
+import (
+	"embed"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"go.mau.fi/util/exerrors"
+
+	"github.com/iKonoTelecomunicaciones/go/federation/eventauth"
+	"github.com/iKonoTelecomunicaciones/go/federation/pdu"
+	"github.com/iKonoTelecomunicaciones/go/federation/stateres"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// Each test file contains a room DAG with one event per line. The event IDs are defined in unsigned.event_id
+// rather than being calculated from the event content, which allows writing the fixtures by hand.
+// The last event in each file has unsigned.expected_state, which maps "type/state_key" to the event ID
+// that should be in the resolved state before that event, or null if the key should not be in the state.
+//
+//go:embed *.jsonl
+var data embed.FS
+
+type eventMap map[id.EventID]*pdu.PDU
+
+func (em eventMap) Get(ids []id.EventID) ([]*pdu.PDU, error) {
+	output := make([]*pdu.PDU, len(ids))
+	for i, evtID := range ids {
+		output[i] = em[evtID]
+	}
+	return output, nil
+}
+
+func GetKey(serverName string, keyID id.KeyID, validUntilTS time.Time) (id.SigningKey, time.Time, error) {
+	return "", time.Time{}, nil
+}
+
+func TestResolve(t *testing.T) {
+	pdu.UseInternalMetaForGetEventID = true
+	t.Cleanup(func() {
+		pdu.UseInternalMetaForGetEventID = false
+	})
+	files := exerrors.Must(data.ReadDir("."))
+	for _, file := range files {
+		t.Run(file.Name(), func(t *testing.T) {
+			decoder := jsontext.NewDecoder(exerrors.Must(data.Open(file.Name())))
+			events := make(eventMap)
+			stateAfter := make(map[id.EventID]stateres.StateMap)
+			var roomVersion id.RoomVersion
+			var lastEvt *pdu.PDU
+			var lastStateBefore stateres.StateMap
+			for i := 1; ; i++ {
+				var evt *pdu.PDU
+				err := json.UnmarshalDecode(decoder, &evt)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(t, err)
+				if roomVersion == "" {
+					require.Equal(t, evt.Type, "m.room.create")
+					roomVersion = id.RoomVersion(gjson.GetBytes(evt.Content, "room_version").Str)
+				}
+				evtID := id.EventID(gjson.GetBytes(evt.Unsigned, "event_id").Str)
+				evt.InternalMeta.EventID = evtID
+				events[evtID] = evt
+
+				err = eventauth.Authorize(roomVersion, evt, events.Get, GetKey)
+				require.NoErrorf(t, err, "Failed to authorize event #%d / %s of type %s", i, evtID, evt.Type)
+
+				prevStates := make([]stateres.StateMap, len(evt.PrevEvents))
+				for j, prevEvtID := range evt.PrevEvents {
+					prevStates[j] = stateAfter[prevEvtID]
+					require.NotNilf(t, prevStates[j], "Missing prev event %s for event #%d", prevEvtID, i)
+				}
+				stateBefore, err := stateres.Resolve(roomVersion, prevStates, events.Get, GetKey)
+				require.NoErrorf(t, err, "Failed to resolve state before event #%d / %s", i, evtID)
+				state := make(stateres.StateMap, len(stateBefore)+1)
+				for key, stateEvtID := range stateBefore {
+					state[key] = stateEvtID
+				}
+				if evt.StateKey != nil {
+					state[pdu.StateKey{Type: evt.Type, StateKey: *evt.StateKey}] = evtID
+				}
+				stateAfter[evtID] = state
+				lastEvt = evt
+				lastStateBefore = stateBefore
+			}
+			require.NotNil(t, lastEvt)
+			expectedState := gjson.GetBytes(lastEvt.Unsigned, "expected_state")
+			require.True(t, expectedState.IsObject(), "Last event doesn't have expected_state")
+			expectedState.ForEach(func(key, value gjson.Result) bool {
+				evtType, stateKey, _ := strings.Cut(key.Str, "/")
+				actual, ok := lastStateBefore[pdu.StateKey{Type: evtType, StateKey: stateKey}]
+				if value.Type == gjson.Null {
+					assert.Falsef(t, ok, "Expected %s to not be in state, got %s", key.Str, actual)
+				} else {
+					assert.Equalf(t, id.EventID(value.Str), actual, "State mismatch for %s", key.Str)
+				}
+				return true
+			})
+		})
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build goexperiment.jsonv2
+
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	jsonv2 "encoding/json/v2"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/tidwall/gjson"
+	"go.mau.fi/util/exhttp"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/federation/eventauth"
+	"github.com/iKonoTelecomunicaciones/go/federation/pdu"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// Limits for the number of PDUs and EDUs in a single transaction as defined in
+// https://spec.matrix.org/v1.15/server-server-api/#transactions
+const (
+	MaxPDUsPerTransaction = 50
+	MaxEDUsPerTransaction = 100
+	// MaxPDUSize is the maximum size of a single PDU as defined in https://spec.matrix.org/v1.15/client-server-api/#size-limits
+	MaxPDUSize = 65536
+	// MaxTransactionSize is the maximum accepted request body size for transactions.
+	// EDUs don't have a size limit in the spec, so they're assumed to be no larger than PDUs.
+	MaxTransactionSize = (MaxPDUsPerTransaction + MaxEDUsPerTransaction) * MaxPDUSize
+)
+
+// guessedRoomVersion is used to calculate event IDs of PDUs in unknown rooms,
+// so that the error can be included in the transaction response.
+const guessedRoomVersion = id.RoomV12
+
+// maxCachedTransactions is the number of recent transaction responses kept for deduplicating retries.
+const maxCachedTransactions = 1024
+
+var (
+	ErrUnsupportedRoomVersion = errors.New("room version is not supported")
+	ErrInvalidPDUSignature    = errors.New("failed to verify PDU signature")
+)
+
+var (
+	errTransactionOriginMismatch = mautrix.MForbidden.WithMessage("Transaction origin doesn't match authenticated origin")
+	errTooManyPDUs               = mautrix.MBadJSON.WithMessage("Transaction contains more than %d PDUs", MaxPDUsPerTransaction)
+	errTooManyEDUs               = mautrix.MBadJSON.WithMessage("Transaction contains more than %d EDUs", MaxEDUsPerTransaction)
+)
+
+// IncomingPDU is a PDU received in a transaction that passed signature and authorization checks.
+type IncomingPDU struct {
+	*pdu.PDU
+	EventID     id.EventID
+	RoomID      id.RoomID
+	RoomVersion id.RoomVersion
+	// The server that sent the transaction, which is not necessarily the server that created the event.
+	Origin string
+	// True if the content hash didn't match and the event was redacted before processing.
+	Redacted bool
+}
+
+// IncomingEDU is an EDU received in a transaction.
+type IncomingEDU struct {
+	Type    string          `json:"edu_type"`
+	Content json.RawMessage `json:"content"`
+	Origin  string          `json:"-"`
+}
+
+// TransactionReceiver implements the `PUT /_matrix/federation/v1/send/{txnID}` endpoint.
+//
+// Incoming PDUs have their signatures and content hashes verified and are authorized using [eventauth.Authorize]
+// against the auth events returned by GetEvents. Accepted PDUs and all EDUs are passed to the handler functions.
+// PDUs are processed in the order they appear in the transaction, so HandlePDU should store events such that
+// GetEvents can find them when later PDUs in the same transaction reference them.
+type TransactionReceiver struct {
+	ServerAuth *ServerAuth
+
+	// GetRoomVersion returns the version of the given room. If the room is unknown, an error should be returned.
+	GetRoomVersion func(ctx context.Context, roomID id.RoomID) (id.RoomVersion, error)
+	// GetEvents returns the given events in the room. Missing events should be returned as nil.
+	GetEvents func(ctx context.Context, roomID id.RoomID, eventIDs []id.EventID) ([]*pdu.PDU, error)
+	// HandlePDU is called for every accepted PDU. Any returned error is included in the transaction response.
+	HandlePDU func(ctx context.Context, evt *IncomingPDU) error
+	// HandleEDU is called for every EDU.
+	HandleEDU func(ctx context.Context, edu *IncomingEDU)
+
+	recentTxns     map[string]*RespSendTransaction
+	recentTxnOrder []string
+	inFlightTxns   map[string]*inFlightTransaction
+	recentTxnsLock sync.Mutex
+}
+
+type inFlightTransaction struct {
+	done chan struct{}
+	resp *RespSendTransaction
+}
+
+// Register registers the transaction endpoint to the given router.
+// The endpoint is wrapped with [ServerAuth.AuthenticateMiddleware].
+func (tr *TransactionReceiver) Register(r *http.ServeMux) {
+	r.Handle("PUT /_matrix/federation/v1/send/{txnID}", tr.ServerAuth.AuthenticateMiddleware(http.HandlerFunc(tr.PutSendTransaction)))
+}
+
+// PutSendTransaction implements the `PUT /_matrix/federation/v1/send/{txnID}` endpoint.
+// The request must have already been authenticated using [ServerAuth].
+//
+// https://spec.matrix.org/v1.15/server-server-api/#put_matrixfederationv1sendtxnid
+func (tr *TransactionReceiver) PutSendTransaction(w http.ResponseWriter, r *http.Request) {
+	origin := OriginServerNameFromRequest(r)
+	txnID := r.PathValue("txnID")
+	var req ReqSendTransaction
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxTransactionSize)).Decode(&req)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		mautrix.MTooLarge.WithMessage("Transaction is larger than %d bytes", maxBytesErr.Limit).Write(w)
+		return
+	} else if err != nil {
+		mautrix.MBadJSON.WithMessage("failed to parse request: %v", err).Write(w)
+		return
+	} else if origin == "" || req.Origin != origin {
+		errTransactionOriginMismatch.Write(w)
+		return
+	} else if len(req.PDUs) > MaxPDUsPerTransaction {
+		errTooManyPDUs.Write(w)
+		return
+	} else if len(req.EDUs) > MaxEDUsPerTransaction {
+		errTooManyEDUs.Write(w)
+		return
+	}
+	cacheKey := fmt.Sprintf("%s/%s", origin, txnID)
+	resp, inFlight, isNew := tr.startTransaction(cacheKey)
+	if resp != nil {
+		exhttp.WriteJSONResponse(w, http.StatusOK, resp)
+		return
+	} else if !isNew {
+		// The same transaction is already being processed by another request, so wait for it to finish
+		select {
+		case <-inFlight.done:
+			exhttp.WriteJSONResponse(w, http.StatusOK, inFlight.resp)
+		case <-r.Context().Done():
+		}
+		return
+	}
+	ctx := zerolog.Ctx(r.Context()).With().
+		Str("txn_id", txnID).
+		Logger().WithContext(r.Context())
+	resp = tr.ProcessTransaction(ctx, origin, &req)
+	tr.finishTransaction(cacheKey, inFlight, resp)
+	exhttp.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+// startTransaction returns the cached response if the transaction was already processed. Otherwise, it returns the
+// in-flight state of the transaction, and isNew is true if the caller should process it and call finishTransaction.
+func (tr *TransactionReceiver) startTransaction(key string) (resp *RespSendTransaction, inFlight *inFlightTransaction, isNew bool) {
+	tr.recentTxnsLock.Lock()
+	defer tr.recentTxnsLock.Unlock()
+	if resp = tr.recentTxns[key]; resp != nil {
+		return
+	} else if inFlight = tr.inFlightTxns[key]; inFlight != nil {
+		return
+	}
+	if tr.inFlightTxns == nil {
+		tr.inFlightTxns = make(map[string]*inFlightTransaction)
+	}
+	inFlight = &inFlightTransaction{done: make(chan struct{})}
+	tr.inFlightTxns[key] = inFlight
+	isNew = true
+	return
+}
+
+func (tr *TransactionReceiver) finishTransaction(key string, inFlight *inFlightTransaction, resp *RespSendTransaction) {
+	tr.recentTxnsLock.Lock()
+	defer tr.recentTxnsLock.Unlock()
+	inFlight.resp = resp
+	close(inFlight.done)
+	delete(tr.inFlightTxns, key)
+	if tr.recentTxns == nil {
+		tr.recentTxns = make(map[string]*RespSendTransaction)
+	}
+	tr.recentTxns[key] = resp
+	tr.recentTxnOrder = append(tr.recentTxnOrder, key)
+	if len(tr.recentTxnOrder) > maxCachedTransactions {
+		delete(tr.recentTxns, tr.recentTxnOrder[0])
+		tr.recentTxnOrder = tr.recentTxnOrder[1:]
+	}
+}
+
+// ProcessTransaction processes all PDUs and EDUs in a transaction from the given origin server
+// and returns the per-PDU results.
+func (tr *TransactionReceiver) ProcessTransaction(ctx context.Context, origin string, req *ReqSendTransaction) *RespSendTransaction {
+	log := zerolog.Ctx(ctx)
+	resp := &RespSendTransaction{PDUs: make(map[id.EventID]PDUProcessingResult, len(req.PDUs))}
+	for i, rawPDU := range req.PDUs {
+		eventID, err := tr.processPDU(ctx, origin, rawPDU)
+		if eventID == "" {
+			log.Debug().Err(err).Int("pdu_index", i).Msg("Dropping unparseable PDU")
+			continue
+		}
+		var result PDUProcessingResult
+		if err != nil {
+			log.Debug().Err(err).Stringer("event_id", eventID).Msg("Failed to process PDU")
+			result.Error = err.Error()
+		}
+		resp.PDUs[eventID] = result
+	}
+	for i, rawEDU := range req.EDUs {
+		var edu IncomingEDU
+		if err := json.Unmarshal(rawEDU, &edu); err != nil || edu.Type == "" {
+			log.Debug().Err(err).Int("edu_index", i).Msg("Dropping invalid EDU")
+			continue
+		}
+		edu.Origin = origin
+		if tr.HandleEDU != nil {
+			tr.HandleEDU(ctx, &edu)
+		}
+	}
+	return resp
+}
+
+func (tr *TransactionReceiver) processPDU(ctx context.Context, origin string, rawPDU PDU) (id.EventID, error) {
+	var evt pdu.PDU
+	err := jsonv2.Unmarshal(rawPDU, &evt)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse PDU: %w", err)
+	}
+	roomID := evt.RoomID
+	var roomVersion id.RoomVersion
+	if evt.Type == event.StateCreate.Type && evt.StateKey != nil && *evt.StateKey == "" {
+		roomVersion = id.RoomVersion(gjson.GetBytes(evt.Content, "room_version").Str)
+		if roomVersion == "" {
+			roomVersion = id.RoomV1
+		}
+		if roomVersion.RoomIDIsCreateEventID() {
+			roomID, err = evt.GetRoomID()
+			if err != nil {
+				return "", fmt.Errorf("failed to calculate room ID: %w", err)
+			}
+		}
+	} else {
+		roomVersion, err = tr.GetRoomVersion(ctx, roomID)
+		if err != nil {
+			// The event ID depends on the room version, so it's only a guess for unknown rooms
+			eventID, _ := evt.GetEventID(guessedRoomVersion)
+			return eventID, fmt.Errorf("failed to get version of room %s: %w", roomID, err)
+		}
+	}
+	if roomVersion.EventIDFormat() == id.EventIDFormatCustom {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedRoomVersion, roomVersion)
+	}
+	eventID, err := evt.GetEventID(roomVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to calculate event ID: %w", err)
+	}
+	evt.InternalMeta.EventID = eventID
+	getKey := tr.ServerAuth.GetKeyFunc(ctx)
+	err = evt.VerifySignature(roomVersion, evt.Sender.Homeserver(), getKey)
+	if err != nil {
+		return eventID, fmt.Errorf("%w: %w", ErrInvalidPDUSignature, err)
+	}
+	redacted := false
+	if !evt.VerifyContentHash() {
+		zerolog.Ctx(ctx).Debug().
+			Stringer("event_id", eventID).
+			Msg("Content hash mismatch, redacting event")
+		evt.Redact(roomVersion)
+		redacted = true
+	}
+	err = eventauth.Authorize(roomVersion, &evt, func(ids []id.EventID) ([]*pdu.PDU, error) {
+		return tr.GetEvents(ctx, roomID, ids)
+	}, getKey)
+	if err != nil {
+		return eventID, fmt.Errorf("event was rejected: %w", err)
+	}
+	if tr.HandlePDU != nil {
+		err = tr.HandlePDU(ctx, &IncomingPDU{
+			PDU:         &evt,
+			EventID:     eventID,
+			RoomID:      roomID,
+			RoomVersion: roomVersion,
+			Origin:      origin,
+			Redacted:    redacted,
+		})
+	}
+	return eventID, err
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build goexperiment.jsonv2
+
+package federation_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/json/jsontext"
+	jsonv2 "encoding/json/v2"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/util/ptr"
+
+	"github.com/iKonoTelecomunicaciones/go/federation"
+	"github.com/iKonoTelecomunicaciones/go/federation/eventauth"
+	"github.com/iKonoTelecomunicaciones/go/federation/pdu"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+type testRoom struct {
+	t       *testing.T
+	key     *federation.SigningKey
+	roomID  id.RoomID
+	events  map[id.EventID]*pdu.PDU
+	lastID  id.EventID
+	depth   int64
+	handled []*federation.IncomingPDU
+}
+
+func (tr *testRoom) makeEvent(sender id.UserID, evtType string, stateKey *string, content string, authEvents ...id.EventID) *pdu.PDU {
+	tr.depth++
+	evt := &pdu.PDU{
+		AuthEvents:     authEvents,
+		Content:        jsontext.Value(content),
+		Depth:          tr.depth,
+		OriginServerTS: time.Now().UnixMilli(),
+		PrevEvents:     []id.EventID{},
+		RoomID:         tr.roomID,
+		Sender:         sender,
+		StateKey:       stateKey,
+		Type:           evtType,
+	}
+	if evt.AuthEvents == nil {
+		evt.AuthEvents = []id.EventID{}
+	}
+	if tr.lastID != "" {
+		evt.PrevEvents = []id.EventID{tr.lastID}
+	}
+	require.NoError(tr.t, evt.Sign(id.RoomV12, sender.Homeserver(), tr.key.ID, tr.key.Priv))
+	return evt
+}
+
+func (tr *testRoom) getEvents(ctx context.Context, roomID id.RoomID, ids []id.EventID) ([]*pdu.PDU, error) {
+	output := make([]*pdu.PDU, len(ids))
+	for i, evtID := range ids {
+		output[i] = tr.events[evtID]
+	}
+	return output, nil
+}
+
+func (tr *testRoom) handlePDU(ctx context.Context, evt *federation.IncomingPDU) error {
+	tr.events[evt.EventID] = evt.PDU
+	tr.handled = append(tr.handled, evt)
+	return nil
+}
+
+func marshalPDU(t *testing.T, evt *pdu.PDU) federation.PDU {
+	data, err := jsonv2.Marshal(evt)
+	require.NoError(t, err)
+	return data
+}
+
+func TestTransactionReceiver_ProcessTransaction(t *testing.T) {
+	ctx := context.Background()
+	key := federation.GenerateSigningKey()
+	keyResp, err := json.Marshal(key.GenerateKeyResponse("example.com", nil))
+	require.NoError(t, err)
+	var parsedKeyResp federation.ServerKeyResponse
+	require.NoError(t, json.Unmarshal(keyResp, &parsedKeyResp))
+	cache := federation.NewInMemoryCache()
+	cache.StoreKeys(&parsedKeyResp)
+
+	room := &testRoom{t: t, key: key, events: make(map[id.EventID]*pdu.PDU)}
+	receiver := &federation.TransactionReceiver{
+		ServerAuth: federation.NewServerAuth(federation.NewClient("", nil, cache), cache, nil),
+		GetRoomVersion: func(ctx context.Context, roomID id.RoomID) (id.RoomVersion, error) {
+			if roomID != room.roomID {
+				return "", errors.New("unknown room")
+			}
+			return id.RoomV12, nil
+		},
+		GetEvents: room.getEvents,
+		HandlePDU: room.handlePDU,
+	}
+	var edus []*federation.IncomingEDU
+	receiver.HandleEDU = func(ctx context.Context, edu *federation.IncomingEDU) {
+		edus = append(edus, edu)
+	}
+
+	createEvt := room.makeEvent("@alice:example.com", "m.room.create", ptr.Ptr(""), `{"room_version":"12"}`)
+	createID, err := createEvt.GetEventID(id.RoomV12)
+	require.NoError(t, err)
+	room.roomID, err = createEvt.GetRoomID()
+	require.NoError(t, err)
+	room.lastID = createID
+	joinEvt := room.makeEvent("@alice:example.com", "m.room.member", ptr.Ptr("@alice:example.com"), `{"membership":"join"}`)
+	joinID, err := joinEvt.GetEventID(id.RoomV12)
+	require.NoError(t, err)
+	room.lastID = joinID
+
+	msgEvt := room.makeEvent("@alice:example.com", "m.room.message", nil, `{"msgtype":"m.text","body":"hello"}`, joinID)
+	tamperedEvt := room.makeEvent("@alice:example.com", "m.room.message", nil, `{"msgtype":"m.text","body":"hi"}`, joinID)
+	tamperedEvt.Content = jsontext.Value(`{"msgtype":"m.text","body":"tampered"}`)
+	notJoinedEvt := room.makeEvent("@bob:example.com", "m.room.message", nil, `{"msgtype":"m.text","body":"hello"}`)
+	otherKey := federation.GenerateSigningKey()
+	badSigEvt := room.makeEvent("@alice:example.com", "m.room.message", nil, `{"msgtype":"m.text","body":"hello"}`, joinID)
+	badSigEvt.Signatures = nil
+	require.NoError(t, badSigEvt.Sign(id.RoomV12, "example.com", otherKey.ID, otherKey.Priv))
+	unknownRoomEvt := room.makeEvent("@alice:example.com", "m.room.message", nil, `{"msgtype":"m.text","body":"hello"}`, joinID)
+	unknownRoomEvt.RoomID = "!unknown:example.com"
+
+	resp := receiver.ProcessTransaction(ctx, "example.com", &federation.ReqSendTransaction{
+		Origin: "example.com",
+		PDUs: []federation.PDU{
+			marshalPDU(t, createEvt),
+			marshalPDU(t, joinEvt),
+			marshalPDU(t, msgEvt),
+			marshalPDU(t, tamperedEvt),
+			marshalPDU(t, notJoinedEvt),
+			marshalPDU(t, badSigEvt),
+			marshalPDU(t, unknownRoomEvt),
+			federation.PDU(`{"type": 1}`),
+		},
+		EDUs: []federation.EDU{
+			federation.EDU(`{"edu_type": "m.typing", "content": {"room_id": "!foo:example.com", "typing": true}}`),
+		},
+	})
+
+	require.Len(t, resp.PDUs, 7)
+	require.Len(t, room.handled, 4)
+	assert.Equal(t, createID, room.handled[0].EventID)
+	assert.Equal(t, room.roomID, room.handled[0].RoomID)
+	assert.Equal(t, joinID, room.handled[1].EventID)
+	assert.Equal(t, "example.com", room.handled[2].Origin)
+	assert.False(t, room.handled[2].Redacted)
+	for _, evtID := range []id.EventID{createID, joinID, room.handled[2].EventID, room.handled[3].EventID} {
+		assert.Empty(t, resp.PDUs[evtID].Error)
+	}
+
+	assert.True(t, room.handled[3].Redacted, "event with invalid content hash should be redacted")
+	assert.JSONEq(t, `{}`, string(room.handled[3].Content))
+
+	notJoinedID, err := notJoinedEvt.GetEventID(id.RoomV12)
+	require.NoError(t, err)
+	assert.Contains(t, resp.PDUs[notJoinedID].Error, eventauth.ErrNotInRoom.Error())
+	badSigID, err := badSigEvt.GetEventID(id.RoomV12)
+	require.NoError(t, err)
+	assert.Contains(t, resp.PDUs[badSigID].Error, federation.ErrInvalidPDUSignature.Error())
+	unknownRoomID, err := unknownRoomEvt.GetEventID(id.RoomV12)
+	require.NoError(t, err)
+	assert.Contains(t, resp.PDUs[unknownRoomID].Error, "failed to get version of room")
+
+	require.Len(t, edus, 1)
+	assert.Equal(t, "m.typing", edus[0].Type)
+	assert.Equal(t, "example.com", edus[0].Origin)
+}
+
+func TestTransactionReceiver_PutSendTransaction(t *testing.T) {
+	unblock := make(chan struct{})
+	var eduCount atomic.Int32
+	receiver := &federation.TransactionReceiver{
+		HandleEDU: func(ctx context.Context, edu *federation.IncomingEDU) {
+			eduCount.Add(1)
+			<-unblock
+		},
+	}
+	body, err := json.Marshal(&federation.ReqSendTransaction{
+		Origin: "example.com",
+		PDUs:   []federation.PDU{},
+		EDUs:   []federation.EDU{federation.EDU(`{"edu_type": "m.typing", "content": {}}`)},
+	})
+	require.NoError(t, err)
+	send := func(txnID string, body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/_matrix/federation/v1/send/"+txnID, body)
+		req = req.WithContext(federation.ContextWithOriginServerName(req.Context(), "example.com"))
+		req.SetPathValue("txnID", txnID)
+		w := httptest.NewRecorder()
+		receiver.PutSendTransaction(w, req)
+		return w
+	}
+
+	// Concurrent retries of the same transaction are only processed once
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, 3)
+	for i := range responses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = send("txn1", bytes.NewReader(body))
+		}()
+	}
+	require.Eventually(t, func() bool { return eduCount.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	// Give the other requests time to reach the in-flight check
+	time.Sleep(50 * time.Millisecond)
+	close(unblock)
+	wg.Wait()
+	assert.EqualValues(t, 1, eduCount.Load())
+	for _, w := range responses {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"pdus": {}}`, w.Body.String())
+	}
+
+	// Retries after the transaction finished get the cached response
+	assert.Equal(t, http.StatusOK, send("txn1", bytes.NewReader(body)).Code)
+	assert.EqualValues(t, 1, eduCount.Load())
+	assert.Equal(t, http.StatusOK, send("txn2", bytes.NewReader(body)).Code)
+	assert.EqualValues(t, 2, eduCount.Load())
+
+	w := send("txn3", io.MultiReader(strings.NewReader(`{"pdus": [`), strings.NewReader(strings.Repeat(" ", federation.MaxTransactionSize))))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.EqualValues(t, 2, eduCount.Load())
+}
@@ -0,0 +1,224 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mockserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mau.fi/util/exhttp"
+	"go.mau.fi/util/jsontime"
+	"go.mau.fi/util/random"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// UIAFlows are the user-interactive auth flows that the mock server offers for endpoints that require UIA.
+// Any non-empty captcha response is accepted for the m.login.recaptcha stage.
+var UIAFlows = []mautrix.UIAFlow{
+	{Stages: []mautrix.AuthType{mautrix.AuthTypePassword}},
+	{Stages: []mautrix.AuthType{mautrix.AuthTypeReCAPTCHA, mautrix.AuthTypeDummy}},
+}
+
+type uiaSession struct {
+	userID    id.UserID
+	completed []string
+}
+
+type pendingThreePID struct {
+	clientSecret string
+	address      string
+}
+
+// checkUIA validates the auth data of a request. If it doesn't complete any flow, the UIA response is written
+// and false is returned.
+func (ms *MockServer) checkUIA(w http.ResponseWriter, userID id.UserID, rawAuth json.RawMessage) bool {
+	var base mautrix.BaseAuthData
+	if len(rawAuth) > 0 {
+		_ = json.Unmarshal(rawAuth, &base)
+	}
+	session, ok := ms.uiaSessions[base.Session]
+	if !ok || session.userID != userID {
+		base.Session = random.String(16)
+		session = &uiaSession{userID: userID}
+		ms.uiaSessions[base.Session] = session
+	}
+	var stageOK bool
+	switch base.Type {
+	case "":
+	case mautrix.AuthTypePassword:
+		var auth mautrix.UIAuthPassword
+		_ = json.Unmarshal(rawAuth, &auth)
+		password, hasPassword := ms.Passwords[userID]
+		stageOK = hasPassword && auth.Identifier.User == userID.String() && auth.Password == password
+	case mautrix.AuthTypeReCAPTCHA:
+		var auth mautrix.UIAuthReCAPTCHA
+		_ = json.Unmarshal(rawAuth, &auth)
+		stageOK = auth.Response != ""
+	case mautrix.AuthTypeDummy:
+		stageOK = true
+	}
+	if stageOK {
+		session.completed = append(session.completed, string(base.Type))
+	}
+	for _, flow := range UIAFlows {
+		if slices.EqualFunc(session.completed, flow.Stages, func(completed string, stage mautrix.AuthType) bool {
+			return completed == string(stage)
+		}) {
+			delete(ms.uiaSessions, base.Session)
+			return true
+		}
+	}
+	resp := &mautrix.RespUserInteractive{
+		Flows:     UIAFlows,
+		Params:    map[mautrix.AuthType]any{mautrix.AuthTypeReCAPTCHA: map[string]any{"public_key": "mock"}},
+		Session:   base.Session,
+		Completed: session.completed,
+	}
+	if base.Type != "" && !stageOK {
+		resp.ErrCode = mautrix.MForbidden.ErrCode
+		resp.Error = "Invalid auth data for " + string(base.Type)
+	}
+	exhttp.WriteJSONResponse(w, http.StatusUnauthorized, resp)
+	return false
+}
+
+// logoutUser invalidates all access tokens of the given user except the given one.
+func (ms *MockServer) logoutUser(userID id.UserID, exceptToken string) {
+	for token, user := range ms.AccessTokenToUserID {
+		if user.UserID == userID && token != exceptToken {
+			delete(ms.AccessTokenToUserID, token)
+		}
+	}
+}
+
+func (ms *MockServer) postChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		mautrix.ReqChangePassword
+		Auth json.RawMessage `json:"auth"`
+	}
+	mustDecode(r, &req)
+	if !ms.checkUIA(w, userID.UserID, req.Auth) {
+		return
+	}
+	ms.Passwords[userID.UserID] = req.NewPassword
+	if req.LogoutDevices == nil || *req.LogoutDevices {
+		ms.logoutUser(userID.UserID, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	}
+	exhttp.WriteEmptyJSONResponse(w, http.StatusOK)
+}
+
+func (ms *MockServer) postDeactivateAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		mautrix.ReqDeactivateAccount
+		Auth json.RawMessage `json:"auth"`
+	}
+	mustDecode(r, &req)
+	if !ms.checkUIA(w, userID.UserID, req.Auth) {
+		return
+	}
+	ms.logoutUser(userID.UserID, "")
+	delete(ms.Passwords, userID.UserID)
+	delete(ms.ThreePIDs, userID.UserID)
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespDeactivateAccount{IDServerUnbindResult: "no-support"})
+}
+
+func (ms *MockServer) getThreePIDs(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	threePIDs := ms.ThreePIDs[userID.UserID]
+	if threePIDs == nil {
+		threePIDs = []mautrix.ThreePID{}
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespThreePIDs{ThreePIDs: threePIDs})
+}
+
+// postRequestEmailToken creates a validation session for an email address. No email is sent,
+// the session is considered validated immediately.
+func (ms *MockServer) postRequestEmailToken(w http.ResponseWriter, r *http.Request) {
+	if _, ok := ms.authenticate(w, r); !ok {
+		return
+	}
+	var req mautrix.ReqRequestEmailToken
+	mustDecode(r, &req)
+	sid := random.String(16)
+	ms.pendingThreePIDs[sid] = &pendingThreePID{clientSecret: req.ClientSecret, address: req.Email}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespRequestToken{SID: sid})
+}
+
+func (ms *MockServer) postAddThreePID(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		mautrix.ReqAddThreePID
+		Auth json.RawMessage `json:"auth"`
+	}
+	mustDecode(r, &req)
+	pending, ok := ms.pendingThreePIDs[req.SID]
+	if !ok || pending.clientSecret != req.ClientSecret {
+		mautrix.MForbidden.WithMessage("Unknown validation session").Write(w)
+		return
+	} else if !ms.checkUIA(w, userID.UserID, req.Auth) {
+		return
+	}
+	delete(ms.pendingThreePIDs, req.SID)
+	now := jsontime.UnixMilliNow()
+	ms.ThreePIDs[userID.UserID] = append(ms.ThreePIDs[userID.UserID], mautrix.ThreePID{
+		Medium:      "email",
+		Address:     pending.address,
+		AddedAt:     now,
+		ValidatedAt: now,
+	})
+	exhttp.WriteEmptyJSONResponse(w, http.StatusOK)
+}
+
+func (ms *MockServer) postDeleteThreePID(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	var req mautrix.ReqRemoveThreePID
+	mustDecode(r, &req)
+	ms.ThreePIDs[userID.UserID] = slices.DeleteFunc(ms.ThreePIDs[userID.UserID], func(threePID mautrix.ThreePID) bool {
+		return threePID.Medium == req.Medium && threePID.Address == req.Address
+	})
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespRemoveThreePID{IDServerUnbindResult: "no-support"})
+}
+
+func (ms *MockServer) postGetLoginToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Auth json.RawMessage `json:"auth"`
+	}
+	mustDecode(r, &req)
+	if !ms.checkUIA(w, userID.UserID, req.Auth) {
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespLoginToken{
+		LoginToken:  random.String(32),
+		ExpiresInMS: (2 * time.Minute).Milliseconds(),
+	})
+}
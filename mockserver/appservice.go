@@ -0,0 +1,301 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mockserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/util/exhttp"
+	"go.mau.fi/util/random"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/appservice"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// Appservice is an application service registered on the mock server.
+type Appservice struct {
+	Registration *appservice.Registration
+	// Transactions contains every transaction that has been successfully delivered to the appservice.
+	Transactions []*appservice.Transaction
+
+	botUserID    id.UserID
+	userIDs      []*regexp.Regexp
+	roomAliases  []*regexp.Regexp
+	roomIDs      []*regexp.Regexp
+	queue        []*event.Event
+	inFlight     bool
+	wake         chan struct{}
+	txnIDCounter int
+}
+
+func compileNamespaces(namespaces appservice.NamespaceList) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, len(namespaces))
+	for i, ns := range namespaces {
+		var err error
+		compiled[i], err = regexp.Compile("^" + ns.Regex + "$")
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile namespace %q: %w", ns.Regex, err)
+		}
+	}
+	return compiled, nil
+}
+
+func matchesAny(regexes []*regexp.Regexp, value string) bool {
+	return slices.ContainsFunc(regexes, func(re *regexp.Regexp) bool {
+		return re.MatchString(value)
+	})
+}
+
+// BotUserID returns the user ID of the appservice's bot user.
+func (as *Appservice) BotUserID() id.UserID {
+	return as.botUserID
+}
+
+// IsInterestedInUser returns true if the given user ID is the appservice bot or is in the appservice's user namespace.
+func (as *Appservice) IsInterestedInUser(userID id.UserID) bool {
+	return userID == as.botUserID || matchesAny(as.userIDs, userID.String())
+}
+
+func (as *Appservice) isInterestedInEvent(room *Room, evt *event.Event) bool {
+	if as.IsInterestedInUser(evt.Sender) || matchesAny(as.roomIDs, room.ID.String()) {
+		return true
+	} else if evt.Type == event.StateMember && as.IsInterestedInUser(id.UserID(evt.GetStateKey())) {
+		return true
+	}
+	for _, member := range room.Members(event.MembershipJoin) {
+		if as.IsInterestedInUser(member) {
+			return true
+		}
+	}
+	if canonicalAlias := room.GetState(event.StateCanonicalAlias, ""); canonicalAlias != nil {
+		return matchesAny(as.roomAliases, getCanonicalAlias(canonicalAlias).String())
+	}
+	return false
+}
+
+func getCanonicalAlias(evt *event.Event) id.RoomAlias {
+	var content event.CanonicalAliasEventContent
+	_ = json.Unmarshal(evt.Content.VeryRaw, &content)
+	return content.Alias
+}
+
+// RegisterAppservice registers an appservice on the mock server. Events in rooms the appservice is interested in
+// will be pushed to the URL in the registration until the test finishes.
+func (ms *MockServer) RegisterAppservice(t testing.TB, reg *appservice.Registration) *Appservice {
+	t.Helper()
+	var err error
+	as := &Appservice{
+		Registration: reg,
+		botUserID:    id.NewUserID(reg.SenderLocalpart, ms.ServerName),
+		wake:         make(chan struct{}, 1),
+	}
+	as.userIDs, err = compileNamespaces(reg.Namespaces.UserIDs)
+	require.NoError(t, err)
+	as.roomAliases, err = compileNamespaces(reg.Namespaces.RoomAliases)
+	require.NoError(t, err)
+	as.roomIDs, err = compileNamespaces(reg.Namespaces.RoomIDs)
+	require.NoError(t, err)
+
+	ms.lock.Lock()
+	ms.Appservices = append(ms.Appservices, as)
+	ms.ensureUser(as.botUserID)
+	ms.lock.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go ms.appserviceTransactionLoop(ctx, as)
+	return as
+}
+
+// queueAppserviceEvent queues the event to be sent to all interested appservices. The lock must be held when calling this.
+func (ms *MockServer) queueAppserviceEvent(room *Room, evt *event.Event) {
+	for _, as := range ms.Appservices {
+		if as.isInterestedInEvent(room, evt) {
+			as.queue = append(as.queue, evt)
+			select {
+			case as.wake <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (ms *MockServer) appserviceTransactionLoop(ctx context.Context, as *Appservice) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	for {
+		ms.lock.Lock()
+		if len(as.queue) == 0 {
+			ms.lock.Unlock()
+			select {
+			case <-as.wake:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		txn := &appservice.Transaction{Events: as.queue}
+		as.queue = nil
+		as.inFlight = true
+		as.txnIDCounter++
+		txnID := strconv.Itoa(as.txnIDCounter)
+		ms.lock.Unlock()
+
+		for {
+			err := sendTransaction(ctx, client, as.Registration, txnID, txn)
+			if err == nil {
+				break
+			}
+			select {
+			case <-time.After(100 * time.Millisecond):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		ms.lock.Lock()
+		as.Transactions = append(as.Transactions, txn)
+		as.inFlight = false
+		ms.lock.Unlock()
+	}
+}
+
+func sendTransaction(ctx context.Context, client *http.Client, reg *appservice.Registration, txnID string, txn *appservice.Transaction) error {
+	body, err := json.Marshal(txn)
+	if err != nil {
+		return err
+	}
+	url := strings.TrimSuffix(reg.URL, "/") + "/_matrix/app/v1/transactions/" + txnID
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+reg.ServerToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// WaitForAppserviceTransactions waits until all queued events have been delivered to appservices.
+func (ms *MockServer) WaitForAppserviceTransactions(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		ms.lock.Lock()
+		pending := slices.ContainsFunc(ms.Appservices, func(as *Appservice) bool {
+			return len(as.queue) > 0 || as.inFlight
+		})
+		ms.lock.Unlock()
+		if !pending {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (ms *MockServer) postRegister(w http.ResponseWriter, r *http.Request) {
+	var req mautrix.ReqRegister
+	mustDecode(r, &req)
+	if req.Username == "" {
+		mautrix.MInvalidUsername.WithMessage("Username is required").Write(w)
+		return
+	}
+	userID := id.NewUserID(strings.ToLower(req.Username), ms.ServerName)
+	if req.Type == mautrix.AuthTypeAppservice {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		asIdx := slices.IndexFunc(ms.Appservices, func(as *Appservice) bool {
+			return as.Registration.AppToken == token
+		})
+		if asIdx < 0 {
+			mautrix.MUnknownToken.WithMessage("Unknown appservice token").Write(w)
+			return
+		} else if !ms.Appservices[asIdx].IsInterestedInUser(userID) {
+			mautrix.MExclusive.WithMessage("User ID is not in the appservice's namespace").Write(w)
+			return
+		}
+	}
+	if _, exists := ms.Users[userID]; exists {
+		mautrix.MUserInUse.WithMessage("User ID already taken").Write(w)
+		return
+	}
+	ms.ensureUser(userID)
+	resp := &mautrix.RespRegister{UserID: userID}
+	if !req.InhibitLogin {
+		resp.DeviceID = req.DeviceID
+		if resp.DeviceID == "" {
+			resp.DeviceID = id.DeviceID(random.String(10))
+		}
+		resp.AccessToken = random.String(30)
+		ms.AccessTokenToUserID[resp.AccessToken] = userAndDeviceID{UserID: userID, DeviceID: resp.DeviceID}
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (ms *MockServer) postAppservicePing(w http.ResponseWriter, r *http.Request) {
+	var req mautrix.ReqAppservicePing
+	mustDecode(r, &req)
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	ms.lock.Lock()
+	asIdx := slices.IndexFunc(ms.Appservices, func(as *Appservice) bool {
+		return as.Registration.AppToken == token
+	})
+	var reg *appservice.Registration
+	if asIdx >= 0 {
+		reg = ms.Appservices[asIdx].Registration
+	}
+	ms.lock.Unlock()
+	if reg == nil {
+		mautrix.MUnknownToken.WithMessage("Unknown appservice token").Write(w)
+		return
+	} else if reg.ID != r.PathValue("appserviceID") {
+		mautrix.MForbidden.WithMessage("Appservice ID doesn't match access token").Write(w)
+		return
+	}
+	body, _ := json.Marshal(&req)
+	pingReq, err := http.NewRequestWithContext(r.Context(), http.MethodPost, strings.TrimSuffix(reg.URL, "/")+"/_matrix/app/v1/ping", bytes.NewReader(body))
+	if err != nil {
+		mautrix.MURLNotSet.WithMessage("Invalid appservice URL").WithStatus(http.StatusBadRequest).Write(w)
+		return
+	}
+	pingReq.Header.Set("Authorization", "Bearer "+reg.ServerToken)
+	pingReq.Header.Set("Content-Type", "application/json")
+	start := time.Now()
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(pingReq)
+	if err != nil {
+		mautrix.MConnectionFailed.WithMessage("Failed to ping appservice: %v", err).WithStatus(http.StatusBadGateway).Write(w)
+		return
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		mautrix.MBadStatus.WithMessage("Appservice returned HTTP %d", resp.StatusCode).WithStatus(http.StatusBadGateway).Write(w)
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespAppservicePing{DurationMS: time.Since(start).Milliseconds()})
+}
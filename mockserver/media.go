@@ -0,0 +1,134 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mockserver
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.mau.fi/util/exhttp"
+	"go.mau.fi/util/jsontime"
+	"go.mau.fi/util/random"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// MaxUploadSize is the maximum media upload size reported by and enforced in the mock server.
+const MaxUploadSize = 50 * 1024 * 1024
+
+// Media is a file in the mock server's content repository.
+type Media struct {
+	Uploader    id.UserID
+	ContentType string
+	FileName    string
+	// Data is nil if the media ID has been created with /create, but the upload hasn't been completed yet.
+	Data []byte
+}
+
+func (ms *MockServer) getMediaConfig(w http.ResponseWriter, r *http.Request) {
+	if _, ok := ms.authenticate(w, r); !ok {
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespMediaConfig{UploadSize: MaxUploadSize})
+}
+
+func (ms *MockServer) postCreateMedia(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	mediaID := random.String(24)
+	ms.Media[mediaID] = &Media{Uploader: userID.UserID}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespCreateMXC{
+		ContentURI:      id.ContentURI{Homeserver: ms.ServerName, FileID: mediaID},
+		UnusedExpiresAt: jsontime.UM(time.Now().Add(24 * time.Hour)),
+	})
+}
+
+func (ms *MockServer) readMedia(w http.ResponseWriter, r *http.Request, media *Media) bool {
+	data, err := io.ReadAll(io.LimitReader(r.Body, MaxUploadSize+1))
+	if err != nil {
+		mautrix.MUnknown.WithMessage("Failed to read request body").Write(w)
+		return false
+	} else if len(data) > MaxUploadSize {
+		mautrix.MTooLarge.WithMessage("Upload is too large").Write(w)
+		return false
+	}
+	media.Data = data
+	media.ContentType = r.Header.Get("Content-Type")
+	if media.ContentType == "" {
+		media.ContentType = "application/octet-stream"
+	}
+	media.FileName = r.URL.Query().Get("filename")
+	return true
+}
+
+func (ms *MockServer) postUploadMedia(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	media := &Media{Uploader: userID.UserID}
+	if !ms.readMedia(w, r, media) {
+		return
+	}
+	mediaID := random.String(24)
+	ms.Media[mediaID] = media
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespMediaUpload{
+		ContentURI: id.ContentURI{Homeserver: ms.ServerName, FileID: mediaID},
+	})
+}
+
+func (ms *MockServer) putUploadMedia(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	media, ok := ms.Media[r.PathValue("mediaID")]
+	if !ok || r.PathValue("serverName") != ms.ServerName {
+		mautrix.MNotFound.WithMessage("Media ID not found").Write(w)
+		return
+	} else if media.Uploader != userID.UserID {
+		mautrix.MForbidden.WithMessage("Media ID was created by another user").Write(w)
+		return
+	} else if media.Data != nil {
+		mautrix.MCannotOverwriteMedia.WithMessage("Media has already been uploaded").Write(w)
+		return
+	}
+	if !ms.readMedia(w, r, media) {
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespMediaUpload{
+		ContentURI: id.ContentURI{Homeserver: ms.ServerName, FileID: r.PathValue("mediaID")},
+	})
+}
+
+func (ms *MockServer) getDownloadMedia(w http.ResponseWriter, r *http.Request) {
+	media, ok := ms.Media[r.PathValue("mediaID")]
+	if !ok || r.PathValue("serverName") != ms.ServerName {
+		mautrix.MNotFound.WithMessage("Media not found").Write(w)
+		return
+	} else if media.Data == nil {
+		mautrix.MNotYetUploaded.WithMessage("Media has not been uploaded yet").Write(w)
+		return
+	}
+	fileName := r.PathValue("fileName")
+	if fileName == "" {
+		fileName = media.FileName
+	}
+	w.Header().Set("Content-Type", media.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(media.Data)))
+	if fileName != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(media.Data)
+}
@@ -15,6 +15,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 
 	globallog "github.com/rs/zerolog/log" // zerolog-allow-global-log
@@ -44,58 +45,227 @@ type MockServer struct {
 	Router *http.ServeMux
 	Server *httptest.Server
 
+	// ServerName is the server name used for room IDs, event IDs, aliases and media.
+	ServerName string
+
 	AccessTokenToUserID map[string]userAndDeviceID
 	DeviceInbox         map[id.UserID]map[id.DeviceID][]event.Event
 	AccountData         map[id.UserID]map[event.Type]json.RawMessage
 	DeviceKeys          map[id.UserID]map[id.DeviceID]mautrix.DeviceKeys
 	OneTimeKeys         map[id.UserID]map[id.DeviceID]map[id.KeyID]mautrix.OneTimeKey
+	FallbackKeys        map[id.UserID]map[id.DeviceID]map[id.KeyID]mautrix.OneTimeKey
 	MasterKeys          map[id.UserID]mautrix.CrossSigningKeys
 	SelfSigningKeys     map[id.UserID]mautrix.CrossSigningKeys
 	UserSigningKeys     map[id.UserID]mautrix.CrossSigningKeys
+	DehydratedDevices   map[id.UserID]*mautrix.RespGetDehydratedDevice
+	Pushers             map[id.UserID][]mautrix.Pusher
+	// Passwords contains the passwords used for the m.login.password user-interactive auth stage.
+	Passwords map[id.UserID]string
+	ThreePIDs map[id.UserID][]mautrix.ThreePID
+
+	Users       map[id.UserID]*UserProfile
+	Rooms       map[id.RoomID]*Room
+	RoomAliases map[id.RoomAlias]id.RoomID
+	Media       map[string]*Media
+	Appservices []*Appservice
+	// Events contains every room event in the order they were sent. The index of an event in this list is its
+	// stream position, which is used for sync and pagination tokens.
+	Events []*event.Event
 
 	PopOTKs     bool
 	MemoryStore bool
+
+	lock              sync.Mutex
+	accountDataPos    map[id.UserID]map[event.Type]int
+	accountDataStream int
+	notify            chan struct{}
+	uiaSessions       map[string]*uiaSession
+	pendingThreePIDs  map[string]*pendingThreePID
 }
 
 func Create(t testing.TB) *MockServer {
 	t.Helper()
 
 	server := MockServer{
+		ServerName:          "localhost",
 		AccessTokenToUserID: map[string]userAndDeviceID{},
 		DeviceInbox:         map[id.UserID]map[id.DeviceID][]event.Event{},
 		AccountData:         map[id.UserID]map[event.Type]json.RawMessage{},
 		DeviceKeys:          map[id.UserID]map[id.DeviceID]mautrix.DeviceKeys{},
 		OneTimeKeys:         map[id.UserID]map[id.DeviceID]map[id.KeyID]mautrix.OneTimeKey{},
+		FallbackKeys:        map[id.UserID]map[id.DeviceID]map[id.KeyID]mautrix.OneTimeKey{},
 		MasterKeys:          map[id.UserID]mautrix.CrossSigningKeys{},
 		SelfSigningKeys:     map[id.UserID]mautrix.CrossSigningKeys{},
 		UserSigningKeys:     map[id.UserID]mautrix.CrossSigningKeys{},
+		DehydratedDevices:   map[id.UserID]*mautrix.RespGetDehydratedDevice{},
+		Pushers:             map[id.UserID][]mautrix.Pusher{},
+		Passwords:           map[id.UserID]string{},
+		ThreePIDs:           map[id.UserID][]mautrix.ThreePID{},
+		Users:               map[id.UserID]*UserProfile{},
+		Rooms:               map[id.RoomID]*Room{},
+		RoomAliases:         map[id.RoomAlias]id.RoomID{},
+		Media:               map[string]*Media{},
 		PopOTKs:             true,
 		MemoryStore:         true,
+
+		accountDataPos: map[id.UserID]map[event.Type]int{},
+		notify:         make(chan struct{}),
+
+		uiaSessions:      map[string]*uiaSession{},
+		pendingThreePIDs: map[string]*pendingThreePID{},
 	}
 
 	router := http.NewServeMux()
-	router.HandleFunc("POST /_matrix/client/v3/login", server.postLogin)
-	router.HandleFunc("POST /_matrix/client/v3/keys/query", server.postKeysQuery)
-	router.HandleFunc("POST /_matrix/client/v3/keys/claim", server.postKeysClaim)
-	router.HandleFunc("PUT /_matrix/client/v3/sendToDevice/{type}/{txn}", server.putSendToDevice)
-	router.HandleFunc("PUT /_matrix/client/v3/user/{userID}/account_data/{type}", server.putAccountData)
-	router.HandleFunc("POST /_matrix/client/v3/keys/device_signing/upload", server.postDeviceSigningUpload)
-	router.HandleFunc("POST /_matrix/client/v3/keys/signatures/upload", server.emptyResp)
-	router.HandleFunc("POST /_matrix/client/v3/keys/upload", server.postKeysUpload)
+	handle := func(pattern string, handler http.HandlerFunc) {
+		router.HandleFunc(pattern, server.locked(handler))
+	}
+	handle("GET /_matrix/client/versions", server.getVersions)
+	handle("POST /_matrix/client/v3/login", server.postLogin)
+	handle("POST /_matrix/client/v3/register", server.postRegister)
+	handle("GET /_matrix/client/v3/account/whoami", server.getWhoami)
+	handle("POST /_matrix/client/v3/account/password", server.postChangePassword)
+	handle("POST /_matrix/client/v3/account/deactivate", server.postDeactivateAccount)
+	handle("GET /_matrix/client/v3/account/3pid", server.getThreePIDs)
+	handle("POST /_matrix/client/v3/account/3pid/email/requestToken", server.postRequestEmailToken)
+	handle("POST /_matrix/client/v3/account/3pid/add", server.postAddThreePID)
+	handle("POST /_matrix/client/v3/account/3pid/delete", server.postDeleteThreePID)
+	handle("POST /_matrix/client/v1/login/get_token", server.postGetLoginToken)
+	handle("POST /_matrix/client/v3/keys/query", server.postKeysQuery)
+	handle("POST /_matrix/client/v3/keys/claim", server.postKeysClaim)
+	handle("PUT /_matrix/client/v3/sendToDevice/{type}/{txn}", server.putSendToDevice)
+	handle("GET /_matrix/client/v3/user/{userID}/account_data/{type}", server.getAccountData)
+	handle("PUT /_matrix/client/v3/user/{userID}/account_data/{type}", server.putAccountData)
+	handle("POST /_matrix/client/v3/keys/device_signing/upload", server.postDeviceSigningUpload)
+	handle("POST /_matrix/client/v3/keys/signatures/upload", server.emptyResp)
+	handle("POST /_matrix/client/v3/keys/upload", server.postKeysUpload)
+	handle("GET /_matrix/client/unstable/org.matrix.msc3814.v1/dehydrated_device", server.getDehydratedDevice)
+	handle("PUT /_matrix/client/unstable/org.matrix.msc3814.v1/dehydrated_device", server.putDehydratedDevice)
+	handle("DELETE /_matrix/client/unstable/org.matrix.msc3814.v1/dehydrated_device", server.deleteDehydratedDevice)
+	handle("POST /_matrix/client/unstable/org.matrix.msc3814.v1/dehydrated_device/{deviceID}/events", server.postDehydratedDeviceEvents)
+
+	handle("GET /_matrix/client/v3/profile/{userID}", server.getProfile)
+	handle("PUT /_matrix/client/v3/profile/{userID}/{field}", server.putProfileField)
+
+	handle("POST /_matrix/client/v3/createRoom", server.postCreateRoom)
+	handle("GET /_matrix/client/v3/joined_rooms", server.getJoinedRooms)
+	handle("GET /_matrix/client/v3/directory/room/{alias}", server.getAlias)
+	handle("PUT /_matrix/client/v3/directory/room/{alias}", server.putAlias)
+	handle("DELETE /_matrix/client/v3/directory/room/{alias}", server.deleteAlias)
+	handle("POST /_matrix/client/v3/join/{roomIDOrAlias}", server.postJoin)
+	handle("POST /_matrix/client/v3/rooms/{roomID}/join", server.postJoin)
+	handle("POST /_matrix/client/v3/rooms/{roomID}/leave", server.postLeave)
+	handle("POST /_matrix/client/v3/rooms/{roomID}/upgrade", server.postUpgradeRoom)
+	handle("GET /_matrix/client/v3/rooms/{roomID}/aliases", server.getLocalAliases)
+	handle("POST /_matrix/client/v3/rooms/{roomID}/invite", server.postMembershipChange(event.MembershipInvite))
+	handle("POST /_matrix/client/v3/rooms/{roomID}/kick", server.postMembershipChange(event.MembershipLeave))
+	handle("POST /_matrix/client/v3/rooms/{roomID}/ban", server.postMembershipChange(event.MembershipBan))
+	handle("POST /_matrix/client/v3/rooms/{roomID}/unban", server.postMembershipChange(event.MembershipLeave))
+	handle("PUT /_matrix/client/v3/rooms/{roomID}/send/{type}/{txnID}", server.putSendEvent)
+	handle("PUT /_matrix/client/v3/rooms/{roomID}/redact/{eventID}/{txnID}", server.putRedactEvent)
+	handle("PUT /_matrix/client/v3/rooms/{roomID}/state/{type}", server.putStateEvent)
+	handle("PUT /_matrix/client/v3/rooms/{roomID}/state/{type}/{stateKey...}", server.putStateEvent)
+	handle("GET /_matrix/client/v3/rooms/{roomID}/state/{type}", server.getStateEvent)
+	handle("GET /_matrix/client/v3/rooms/{roomID}/state/{type}/{stateKey...}", server.getStateEvent)
+	handle("GET /_matrix/client/v3/rooms/{roomID}/state", server.getFullState)
+	handle("GET /_matrix/client/v3/rooms/{roomID}/members", server.getMembers)
+	handle("GET /_matrix/client/v3/rooms/{roomID}/joined_members", server.getJoinedMembers)
+	handle("GET /_matrix/client/v3/rooms/{roomID}/event/{eventID}", server.getEvent)
+	handle("GET /_matrix/client/v3/rooms/{roomID}/messages", server.getMessages)
+	handle("PUT /_matrix/client/v3/rooms/{roomID}/typing/{userID}", server.emptyResp)
+	handle("POST /_matrix/client/v3/rooms/{roomID}/receipt/{receiptType}/{eventID}", server.emptyResp)
+	handle("POST /_matrix/client/v3/rooms/{roomID}/read_markers", server.emptyResp)
+	handle("POST /_matrix/client/v3/search", server.postSearch)
+	handle("GET /_matrix/client/v1/rooms/{roomID}/relations/{eventID}", server.getRelations)
+	handle("GET /_matrix/client/v1/rooms/{roomID}/relations/{eventID}/{relType}", server.getRelations)
+	handle("GET /_matrix/client/v1/rooms/{roomID}/relations/{eventID}/{relType}/{eventType}", server.getRelations)
+	handle("GET /_matrix/client/v1/rooms/{roomID}/threads", server.getThreads)
+	handle("GET /_matrix/client/v3/pushers", server.getPushers)
+	handle("POST /_matrix/client/v3/pushers/set", server.postSetPusher)
+	// Sync handles locking by itself, as it needs to release the lock while waiting for new events.
+	router.HandleFunc("GET /_matrix/client/v3/sync", server.getSync)
+
+	handle("GET /_matrix/client/v1/media/config", server.getMediaConfig)
+	handle("POST /_matrix/media/v1/create", server.postCreateMedia)
+	handle("POST /_matrix/media/v3/upload", server.postUploadMedia)
+	handle("PUT /_matrix/media/v3/upload/{serverName}/{mediaID}", server.putUploadMedia)
+	handle("GET /_matrix/client/v1/media/download/{serverName}/{mediaID}", server.getDownloadMedia)
+	handle("GET /_matrix/client/v1/media/download/{serverName}/{mediaID}/{fileName}", server.getDownloadMedia)
+	handle("GET /_matrix/media/v3/download/{serverName}/{mediaID}", server.getDownloadMedia)
+	handle("GET /_matrix/media/v3/download/{serverName}/{mediaID}/{fileName}", server.getDownloadMedia)
+
+	// Appservice pings make a request back to the appservice, so they must not hold the lock.
+	router.HandleFunc("POST /_matrix/client/v1/appservice/{appserviceID}/ping", server.postAppservicePing)
 	server.Router = router
 	server.Server = httptest.NewServer(router)
 	t.Cleanup(server.Server.Close)
 	return &server
 }
 
+func (ms *MockServer) locked(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ms.lock.Lock()
+		defer ms.lock.Unlock()
+		handler(w, r)
+	}
+}
+
+// wakeSyncers wakes up all pending /sync requests. The lock must be held when calling this.
+func (ms *MockServer) wakeSyncers() {
+	close(ms.notify)
+	ms.notify = make(chan struct{})
+}
+
 func (ms *MockServer) getUserID(r *http.Request) userAndDeviceID {
+	userID, ok := ms.lookupUserID(r)
+	if !ok {
+		panic("no user ID found for access token " + r.Header.Get("Authorization"))
+	}
+	return userID
+}
+
+func (ms *MockServer) lookupUserID(r *http.Request) (userAndDeviceID, bool) {
 	authHeader := r.Header.Get("Authorization")
 	authHeader = strings.TrimPrefix(authHeader, "Bearer ")
-	userID, ok := ms.AccessTokenToUserID[authHeader]
+	if userID, ok := ms.AccessTokenToUserID[authHeader]; ok {
+		return userID, true
+	}
+	for _, as := range ms.Appservices {
+		if as.Registration.AppToken != authHeader {
+			continue
+		}
+		userID := as.BotUserID()
+		if masqueradeAs := id.UserID(r.URL.Query().Get("user_id")); masqueradeAs != "" && as.IsInterestedInUser(masqueradeAs) {
+			userID = masqueradeAs
+		}
+		return userAndDeviceID{UserID: userID, DeviceID: id.DeviceID(r.URL.Query().Get("device_id"))}, true
+	}
+	return userAndDeviceID{}, false
+}
+
+// authenticate finds the user who made the request, or writes an error response if the access token is invalid.
+func (ms *MockServer) authenticate(w http.ResponseWriter, r *http.Request) (userAndDeviceID, bool) {
+	userID, ok := ms.lookupUserID(r)
 	if !ok {
-		panic("no user ID found for access token " + authHeader)
+		mautrix.MUnknownToken.WithMessage("Unknown access token").Write(w)
 	}
-	return userID
+	return userID, ok
+}
+
+func (ms *MockServer) getVersions(w http.ResponseWriter, _ *http.Request) {
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespVersions{
+		Versions: []mautrix.SpecVersion{mautrix.SpecV11, mautrix.SpecV14, mautrix.SpecV17, mautrix.SpecV111},
+	})
+}
+
+func (ms *MockServer) getWhoami(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespWhoami{
+		UserID:   userID.UserID,
+		DeviceID: userID.DeviceID,
+	})
 }
 
 func (ms *MockServer) emptyResp(w http.ResponseWriter, _ *http.Request) {
@@ -113,6 +283,7 @@ func (ms *MockServer) postLogin(w http.ResponseWriter, r *http.Request) {
 
 	accessToken := random.String(30)
 	userID := id.UserID(loginReq.Identifier.User)
+	ms.ensureUser(userID)
 	ms.AccessTokenToUserID[accessToken] = userAndDeviceID{
 		UserID:   userID,
 		DeviceID: deviceID,
@@ -143,18 +314,29 @@ func (ms *MockServer) putSendToDevice(w http.ResponseWriter, r *http.Request) {
 			})
 		}
 	}
+	ms.wakeSyncers()
 	ms.emptyResp(w, r)
 }
 
+func (ms *MockServer) getAccountData(w http.ResponseWriter, r *http.Request) {
+	userID := id.UserID(r.PathValue("userID"))
+	eventType := event.Type{Type: r.PathValue("type"), Class: event.AccountDataEventType}
+
+	data, ok := ms.AccountData[userID][eventType]
+	if !ok {
+		mautrix.MNotFound.WithMessage("Account data not found").Write(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
 func (ms *MockServer) putAccountData(w http.ResponseWriter, r *http.Request) {
 	userID := id.UserID(r.PathValue("userID"))
 	eventType := event.Type{Type: r.PathValue("type"), Class: event.AccountDataEventType}
 
 	jsonData, _ := io.ReadAll(r.Body)
-	if _, ok := ms.AccountData[userID]; !ok {
-		ms.AccountData[userID] = map[event.Type]json.RawMessage{}
-	}
-	ms.AccountData[userID][eventType] = json.RawMessage(jsonData)
+	ms.setAccountData(userID, eventType, jsonData)
 	ms.emptyResp(w, r)
 }
 
@@ -186,6 +368,11 @@ func (ms *MockServer) postKeysClaim(w http.ResponseWriter, r *http.Request) {
 		resp.OneTimeKeys[user] = map[id.DeviceID]map[id.KeyID]mautrix.OneTimeKey{}
 		for device := range devices {
 			keys := ms.OneTimeKeys[user][device]
+			if len(keys) == 0 {
+				// Fallback keys are returned when there are no one-time keys left, but they're never removed
+				resp.OneTimeKeys[user][device] = maps.Clone(ms.FallbackKeys[user][device])
+				continue
+			}
 			for keyID, key := range keys {
 				if ms.PopOTKs {
 					delete(keys, keyID)
@@ -242,7 +429,84 @@ func (ms *MockServer) postDeviceSigningUpload(w http.ResponseWriter, r *http.Req
 	ms.emptyResp(w, r)
 }
 
-func (ms *MockServer) Login(t testing.TB, ctx context.Context, userID id.UserID, deviceID id.DeviceID) (*mautrix.Client, crypto.Store) {
+func (ms *MockServer) getDehydratedDevice(w http.ResponseWriter, r *http.Request) {
+	device, ok := ms.DehydratedDevices[ms.getUserID(r).UserID]
+	if !ok {
+		mautrix.MNotFound.WithMessage("No dehydrated device found").Write(w)
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, device)
+}
+
+func (ms *MockServer) putDehydratedDevice(w http.ResponseWriter, r *http.Request) {
+	var req mautrix.ReqPutDehydratedDevice
+	mustDecode(r, &req)
+
+	userID := ms.getUserID(r).UserID
+	if prev, ok := ms.DehydratedDevices[userID]; ok {
+		delete(ms.DeviceKeys[userID], prev.DeviceID)
+		delete(ms.OneTimeKeys[userID], prev.DeviceID)
+		delete(ms.FallbackKeys[userID], prev.DeviceID)
+		delete(ms.DeviceInbox[userID], prev.DeviceID)
+	}
+	ms.DehydratedDevices[userID] = &mautrix.RespGetDehydratedDevice{
+		DeviceID:   req.DeviceID,
+		DeviceData: req.DeviceData,
+	}
+	if req.DeviceKeys != nil {
+		if _, ok := ms.DeviceKeys[userID]; !ok {
+			ms.DeviceKeys[userID] = map[id.DeviceID]mautrix.DeviceKeys{}
+		}
+		ms.DeviceKeys[userID][req.DeviceID] = *req.DeviceKeys
+	}
+	if _, ok := ms.OneTimeKeys[userID]; !ok {
+		ms.OneTimeKeys[userID] = map[id.DeviceID]map[id.KeyID]mautrix.OneTimeKey{}
+	}
+	ms.OneTimeKeys[userID][req.DeviceID] = maps.Clone(req.OneTimeKeys)
+	if _, ok := ms.FallbackKeys[userID]; !ok {
+		ms.FallbackKeys[userID] = map[id.DeviceID]map[id.KeyID]mautrix.OneTimeKey{}
+	}
+	ms.FallbackKeys[userID][req.DeviceID] = maps.Clone(req.FallbackKeys)
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespPutDehydratedDevice{DeviceID: req.DeviceID})
+}
+
+func (ms *MockServer) deleteDehydratedDevice(w http.ResponseWriter, r *http.Request) {
+	userID := ms.getUserID(r).UserID
+	device, ok := ms.DehydratedDevices[userID]
+	if !ok {
+		mautrix.MNotFound.WithMessage("No dehydrated device found").Write(w)
+		return
+	}
+	delete(ms.DehydratedDevices, userID)
+	delete(ms.DeviceKeys[userID], device.DeviceID)
+	delete(ms.OneTimeKeys[userID], device.DeviceID)
+	delete(ms.FallbackKeys[userID], device.DeviceID)
+	delete(ms.DeviceInbox[userID], device.DeviceID)
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespDeleteDehydratedDevice{DeviceID: device.DeviceID})
+}
+
+func (ms *MockServer) postDehydratedDeviceEvents(w http.ResponseWriter, r *http.Request) {
+	var req mautrix.ReqDehydratedDeviceEvents
+	mustDecode(r, &req)
+
+	userID := ms.getUserID(r).UserID
+	deviceID := id.DeviceID(r.PathValue("deviceID"))
+	if device, ok := ms.DehydratedDevices[userID]; !ok || device.DeviceID != deviceID {
+		mautrix.MNotFound.WithMessage("Dehydrated device not found").Write(w)
+		return
+	}
+	// The mock server returns all events in one batch, so any next batch token means there are no more events.
+	resp := mautrix.RespDehydratedDeviceEvents{Events: []*event.Event{}, NextBatch: "end"}
+	if req.NextBatch == "" {
+		for _, evt := range ms.DeviceInbox[userID][deviceID] {
+			resp.Events = append(resp.Events, &evt)
+		}
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &resp)
+}
+
+// NewClient logs in as the given user and returns a client without end-to-end encryption support.
+func (ms *MockServer) NewClient(t testing.TB, ctx context.Context, userID id.UserID, deviceID id.DeviceID) *mautrix.Client {
 	t.Helper()
 	if ctx == nil {
 		ctx = context.TODO()
@@ -262,8 +526,18 @@ func (ms *MockServer) Login(t testing.TB, ctx context.Context, userID id.UserID,
 		StoreCredentials: true,
 	})
 	require.NoError(t, err)
+	return client
+}
+
+func (ms *MockServer) Login(t testing.TB, ctx context.Context, userID id.UserID, deviceID id.DeviceID) (*mautrix.Client, crypto.Store) {
+	t.Helper()
+	if ctx == nil {
+		ctx = context.TODO()
+	}
+	client := ms.NewClient(t, ctx, userID, deviceID)
 
 	var store any
+	var err error
 	if ms.MemoryStore {
 		store = crypto.NewMemoryStore(nil)
 		client.StateStore = mautrix.NewMemoryStateStore()
@@ -0,0 +1,208 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mockserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/appservice"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+	"github.com/iKonoTelecomunicaciones/go/mockserver"
+)
+
+func TestMockServer_RoomsAndSync(t *testing.T) {
+	ctx := context.Background()
+	server := mockserver.Create(t)
+	alice := server.NewClient(t, ctx, "@alice:localhost", "ALICE")
+	bob := server.NewClient(t, ctx, "@bob:localhost", "BOB")
+
+	initialSync, err := bob.SyncRequest(ctx, 0, "", "", false, "")
+	require.NoError(t, err)
+	assert.Empty(t, initialSync.Rooms.Join)
+
+	createResp, err := alice.CreateRoom(ctx, &mautrix.ReqCreateRoom{
+		Name:     "Test room",
+		Invite:   []id.UserID{bob.UserID},
+		IsDirect: true,
+	})
+	require.NoError(t, err)
+	roomID := createResp.RoomID
+
+	_, err = bob.SendText(ctx, roomID, "not joined yet")
+	assert.ErrorIs(t, err, mautrix.MForbidden)
+
+	inviteSync, err := bob.SyncRequest(ctx, 1000, initialSync.NextBatch, "", false, "")
+	require.NoError(t, err)
+	require.Contains(t, inviteSync.Rooms.Invite, roomID)
+	inviteState := inviteSync.Rooms.Invite[roomID].State.Events
+	require.NotEmpty(t, inviteState)
+	lastInviteEvt := inviteState[len(inviteState)-1]
+	require.NoError(t, lastInviteEvt.Content.ParseRaw(event.StateMember))
+	assert.Equal(t, event.MembershipInvite, lastInviteEvt.Content.AsMember().Membership)
+	assert.True(t, lastInviteEvt.Content.AsMember().IsDirect)
+
+	_, err = bob.JoinRoomByID(ctx, roomID)
+	require.NoError(t, err)
+	joinSync, err := bob.SyncRequest(ctx, 1000, inviteSync.NextBatch, "", false, "")
+	require.NoError(t, err)
+	require.Contains(t, joinSync.Rooms.Join, roomID)
+	var roomName string
+	joinedRoom := joinSync.Rooms.Join[roomID]
+	for _, evt := range append(joinedRoom.State.Events, joinedRoom.Timeline.Events...) {
+		if evt.Type.Type == event.StateRoomName.Type {
+			roomName, _ = evt.Content.Raw["name"].(string)
+		}
+	}
+	assert.Equal(t, "Test room", roomName)
+
+	// An incremental sync should wait until the next event is sent.
+	var wg sync.WaitGroup
+	var msgSync *mautrix.RespSync
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var syncErr error
+		msgSync, syncErr = bob.SyncRequest(ctx, 5000, joinSync.NextBatch, "", false, "")
+		assert.NoError(t, syncErr)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	sendResp, err := alice.SendText(ctx, roomID, "Hello, world!")
+	require.NoError(t, err)
+	wg.Wait()
+	require.Contains(t, msgSync.Rooms.Join, roomID)
+	timeline := msgSync.Rooms.Join[roomID].Timeline.Events
+	require.Len(t, timeline, 1)
+	assert.Equal(t, sendResp.EventID, timeline[0].ID)
+	assert.Equal(t, "Hello, world!", timeline[0].Content.Raw["body"])
+
+	emptySync, err := bob.SyncRequest(ctx, 0, msgSync.NextBatch, "", false, "")
+	require.NoError(t, err)
+	assert.Empty(t, emptySync.Rooms.Join)
+
+	_, err = bob.SyncRequest(ctx, 0, "invalid", "", false, "")
+	assert.ErrorIs(t, err, mautrix.MInvalidParam)
+}
+
+func TestMockServer_Messages(t *testing.T) {
+	ctx := context.Background()
+	server := mockserver.Create(t)
+	alice := server.NewClient(t, ctx, "@alice:localhost", "ALICE")
+	createResp, err := alice.CreateRoom(ctx, &mautrix.ReqCreateRoom{})
+	require.NoError(t, err)
+	var sent []id.EventID
+	for _, text := range []string{"one", "two", "three", "four", "five"} {
+		resp, err := alice.SendText(ctx, createResp.RoomID, text)
+		require.NoError(t, err)
+		sent = append(sent, resp.EventID)
+	}
+
+	page1, err := alice.Messages(ctx, createResp.RoomID, "", "", mautrix.DirectionBackward, nil, 3)
+	require.NoError(t, err)
+	require.Len(t, page1.Chunk, 3)
+	assert.Equal(t, sent[4], page1.Chunk[0].ID)
+	assert.Equal(t, sent[2], page1.Chunk[2].ID)
+	require.NotEmpty(t, page1.End)
+
+	page2, err := alice.Messages(ctx, createResp.RoomID, page1.End, "", mautrix.DirectionBackward, nil, 2)
+	require.NoError(t, err)
+	require.Len(t, page2.Chunk, 2)
+	assert.Equal(t, sent[1], page2.Chunk[0].ID)
+	assert.Equal(t, sent[0], page2.Chunk[1].ID)
+
+	forward, err := alice.Messages(ctx, createResp.RoomID, page1.End, "", mautrix.DirectionForward, nil, 10)
+	require.NoError(t, err)
+	require.Len(t, forward.Chunk, 3)
+	assert.Equal(t, sent[2], forward.Chunk[0].ID)
+	assert.Empty(t, forward.End)
+}
+
+func TestMockServer_Media(t *testing.T) {
+	ctx := context.Background()
+	server := mockserver.Create(t)
+	alice := server.NewClient(t, ctx, "@alice:localhost", "ALICE")
+
+	resp, err := alice.UploadBytes(ctx, []byte("hello"), "text/plain")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost", resp.ContentURI.Homeserver)
+	data, err := alice.DownloadBytes(ctx, resp.ContentURI)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), data)
+
+	_, err = alice.DownloadBytes(ctx, id.ContentURI{Homeserver: "localhost", FileID: "missing"})
+	assert.ErrorIs(t, err, mautrix.MNotFound)
+}
+
+func TestMockServer_Appservice(t *testing.T) {
+	ctx := context.Background()
+	server := mockserver.Create(t)
+
+	var lock sync.Mutex
+	var received []*event.Event
+	asServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var txn appservice.Transaction
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&txn))
+		lock.Lock()
+		received = append(received, txn.Events...)
+		lock.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("{}"))
+	}))
+	t.Cleanup(asServer.Close)
+	reg := appservice.CreateRegistration()
+	reg.ID = "test"
+	reg.URL = asServer.URL
+	reg.SenderLocalpart = "bot"
+	reg.Namespaces.UserIDs.Register(regexp.MustCompile("@ghost_.+:localhost"), true)
+	server.RegisterAppservice(t, reg)
+
+	bot, err := mautrix.NewClient(server.Server.URL, "@bot:localhost", reg.AppToken)
+	require.NoError(t, err)
+	bot.SetAppServiceUserID = true
+	_, _, err = bot.Register(ctx, &mautrix.ReqRegister{Username: "ghost_1", Type: mautrix.AuthTypeAppservice, InhibitLogin: true})
+	require.NoError(t, err)
+	_, _, err = bot.Register(ctx, &mautrix.ReqRegister{Username: "alice", Type: mautrix.AuthTypeAppservice, InhibitLogin: true})
+	assert.ErrorIs(t, err, mautrix.MExclusive)
+
+	alice := server.NewClient(t, ctx, "@alice:localhost", "ALICE")
+	createResp, err := alice.CreateRoom(ctx, &mautrix.ReqCreateRoom{})
+	require.NoError(t, err)
+	_, err = alice.SendText(ctx, createResp.RoomID, "not visible to the appservice")
+	require.NoError(t, err)
+	_, err = alice.InviteUser(ctx, createResp.RoomID, &mautrix.ReqInviteUser{UserID: "@ghost_1:localhost"})
+	require.NoError(t, err)
+
+	ghost, err := mautrix.NewClient(server.Server.URL, "@ghost_1:localhost", reg.AppToken)
+	require.NoError(t, err)
+	ghost.SetAppServiceUserID = true
+	_, err = ghost.JoinRoomByID(ctx, createResp.RoomID)
+	require.NoError(t, err)
+	_, err = alice.SendText(ctx, createResp.RoomID, "visible to the appservice")
+	require.NoError(t, err)
+
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	require.NoError(t, server.WaitForAppserviceTransactions(waitCtx))
+	lock.Lock()
+	defer lock.Unlock()
+	require.Len(t, received, 3)
+	assert.Equal(t, event.StateMember, received[0].Type)
+	assert.Equal(t, event.StateMember, received[1].Type)
+	assert.Equal(t, id.UserID("@ghost_1:localhost"), received[1].Sender)
+	assert.Equal(t, "visible to the appservice", received[2].Content.Raw["body"])
+}
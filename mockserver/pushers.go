@@ -0,0 +1,60 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mockserver
+
+import (
+	"net/http"
+	"slices"
+
+	"go.mau.fi/util/exhttp"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+)
+
+func (ms *MockServer) getPushers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	pushers := ms.Pushers[userID.UserID]
+	if pushers == nil {
+		pushers = []mautrix.Pusher{}
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespPushers{Pushers: pushers})
+}
+
+func (ms *MockServer) postSetPusher(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	var req mautrix.ReqSetPusher
+	mustDecode(r, &req)
+	if req.AppID == "" || req.PushKey == "" {
+		mautrix.MBadJSON.WithMessage("app_id and pushkey are required").Write(w)
+		return
+	}
+	isSame := func(pusher mautrix.Pusher) bool {
+		return pusher.AppID == req.AppID && pusher.PushKey == req.PushKey
+	}
+	if req.Kind == nil {
+		ms.Pushers[userID.UserID] = slices.DeleteFunc(ms.Pushers[userID.UserID], isSame)
+	} else {
+		if !req.Append {
+			for otherUserID, pushers := range ms.Pushers {
+				ms.Pushers[otherUserID] = slices.DeleteFunc(pushers, isSame)
+			}
+		}
+		pushers := ms.Pushers[userID.UserID]
+		if idx := slices.IndexFunc(pushers, isSame); idx >= 0 {
+			pushers[idx] = req.Pusher
+		} else {
+			ms.Pushers[userID.UserID] = append(pushers, req.Pusher)
+		}
+	}
+	exhttp.WriteEmptyJSONResponse(w, http.StatusOK)
+}
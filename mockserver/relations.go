@@ -0,0 +1,155 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mockserver
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/tidwall/gjson"
+	"go.mau.fi/util/exhttp"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+func getRelatesTo(evt *event.Event) (relType event.RelationType, target id.EventID) {
+	relatesTo := gjson.GetBytes(evt.Content.VeryRaw, `m\.relates_to`)
+	return event.RelationType(relatesTo.Get("rel_type").Str), id.EventID(relatesTo.Get("event_id").Str)
+}
+
+func (ms *MockServer) findRoomEvent(room *Room, eventID id.EventID) *event.Event {
+	for _, pos := range room.EventPositions {
+		if ms.Events[pos].ID == eventID {
+			return ms.Events[pos]
+		}
+	}
+	return nil
+}
+
+// getRelations returns events relating to the given event. Recursion is not supported.
+func (ms *MockServer) getRelations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	room := ms.getRoom(w, r, userID.UserID)
+	if room == nil {
+		return
+	}
+	targetID := id.EventID(r.PathValue("eventID"))
+	filterRelType := event.RelationType(r.PathValue("relType"))
+	filterEventType := r.PathValue("eventType")
+	query := r.URL.Query()
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	backwards := query.Get("dir") != "f"
+
+	var positions []int
+	for _, pos := range room.EventPositions {
+		evt := ms.Events[pos]
+		relType, target := getRelatesTo(evt)
+		if target != targetID ||
+			(filterRelType != "" && relType != filterRelType) ||
+			(filterEventType != "" && evt.Type.Type != filterEventType) {
+			continue
+		}
+		positions = append(positions, pos)
+	}
+	if backwards {
+		slices.Reverse(positions)
+	}
+	if fromToken := query.Get("from"); fromToken != "" {
+		from, ok := parseStreamToken(fromToken)
+		if !ok {
+			mautrix.MInvalidParam.WithMessage("Invalid from token").Write(w)
+			return
+		}
+		positions = slices.DeleteFunc(positions, func(pos int) bool {
+			return (backwards && pos >= from) || (!backwards && pos < from)
+		})
+	}
+	resp := &mautrix.RespGetRelations{Chunk: []*event.Event{}}
+	for i, pos := range positions {
+		if i == limit {
+			resp.NextBatch = makeStreamToken(pos)
+			if backwards {
+				resp.NextBatch = makeStreamToken(pos + 1)
+			}
+			break
+		}
+		resp.Chunk = append(resp.Chunk, ms.Events[pos])
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+// getThreads returns the thread roots in a room, ordered by their latest reply.
+func (ms *MockServer) getThreads(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	room := ms.getRoom(w, r, userID.UserID)
+	if room == nil {
+		return
+	}
+	query := r.URL.Query()
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	offset := 0
+	if from := query.Get("from"); from != "" {
+		offset, err = strconv.Atoi(from)
+		if err != nil || offset < 0 {
+			mautrix.MInvalidParam.WithMessage("Invalid from token").Write(w)
+			return
+		}
+	}
+	onlyParticipated := mautrix.ThreadInclude(query.Get("include")) == mautrix.ThreadIncludeParticipated
+
+	var rootIDs []id.EventID
+	participated := make(map[id.EventID]bool)
+	// Iterate from newest to oldest, so the first reply seen for each thread is the latest one.
+	for _, pos := range slices.Backward(room.EventPositions) {
+		evt := ms.Events[pos]
+		relType, target := getRelatesTo(evt)
+		if relType != event.RelThread {
+			continue
+		}
+		if !slices.Contains(rootIDs, target) {
+			rootIDs = append(rootIDs, target)
+		}
+		if evt.Sender == userID.UserID {
+			participated[target] = true
+		}
+	}
+	resp := &mautrix.RespGetThreads{Chunk: []*event.Event{}}
+	for _, rootID := range rootIDs {
+		root := ms.findRoomEvent(room, rootID)
+		if root == nil {
+			continue
+		} else if onlyParticipated && !participated[rootID] && root.Sender != userID.UserID {
+			continue
+		}
+		resp.Chunk = append(resp.Chunk, root)
+	}
+	if offset < len(resp.Chunk) {
+		resp.Chunk = resp.Chunk[offset:]
+	} else {
+		resp.Chunk = []*event.Event{}
+	}
+	if len(resp.Chunk) > limit {
+		resp.Chunk = resp.Chunk[:limit]
+		resp.NextBatch = strconv.Itoa(offset + limit)
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,919 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mockserver
+
+import (
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"go.mau.fi/util/exhttp"
+	"go.mau.fi/util/ptr"
+	"go.mau.fi/util/random"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// UserProfile contains the global profile of a user on the mock server.
+type UserProfile struct {
+	DisplayName string              `json:"displayname,omitempty"`
+	AvatarURL   id.ContentURIString `json:"avatar_url,omitempty"`
+}
+
+// Room is a room on the mock server.
+type Room struct {
+	ID      id.RoomID
+	Version id.RoomVersion
+	// State contains the current state of the room.
+	State map[event.Type]map[string]*event.Event
+	// EventPositions contains the stream positions of all events in the room in order.
+	// The events themselves can be found in [MockServer.Events].
+	EventPositions []int
+
+	memberHistory map[id.UserID][]membershipChange
+	txnIDs        map[string]id.EventID
+}
+
+type membershipChange struct {
+	pos        int
+	membership event.Membership
+}
+
+// Membership returns the current membership of the given user in the room.
+func (room *Room) Membership(userID id.UserID) event.Membership {
+	return room.MembershipAt(userID, -1)
+}
+
+// MembershipAt returns the membership of the given user after the event at the given stream position.
+// If the position is negative, the current membership is returned.
+func (room *Room) MembershipAt(userID id.UserID, pos int) event.Membership {
+	history := room.memberHistory[userID]
+	for i := len(history) - 1; i >= 0; i-- {
+		if pos < 0 || history[i].pos <= pos {
+			return history[i].membership
+		}
+	}
+	return event.MembershipLeave
+}
+
+// Members returns all users whose current membership is one of the given memberships.
+func (room *Room) Members(memberships ...event.Membership) []id.UserID {
+	var members []id.UserID
+	for userID := range room.memberHistory {
+		if slices.Contains(memberships, room.Membership(userID)) {
+			members = append(members, userID)
+		}
+	}
+	slices.Sort(members)
+	return members
+}
+
+// GetState returns the current state event with the given type and state key, or nil if there isn't one.
+func (room *Room) GetState(evtType event.Type, stateKey string) *event.Event {
+	return room.State[evtType][stateKey]
+}
+
+func (room *Room) stateEvents() []*event.Event {
+	var events []*event.Event
+	for _, byKey := range room.State {
+		for _, evt := range byKey {
+			events = append(events, evt)
+		}
+	}
+	slices.SortFunc(events, func(a, b *event.Event) int {
+		return int(a.Unsigned.BeeperHSOrder - b.Unsigned.BeeperHSOrder)
+	})
+	return events
+}
+
+// stateBefore returns the state of the room before the event at the given stream position.
+func (ms *MockServer) stateBefore(room *Room, pos int) []*event.Event {
+	type stateKey struct {
+		evtType  event.Type
+		stateKey string
+	}
+	state := make(map[stateKey]*event.Event)
+	var keys []stateKey
+	for _, evtPos := range room.EventPositions {
+		if evtPos >= pos {
+			break
+		}
+		evt := ms.Events[evtPos]
+		if evt.StateKey == nil {
+			continue
+		}
+		key := stateKey{evtType: evt.Type, stateKey: *evt.StateKey}
+		if _, exists := state[key]; !exists {
+			keys = append(keys, key)
+		}
+		state[key] = evt
+	}
+	events := make([]*event.Event, len(keys))
+	for i, key := range keys {
+		events[i] = state[key]
+	}
+	return events
+}
+
+func parseContent(data []byte) (event.Content, error) {
+	var content event.Content
+	err := json.Unmarshal(data, &content)
+	if err != nil {
+		return content, err
+	} else if content.Raw == nil {
+		content.Raw = map[string]any{}
+	}
+	return content, nil
+}
+
+func makeContent(data any) event.Content {
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		panic(err)
+	}
+	var content event.Content
+	_ = json.Unmarshal(rawData, &content)
+	return content
+}
+
+func getMembership(evt *event.Event) event.Membership {
+	return event.Membership(gjson.GetBytes(evt.Content.VeryRaw, "membership").Str)
+}
+
+func (ms *MockServer) newEventID() id.EventID {
+	return id.EventID("$" + random.String(32))
+}
+
+// addEvent stores the given event in the room and sends it to all syncing clients and appservices.
+// The lock must be held when calling this.
+func (ms *MockServer) addEvent(room *Room, evt *event.Event) *event.Event {
+	pos := len(ms.Events)
+	evt.ID = ms.newEventID()
+	evt.RoomID = room.ID
+	if evt.Timestamp == 0 {
+		evt.Timestamp = time.Now().UnixMilli()
+	}
+	evt.Unsigned.BeeperHSOrder = int64(pos)
+	if evt.StateKey != nil {
+		if room.State[evt.Type] == nil {
+			room.State[evt.Type] = make(map[string]*event.Event)
+		}
+		if prev := room.State[evt.Type][*evt.StateKey]; prev != nil {
+			evt.Unsigned.PrevContent = &prev.Content
+			evt.Unsigned.PrevSender = prev.Sender
+			evt.Unsigned.ReplacesState = prev.ID
+		}
+		room.State[evt.Type][*evt.StateKey] = evt
+		if evt.Type == event.StateMember {
+			userID := id.UserID(*evt.StateKey)
+			room.memberHistory[userID] = append(room.memberHistory[userID], membershipChange{
+				pos:        pos,
+				membership: getMembership(evt),
+			})
+		} else if evt.Type == event.StateCanonicalAlias {
+			alias := id.RoomAlias(gjson.GetBytes(evt.Content.VeryRaw, "alias").Str)
+			if alias != "" {
+				ms.RoomAliases[alias] = room.ID
+			}
+		}
+	}
+	ms.Events = append(ms.Events, evt)
+	room.EventPositions = append(room.EventPositions, pos)
+	ms.queueAppserviceEvent(room, evt)
+	ms.wakeSyncers()
+	return evt
+}
+
+// getRoom finds the room in the request path and checks that the user is in it,
+// or writes an error response if they aren't.
+func (ms *MockServer) getRoom(w http.ResponseWriter, r *http.Request, userID id.UserID, memberships ...event.Membership) *Room {
+	room, ok := ms.Rooms[id.RoomID(r.PathValue("roomID"))]
+	if !ok {
+		mautrix.MNotFound.WithMessage("Room not found").Write(w)
+		return nil
+	}
+	if len(memberships) == 0 {
+		memberships = []event.Membership{event.MembershipJoin}
+	}
+	if !slices.Contains(memberships, room.Membership(userID)) {
+		mautrix.MForbidden.WithMessage("You are not in the room").Write(w)
+		return nil
+	}
+	return room
+}
+
+func (ms *MockServer) getProfile(w http.ResponseWriter, r *http.Request) {
+	profile, ok := ms.Users[id.UserID(r.PathValue("userID"))]
+	if !ok {
+		mautrix.MNotFound.WithMessage("Profile not found").Write(w)
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, profile)
+}
+
+func (ms *MockServer) putProfileField(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	} else if userID.UserID != id.UserID(r.PathValue("userID")) {
+		mautrix.MForbidden.WithMessage("Can't change other users' profiles").Write(w)
+		return
+	}
+	var req UserProfile
+	mustDecode(r, &req)
+	profile := ms.ensureUser(userID.UserID)
+	switch r.PathValue("field") {
+	case "displayname":
+		profile.DisplayName = req.DisplayName
+	case "avatar_url":
+		profile.AvatarURL = req.AvatarURL
+	default:
+		mautrix.MUnrecognized.WithMessage("Unsupported profile field").Write(w)
+		return
+	}
+	for _, roomID := range ms.joinedRooms(userID.UserID) {
+		room := ms.Rooms[roomID]
+		memberContent := maps.Clone(room.GetState(event.StateMember, userID.UserID.String()).Content.Raw)
+		memberContent["displayname"] = profile.DisplayName
+		memberContent["avatar_url"] = profile.AvatarURL
+		ms.addEvent(room, &event.Event{
+			Type:     event.StateMember,
+			StateKey: ptr.Ptr(userID.UserID.String()),
+			Sender:   userID.UserID,
+			Content:  makeContent(memberContent),
+		})
+	}
+	ms.emptyResp(w, r)
+}
+
+func (ms *MockServer) ensureUser(userID id.UserID) *UserProfile {
+	profile, ok := ms.Users[userID]
+	if !ok {
+		profile = &UserProfile{}
+		ms.Users[userID] = profile
+	}
+	return profile
+}
+
+func (ms *MockServer) joinedRooms(userID id.UserID) []id.RoomID {
+	var rooms []id.RoomID
+	for roomID, room := range ms.Rooms {
+		if room.Membership(userID) == event.MembershipJoin {
+			rooms = append(rooms, roomID)
+		}
+	}
+	slices.Sort(rooms)
+	return rooms
+}
+
+func (ms *MockServer) getJoinedRooms(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespJoinedRooms{
+		JoinedRooms: append([]id.RoomID{}, ms.joinedRooms(userID.UserID)...),
+	})
+}
+
+func (ms *MockServer) getAlias(w http.ResponseWriter, r *http.Request) {
+	roomID, ok := ms.RoomAliases[id.RoomAlias(r.PathValue("alias"))]
+	if !ok {
+		mautrix.MNotFound.WithMessage("Room alias not found").Write(w)
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespAliasResolve{
+		RoomID:  roomID,
+		Servers: []string{ms.ServerName},
+	})
+}
+
+func (ms *MockServer) memberEvent(room *Room, sender, target id.UserID, membership event.Membership, extra map[string]any) *event.Event {
+	content := map[string]any{"membership": membership}
+	if membership == event.MembershipJoin || membership == event.MembershipInvite {
+		if profile, ok := ms.Users[target]; ok {
+			content["displayname"] = profile.DisplayName
+			content["avatar_url"] = profile.AvatarURL
+		}
+	}
+	maps.Copy(content, extra)
+	return ms.addEvent(room, &event.Event{
+		Type:     event.StateMember,
+		StateKey: ptr.Ptr(target.String()),
+		Sender:   sender,
+		Content:  makeContent(content),
+	})
+}
+
+func (ms *MockServer) newRoom(version id.RoomVersion) *Room {
+	room := &Room{
+		ID:            id.RoomID("!" + random.String(18) + ":" + ms.ServerName),
+		Version:       version,
+		State:         make(map[event.Type]map[string]*event.Event),
+		memberHistory: make(map[id.UserID][]membershipChange),
+		txnIDs:        make(map[string]id.EventID),
+	}
+	ms.Rooms[room.ID] = room
+	return room
+}
+
+func (ms *MockServer) postCreateRoom(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	var req mautrix.ReqCreateRoom
+	mustDecode(r, &req)
+	if req.RoomVersion == "" {
+		req.RoomVersion = id.RoomV11
+	}
+	var alias id.RoomAlias
+	if req.RoomAliasName != "" {
+		alias = id.NewRoomAlias(req.RoomAliasName, ms.ServerName)
+		if _, exists := ms.RoomAliases[alias]; exists {
+			mautrix.MRoomInUse.WithMessage("Room alias already taken").Write(w)
+			return
+		}
+	}
+	ms.ensureUser(userID.UserID)
+	room := ms.newRoom(req.RoomVersion)
+
+	createContent := map[string]any{}
+	maps.Copy(createContent, req.CreationContent)
+	createContent["room_version"] = req.RoomVersion
+	ms.addEvent(room, &event.Event{
+		Type:     event.StateCreate,
+		StateKey: ptr.Ptr(""),
+		Sender:   userID.UserID,
+		Content:  makeContent(createContent),
+	})
+	ms.memberEvent(room, userID.UserID, userID.UserID, event.MembershipJoin, nil)
+
+	users := map[id.UserID]int{userID.UserID: 100}
+	if req.Preset == "trusted_private_chat" {
+		for _, invitee := range req.Invite {
+			users[invitee] = 100
+		}
+	}
+	powerLevels := map[string]any{
+		"users":          users,
+		"users_default":  0,
+		"events_default": 0,
+		"state_default":  50,
+		"ban":            50,
+		"kick":           50,
+		"redact":         50,
+		"invite":         0,
+	}
+	if req.PowerLevelOverride != nil {
+		maps.Copy(powerLevels, makeContent(req.PowerLevelOverride).Raw)
+	}
+	ms.addEvent(room, &event.Event{
+		Type:     event.StatePowerLevels,
+		StateKey: ptr.Ptr(""),
+		Sender:   userID.UserID,
+		Content:  makeContent(powerLevels),
+	})
+	if alias != "" {
+		ms.addEvent(room, &event.Event{
+			Type:     event.StateCanonicalAlias,
+			StateKey: ptr.Ptr(""),
+			Sender:   userID.UserID,
+			Content:  makeContent(map[string]any{"alias": alias}),
+		})
+	}
+	joinRule := event.JoinRuleInvite
+	if req.Preset == "public_chat" {
+		joinRule = event.JoinRulePublic
+	}
+	ms.addEvent(room, &event.Event{
+		Type:     event.StateJoinRules,
+		StateKey: ptr.Ptr(""),
+		Sender:   userID.UserID,
+		Content:  makeContent(map[string]any{"join_rule": joinRule}),
+	})
+	ms.addEvent(room, &event.Event{
+		Type:     event.StateHistoryVisibility,
+		StateKey: ptr.Ptr(""),
+		Sender:   userID.UserID,
+		Content:  makeContent(map[string]any{"history_visibility": event.HistoryVisibilityShared}),
+	})
+	for _, evt := range req.InitialState {
+		evt.Type.Class = event.StateEventType
+		ms.addEvent(room, &event.Event{
+			Type:     evt.Type,
+			StateKey: ptr.Ptr(ptr.Val(evt.StateKey)),
+			Sender:   userID.UserID,
+			Content:  makeContent(&evt.Content),
+		})
+	}
+	if req.Name != "" {
+		ms.addEvent(room, &event.Event{
+			Type:     event.StateRoomName,
+			StateKey: ptr.Ptr(""),
+			Sender:   userID.UserID,
+			Content:  makeContent(map[string]any{"name": req.Name}),
+		})
+	}
+	if req.Topic != "" {
+		ms.addEvent(room, &event.Event{
+			Type:     event.StateTopic,
+			StateKey: ptr.Ptr(""),
+			Sender:   userID.UserID,
+			Content:  makeContent(map[string]any{"topic": req.Topic}),
+		})
+	}
+	for _, member := range req.BeeperInitialMembers {
+		ms.memberEvent(room, member, member, event.MembershipJoin, nil)
+	}
+	for _, invitee := range req.Invite {
+		if room.Membership(invitee) == event.MembershipJoin {
+			continue
+		}
+		var extra map[string]any
+		if req.IsDirect {
+			extra = map[string]any{"is_direct": true}
+		}
+		ms.memberEvent(room, userID.UserID, invitee, event.MembershipInvite, extra)
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespCreateRoom{RoomID: room.ID})
+}
+
+func (ms *MockServer) postJoin(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	var req mautrix.ReqJoinRoom
+	mustDecode(r, &req)
+	roomID := id.RoomID(r.PathValue("roomID"))
+	if roomIDOrAlias := r.PathValue("roomIDOrAlias"); roomIDOrAlias != "" {
+		roomID = id.RoomID(roomIDOrAlias)
+		if aliasTarget, isAlias := ms.RoomAliases[id.RoomAlias(roomIDOrAlias)]; isAlias {
+			roomID = aliasTarget
+		}
+	}
+	room, ok := ms.Rooms[roomID]
+	if !ok {
+		mautrix.MNotFound.WithMessage("Room not found").Write(w)
+		return
+	}
+	switch room.Membership(userID.UserID) {
+	case event.MembershipJoin:
+		// Already joined, no-op
+	case event.MembershipBan:
+		mautrix.MForbidden.WithMessage("You are banned from the room").Write(w)
+		return
+	case event.MembershipInvite:
+		ms.memberEvent(room, userID.UserID, userID.UserID, event.MembershipJoin, reasonContent(req.Reason))
+	default:
+		joinRules := room.GetState(event.StateJoinRules, "")
+		if joinRules == nil || gjson.GetBytes(joinRules.Content.VeryRaw, "join_rule").Str != string(event.JoinRulePublic) {
+			mautrix.MForbidden.WithMessage("You are not invited to the room").Write(w)
+			return
+		}
+		ms.memberEvent(room, userID.UserID, userID.UserID, event.MembershipJoin, reasonContent(req.Reason))
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespJoinRoom{RoomID: room.ID})
+}
+
+func reasonContent(reason string) map[string]any {
+	if reason == "" {
+		return nil
+	}
+	return map[string]any{"reason": reason}
+}
+
+func (ms *MockServer) postLeave(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	var req mautrix.ReqLeave
+	mustDecode(r, &req)
+	room := ms.getRoom(w, r, userID.UserID, event.MembershipJoin, event.MembershipInvite, event.MembershipKnock)
+	if room == nil {
+		return
+	}
+	ms.memberEvent(room, userID.UserID, userID.UserID, event.MembershipLeave, reasonContent(req.Reason))
+	ms.emptyResp(w, r)
+}
+
+func (ms *MockServer) postMembershipChange(membership event.Membership) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := ms.authenticate(w, r)
+		if !ok {
+			return
+		}
+		var req mautrix.ReqInviteUser
+		mustDecode(r, &req)
+		room := ms.getRoom(w, r, userID.UserID)
+		if room == nil {
+			return
+		}
+		currentMembership := room.Membership(req.UserID)
+		switch {
+		case membership == event.MembershipInvite && (currentMembership == event.MembershipJoin || currentMembership == event.MembershipBan):
+			mautrix.MForbidden.WithMessage("User can't be invited").Write(w)
+			return
+		case membership == event.MembershipInvite && currentMembership == event.MembershipInvite:
+			// Already invited, no-op
+		default:
+			ms.memberEvent(room, userID.UserID, req.UserID, membership, reasonContent(req.Reason))
+		}
+		ms.emptyResp(w, r)
+	}
+}
+
+func (ms *MockServer) readEventContent(w http.ResponseWriter, r *http.Request) (event.Content, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		mautrix.MNotJSON.WithMessage("Failed to read request body").Write(w)
+		return event.Content{}, false
+	}
+	content, err := parseContent(body)
+	if err != nil {
+		mautrix.MNotJSON.WithMessage("Request body is not valid JSON").Write(w)
+		return event.Content{}, false
+	}
+	return content, true
+}
+
+func parseTimestamp(r *http.Request) int64 {
+	ts, _ := strconv.ParseInt(r.URL.Query().Get("ts"), 10, 64)
+	return ts
+}
+
+func (ms *MockServer) putSendEvent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	room := ms.getRoom(w, r, userID.UserID)
+	if room == nil {
+		return
+	}
+	txnKey := userID.UserID.String() + "/" + r.PathValue("txnID")
+	if eventID, ok := room.txnIDs[txnKey]; ok {
+		exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespSendEvent{EventID: eventID})
+		return
+	}
+	evtType := event.Type{Type: r.PathValue("type"), Class: event.MessageEventType}
+	content, ok := ms.readEventContent(w, r)
+	if !ok {
+		return
+	}
+	evt := ms.addEvent(room, &event.Event{
+		Type:      evtType,
+		Sender:    userID.UserID,
+		Content:   content,
+		Timestamp: parseTimestamp(r),
+	})
+	room.txnIDs[txnKey] = evt.ID
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespSendEvent{EventID: evt.ID})
+}
+
+func (ms *MockServer) putRedactEvent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	room := ms.getRoom(w, r, userID.UserID)
+	if room == nil {
+		return
+	}
+	targetID := id.EventID(r.PathValue("eventID"))
+	content, ok := ms.readEventContent(w, r)
+	if !ok {
+		return
+	}
+	content.Raw["redacts"] = targetID
+	evt := ms.addEvent(room, &event.Event{
+		Type:    event.EventRedaction,
+		Sender:  userID.UserID,
+		Content: makeContent(content.Raw),
+		Redacts: targetID,
+	})
+	for _, pos := range room.EventPositions {
+		if target := ms.Events[pos]; target.ID == targetID && target.StateKey == nil {
+			target.Content = makeContent(map[string]any{})
+			target.Unsigned.RedactedBecause = evt
+			break
+		}
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespSendEvent{EventID: evt.ID})
+}
+
+func (ms *MockServer) putStateEvent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	room := ms.getRoom(w, r, userID.UserID)
+	if room == nil {
+		return
+	}
+	evtType := event.Type{Type: r.PathValue("type"), Class: event.StateEventType}
+	content, ok := ms.readEventContent(w, r)
+	if !ok {
+		return
+	}
+	evt := ms.addEvent(room, &event.Event{
+		Type:      evtType,
+		StateKey:  ptr.Ptr(r.PathValue("stateKey")),
+		Sender:    userID.UserID,
+		Content:   content,
+		Timestamp: parseTimestamp(r),
+	})
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespSendEvent{EventID: evt.ID})
+}
+
+func (ms *MockServer) getStateEvent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	room := ms.getRoom(w, r, userID.UserID)
+	if room == nil {
+		return
+	}
+	evt := room.GetState(event.Type{Type: r.PathValue("type"), Class: event.StateEventType}, r.PathValue("stateKey"))
+	if evt == nil {
+		mautrix.MNotFound.WithMessage("State event not found").Write(w)
+	} else if r.URL.Query().Get("format") == "event" {
+		exhttp.WriteJSONResponse(w, http.StatusOK, evt)
+	} else {
+		exhttp.WriteJSONResponse(w, http.StatusOK, &evt.Content)
+	}
+}
+
+func (ms *MockServer) getFullState(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	room := ms.getRoom(w, r, userID.UserID)
+	if room == nil {
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, room.stateEvents())
+}
+
+func (ms *MockServer) getMembers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	room := ms.getRoom(w, r, userID.UserID)
+	if room == nil {
+		return
+	}
+	resp := mautrix.RespMembers{Chunk: []*event.Event{}}
+	for _, evt := range room.State[event.StateMember] {
+		resp.Chunk = append(resp.Chunk, evt)
+	}
+	slices.SortFunc(resp.Chunk, func(a, b *event.Event) int {
+		return int(a.Unsigned.BeeperHSOrder - b.Unsigned.BeeperHSOrder)
+	})
+	exhttp.WriteJSONResponse(w, http.StatusOK, &resp)
+}
+
+func (ms *MockServer) getJoinedMembers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	room := ms.getRoom(w, r, userID.UserID)
+	if room == nil {
+		return
+	}
+	resp := mautrix.RespJoinedMembers{Joined: make(map[id.UserID]mautrix.JoinedMember)}
+	for _, member := range room.Members(event.MembershipJoin) {
+		memberEvt := room.GetState(event.StateMember, member.String())
+		resp.Joined[member] = mautrix.JoinedMember{
+			DisplayName: gjson.GetBytes(memberEvt.Content.VeryRaw, "displayname").Str,
+			AvatarURL:   gjson.GetBytes(memberEvt.Content.VeryRaw, "avatar_url").Str,
+		}
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &resp)
+}
+
+func (ms *MockServer) getEvent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	room := ms.getRoom(w, r, userID.UserID)
+	if room == nil {
+		return
+	}
+	eventID := id.EventID(r.PathValue("eventID"))
+	for _, pos := range room.EventPositions {
+		if ms.Events[pos].ID == eventID {
+			exhttp.WriteJSONResponse(w, http.StatusOK, ms.Events[pos])
+			return
+		}
+	}
+	mautrix.MNotFound.WithMessage("Event not found").Write(w)
+}
+
+func (ms *MockServer) getMessages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	room := ms.getRoom(w, r, userID.UserID)
+	if room == nil {
+		return
+	}
+	query := r.URL.Query()
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	backwards := query.Get("dir") != "f"
+	from := len(ms.Events)
+	if !backwards {
+		from = 0
+	}
+	if fromToken := query.Get("from"); fromToken != "" {
+		from, ok = parseStreamToken(fromToken)
+		if !ok {
+			mautrix.MInvalidParam.WithMessage("Invalid from token").Write(w)
+			return
+		}
+	}
+	resp := mautrix.RespMessages{Start: makeStreamToken(from), Chunk: []*event.Event{}}
+	positions := room.EventPositions
+	if backwards {
+		i := len(positions) - 1
+		for ; i >= 0 && len(resp.Chunk) < limit; i-- {
+			if positions[i] < from {
+				resp.Chunk = append(resp.Chunk, ms.Events[positions[i]])
+			}
+		}
+		if i >= 0 && len(resp.Chunk) > 0 {
+			resp.End = makeStreamToken(positions[i+1])
+		}
+	} else {
+		i := 0
+		for ; i < len(positions) && len(resp.Chunk) < limit; i++ {
+			if positions[i] >= from {
+				resp.Chunk = append(resp.Chunk, ms.Events[positions[i]])
+			}
+		}
+		if i < len(positions) {
+			resp.End = makeStreamToken(positions[i])
+		}
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &resp)
+}
+
+// upgradeCopiedStateTypes are the state event types that are copied to the replacement room when upgrading.
+var upgradeCopiedStateTypes = []event.Type{
+	event.StateServerACL, event.StateEncryption, event.StateRoomName, event.StateRoomAvatar, event.StateTopic,
+	event.StateGuestAccess, event.StateHistoryVisibility, event.StateJoinRules, event.StatePowerLevels,
+}
+
+func (ms *MockServer) postUpgradeRoom(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	var req mautrix.ReqUpgradeRoom
+	mustDecode(r, &req)
+	oldRoom := ms.getRoom(w, r, userID.UserID)
+	if oldRoom == nil {
+		return
+	} else if !req.NewVersion.IsKnown() {
+		mautrix.MUnsupportedRoomVersion.WithMessage("Unsupported room version").WithStatus(http.StatusBadRequest).Write(w)
+		return
+	}
+	newRoom := ms.newRoom(req.NewVersion)
+	createContent := maps.Clone(oldRoom.GetState(event.StateCreate, "").Content.Raw)
+	createContent["room_version"] = req.NewVersion
+	createContent["predecessor"] = map[string]any{"room_id": oldRoom.ID}
+	if len(req.AdditionalCreators) > 0 {
+		createContent["additional_creators"] = req.AdditionalCreators
+	} else {
+		delete(createContent, "additional_creators")
+	}
+	ms.addEvent(newRoom, &event.Event{
+		Type:     event.StateCreate,
+		StateKey: ptr.Ptr(""),
+		Sender:   userID.UserID,
+		Content:  makeContent(createContent),
+	})
+	ms.memberEvent(newRoom, userID.UserID, userID.UserID, event.MembershipJoin, nil)
+	for _, evtType := range upgradeCopiedStateTypes {
+		if evt := oldRoom.GetState(evtType, ""); evt != nil {
+			content := evt.Content.Raw
+			if evtType == event.StatePowerLevels && req.NewVersion.PrivilegedRoomCreators() {
+				users, _ := content["users"].(map[string]any)
+				users = maps.Clone(users)
+				delete(users, userID.UserID.String())
+				for _, creator := range req.AdditionalCreators {
+					delete(users, creator.String())
+				}
+				content = maps.Clone(content)
+				content["users"] = users
+			}
+			ms.addEvent(newRoom, &event.Event{
+				Type:     evtType,
+				StateKey: ptr.Ptr(""),
+				Sender:   userID.UserID,
+				Content:  makeContent(content),
+			})
+		}
+	}
+	for alias, roomID := range ms.RoomAliases {
+		if roomID == oldRoom.ID {
+			ms.RoomAliases[alias] = newRoom.ID
+		}
+	}
+	if canonicalAlias := oldRoom.GetState(event.StateCanonicalAlias, ""); canonicalAlias != nil && len(canonicalAlias.Content.Raw) > 0 {
+		ms.addEvent(newRoom, &event.Event{
+			Type:     event.StateCanonicalAlias,
+			StateKey: ptr.Ptr(""),
+			Sender:   userID.UserID,
+			Content:  canonicalAlias.Content,
+		})
+		ms.addEvent(oldRoom, &event.Event{
+			Type:     event.StateCanonicalAlias,
+			StateKey: ptr.Ptr(""),
+			Sender:   userID.UserID,
+			Content:  makeContent(map[string]any{}),
+		})
+	}
+	ms.addEvent(oldRoom, &event.Event{
+		Type:     event.StateTombstone,
+		StateKey: ptr.Ptr(""),
+		Sender:   userID.UserID,
+		Content: makeContent(map[string]any{
+			"body":             "This room has been replaced",
+			"replacement_room": newRoom.ID,
+		}),
+	})
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespUpgradeRoom{ReplacementRoom: newRoom.ID})
+}
+
+func (ms *MockServer) getLocalAliases(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	room := ms.getRoom(w, r, userID.UserID)
+	if room == nil {
+		return
+	}
+	resp := mautrix.RespAliasList{Aliases: []id.RoomAlias{}}
+	for alias, roomID := range ms.RoomAliases {
+		if roomID == room.ID {
+			resp.Aliases = append(resp.Aliases, alias)
+		}
+	}
+	slices.Sort(resp.Aliases)
+	exhttp.WriteJSONResponse(w, http.StatusOK, &resp)
+}
+
+func (ms *MockServer) putAlias(w http.ResponseWriter, r *http.Request) {
+	if _, ok := ms.authenticate(w, r); !ok {
+		return
+	}
+	var req mautrix.ReqAliasCreate
+	mustDecode(r, &req)
+	alias := id.RoomAlias(r.PathValue("alias"))
+	if _, exists := ms.RoomAliases[alias]; exists {
+		mautrix.RespError{ErrCode: "M_UNKNOWN", Err: "Room alias already exists", StatusCode: http.StatusConflict}.Write(w)
+		return
+	} else if _, exists = ms.Rooms[req.RoomID]; !exists {
+		mautrix.MNotFound.WithMessage("Room not found").Write(w)
+		return
+	}
+	ms.RoomAliases[alias] = req.RoomID
+	ms.emptyResp(w, r)
+}
+
+func (ms *MockServer) deleteAlias(w http.ResponseWriter, r *http.Request) {
+	if _, ok := ms.authenticate(w, r); !ok {
+		return
+	}
+	alias := id.RoomAlias(r.PathValue("alias"))
+	if _, exists := ms.RoomAliases[alias]; !exists {
+		mautrix.MNotFound.WithMessage("Room alias not found").Write(w)
+		return
+	}
+	delete(ms.RoomAliases, alias)
+	ms.emptyResp(w, r)
+}
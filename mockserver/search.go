@@ -0,0 +1,193 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mockserver
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"go.mau.fi/util/exhttp"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+var defaultSearchKeys = []string{mautrix.SearchKeyContentBody, mautrix.SearchKeyContentName, mautrix.SearchKeyContentTopic}
+
+func eventMatchesSearch(evt *event.Event, term string, keys []string) bool {
+	for _, key := range keys {
+		value := gjson.GetBytes(evt.Content.VeryRaw, strings.TrimPrefix(key, "content."))
+		if value.Type == gjson.String && strings.Contains(strings.ToLower(value.Str), term) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchContext returns the events around the event at the given index in the room.
+func (ms *MockServer) searchContext(room *Room, idx int, opts *mautrix.ReqSearchEventContext) *mautrix.SearchResultContext {
+	before, after := 5, 5
+	if opts.BeforeLimit != nil {
+		before = *opts.BeforeLimit
+	}
+	if opts.AfterLimit != nil {
+		after = *opts.AfterLimit
+	}
+	ctx := &mautrix.SearchResultContext{EventsBefore: []*event.Event{}, EventsAfter: []*event.Event{}}
+	for i := idx - 1; i >= 0 && len(ctx.EventsBefore) < before; i-- {
+		ctx.EventsBefore = append(ctx.EventsBefore, ms.Events[room.EventPositions[i]])
+	}
+	for i := idx + 1; i < len(room.EventPositions) && len(ctx.EventsAfter) < after; i++ {
+		ctx.EventsAfter = append(ctx.EventsAfter, ms.Events[room.EventPositions[i]])
+	}
+	ctx.Start = makeStreamToken(room.EventPositions[idx-len(ctx.EventsBefore)])
+	ctx.End = makeStreamToken(room.EventPositions[idx+len(ctx.EventsAfter)] + 1)
+	if opts.IncludeProfile {
+		ctx.ProfileInfo = make(map[id.UserID]*mautrix.RespUserProfile)
+		for _, evt := range slices.Concat(ctx.EventsBefore, ctx.EventsAfter) {
+			if profile, ok := ms.Users[evt.Sender]; ok {
+				ctx.ProfileInfo[evt.Sender] = &mautrix.RespUserProfile{
+					DisplayName: profile.DisplayName,
+					AvatarURL:   profile.AvatarURL.ParseOrIgnore(),
+				}
+			}
+		}
+	}
+	return ctx
+}
+
+// postSearch implements a simple case-insensitive substring search over non-state events in the user's joined rooms.
+// Results are always ordered by recency and have a rank of 1.
+func (ms *MockServer) postSearch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	var req mautrix.ReqSearch
+	mustDecode(r, &req)
+	criteria := req.SearchCategories.RoomEvents
+	if criteria == nil {
+		exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespSearch{})
+		return
+	}
+	term := strings.ToLower(criteria.SearchTerm)
+	keys := criteria.Keys
+	if len(keys) == 0 {
+		keys = defaultSearchKeys
+	}
+	limit := 10
+	filter := criteria.Filter
+	if filter == nil {
+		filter = &mautrix.FilterPart{}
+	}
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+	offset := 0
+	if nextBatch := r.URL.Query().Get("next_batch"); nextBatch != "" {
+		var err error
+		offset, err = strconv.Atoi(nextBatch)
+		if err != nil || offset < 0 {
+			mautrix.MInvalidParam.WithMessage("Invalid next_batch token").Write(w)
+			return
+		}
+	}
+
+	type match struct {
+		room *Room
+		idx  int
+	}
+	var matches []match
+	for _, roomID := range ms.joinedRooms(userID.UserID) {
+		if (len(filter.Rooms) > 0 && !slices.Contains(filter.Rooms, roomID)) || slices.Contains(filter.NotRooms, roomID) {
+			continue
+		}
+		room := ms.Rooms[roomID]
+		for i, pos := range room.EventPositions {
+			evt := ms.Events[pos]
+			if evt.StateKey != nil ||
+				(len(filter.Senders) > 0 && !slices.Contains(filter.Senders, evt.Sender)) ||
+				slices.Contains(filter.NotSenders, evt.Sender) ||
+				(len(filter.Types) > 0 && !slices.Contains(filter.Types, evt.Type)) ||
+				slices.Contains(filter.NotTypes, evt.Type) ||
+				!eventMatchesSearch(evt, term, keys) {
+				continue
+			}
+			matches = append(matches, match{room: room, idx: i})
+		}
+	}
+	slices.SortFunc(matches, func(a, b match) int {
+		return b.room.EventPositions[b.idx] - a.room.EventPositions[a.idx]
+	})
+
+	count := len(matches)
+	resp := &mautrix.RespSearchRoomEvents{
+		Count:      &count,
+		Highlights: []string{criteria.SearchTerm},
+		Results:    []*mautrix.SearchResult{},
+	}
+	if offset < len(matches) {
+		matches = matches[offset:]
+	} else {
+		matches = nil
+	}
+	if len(matches) > limit {
+		matches = matches[:limit]
+		resp.NextBatch = strconv.Itoa(offset + limit)
+	}
+	var groupBy []mautrix.ReqSearchGroup
+	if criteria.Groupings != nil && len(criteria.Groupings.GroupBy) > 0 {
+		groupBy = criteria.Groupings.GroupBy
+		resp.Groups = make(map[mautrix.SearchGroupKey]map[string]*mautrix.SearchResultGroup)
+	}
+	for _, m := range matches {
+		evt := ms.Events[m.room.EventPositions[m.idx]]
+		result := &mautrix.SearchResult{Rank: 1, Result: evt}
+		if criteria.EventContext != nil {
+			result.Context = ms.searchContext(m.room, m.idx, criteria.EventContext)
+		}
+		resp.Results = append(resp.Results, result)
+		if criteria.IncludeState {
+			if resp.State == nil {
+				resp.State = make(map[id.RoomID][]*event.Event)
+			}
+			if _, alreadyAdded := resp.State[m.room.ID]; !alreadyAdded {
+				resp.State[m.room.ID] = m.room.stateEvents()
+			}
+		}
+		for _, grouping := range groupBy {
+			var groupValue string
+			switch grouping.Key {
+			case mautrix.SearchGroupByRoomID:
+				groupValue = m.room.ID.String()
+			case mautrix.SearchGroupBySender:
+				groupValue = evt.Sender.String()
+			default:
+				continue
+			}
+			groups := resp.Groups[grouping.Key]
+			if groups == nil {
+				groups = make(map[string]*mautrix.SearchResultGroup)
+				resp.Groups[grouping.Key] = groups
+			}
+			group, ok := groups[groupValue]
+			if !ok {
+				// Groups are ordered by their most recent result, as results are already sorted by recency.
+				group = &mautrix.SearchResultGroup{Order: len(groups) + 1}
+				groups[groupValue] = group
+			}
+			group.Results = append(group.Results, evt.ID)
+		}
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, &mautrix.RespSearch{
+		SearchCategories: mautrix.RespSearchCategories{RoomEvents: resp},
+	})
+}
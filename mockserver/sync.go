@@ -0,0 +1,262 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mau.fi/util/exhttp"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// SyncTimelineLimit is the maximum number of timeline events returned per room in a single /sync response.
+const SyncTimelineLimit = 20
+
+func makeStreamToken(pos int) string {
+	return fmt.Sprintf("s%d", pos)
+}
+
+func parseStreamToken(token string) (int, bool) {
+	token, _, _ = strings.Cut(token, "_")
+	if !strings.HasPrefix(token, "s") {
+		return 0, false
+	}
+	pos, err := strconv.Atoi(token[1:])
+	return pos, err == nil && pos >= 0
+}
+
+type syncToken struct {
+	events      int
+	accountData int
+}
+
+func (st syncToken) String() string {
+	return fmt.Sprintf("s%d_%d", st.events, st.accountData)
+}
+
+func parseSyncToken(token string) (st syncToken, ok bool) {
+	eventPart, accountDataPart, _ := strings.Cut(token, "_")
+	st.events, ok = parseStreamToken(eventPart)
+	if !ok {
+		return
+	}
+	st.accountData, ok = parseStreamToken("s" + accountDataPart)
+	return
+}
+
+func (ms *MockServer) currentSyncToken() syncToken {
+	return syncToken{events: len(ms.Events), accountData: ms.accountDataStream}
+}
+
+func (ms *MockServer) hasNewSyncData(userID userAndDeviceID, since syncToken) bool {
+	if len(ms.DeviceInbox[userID.UserID][userID.DeviceID]) > 0 {
+		return true
+	}
+	for _, pos := range ms.accountDataPos[userID.UserID] {
+		if pos > since.accountData {
+			return true
+		}
+	}
+	for pos := since.events; pos < len(ms.Events); pos++ {
+		evt := ms.Events[pos]
+		if evt.Type == event.StateMember && evt.GetStateKey() == userID.UserID.String() {
+			return true
+		} else if ms.Rooms[evt.RoomID].MembershipAt(userID.UserID, pos) == event.MembershipJoin {
+			return true
+		}
+	}
+	return false
+}
+
+func (ms *MockServer) getSync(w http.ResponseWriter, r *http.Request) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	userID, ok := ms.authenticate(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	var since syncToken
+	initial := true
+	if sinceStr := query.Get("since"); sinceStr != "" {
+		since, ok = parseSyncToken(sinceStr)
+		if !ok || since.events > len(ms.Events) {
+			mautrix.MInvalidParam.WithMessage("Invalid since token").Write(w)
+			return
+		}
+		initial = false
+	}
+	timeoutMS, _ := strconv.Atoi(query.Get("timeout"))
+	if !initial && timeoutMS > 0 {
+		deadline := time.After(time.Duration(timeoutMS) * time.Millisecond)
+	Loop:
+		for !ms.hasNewSyncData(userID, since) {
+			notify := ms.notify
+			ms.lock.Unlock()
+			select {
+			case <-notify:
+				ms.lock.Lock()
+			case <-deadline:
+				ms.lock.Lock()
+				break Loop
+			case <-r.Context().Done():
+				ms.lock.Lock()
+				return
+			}
+		}
+	}
+	resp := ms.buildSync(userID, since, initial)
+	exhttp.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (ms *MockServer) buildSync(userID userAndDeviceID, since syncToken, initial bool) *mautrix.RespSync {
+	next := ms.currentSyncToken()
+	resp := &mautrix.RespSync{
+		NextBatch: next.String(),
+		Rooms: mautrix.RespSyncRooms{
+			Join:   make(map[id.RoomID]*mautrix.SyncJoinedRoom),
+			Invite: make(map[id.RoomID]*mautrix.SyncInvitedRoom),
+			Leave:  make(map[id.RoomID]*mautrix.SyncLeftRoom),
+		},
+	}
+	for _, evt := range ms.DeviceInbox[userID.UserID][userID.DeviceID] {
+		resp.ToDevice.Events = append(resp.ToDevice.Events, &evt)
+	}
+	delete(ms.DeviceInbox[userID.UserID], userID.DeviceID)
+	for evtType, pos := range ms.accountDataPos[userID.UserID] {
+		if initial || pos > since.accountData {
+			resp.AccountData.Events = append(resp.AccountData.Events, &event.Event{
+				Type:    evtType,
+				Content: event.Content{VeryRaw: ms.AccountData[userID.UserID][evtType]},
+			})
+		}
+	}
+	for roomID, room := range ms.Rooms {
+		currentMembership := room.Membership(userID.UserID)
+		previousMembership := event.MembershipLeave
+		if !initial && since.events > 0 {
+			previousMembership = room.MembershipAt(userID.UserID, since.events-1)
+		}
+		switch currentMembership {
+		case event.MembershipJoin:
+			if joined := ms.syncJoinedRoom(room, userID.UserID, since.events, initial || previousMembership != event.MembershipJoin); joined != nil {
+				resp.Rooms.Join[roomID] = joined
+			}
+		case event.MembershipInvite:
+			if initial || previousMembership != event.MembershipInvite {
+				resp.Rooms.Invite[roomID] = &mautrix.SyncInvitedRoom{State: mautrix.SyncEventsList{Events: ms.strippedState(room, userID.UserID)}}
+			}
+		default:
+			if !initial && (previousMembership == event.MembershipJoin || previousMembership == event.MembershipInvite) {
+				resp.Rooms.Leave[roomID] = ms.syncLeftRoom(room, userID.UserID, since.events)
+			}
+		}
+	}
+	return resp
+}
+
+// visibleTimeline returns the stream positions of events in the room after the given position
+// that the user is allowed to see based on their membership at the time of each event.
+func (ms *MockServer) visibleTimeline(room *Room, userID id.UserID, since int) []int {
+	var positions []int
+	for _, pos := range room.EventPositions {
+		if pos < since {
+			continue
+		}
+		evt := ms.Events[pos]
+		membership := room.MembershipAt(userID, pos)
+		if membership == event.MembershipJoin || (evt.Type == event.StateMember && evt.GetStateKey() == userID.String()) {
+			positions = append(positions, pos)
+		}
+	}
+	return positions
+}
+
+func (ms *MockServer) syncJoinedRoom(room *Room, userID id.UserID, since int, full bool) *mautrix.SyncJoinedRoom {
+	if full {
+		since = 0
+	}
+	positions := ms.visibleTimeline(room, userID, since)
+	if len(positions) == 0 && !full {
+		return nil
+	}
+	joined := &mautrix.SyncJoinedRoom{}
+	if len(positions) > SyncTimelineLimit {
+		positions = positions[len(positions)-SyncTimelineLimit:]
+		joined.Timeline.Limited = true
+	}
+	for _, pos := range positions {
+		joined.Timeline.Events = append(joined.Timeline.Events, ms.Events[pos])
+	}
+	if len(positions) > 0 {
+		joined.Timeline.PrevBatch = makeStreamToken(positions[0])
+		if joined.Timeline.Limited || full {
+			joined.State.Events = ms.stateBefore(room, positions[0])
+		}
+	}
+	return joined
+}
+
+func (ms *MockServer) syncLeftRoom(room *Room, userID id.UserID, since int) *mautrix.SyncLeftRoom {
+	left := &mautrix.SyncLeftRoom{}
+	for _, pos := range ms.visibleTimeline(room, userID, since) {
+		left.Timeline.Events = append(left.Timeline.Events, ms.Events[pos])
+	}
+	if len(left.Timeline.Events) > 0 {
+		left.Timeline.PrevBatch = makeStreamToken(int(left.Timeline.Events[0].Unsigned.BeeperHSOrder))
+	}
+	return left
+}
+
+var strippedStateTypes = []event.Type{
+	event.StateCreate, event.StateJoinRules, event.StateRoomName, event.StateRoomAvatar,
+	event.StateCanonicalAlias, event.StateEncryption, event.StateTopic,
+}
+
+func (ms *MockServer) strippedState(room *Room, userID id.UserID) []*event.Event {
+	var events []*event.Event
+	addStripped := func(evt *event.Event) {
+		if evt != nil {
+			events = append(events, &event.Event{
+				Type:     evt.Type,
+				StateKey: evt.StateKey,
+				Sender:   evt.Sender,
+				Content:  event.Content{VeryRaw: evt.Content.VeryRaw},
+			})
+		}
+	}
+	for _, evtType := range strippedStateTypes {
+		addStripped(room.GetState(evtType, ""))
+	}
+	inviteEvt := room.GetState(event.StateMember, userID.String())
+	if inviteEvt != nil {
+		addStripped(room.GetState(event.StateMember, inviteEvt.Sender.String()))
+		addStripped(inviteEvt)
+	}
+	return events
+}
+
+func (ms *MockServer) setAccountData(userID id.UserID, evtType event.Type, data json.RawMessage) {
+	if _, ok := ms.AccountData[userID]; !ok {
+		ms.AccountData[userID] = map[event.Type]json.RawMessage{}
+	}
+	ms.AccountData[userID][evtType] = data
+	if _, ok := ms.accountDataPos[userID]; !ok {
+		ms.accountDataPos[userID] = map[event.Type]int{}
+	}
+	ms.accountDataStream++
+	ms.accountDataPos[userID][evtType] = ms.accountDataStream
+	ms.wakeSyncers()
+}
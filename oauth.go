@@ -0,0 +1,459 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mau.fi/util/random"
+
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// OAuth scopes defined in https://spec.matrix.org/v1.15/client-server-api/#scope
+const (
+	OAuthScopeClientAPI    = "urn:matrix:client:api:*"
+	OAuthScopeDevicePrefix = "urn:matrix:client:device:"
+)
+
+const (
+	OAuthGrantTypeAuthorizationCode = "authorization_code"
+	OAuthGrantTypeRefreshToken      = "refresh_token"
+	OAuthGrantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+var (
+	ErrOAuthNotConfigured    = errors.New("client doesn't have OAuth info set")
+	ErrOAuthNotSupported     = errors.New("homeserver doesn't support OAuth 2.0 authentication")
+	ErrOAuthStateMismatch    = errors.New("state in OAuth callback doesn't match the authorization request")
+	ErrOAuthNoRegistration   = errors.New("authorization server doesn't support dynamic client registration")
+	ErrOAuthNoDeviceEndpoint = errors.New("authorization server doesn't support the device authorization grant")
+)
+
+// Common OAuth error codes from https://datatracker.ietf.org/doc/html/rfc6749#section-5.2
+// and https://datatracker.ietf.org/doc/html/rfc8628#section-3.5
+//
+// Can be used with errors.Is() to check the error code.
+var (
+	ErrOAuthInvalidGrant         = OAuthError{ErrCode: "invalid_grant"}
+	ErrOAuthInvalidClient        = OAuthError{ErrCode: "invalid_client"}
+	ErrOAuthAuthorizationPending = OAuthError{ErrCode: "authorization_pending"}
+	ErrOAuthSlowDown             = OAuthError{ErrCode: "slow_down"}
+	ErrOAuthAccessDenied         = OAuthError{ErrCode: "access_denied"}
+	ErrOAuthExpiredToken         = OAuthError{ErrCode: "expired_token"}
+)
+
+// OAuthError is the JSON error response from an OAuth 2.0 authorization server.
+type OAuthError struct {
+	ErrCode     string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	StatusCode  int    `json:"-"`
+}
+
+func (e OAuthError) Error() string {
+	msg := e.ErrCode
+	if e.StatusCode != 0 {
+		msg = fmt.Sprintf("%s (HTTP %d)", msg, e.StatusCode)
+	}
+	if e.Description != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Description)
+	}
+	return msg
+}
+
+func (e OAuthError) Is(err error) bool {
+	var other OAuthError
+	return errors.As(err, &other) && other.ErrCode == e.ErrCode
+}
+
+// OAuthServerMetadata is the response for https://spec.matrix.org/v1.15/client-server-api/#get_matrixclientv1auth_metadata
+type OAuthServerMetadata struct {
+	Issuer                      string `json:"issuer"`
+	AuthorizationEndpoint       string `json:"authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	RegistrationEndpoint        string `json:"registration_endpoint,omitempty"`
+	RevocationEndpoint          string `json:"revocation_endpoint,omitempty"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint,omitempty"`
+	AccountManagementURI        string `json:"account_management_uri,omitempty"`
+
+	ResponseTypesSupported        []string `json:"response_types_supported,omitempty"`
+	ResponseModesSupported        []string `json:"response_modes_supported,omitempty"`
+	GrantTypesSupported           []string `json:"grant_types_supported,omitempty"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+	PromptValuesSupported         []string `json:"prompt_values_supported,omitempty"`
+	AccountManagementActions      []string `json:"account_management_actions_supported,omitempty"`
+}
+
+// ReqOAuthClientRegistration contains the client metadata for https://datatracker.ietf.org/doc/html/rfc7591#section-2
+type ReqOAuthClientRegistration struct {
+	ClientName              string   `json:"client_name,omitempty"`
+	ClientURI               string   `json:"client_uri"`
+	LogoURI                 string   `json:"logo_uri,omitempty"`
+	TOSURI                  string   `json:"tos_uri,omitempty"`
+	PolicyURI               string   `json:"policy_uri,omitempty"`
+	RedirectURIs            []string `json:"redirect_uris,omitempty"`
+	GrantTypes              []string `json:"grant_types"`
+	ResponseTypes           []string `json:"response_types,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	ApplicationType         string   `json:"application_type,omitempty"`
+}
+
+// RespOAuthClientRegistration is the response for https://datatracker.ietf.org/doc/html/rfc7591#section-3.2.1
+type RespOAuthClientRegistration struct {
+	ReqOAuthClientRegistration
+	ClientID         string `json:"client_id"`
+	ClientIDIssuedAt int64  `json:"client_id_issued_at,omitempty"`
+}
+
+// RespOAuthToken is the response for https://datatracker.ietf.org/doc/html/rfc6749#section-5.1
+type RespOAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// RespOAuthDeviceAuthorization is the response for https://datatracker.ietf.org/doc/html/rfc8628#section-3.2
+type RespOAuthDeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval,omitempty"`
+}
+
+// OAuthClientInfo contains the authorization server metadata and the client ID registered with it.
+type OAuthClientInfo struct {
+	Metadata *OAuthServerMetadata
+	ClientID string
+}
+
+// OAuthAuthorizationRequest contains the parameters of an authorization code flow that must be remembered
+// until the user is redirected back to the client.
+type OAuthAuthorizationRequest struct {
+	// The URL that the user should open in their browser.
+	URL          string
+	RedirectURI  string
+	State        string
+	CodeVerifier string
+	DeviceID     id.DeviceID
+}
+
+// OAuthDeviceAuthorization is an in-progress device authorization grant.
+type OAuthDeviceAuthorization struct {
+	RespOAuthDeviceAuthorization
+	DeviceID     id.DeviceID
+	ExpiresAt    time.Time
+	PollInterval time.Duration
+}
+
+// ReqOAuthAuthorization contains the optional parameters for PrepareOAuthLogin.
+type ReqOAuthAuthorization struct {
+	RedirectURI string
+	// The device ID to request. If empty, a random device ID is generated.
+	DeviceID id.DeviceID
+	// Either "query" or "fragment". Defaults to "fragment".
+	ResponseMode string
+	// Set to "create" to ask the server to show the registration page.
+	Prompt    string
+	LoginHint string
+}
+
+// MakeOAuthScope returns the scope that grants full client-server API access for the given device.
+func MakeOAuthScope(deviceID id.DeviceID) string {
+	return fmt.Sprintf("%s %s%s", OAuthScopeClientAPI, OAuthScopeDevicePrefix, deviceID)
+}
+
+func parseDeviceIDFromScope(scope string) id.DeviceID {
+	for _, part := range strings.Fields(scope) {
+		if strings.HasPrefix(part, OAuthScopeDevicePrefix) {
+			return id.DeviceID(strings.TrimPrefix(part, OAuthScopeDevicePrefix))
+		}
+	}
+	return ""
+}
+
+// GetAuthMetadata fetches the metadata of the authorization server used by the homeserver.
+// See https://spec.matrix.org/v1.15/client-server-api/#get_matrixclientv1auth_metadata
+//
+// If the stable endpoint isn't supported, the unstable MSC2965 endpoint is tried.
+// If neither is supported, ErrOAuthNotSupported is returned.
+func (cli *Client) GetAuthMetadata(ctx context.Context) (resp *OAuthServerMetadata, err error) {
+	_, err = cli.MakeFullRequest(ctx, FullRequest{
+		Method:          http.MethodGet,
+		URL:             cli.BuildClientURL("v1", "auth_metadata"),
+		ResponseJSON:    &resp,
+		omitAccessToken: true,
+	})
+	if isNotSupportedError(err) {
+		_, err = cli.MakeFullRequest(ctx, FullRequest{
+			Method:          http.MethodGet,
+			URL:             cli.BuildClientURL("unstable", "org.matrix.msc2965", "auth_metadata"),
+			ResponseJSON:    &resp,
+			omitAccessToken: true,
+		})
+	}
+	if isNotSupportedError(err) {
+		err = ErrOAuthNotSupported
+	}
+	return
+}
+
+func isNotSupportedError(err error) bool {
+	var httpErr HTTPError
+	return errors.Is(err, MUnrecognized) ||
+		(errors.As(err, &httpErr) && (httpErr.IsStatus(http.StatusNotFound) || httpErr.IsStatus(http.StatusMethodNotAllowed)))
+}
+
+// RegisterOAuthClient registers the client with the authorization server using dynamic client registration.
+// See https://spec.matrix.org/v1.15/client-server-api/#client-registration
+func (cli *Client) RegisterOAuthClient(ctx context.Context, metadata *OAuthServerMetadata, req *ReqOAuthClientRegistration) (resp *RespOAuthClientRegistration, err error) {
+	if metadata.RegistrationEndpoint == "" {
+		return nil, ErrOAuthNoRegistration
+	}
+	if req.TokenEndpointAuthMethod == "" {
+		req.TokenEndpointAuthMethod = "none"
+	}
+	if len(req.GrantTypes) == 0 {
+		req.GrantTypes = []string{OAuthGrantTypeRefreshToken}
+		if len(req.RedirectURIs) > 0 {
+			req.GrantTypes = append(req.GrantTypes, OAuthGrantTypeAuthorizationCode)
+		}
+		if metadata.DeviceAuthorizationEndpoint != "" {
+			req.GrantTypes = append(req.GrantTypes, OAuthGrantTypeDeviceCode)
+		}
+	}
+	if len(req.ResponseTypes) == 0 && slices.Contains(req.GrantTypes, OAuthGrantTypeAuthorizationCode) {
+		req.ResponseTypes = []string{"code"}
+	}
+	_, err = cli.makeOAuthRequest(ctx, FullRequest{
+		Method:       http.MethodPost,
+		URL:          metadata.RegistrationEndpoint,
+		RequestJSON:  req,
+		ResponseJSON: &resp,
+	})
+	return
+}
+
+// SetupOAuth discovers the authorization server of the homeserver, registers the client with it
+// and stores the resulting info in the OAuth field.
+func (cli *Client) SetupOAuth(ctx context.Context, req *ReqOAuthClientRegistration) error {
+	metadata, err := cli.GetAuthMetadata(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get auth metadata: %w", err)
+	}
+	resp, err := cli.RegisterOAuthClient(ctx, metadata, req)
+	if err != nil {
+		return fmt.Errorf("failed to register client: %w", err)
+	}
+	cli.OAuth = &OAuthClientInfo{Metadata: metadata, ClientID: resp.ClientID}
+	return nil
+}
+
+// PrepareOAuthLogin creates an authorization code request with PKCE.
+// See https://spec.matrix.org/v1.15/client-server-api/#authorization-code-grant
+//
+// The user should be sent to the returned URL, after which the authorization server will redirect them
+// to the redirect URI with the code and state parameters, which must be passed to CompleteOAuthLogin.
+func (cli *Client) PrepareOAuthLogin(req *ReqOAuthAuthorization) (*OAuthAuthorizationRequest, error) {
+	if cli.OAuth == nil {
+		return nil, ErrOAuthNotConfigured
+	}
+	authURL, err := url.Parse(cli.OAuth.Metadata.AuthorizationEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse authorization endpoint: %w", err)
+	}
+	authReq := &OAuthAuthorizationRequest{
+		RedirectURI:  req.RedirectURI,
+		State:        random.String(32),
+		CodeVerifier: random.String(64),
+		DeviceID:     req.DeviceID,
+	}
+	if authReq.DeviceID == "" {
+		authReq.DeviceID = id.DeviceID(strings.ToUpper(random.String(10)))
+	}
+	challenge := sha256.Sum256([]byte(authReq.CodeVerifier))
+	responseMode := req.ResponseMode
+	if responseMode == "" {
+		responseMode = "fragment"
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("response_mode", responseMode)
+	query.Set("client_id", cli.OAuth.ClientID)
+	query.Set("redirect_uri", req.RedirectURI)
+	query.Set("scope", MakeOAuthScope(authReq.DeviceID))
+	query.Set("state", authReq.State)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	if req.Prompt != "" {
+		query.Set("prompt", req.Prompt)
+	}
+	if req.LoginHint != "" {
+		query.Set("login_hint", req.LoginHint)
+	}
+	authURL.RawQuery = query.Encode()
+	authReq.URL = authURL.String()
+	return authReq, nil
+}
+
+// CompleteOAuthLogin exchanges the authorization code from the redirect for tokens and stores them in the client.
+func (cli *Client) CompleteOAuthLogin(ctx context.Context, authReq *OAuthAuthorizationRequest, code, state string) error {
+	if state != authReq.State {
+		return ErrOAuthStateMismatch
+	}
+	resp, err := cli.requestOAuthToken(ctx, OAuthGrantTypeAuthorizationCode, map[string]string{
+		"code":          code,
+		"redirect_uri":  authReq.RedirectURI,
+		"code_verifier": authReq.CodeVerifier,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	return cli.finishOAuthLogin(ctx, resp, authReq.DeviceID)
+}
+
+// StartOAuthDeviceLogin starts a device authorization grant.
+// See https://spec.matrix.org/v1.15/client-server-api/#device-authorization-grant
+//
+// The user code and verification URI in the response should be shown to the user,
+// after which WaitOAuthDeviceLogin should be called to wait for the user to approve the login.
+func (cli *Client) StartOAuthDeviceLogin(ctx context.Context, deviceID id.DeviceID) (*OAuthDeviceAuthorization, error) {
+	if cli.OAuth == nil {
+		return nil, ErrOAuthNotConfigured
+	} else if cli.OAuth.Metadata.DeviceAuthorizationEndpoint == "" {
+		return nil, ErrOAuthNoDeviceEndpoint
+	}
+	if deviceID == "" {
+		deviceID = id.DeviceID(strings.ToUpper(random.String(10)))
+	}
+	resp := &OAuthDeviceAuthorization{DeviceID: deviceID}
+	_, err := cli.makeOAuthRequest(ctx, FullRequest{
+		Method: http.MethodPost,
+		URL:    cli.OAuth.Metadata.DeviceAuthorizationEndpoint,
+		RequestBytes: makeOAuthForm(map[string]string{
+			"client_id": cli.OAuth.ClientID,
+			"scope":     MakeOAuthScope(deviceID),
+		}),
+		ResponseJSON: &resp.RespOAuthDeviceAuthorization,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.ExpiresAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	resp.PollInterval = time.Duration(resp.Interval) * time.Second
+	if resp.PollInterval <= 0 {
+		resp.PollInterval = 5 * time.Second
+	}
+	return resp, nil
+}
+
+// WaitOAuthDeviceLogin polls the token endpoint until the user approves or denies the device authorization grant,
+// then stores the tokens in the client.
+func (cli *Client) WaitOAuthDeviceLogin(ctx context.Context, auth *OAuthDeviceAuthorization) error {
+	interval := auth.PollInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		resp, err := cli.requestOAuthToken(ctx, OAuthGrantTypeDeviceCode, map[string]string{
+			"device_code": auth.DeviceCode,
+		})
+		if errors.Is(err, ErrOAuthAuthorizationPending) {
+			// keep polling
+		} else if errors.Is(err, ErrOAuthSlowDown) {
+			interval += 5 * time.Second
+		} else if err != nil {
+			return fmt.Errorf("failed to poll device authorization: %w", err)
+		} else {
+			return cli.finishOAuthLogin(ctx, resp, auth.DeviceID)
+		}
+		if !auth.ExpiresAt.IsZero() && time.Now().After(auth.ExpiresAt) {
+			return ErrOAuthExpiredToken
+		}
+	}
+}
+
+func (cli *Client) finishOAuthLogin(ctx context.Context, resp *RespOAuthToken, deviceID id.DeviceID) error {
+	if scopeDeviceID := parseDeviceIDFromScope(resp.Scope); scopeDeviceID != "" {
+		deviceID = scopeDeviceID
+	}
+	cli.setAccessToken(resp.AccessToken)
+	whoami, err := cli.Whoami(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get user ID after login: %w", err)
+	}
+	cli.UserID = whoami.UserID
+	cli.DeviceID = deviceID
+	if whoami.DeviceID != "" {
+		cli.DeviceID = whoami.DeviceID
+	}
+	cli.Log.Debug().
+		Str("user_id", cli.UserID.String()).
+		Str("device_id", cli.DeviceID.String()).
+		Msg("Stored credentials after OAuth login")
+	return cli.updateTokens(ctx, resp.AccessToken, resp.RefreshToken, time.Duration(resp.ExpiresIn)*time.Second)
+}
+
+func (cli *Client) requestOAuthToken(ctx context.Context, grantType string, params map[string]string) (resp *RespOAuthToken, err error) {
+	if cli.OAuth == nil {
+		return nil, ErrOAuthNotConfigured
+	}
+	params["grant_type"] = grantType
+	params["client_id"] = cli.OAuth.ClientID
+	_, err = cli.makeOAuthRequest(ctx, FullRequest{
+		Method:           http.MethodPost,
+		URL:              cli.OAuth.Metadata.TokenEndpoint,
+		RequestBytes:     makeOAuthForm(params),
+		ResponseJSON:     &resp,
+		SensitiveContent: true,
+	})
+	return
+}
+
+func makeOAuthForm(params map[string]string) []byte {
+	form := make(url.Values, len(params))
+	for key, value := range params {
+		form.Set(key, value)
+	}
+	return []byte(form.Encode())
+}
+
+func (cli *Client) makeOAuthRequest(ctx context.Context, params FullRequest) ([]byte, error) {
+	params.omitAccessToken = true
+	params.Headers = http.Header{}
+	if params.RequestBytes != nil {
+		params.Headers.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	data, _, err := cli.makeFullRequestWithResp(ctx, params)
+	var httpErr HTTPError
+	if errors.As(err, &httpErr) && httpErr.RespError == nil && httpErr.Response != nil && len(data) > 0 {
+		var oauthErr OAuthError
+		if json.Unmarshal(data, &oauthErr) == nil && oauthErr.ErrCode != "" {
+			oauthErr.StatusCode = httpErr.Response.StatusCode
+			return data, oauthErr
+		}
+	}
+	return data, err
+}
@@ -0,0 +1,294 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// mockIssuer is a stand-in for both the homeserver and the OAuth authorization server.
+type mockIssuer struct {
+	lock   sync.Mutex
+	t      *testing.T
+	server *httptest.Server
+
+	clientID      string
+	codes         map[string]url.Values
+	pendingPolls  int
+	validTokens   map[string]string
+	refreshTokens map[string]string
+	tokenCounter  int
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	mi := &mockIssuer{
+		t:             t,
+		clientID:      "client123",
+		codes:         make(map[string]url.Values),
+		validTokens:   make(map[string]string),
+		refreshTokens: make(map[string]string),
+	}
+	mi.server = httptest.NewServer(mi)
+	t.Cleanup(mi.server.Close)
+	return mi
+}
+
+func (mi *mockIssuer) issueTokens(scope string) map[string]any {
+	mi.tokenCounter++
+	accessToken := fmt.Sprintf("access%d", mi.tokenCounter)
+	refreshToken := fmt.Sprintf("refresh%d", mi.tokenCounter)
+	mi.validTokens[accessToken] = scope
+	mi.refreshTokens[refreshToken] = scope
+	return map[string]any{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    300,
+		"scope":         scope,
+	}
+}
+
+func writeOAuthError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (mi *mockIssuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mi.lock.Lock()
+	defer mi.lock.Unlock()
+	switch r.URL.Path {
+	case "/_matrix/client/v1/auth_metadata":
+		mautrix.MUnrecognized.WithMessage("Unrecognized request").Write(w)
+	case "/_matrix/client/unstable/org.matrix.msc2965/auth_metadata":
+		writeJSON(w, &mautrix.OAuthServerMetadata{
+			Issuer:                      mi.server.URL + "/",
+			AuthorizationEndpoint:       mi.server.URL + "/oauth2/authorize",
+			TokenEndpoint:               mi.server.URL + "/oauth2/token",
+			RegistrationEndpoint:        mi.server.URL + "/oauth2/registration",
+			DeviceAuthorizationEndpoint: mi.server.URL + "/oauth2/device",
+		})
+	case "/oauth2/registration":
+		var req mautrix.ReqOAuthClientRegistration
+		require.NoError(mi.t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(mi.t, "none", req.TokenEndpointAuthMethod)
+		assert.Contains(mi.t, req.GrantTypes, mautrix.OAuthGrantTypeRefreshToken)
+		writeJSON(w, &mautrix.RespOAuthClientRegistration{ReqOAuthClientRegistration: req, ClientID: mi.clientID})
+	case "/oauth2/device":
+		require.NoError(mi.t, r.ParseForm())
+		assert.Equal(mi.t, mi.clientID, r.PostForm.Get("client_id"))
+		mi.codes["device"] = r.PostForm
+		writeJSON(w, &mautrix.RespOAuthDeviceAuthorization{
+			DeviceCode:      "device",
+			UserCode:        "ABCD-EFGH",
+			VerificationURI: mi.server.URL + "/link",
+			ExpiresIn:       60,
+		})
+	case "/oauth2/token":
+		require.NoError(mi.t, r.ParseForm())
+		assert.Equal(mi.t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+		assert.Empty(mi.t, r.Header.Get("Authorization"))
+		assert.Equal(mi.t, mi.clientID, r.PostForm.Get("client_id"))
+		switch r.PostForm.Get("grant_type") {
+		case mautrix.OAuthGrantTypeAuthorizationCode:
+			authParams, ok := mi.codes[r.PostForm.Get("code")]
+			if !ok {
+				writeOAuthError(w, "invalid_grant")
+				return
+			}
+			delete(mi.codes, r.PostForm.Get("code"))
+			challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+			if base64.RawURLEncoding.EncodeToString(challenge[:]) != authParams.Get("code_challenge") {
+				writeOAuthError(w, "invalid_grant")
+				return
+			}
+			assert.Equal(mi.t, authParams.Get("redirect_uri"), r.PostForm.Get("redirect_uri"))
+			writeJSON(w, mi.issueTokens(authParams.Get("scope")))
+		case mautrix.OAuthGrantTypeDeviceCode:
+			if mi.pendingPolls > 0 {
+				mi.pendingPolls--
+				writeOAuthError(w, "authorization_pending")
+				return
+			}
+			writeJSON(w, mi.issueTokens(mi.codes["device"].Get("scope")))
+		case mautrix.OAuthGrantTypeRefreshToken:
+			scope, ok := mi.refreshTokens[r.PostForm.Get("refresh_token")]
+			if !ok {
+				writeOAuthError(w, "invalid_grant")
+				return
+			}
+			delete(mi.refreshTokens, r.PostForm.Get("refresh_token"))
+			writeJSON(w, mi.issueTokens(scope))
+		default:
+			writeOAuthError(w, "unsupported_grant_type")
+		}
+	case "/_matrix/client/v3/account/whoami":
+		scope, ok := mi.validTokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		if !ok {
+			mautrix.MUnknownToken.WithMessage("Unknown access token").Write(w)
+			return
+		}
+		deviceID := strings.TrimPrefix(strings.Fields(scope)[1], mautrix.OAuthScopeDevicePrefix)
+		writeJSON(w, &mautrix.RespWhoami{UserID: "@user:example.com", DeviceID: id.DeviceID(deviceID)})
+	default:
+		mautrix.MUnrecognized.WithMessage("Unrecognized request").Write(w)
+	}
+}
+
+type mockTokenStore struct {
+	saved []*mautrix.TokenSet
+}
+
+func (mts *mockTokenStore) SaveTokens(ctx context.Context, tokens *mautrix.TokenSet) error {
+	mts.saved = append(mts.saved, tokens)
+	return nil
+}
+
+func newOAuthTestClient(t *testing.T) (*mautrix.Client, *mockIssuer, *mockTokenStore) {
+	mi := newMockIssuer(t)
+	cli, err := mautrix.NewClient(mi.server.URL, "", "")
+	require.NoError(t, err)
+	tokenStore := &mockTokenStore{}
+	cli.TokenStore = tokenStore
+	err = cli.SetupOAuth(context.TODO(), &mautrix.ReqOAuthClientRegistration{
+		ClientName:   "Test client",
+		ClientURI:    "https://example.com",
+		RedirectURIs: []string{"https://example.com/callback"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, mi.clientID, cli.OAuth.ClientID)
+	return cli, mi, tokenStore
+}
+
+func TestClient_OAuthLogin(t *testing.T) {
+	ctx := context.TODO()
+	cli, mi, tokenStore := newOAuthTestClient(t)
+
+	authReq, err := cli.PrepareOAuthLogin(&mautrix.ReqOAuthAuthorization{
+		RedirectURI: "https://example.com/callback",
+		DeviceID:    "MYDEVICE",
+	})
+	require.NoError(t, err)
+	parsedURL, err := url.Parse(authReq.URL)
+	require.NoError(t, err)
+	query := parsedURL.Query()
+	assert.Equal(t, "/oauth2/authorize", parsedURL.Path)
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Equal(t, mautrix.MakeOAuthScope("MYDEVICE"), query.Get("scope"))
+	// Simulate the user approving the login in the browser
+	mi.codes["code1"] = query
+
+	err = cli.CompleteOAuthLogin(ctx, authReq, "code1", "wrong state")
+	assert.ErrorIs(t, err, mautrix.ErrOAuthStateMismatch)
+	err = cli.CompleteOAuthLogin(ctx, authReq, "invalid code", authReq.State)
+	assert.ErrorIs(t, err, mautrix.ErrOAuthInvalidGrant)
+	require.NoError(t, cli.CompleteOAuthLogin(ctx, authReq, "code1", authReq.State))
+
+	assert.Equal(t, id.UserID("@user:example.com"), cli.UserID)
+	assert.Equal(t, id.DeviceID("MYDEVICE"), cli.DeviceID)
+	assert.Equal(t, "access1", cli.AccessToken)
+	assert.Equal(t, "refresh1", cli.RefreshToken)
+	require.Len(t, tokenStore.saved, 1)
+	assert.Equal(t, "refresh1", tokenStore.saved[0].RefreshToken)
+	assert.Equal(t, id.DeviceID("MYDEVICE"), tokenStore.saved[0].DeviceID)
+}
+
+func TestClient_OAuthDeviceLogin(t *testing.T) {
+	ctx := context.TODO()
+	cli, mi, _ := newOAuthTestClient(t)
+	mi.pendingPolls = 2
+
+	auth, err := cli.StartOAuthDeviceLogin(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "ABCD-EFGH", auth.UserCode)
+	assert.NotEmpty(t, auth.DeviceID)
+	auth.PollInterval = 10 * time.Millisecond
+	require.NoError(t, cli.WaitOAuthDeviceLogin(ctx, auth))
+
+	assert.Equal(t, 0, mi.pendingPolls)
+	assert.Equal(t, auth.DeviceID, cli.DeviceID)
+	assert.Equal(t, id.UserID("@user:example.com"), cli.UserID)
+	assert.Equal(t, "access1", cli.AccessToken)
+}
+
+func TestClient_OAuthRefresh(t *testing.T) {
+	ctx := context.TODO()
+	cli, mi, tokenStore := newOAuthTestClient(t)
+	auth, err := cli.StartOAuthDeviceLogin(ctx, "DEVICE")
+	require.NoError(t, err)
+	auth.PollInterval = time.Millisecond
+	require.NoError(t, cli.WaitOAuthDeviceLogin(ctx, auth))
+
+	// Revoke the current access token, which should make the client transparently refresh it
+	delete(mi.validTokens, cli.AccessToken)
+	resp, err := cli.Whoami(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, id.DeviceID("DEVICE"), resp.DeviceID)
+	assert.Equal(t, "access2", cli.AccessToken)
+	assert.Equal(t, "refresh2", cli.RefreshToken)
+	require.Len(t, tokenStore.saved, 2)
+	assert.Equal(t, "access2", tokenStore.saved[1].AccessToken)
+
+	// Tokens that are about to expire should be refreshed before making the request
+	cli.AccessTokenExpiresAt = time.Now().Add(time.Second)
+	_, err = cli.Whoami(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "access3", cli.AccessToken)
+	assert.True(t, cli.AccessTokenExpiresAt.After(time.Now().Add(time.Minute)))
+
+	// If the refresh token is invalid too, the original error is returned
+	delete(mi.validTokens, cli.AccessToken)
+	delete(mi.refreshTokens, cli.RefreshToken)
+	_, err = cli.Whoami(ctx)
+	assert.ErrorIs(t, err, mautrix.MUnknownToken)
+}
+
+func TestClient_OAuthConcurrentRefresh(t *testing.T) {
+	ctx := context.TODO()
+	cli, mi, tokenStore := newOAuthTestClient(t)
+	auth, err := cli.StartOAuthDeviceLogin(ctx, "DEVICE")
+	require.NoError(t, err)
+	auth.PollInterval = time.Millisecond
+	require.NoError(t, cli.WaitOAuthDeviceLogin(ctx, auth))
+
+	// All requests fail with the revoked token, but only one of them should refresh it
+	delete(mi.validTokens, cli.AccessToken)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cli.Whoami(ctx)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, "access2", cli.AccessToken)
+	assert.Len(t, tokenStore.saved, 2)
+}
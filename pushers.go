@@ -0,0 +1,103 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix
+
+import (
+	"context"
+	"net/http"
+)
+
+type PushFormat string
+
+const (
+	PushFormatDefault     PushFormat = ""
+	PushFormatEventIDOnly PushFormat = "event_id_only"
+)
+
+type PusherData map[string]any
+
+func (pd PusherData) Format() PushFormat {
+	val, _ := pd["format"].(string)
+	return PushFormat(val)
+}
+
+func (pd PusherData) URL() string {
+	val, _ := pd["url"].(string)
+	return val
+}
+
+// ConvertToNotificationData returns a copy of the map with the url and format fields removed.
+func (pd PusherData) ConvertToNotificationData() PusherData {
+	pdCopy := make(PusherData, max(len(pd)-2, 0))
+	for key, value := range pd {
+		if key != "format" && key != "url" {
+			pdCopy[key] = value
+		}
+	}
+	return pdCopy
+}
+
+type PusherKind string
+
+const (
+	PusherKindHTTP  PusherKind = "http"
+	PusherKindEmail PusherKind = "email"
+)
+
+type PusherAppID string
+
+const (
+	PusherAppEmail PusherAppID = "m.email"
+)
+
+type Pusher struct {
+	AppDisplayName    string      `json:"app_display_name"`
+	AppID             PusherAppID `json:"app_id"`
+	Data              PusherData  `json:"data"`
+	DeviceDisplayName string      `json:"device_display_name"`
+	Kind              *PusherKind `json:"kind"`
+	Language          string      `json:"lang"`
+	ProfileTag        string      `json:"profile_tag,omitempty"`
+	PushKey           string      `json:"pushkey"`
+}
+
+// RespPushers is the JSON response for https://spec.matrix.org/v1.12/client-server-api/#get_matrixclientv3pushers
+type RespPushers struct {
+	Pushers []Pusher `json:"pushers"`
+}
+
+// ReqSetPusher is the JSON request for https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3pushersset
+//
+// If Kind is nil, the pusher with the given app ID and push key is deleted.
+type ReqSetPusher struct {
+	Pusher
+	// If true, the homeserver keeps other pushers with the same push key for other users.
+	Append bool `json:"append,omitempty"`
+}
+
+// GetPushers returns the pushers of the current user.
+//
+// See https://spec.matrix.org/v1.12/client-server-api/#get_matrixclientv3pushers
+func (cli *Client) GetPushers(ctx context.Context) (resp *RespPushers, err error) {
+	urlPath := cli.BuildClientURL("v3", "pushers")
+	_, err = cli.MakeRequest(ctx, http.MethodGet, urlPath, nil, &resp)
+	return
+}
+
+// SetPusher creates, updates or deletes a pusher.
+//
+// See https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3pushersset
+func (cli *Client) SetPusher(ctx context.Context, req *ReqSetPusher) error {
+	urlPath := cli.BuildClientURL("v3", "pushers", "set")
+	_, err := cli.MakeRequest(ctx, http.MethodPost, urlPath, req, nil)
+	return err
+}
+
+// DeletePusher deletes the pusher with the given app ID and push key.
+func (cli *Client) DeletePusher(ctx context.Context, appID PusherAppID, pushKey string) error {
+	return cli.SetPusher(ctx, &ReqSetPusher{Pusher: Pusher{AppID: appID, PushKey: pushKey}})
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/util/ptr"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/mockserver"
+)
+
+func TestClient_Pushers(t *testing.T) {
+	ctx := context.Background()
+	server := mockserver.Create(t)
+	alice := server.NewClient(t, ctx, "@alice:localhost", "ALICE")
+
+	resp, err := alice.GetPushers(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Pushers)
+
+	pusher := mautrix.Pusher{
+		AppDisplayName:    "Example",
+		AppID:             "com.example.app",
+		Data:              mautrix.PusherData{"url": "https://push.example.com/_matrix/push/v1/notify"},
+		DeviceDisplayName: "Phone",
+		Kind:              ptr.Ptr(mautrix.PusherKindHTTP),
+		Language:          "en",
+		PushKey:           "abcdef",
+	}
+	require.NoError(t, alice.SetPusher(ctx, &mautrix.ReqSetPusher{Pusher: pusher}))
+	resp, err = alice.GetPushers(ctx)
+	require.NoError(t, err)
+	require.Len(t, resp.Pushers, 1)
+	assert.Equal(t, pusher.AppID, resp.Pushers[0].AppID)
+	assert.Equal(t, "https://push.example.com/_matrix/push/v1/notify", resp.Pushers[0].Data.URL())
+
+	require.NoError(t, alice.DeletePusher(ctx, pusher.AppID, pusher.PushKey))
+	resp, err = alice.GetPushers(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Pushers)
+}
@@ -0,0 +1,366 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package pushrules
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// UnreadCounts contains the locally computed notification counts of a room.
+type UnreadCounts struct {
+	// The number of events that triggered a notification since the user last read the room.
+	Notifications int `json:"notification_count"`
+	// The number of those events that were also highlighted.
+	Highlights int `json:"highlight_count"`
+}
+
+// Evaluator evaluates the push rules of a user against incoming sync events. It tracks the user's push rules from
+// m.push_rules account data, the room state needed for push conditions, and unread counts for each room.
+//
+// To use it with a mautrix.DefaultSyncer, register [Evaluator.HandleEvent] as a global event handler:
+//
+//	evaluator := pushrules.NewEvaluator(client.UserID)
+//	syncer.OnEvent(evaluator.HandleEvent)
+//
+// The handler should be registered before other handlers so that the state is up to date when they run.
+//
+// Each event is only counted once: if the same event is handled again (e.g. a decrypted event redispatched by the
+// crypto helper after the m.room.encrypted event was already handled), the counts are updated with the new actions.
+type Evaluator struct {
+	UserID id.UserID
+	// OnNotification is called for every timeline event that should trigger a notification.
+	// It's only called once per event ID, so a decrypted event won't notify again if the encrypted event already did.
+	OnNotification func(ctx context.Context, evt *event.Event, should PushActionArrayShould)
+	// WaitForDecryption makes the evaluator ignore m.room.encrypted events and only evaluate the decrypted
+	// events dispatched by a crypto helper. Events that fail to decrypt won't be counted at all.
+	WaitForDecryption bool
+
+	lock    sync.RWMutex
+	ruleset *PushRuleset
+	rooms   map[id.RoomID]*evaluatorRoom
+}
+
+type evaluatorRoom struct {
+	ownDisplayname string
+	members        map[id.UserID]event.Membership
+	memberCount    int
+	powerLevels    *event.PowerLevelsEventContent
+	unread         UnreadCounts
+	// pending contains the timeline events since the oldest event included in the unread counts.
+	// It's used to find which counted events a read receipt covers.
+	pending []pendingEvent
+}
+
+type pendingEvent struct {
+	eventID  id.EventID
+	threadID id.EventID
+	should   PushActionArrayShould
+}
+
+var _ PowerLevelfulRoom = (*evaluatorRoom)(nil)
+
+func (room *evaluatorRoom) GetOwnDisplayname() string {
+	return room.ownDisplayname
+}
+
+func (room *evaluatorRoom) GetMemberCount() int {
+	return room.memberCount
+}
+
+func (room *evaluatorRoom) GetPowerLevels() *event.PowerLevelsEventContent {
+	return room.powerLevels
+}
+
+// markReadUpTo removes the counted events up to and including the given event from the unread counts.
+// If threadID is empty, events in all threads are removed, otherwise only events in that thread
+// (or outside threads for [event.ReadReceiptThreadMain]) are.
+// Receipts for events that aren't tracked are ignored, as they point at events before the oldest counted event.
+func (room *evaluatorRoom) markReadUpTo(eventID id.EventID, threadID event.ThreadID) {
+	var mainOnly bool
+	idx := slices.IndexFunc(room.pending, func(pe pendingEvent) bool {
+		return pe.eventID == eventID
+	})
+	if idx < 0 {
+		return
+	}
+	if threadID == event.ReadReceiptThreadMain {
+		threadID = ""
+		mainOnly = true
+	}
+	remaining := room.pending[:0]
+	for i, pe := range room.pending {
+		if i > idx || ((threadID != "" || mainOnly) && pe.threadID != threadID) {
+			remaining = append(remaining, pe)
+		}
+	}
+	room.pending = remaining
+	room.recount()
+}
+
+// recount recalculates the unread counts from the pending events
+// and drops leading events that aren't included in the counts.
+func (room *evaluatorRoom) recount() {
+	room.unread = UnreadCounts{}
+	for len(room.pending) > 0 && !room.pending[0].should.Notify {
+		room.pending = room.pending[1:]
+	}
+	for _, pe := range room.pending {
+		if pe.should.Notify {
+			room.unread.Notifications++
+			if pe.should.Highlight {
+				room.unread.Highlights++
+			}
+		}
+	}
+	if len(room.pending) == 0 {
+		room.pending = nil
+	}
+}
+
+func (room *evaluatorRoom) setMembership(userID id.UserID, membership event.Membership) {
+	prev := room.members[userID]
+	if prev == event.MembershipJoin {
+		room.memberCount--
+	}
+	if membership == event.MembershipJoin {
+		room.memberCount++
+	}
+	if membership == event.MembershipLeave || membership == event.MembershipBan {
+		delete(room.members, userID)
+	} else {
+		room.members[userID] = membership
+	}
+}
+
+// NewEvaluator creates a new push rule evaluator for the given user.
+func NewEvaluator(userID id.UserID) *Evaluator {
+	return &Evaluator{
+		UserID: userID,
+		rooms:  make(map[id.RoomID]*evaluatorRoom),
+	}
+}
+
+// SetRuleset replaces the push rules used by the evaluator.
+// This can be used to set the initial rules fetched with the /pushrules endpoint.
+func (ev *Evaluator) SetRuleset(ruleset *PushRuleset) {
+	ev.lock.Lock()
+	ev.ruleset = ruleset
+	ev.lock.Unlock()
+}
+
+// GetRuleset returns the push rules currently used by the evaluator.
+func (ev *Evaluator) GetRuleset() *PushRuleset {
+	ev.lock.RLock()
+	defer ev.lock.RUnlock()
+	return ev.ruleset
+}
+
+// GetUnreadCounts returns the locally computed unread counts of the given room.
+func (ev *Evaluator) GetUnreadCounts(roomID id.RoomID) UnreadCounts {
+	ev.lock.RLock()
+	defer ev.lock.RUnlock()
+	room, ok := ev.rooms[roomID]
+	if !ok {
+		return UnreadCounts{}
+	}
+	return room.unread
+}
+
+// MarkRead resets the unread counts of the given room. The evaluator automatically updates the counts when it sees
+// a read receipt or a message from the user, so it only needs to be called manually if those aren't synced.
+func (ev *Evaluator) MarkRead(roomID id.RoomID) {
+	ev.lock.Lock()
+	defer ev.lock.Unlock()
+	if room, ok := ev.rooms[roomID]; ok {
+		room.unread = UnreadCounts{}
+		room.pending = nil
+	}
+}
+
+// Evaluate returns the push actions that the user's push rules specify for the given event.
+// Unlike [Evaluator.HandleEvent], this doesn't update any state or unread counts.
+func (ev *Evaluator) Evaluate(evt *event.Event) PushActionArray {
+	ev.lock.RLock()
+	defer ev.lock.RUnlock()
+	return ev.ruleset.GetActions(ev.getRoom(evt.RoomID), evt)
+}
+
+// getRoom returns the tracked state of the given room. The lock must be held when calling this.
+// If the room isn't tracked yet, an empty room is returned without storing it.
+func (ev *Evaluator) getRoom(roomID id.RoomID) *evaluatorRoom {
+	room, ok := ev.rooms[roomID]
+	if !ok {
+		room = &evaluatorRoom{members: make(map[id.UserID]event.Membership)}
+	}
+	return room
+}
+
+// parseContent parses the content of the event if the syncer didn't already do it.
+func parseContent(evt *event.Event) {
+	if evt.Content.Parsed == nil {
+		_ = evt.Content.ParseRaw(evt.Type)
+	}
+}
+
+// HandleEvent processes a single event from a sync response. It matches the EventHandler signature used by
+// mautrix.DefaultSyncer, and expects evt.Mautrix.EventSource to be set like the syncer does.
+func (ev *Evaluator) HandleEvent(ctx context.Context, evt *event.Event) {
+	source := evt.Mautrix.EventSource
+	if source&event.SourceAccountData != 0 && evt.RoomID == "" && evt.Type.Type == event.AccountDataPushRules.Type {
+		ev.handlePushRules(evt)
+		return
+	} else if evt.RoomID == "" {
+		return
+	} else if source&event.SourceLeave != 0 {
+		ev.lock.Lock()
+		delete(ev.rooms, evt.RoomID)
+		ev.lock.Unlock()
+		return
+	} else if source&event.SourceJoin == 0 {
+		return
+	}
+	switch {
+	case source&event.SourceEphemeral != 0:
+		if evt.Type.Type == event.EphemeralEventReceipt.Type {
+			ev.handleReceipt(evt)
+		}
+	case source&event.SourceState != 0:
+		if !evt.Mautrix.IgnoreState {
+			ev.lock.Lock()
+			ev.updateState(evt)
+			ev.lock.Unlock()
+		}
+	case source&event.SourceTimeline != 0:
+		ev.handleTimelineEvent(ctx, evt)
+	}
+}
+
+func (ev *Evaluator) handlePushRules(evt *event.Event) {
+	var ruleset *PushRuleset
+	if content, ok := evt.Content.Parsed.(*EventContent); ok {
+		ruleset = content.Ruleset
+	} else {
+		var err error
+		ruleset, err = EventToPushRules(evt)
+		if err != nil {
+			return
+		}
+	}
+	ev.SetRuleset(ruleset)
+}
+
+func (ev *Evaluator) handleReceipt(evt *event.Event) {
+	parseContent(evt)
+	content, ok := evt.Content.Parsed.(*event.ReceiptEventContent)
+	if !ok {
+		return
+	}
+	ev.lock.Lock()
+	defer ev.lock.Unlock()
+	room, ok := ev.rooms[evt.RoomID]
+	if !ok {
+		return
+	}
+	for eventID, receipts := range *content {
+		for receiptType, userReceipts := range receipts {
+			if receiptType != event.ReceiptTypeRead && receiptType != event.ReceiptTypeReadPrivate {
+				continue
+			}
+			if receipt, ok := userReceipts[ev.UserID]; ok {
+				room.markReadUpTo(eventID, receipt.ThreadID)
+			}
+		}
+	}
+}
+
+// updateState updates the tracked room state with the given state event. The lock must be held when calling this.
+func (ev *Evaluator) updateState(evt *event.Event) {
+	if evt.StateKey == nil {
+		return
+	}
+	switch evt.Type.Type {
+	case event.StateMember.Type:
+		parseContent(evt)
+		room := ev.ensureRoom(evt.RoomID)
+		content := evt.Content.AsMember()
+		room.setMembership(id.UserID(*evt.StateKey), content.Membership)
+		if id.UserID(*evt.StateKey) == ev.UserID {
+			room.ownDisplayname = content.Displayname
+		}
+	case event.StatePowerLevels.Type:
+		parseContent(evt)
+		if content, ok := evt.Content.Parsed.(*event.PowerLevelsEventContent); ok {
+			ev.ensureRoom(evt.RoomID).powerLevels = content
+		}
+	}
+}
+
+// ensureRoom returns the tracked state of the given room, creating it if necessary. The lock must be held when calling this.
+func (ev *Evaluator) ensureRoom(roomID id.RoomID) *evaluatorRoom {
+	room, ok := ev.rooms[roomID]
+	if !ok {
+		room = &evaluatorRoom{members: make(map[id.UserID]event.Membership)}
+		ev.rooms[roomID] = room
+	}
+	return room
+}
+
+// getThreadID returns the thread root of the given event, or an empty string if it isn't in a thread.
+func getThreadID(evt *event.Event) id.EventID {
+	parseContent(evt)
+	if relatable, ok := evt.Content.Parsed.(event.Relatable); ok {
+		return relatable.OptionalGetRelatesTo().GetThreadParent()
+	}
+	return ""
+}
+
+func (ev *Evaluator) handleTimelineEvent(ctx context.Context, evt *event.Event) {
+	if ev.WaitForDecryption && evt.Type == event.EventEncrypted {
+		return
+	}
+	ev.lock.Lock()
+	room := ev.ensureRoom(evt.RoomID)
+	var should PushActionArrayShould
+	alreadyNotified := false
+	if evt.Sender == ev.UserID {
+		// Sending a message implicitly marks the room as read.
+		room.unread = UnreadCounts{}
+		room.pending = nil
+	} else {
+		should = ev.ruleset.GetActions(room, evt).Should()
+		idx := slices.IndexFunc(room.pending, func(pe pendingEvent) bool {
+			return pe.eventID == evt.ID
+		})
+		if idx >= 0 {
+			// The event was already handled (e.g. the encrypted version of a decrypted event),
+			// so replace the old actions instead of counting it twice.
+			alreadyNotified = room.pending[idx].should.Notify
+			room.pending[idx].should = should
+			room.recount()
+		} else if should.Notify || len(room.pending) > 0 {
+			room.pending = append(room.pending, pendingEvent{
+				eventID:  evt.ID,
+				threadID: getThreadID(evt),
+				should:   should,
+			})
+			room.recount()
+		}
+	}
+	// State events in the timeline are evaluated against the state before them.
+	if !evt.Mautrix.IgnoreState {
+		ev.updateState(evt)
+	}
+	ev.lock.Unlock()
+	if should.Notify && !alreadyNotified && ev.OnNotification != nil {
+		ev.OnNotification(ctx, evt, should)
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package pushrules_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+	"github.com/iKonoTelecomunicaciones/go/pushrules"
+)
+
+const evaluatorTestRoom = id.RoomID("!room:example.com")
+
+var evaluatorTestEventCounter int
+
+func makeSyncEvent(t *testing.T, source event.Source, sender id.UserID, evtType event.Type, stateKey *string, content string) *event.Event {
+	t.Helper()
+	evaluatorTestEventCounter++
+	evt := &event.Event{
+		Sender:   sender,
+		Type:     evtType,
+		StateKey: stateKey,
+		ID:       id.EventID(fmt.Sprintf("$%s-%d", t.Name(), evaluatorTestEventCounter)),
+	}
+	if source&event.SourceAccountData == 0 || source&event.SourceJoin != 0 {
+		evt.RoomID = evaluatorTestRoom
+	}
+	evt.Mautrix.EventSource = source
+	require.NoError(t, json.Unmarshal([]byte(content), &evt.Content))
+	return evt
+}
+
+func makeMemberEvent(t *testing.T, userID id.UserID, membership event.Membership, displayname string) *event.Event {
+	content, err := json.Marshal(&event.MemberEventContent{Membership: membership, Displayname: displayname})
+	require.NoError(t, err)
+	stateKey := userID.String()
+	return makeSyncEvent(t, event.SourceJoin|event.SourceState, userID, event.StateMember, &stateKey, string(content))
+}
+
+func makeMessageEvent(t *testing.T, sender id.UserID, msgtype event.MessageType, body string) *event.Event {
+	content, err := json.Marshal(&event.MessageEventContent{MsgType: msgtype, Body: body})
+	require.NoError(t, err)
+	return makeSyncEvent(t, event.SourceJoin|event.SourceTimeline, sender, event.EventMessage, nil, string(content))
+}
+
+func makeReceiptEvent(t *testing.T, eventID id.EventID, userID id.UserID, threadID event.ThreadID) *event.Event {
+	content, err := json.Marshal(&event.ReceiptEventContent{
+		eventID: {event.ReceiptTypeRead: {userID: {Timestamp: time.Now(), ThreadID: threadID}}},
+	})
+	require.NoError(t, err)
+	return makeSyncEvent(t, event.SourceJoin|event.SourceEphemeral, "", event.EphemeralEventReceipt, nil, string(content))
+}
+
+func TestEvaluator(t *testing.T) {
+	ctx := context.Background()
+	alice := id.UserID("@alice:example.com")
+	bob := id.UserID("@bob:example.com")
+	carol := id.UserID("@carol:example.com")
+
+	ev := pushrules.NewEvaluator(alice)
+	var notified []string
+	ev.OnNotification = func(ctx context.Context, evt *event.Event, should pushrules.PushActionArrayShould) {
+		if evt.Type == event.EventMessage {
+			notified = append(notified, evt.Content.Raw["body"].(string))
+		}
+	}
+
+	// Nothing notifies before the push rules are known.
+	ev.HandleEvent(ctx, makeMessageEvent(t, bob, event.MsgText, "Hello"))
+	assert.Equal(t, pushrules.UnreadCounts{}, ev.GetUnreadCounts(evaluatorTestRoom))
+
+	ev.HandleEvent(ctx, makeSyncEvent(t, event.SourceAccountData, "", event.AccountDataPushRules, nil, JSONExamplePushRules))
+	require.NotNil(t, ev.GetRuleset())
+	ev.HandleEvent(ctx, makeMemberEvent(t, alice, event.MembershipJoin, "Alice Liddell"))
+	ev.HandleEvent(ctx, makeMemberEvent(t, bob, event.MembershipJoin, "Bob"))
+	ev.HandleEvent(ctx, makeMemberEvent(t, carol, event.MembershipJoin, "Carol"))
+
+	ev.HandleEvent(ctx, makeMessageEvent(t, bob, event.MsgText, "Hello"))
+	ev.HandleEvent(ctx, makeMessageEvent(t, bob, event.MsgText, "Hi Alice Liddell!"))
+	notice := makeMessageEvent(t, bob, event.MsgNotice, "Bot says hi")
+	ev.HandleEvent(ctx, notice)
+	assert.Equal(t, pushrules.UnreadCounts{Notifications: 2, Highlights: 1}, ev.GetUnreadCounts(evaluatorTestRoom))
+	assert.Equal(t, []string{"Hello", "Hi Alice Liddell!"}, notified)
+
+	// A read receipt from the user resets the counts.
+	ev.HandleEvent(ctx, makeReceiptEvent(t, notice.ID, alice, ""))
+	assert.Equal(t, pushrules.UnreadCounts{}, ev.GetUnreadCounts(evaluatorTestRoom))
+
+	// Receipts from other users don't.
+	ev.HandleEvent(ctx, makeMessageEvent(t, bob, event.MsgText, "Are you there?"))
+	ev.HandleEvent(ctx, makeReceiptEvent(t, notice.ID, carol, ""))
+	assert.Equal(t, pushrules.UnreadCounts{Notifications: 1}, ev.GetUnreadCounts(evaluatorTestRoom))
+
+	// Receipts for events before the counted ones don't either.
+	ev.HandleEvent(ctx, makeReceiptEvent(t, notice.ID, alice, ""))
+	assert.Equal(t, pushrules.UnreadCounts{Notifications: 1}, ev.GetUnreadCounts(evaluatorTestRoom))
+
+	// Sending a message marks the room as read.
+	ev.HandleEvent(ctx, makeMessageEvent(t, alice, event.MsgText, "Yes"))
+	assert.Equal(t, pushrules.UnreadCounts{}, ev.GetUnreadCounts(evaluatorTestRoom))
+
+	// Member events in the timeline notify and update the member count used by the one-to-one rule.
+	carolLeave := makeMemberEvent(t, carol, event.MembershipLeave, "")
+	carolLeave.Mautrix.EventSource = event.SourceJoin | event.SourceTimeline
+	ev.HandleEvent(ctx, carolLeave)
+	assert.Equal(t, pushrules.UnreadCounts{Notifications: 1}, ev.GetUnreadCounts(evaluatorTestRoom))
+	should := ev.Evaluate(makeMessageEvent(t, bob, event.MsgText, "Just us now")).Should()
+	assert.True(t, should.Notify)
+	assert.Equal(t, "default", should.SoundName)
+
+	// Disabling a rule via account data takes effect immediately.
+	var rules pushrules.EventContent
+	require.NoError(t, json.Unmarshal([]byte(JSONExamplePushRules), &rules))
+	rules.Ruleset.Override[0].Enabled = true
+	rulesJSON, err := json.Marshal(&rules)
+	require.NoError(t, err)
+	ev.HandleEvent(ctx, makeSyncEvent(t, event.SourceAccountData, "", event.AccountDataPushRules, nil, string(rulesJSON)))
+	assert.False(t, ev.Evaluate(makeMessageEvent(t, bob, event.MsgText, "Hi Alice Liddell")).Should().Notify)
+}
+
+func TestEvaluator_EncryptedThenDecrypted(t *testing.T) {
+	ctx := context.Background()
+	alice := id.UserID("@alice:example.com")
+	bob := id.UserID("@bob:example.com")
+
+	ev := pushrules.NewEvaluator(alice)
+	var notified []event.Type
+	ev.OnNotification = func(ctx context.Context, evt *event.Event, should pushrules.PushActionArrayShould) {
+		notified = append(notified, evt.Type)
+	}
+	ev.HandleEvent(ctx, makeSyncEvent(t, event.SourceAccountData, "", event.AccountDataPushRules, nil, JSONExamplePushRules))
+	ev.HandleEvent(ctx, makeMemberEvent(t, alice, event.MembershipJoin, "Alice Liddell"))
+	ev.HandleEvent(ctx, makeMemberEvent(t, bob, event.MembershipJoin, "Bob"))
+
+	dispatch := func(body string) {
+		encrypted := makeSyncEvent(t, event.SourceJoin|event.SourceTimeline, bob, event.EventEncrypted, nil,
+			`{"algorithm": "m.megolm.v1.aes-sha2", "ciphertext": "meow", "session_id": "session", "device_id": "device"}`)
+		ev.HandleEvent(ctx, encrypted)
+		decrypted := makeMessageEvent(t, bob, event.MsgText, body)
+		decrypted.ID = encrypted.ID
+		decrypted.Mautrix.EventSource |= event.SourceDecrypted
+		ev.HandleEvent(ctx, decrypted)
+	}
+
+	dispatch("Hello")
+	assert.Equal(t, pushrules.UnreadCounts{Notifications: 1}, ev.GetUnreadCounts(evaluatorTestRoom))
+	// The decrypted event only updates the actions of the already counted encrypted event
+	dispatch("Hi Alice Liddell!")
+	assert.Equal(t, pushrules.UnreadCounts{Notifications: 2, Highlights: 1}, ev.GetUnreadCounts(evaluatorTestRoom))
+	assert.Equal(t, []event.Type{event.EventEncrypted, event.EventEncrypted}, notified)
+
+	// With WaitForDecryption, only the decrypted event is evaluated
+	ev.MarkRead(evaluatorTestRoom)
+	notified = nil
+	ev.WaitForDecryption = true
+	dispatch("Hello again")
+	assert.Equal(t, pushrules.UnreadCounts{Notifications: 1}, ev.GetUnreadCounts(evaluatorTestRoom))
+	assert.Equal(t, []event.Type{event.EventMessage}, notified)
+}
+
+func TestEvaluator_PartialRead(t *testing.T) {
+	ctx := context.Background()
+	alice := id.UserID("@alice:example.com")
+	bob := id.UserID("@bob:example.com")
+
+	ev := pushrules.NewEvaluator(alice)
+	ev.HandleEvent(ctx, makeSyncEvent(t, event.SourceAccountData, "", event.AccountDataPushRules, nil, JSONExamplePushRules))
+	ev.HandleEvent(ctx, makeMemberEvent(t, alice, event.MembershipJoin, "Alice Liddell"))
+	ev.HandleEvent(ctx, makeMemberEvent(t, bob, event.MembershipJoin, "Bob"))
+
+	first := makeMessageEvent(t, bob, event.MsgText, "Hello")
+	ev.HandleEvent(ctx, first)
+	threadRoot := makeMessageEvent(t, bob, event.MsgText, "Hi Alice Liddell!")
+	ev.HandleEvent(ctx, threadRoot)
+	threadContent, err := json.Marshal(&event.MessageEventContent{
+		MsgType:   event.MsgText,
+		Body:      "In a thread",
+		RelatesTo: (&event.RelatesTo{}).SetThread(threadRoot.ID, threadRoot.ID),
+	})
+	require.NoError(t, err)
+	threadReply := makeSyncEvent(t, event.SourceJoin|event.SourceTimeline, bob, event.EventMessage, nil, string(threadContent))
+	ev.HandleEvent(ctx, threadReply)
+	last := makeMessageEvent(t, bob, event.MsgText, "Bye")
+	ev.HandleEvent(ctx, last)
+	assert.Equal(t, pushrules.UnreadCounts{Notifications: 4, Highlights: 1}, ev.GetUnreadCounts(evaluatorTestRoom))
+
+	// Reading the first message only removes that one from the counts
+	ev.HandleEvent(ctx, makeReceiptEvent(t, first.ID, alice, ""))
+	assert.Equal(t, pushrules.UnreadCounts{Notifications: 3, Highlights: 1}, ev.GetUnreadCounts(evaluatorTestRoom))
+	// Reading the main timeline leaves the thread unread
+	ev.HandleEvent(ctx, makeReceiptEvent(t, last.ID, alice, event.ReadReceiptThreadMain))
+	assert.Equal(t, pushrules.UnreadCounts{Notifications: 1}, ev.GetUnreadCounts(evaluatorTestRoom))
+	// Reading the thread clears the rest
+	ev.HandleEvent(ctx, makeReceiptEvent(t, threadReply.ID, alice, threadRoot.ID))
+	assert.Equal(t, pushrules.UnreadCounts{}, ev.GetUnreadCounts(evaluatorTestRoom))
+}
@@ -34,64 +34,25 @@ const (
 	PushPriorityLow  PushPriority = "low"
 )
 
-type PushFormat string
-
-const (
-	PushFormatDefault     PushFormat = ""
-	PushFormatEventIDOnly PushFormat = "event_id_only"
+type (
+	PushFormat  = mautrix.PushFormat
+	PusherData  = mautrix.PusherData
+	PusherKind  = mautrix.PusherKind
+	PusherAppID = mautrix.PusherAppID
+	Pusher      = mautrix.Pusher
+	RespPushers = mautrix.RespPushers
 )
 
-type PusherData map[string]any
-
-func (pd PusherData) Format() PushFormat {
-	val, _ := pd["format"].(string)
-	return PushFormat(val)
-}
-
-func (pd PusherData) URL() string {
-	val, _ := pd["url"].(string)
-	return val
-}
-
-// ConvertToNotificationData returns a copy of the map with the url and format fields removed.
-func (pd PusherData) ConvertToNotificationData() PusherData {
-	pdCopy := make(PusherData, len(pd)-2)
-	for key, value := range pd {
-		if key != "format" && key != "url" {
-			pdCopy[key] = value
-		}
-	}
-	return pdCopy
-}
-
-type PusherKind string
-
 const (
-	PusherKindHTTP  PusherKind = "http"
-	PusherKindEmail PusherKind = "email"
-)
+	PushFormatDefault     = mautrix.PushFormatDefault
+	PushFormatEventIDOnly = mautrix.PushFormatEventIDOnly
 
-type PusherAppID string
+	PusherKindHTTP  = mautrix.PusherKindHTTP
+	PusherKindEmail = mautrix.PusherKindEmail
 
-const (
-	PusherAppEmail PusherAppID = "m.email"
+	PusherAppEmail = mautrix.PusherAppEmail
 )
 
-type Pusher struct {
-	AppDisplayName    string      `json:"app_display_name"`
-	AppID             PusherAppID `json:"app_id"`
-	Data              PusherData  `json:"data"`
-	DeviceDisplayName string      `json:"device_display_name"`
-	Kind              *PusherKind `json:"kind"`
-	Language          string      `json:"lang"`
-	ProfileTag        string      `json:"profile_tag,omitempty"`
-	PushKey           string      `json:"pushkey"`
-}
-
-type RespPushers struct {
-	Pushers []Pusher `json:"pushers"`
-}
-
 type BaseDevice struct {
 	AppID     PusherAppID `json:"app_id"`
 	PushKey   string      `json:"pushkey"`
@@ -168,6 +129,10 @@ func (rp *RespPush) Is(other error) bool {
 }
 
 func (pn *PushNotification) Push(ctx context.Context, url string) error {
+	return pn.push(ctx, http.DefaultClient, url, nil)
+}
+
+func (pn *PushNotification) push(ctx context.Context, client *http.Client, url string, headers http.Header) error {
 	payload, err := json.Marshal(&ReqPush{Notification: pn})
 	if err != nil {
 		return fmt.Errorf("failed to marshal payload: %w", err)
@@ -176,10 +141,13 @@ func (pn *PushNotification) Push(ctx context.Context, url string) error {
 	if err != nil {
 		return fmt.Errorf("failed to prepare push request: %w", err)
 	}
+	for key, values := range headers {
+		req.Header[key] = values
+	}
 	req.Header.Set("User-Agent", mautrix.DefaultUserAgent+" (notification pusher)")
 	req.Header.Set("Content-Type", "application/json")
 	var respData RespPush
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send push request: %w", err)
 	} else if body, err := io.ReadAll(resp.Body); err != nil {
@@ -0,0 +1,185 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package pushgateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"syscall"
+	"time"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+)
+
+// ErrEndpointAddressNotAllowed is returned when a push endpoint resolves to a loopback, private or otherwise
+// non-public address. Requests are refused with it by the client returned by [NewEndpointClient].
+var ErrEndpointAddressNotAllowed = errors.New("push endpoint address is not allowed")
+
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+func checkEndpointAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	} else if !isPublicAddr(addr) {
+		return fmt.Errorf("%w: %s", ErrEndpointAddressNotAllowed, addr)
+	}
+	return nil
+}
+
+// NewEndpointClient creates a HTTP client for sending requests to push endpoints that come from pusher data.
+//
+// The notify endpoint is unauthenticated, so anyone who can reach the gateway can choose the endpoint URLs.
+// To prevent using the gateway to send requests to internal services, the client refuses to connect to
+// loopback, private, link-local and other non-public addresses. The check is done after DNS resolution
+// and applies to redirects too. Environment proxy settings are ignored.
+func NewEndpointClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   checkEndpointAddress,
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}
+}
+
+var defaultEndpointClient = NewEndpointClient()
+
+// WebhookProvider forwards notifications as push gateway API requests to a fixed URL,
+// such as another push gateway or a custom backend.
+type WebhookProvider struct {
+	URL string
+	// The HTTP client to use. Defaults to [http.DefaultClient].
+	Client *http.Client
+	// Extra headers to include in requests, e.g. for authentication.
+	Headers http.Header
+}
+
+var _ Provider = (*WebhookProvider)(nil)
+
+func (wp *WebhookProvider) Push(ctx context.Context, device *Device, notification *PushNotification) error {
+	client := wp.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	err := notification.push(ctx, client, wp.URL, wp.Headers)
+	var rejected *RespPush
+	if errors.As(err, &rejected) && slices.Contains(rejected.Rejected, device.PushKey) {
+		return fmt.Errorf("%w: %w", ErrPushKeyRejected, err)
+	}
+	return err
+}
+
+// UnifiedPushProvider delivers notifications to [UnifiedPush] endpoints. The push key of the device is the endpoint URL,
+// and the push gateway request body is posted to it as-is, like the UnifiedPush Matrix gateway specifies.
+//
+// [UnifiedPush]: https://unifiedpush.org/developers/gateway/
+type UnifiedPushProvider struct {
+	// The HTTP client to use. Defaults to a client created with [NewEndpointClient],
+	// which only connects to public addresses.
+	Client *http.Client
+	// If set, only endpoints on these hosts are accepted. Other push keys are rejected.
+	AllowedHosts []string
+	// If true, plain HTTP endpoints are allowed in addition to HTTPS.
+	AllowInsecure bool
+}
+
+var _ Provider = (*UnifiedPushProvider)(nil)
+
+func (up *UnifiedPushProvider) validateEndpoint(endpoint string) error {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("%w: invalid endpoint URL: %w", ErrPushKeyRejected, err)
+	} else if parsed.Scheme != "https" && (parsed.Scheme != "http" || !up.AllowInsecure) {
+		return fmt.Errorf("%w: unsupported endpoint URL scheme %q", ErrPushKeyRejected, parsed.Scheme)
+	} else if len(up.AllowedHosts) > 0 && !slices.Contains(up.AllowedHosts, parsed.Hostname()) {
+		return fmt.Errorf("%w: endpoint host %q is not allowed", ErrPushKeyRejected, parsed.Hostname())
+	}
+	return nil
+}
+
+func (up *UnifiedPushProvider) Push(ctx context.Context, device *Device, notification *PushNotification) error {
+	if err := up.validateEndpoint(device.PushKey); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(&ReqPush{Notification: notification})
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, device.PushKey, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to prepare push request: %w", err)
+	}
+	req.Header.Set("User-Agent", mautrix.DefaultUserAgent+" (push gateway)")
+	req.Header.Set("Content-Type", "application/json")
+	client := up.Client
+	if client == nil {
+		client = defaultEndpointClient
+	}
+	return doEndpointRequest(client, req)
+}
+
+// doEndpointRequest sends a request to a push service endpoint. 404 and 410 responses mean that
+// the subscription is gone, so they're returned as [ErrPushKeyRejected], as are endpoints with non-public addresses.
+func doEndpointRequest(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if errors.Is(err, ErrEndpointAddressNotAllowed) {
+		return fmt.Errorf("%w: %w", ErrPushKeyRejected, err)
+	} else if err != nil {
+		return fmt.Errorf("failed to send push request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, bytes.ReplaceAll(body, []byte("\n"), []byte("\\n")))
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return fmt.Errorf("%w: %w", ErrPushKeyRejected, err)
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package pushgateway
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is a simple fixed window rate limiter keyed by push key.
+type rateLimiter struct {
+	limit  int
+	period time.Duration
+
+	lock      sync.Mutex
+	windows   map[string]*rateLimitWindow
+	lastSweep time.Time
+}
+
+type rateLimitWindow struct {
+	start time.Time
+	count int
+}
+
+func newRateLimiter(limit int, period time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:     limit,
+		period:    period,
+		windows:   make(map[string]*rateLimitWindow),
+		lastSweep: time.Now(),
+	}
+}
+
+func (rl *rateLimiter) allow(key string) bool {
+	now := time.Now()
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+	if now.Sub(rl.lastSweep) > rl.period {
+		// Drop expired windows so that the map doesn't grow forever.
+		for windowKey, window := range rl.windows {
+			if now.Sub(window.start) > rl.period {
+				delete(rl.windows, windowKey)
+			}
+		}
+		rl.lastSweep = now
+	}
+	window, ok := rl.windows[key]
+	if !ok || now.Sub(window.start) > rl.period {
+		window = &rateLimitWindow{start: now}
+		rl.windows[key] = window
+	}
+	if window.count >= rl.limit {
+		return false
+	}
+	window.count++
+	return true
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package pushgateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mau.fi/util/exhttp"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+)
+
+// NotifyPath is the path of the push gateway notify endpoint.
+const NotifyPath = "/_matrix/push/v1/notify"
+
+// DefaultPushTimeout is the default timeout for delivering a notification to all of its devices.
+const DefaultPushTimeout = 30 * time.Second
+
+// DefaultMaxConcurrentPushes is the default number of devices a single notification is delivered to in parallel.
+const DefaultMaxConcurrentPushes = 8
+
+// DefaultMaxRequestSize is the default maximum size of a notify request body in bytes.
+const DefaultMaxRequestSize = 8 * 1024
+
+// ErrPushKeyRejected should be returned (possibly wrapped) by providers when a push key is permanently invalid.
+// The key is then included in the rejected list of the response, which tells the homeserver to remove the pusher.
+var ErrPushKeyRejected = errors.New("push key rejected")
+
+// Provider delivers notifications to devices using a specific push service.
+type Provider interface {
+	// Push delivers the notification to a single device.
+	// The Devices field of the notification only contains the same device.
+	Push(ctx context.Context, device *Device, notification *PushNotification) error
+}
+
+// AppConfig is the configuration of a single app ID in a push gateway [Server].
+type AppConfig struct {
+	Provider Provider
+	// The maximum number of notifications to deliver to a single push key in RateLimitPeriod.
+	// Notifications over the limit are dropped without rejecting the key. Zero disables rate limiting.
+	RateLimit       int
+	RateLimitPeriod time.Duration
+}
+
+type registeredApp struct {
+	*AppConfig
+	limiter *rateLimiter
+}
+
+// Server is a push gateway that receives notifications from homeservers and delivers them using the provider
+// configured for the app ID of each device.
+type Server struct {
+	Log zerolog.Logger
+	// The timeout for delivering a notification to all of its devices. Defaults to [DefaultPushTimeout].
+	PushTimeout time.Duration
+	// The maximum number of devices to deliver a single notification to in parallel.
+	// Defaults to [DefaultMaxConcurrentPushes].
+	MaxConcurrentPushes int
+	// The maximum size of a notify request body in bytes. Defaults to [DefaultMaxRequestSize].
+	// Larger requests are rejected with M_TOO_LARGE.
+	MaxRequestSize int64
+	// OnRejected is called when a push key is rejected, either because the app ID is unknown,
+	// or because the provider returned [ErrPushKeyRejected]. It's called after delivery to all devices is done.
+	OnRejected func(ctx context.Context, device *Device, err error)
+
+	appsLock sync.RWMutex
+	apps     map[PusherAppID]*registeredApp
+}
+
+// NewServer creates a new push gateway server with no apps registered.
+func NewServer(log zerolog.Logger) *Server {
+	return &Server{
+		Log:  log,
+		apps: make(map[PusherAppID]*registeredApp),
+	}
+}
+
+// RegisterApp sets the configuration for the given app ID, replacing any previous configuration.
+func (srv *Server) RegisterApp(appID PusherAppID, cfg *AppConfig) {
+	app := &registeredApp{AppConfig: cfg}
+	if cfg.RateLimit > 0 && cfg.RateLimitPeriod > 0 {
+		app.limiter = newRateLimiter(cfg.RateLimit, cfg.RateLimitPeriod)
+	}
+	srv.appsLock.Lock()
+	srv.apps[appID] = app
+	srv.appsLock.Unlock()
+}
+
+// UnregisterApp removes the given app ID. Push keys of unregistered apps are rejected.
+func (srv *Server) UnregisterApp(appID PusherAppID) {
+	srv.appsLock.Lock()
+	delete(srv.apps, appID)
+	srv.appsLock.Unlock()
+}
+
+func (srv *Server) getApp(appID PusherAppID) *registeredApp {
+	srv.appsLock.RLock()
+	defer srv.appsLock.RUnlock()
+	return srv.apps[appID]
+}
+
+// Handler returns a HTTP handler that serves the push gateway API.
+func (srv *Server) Handler() http.Handler {
+	router := http.NewServeMux()
+	router.HandleFunc("POST "+NotifyPath, srv.PostNotify)
+	return router
+}
+
+// PostNotify handles a request to the notify endpoint.
+//
+// See https://spec.matrix.org/v1.12/push-gateway-api/#post_matrixpushv1notify
+func (srv *Server) PostNotify(w http.ResponseWriter, r *http.Request) {
+	maxSize := srv.MaxRequestSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxRequestSize
+	}
+	var req ReqPush
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSize)).Decode(&req)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		mautrix.MTooLarge.WithMessage("Request body is too large").Write(w)
+		return
+	} else if err != nil {
+		mautrix.MNotJSON.WithMessage("Failed to decode request body").Write(w)
+		return
+	} else if req.Notification == nil {
+		mautrix.MBadJSON.WithMessage("Missing notification").Write(w)
+		return
+	}
+	exhttp.WriteJSONResponse(w, http.StatusOK, srv.Notify(r.Context(), req.Notification))
+}
+
+// Notify delivers the notification to all devices in it and returns the list of rejected push keys.
+// Devices are pushed to concurrently, and the whole delivery is limited by PushTimeout.
+//
+// Delivery errors other than [ErrPushKeyRejected] are only logged,
+// as the homeserver doesn't retry notifications that the gateway accepted.
+func (srv *Server) Notify(ctx context.Context, notification *PushNotification) *RespPush {
+	timeout := srv.PushTimeout
+	if timeout <= 0 {
+		timeout = DefaultPushTimeout
+	}
+	maxConcurrent := srv.MaxConcurrentPushes
+	if maxConcurrent <= 0 {
+		maxConcurrent = DefaultMaxConcurrentPushes
+	}
+	pushCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	errs := make([]error, len(notification.Devices))
+	sema := make(chan struct{}, maxConcurrent)
+	var wg sync.WaitGroup
+	for i, device := range notification.Devices {
+		log := srv.Log.With().
+			Str("app_id", string(device.AppID)).
+			Stringer("event_id", notification.EventID).
+			Logger()
+		app := srv.getApp(device.AppID)
+		if app == nil {
+			log.Debug().Msg("Rejecting push key of unknown app")
+			errs[i] = ErrPushKeyRejected
+			continue
+		} else if app.limiter != nil && !app.limiter.allow(device.PushKey) {
+			log.Debug().Msg("Dropping notification to rate limited push key")
+			continue
+		}
+		sema <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sema
+				wg.Done()
+			}()
+			err := srv.pushToDevice(pushCtx, app.Provider, device, notification)
+			if errors.Is(err, ErrPushKeyRejected) {
+				log.Debug().Err(err).Msg("Provider rejected push key")
+			} else if err != nil {
+				log.Err(err).Msg("Failed to deliver notification")
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+	resp := &RespPush{Rejected: []string{}}
+	for i, err := range errs {
+		if errors.Is(err, ErrPushKeyRejected) {
+			srv.reject(ctx, resp, &notification.Devices[i], err)
+		}
+	}
+	return resp
+}
+
+func (srv *Server) pushToDevice(ctx context.Context, provider Provider, device Device, notification *PushNotification) error {
+	deviceNotification := *notification
+	deviceNotification.Devices = []Device{device}
+	return provider.Push(ctx, &device, &deviceNotification)
+}
+
+func (srv *Server) reject(ctx context.Context, resp *RespPush, device *Device, err error) {
+	resp.Rejected = append(resp.Rejected, device.PushKey)
+	if srv.OnRejected != nil {
+		srv.OnRejected(ctx, device, err)
+	}
+}
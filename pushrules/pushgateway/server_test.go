@@ -0,0 +1,243 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package pushgateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+type recordingProvider struct {
+	lock     sync.Mutex
+	pushed   []string
+	rejected map[string]bool
+}
+
+func (rp *recordingProvider) Push(ctx context.Context, device *Device, notification *PushNotification) error {
+	rp.lock.Lock()
+	defer rp.lock.Unlock()
+	if rp.rejected[device.PushKey] {
+		return fmt.Errorf("%w: unregistered", ErrPushKeyRejected)
+	} else if len(notification.Devices) != 1 || notification.Devices[0].PushKey != device.PushKey {
+		return fmt.Errorf("unexpected devices in notification")
+	}
+	rp.pushed = append(rp.pushed, device.PushKey)
+	return nil
+}
+
+func TestServer_Notify(t *testing.T) {
+	provider := &recordingProvider{rejected: map[string]bool{"gone": true}}
+	srv := NewServer(zerolog.Nop())
+	srv.RegisterApp("com.example.app", &AppConfig{Provider: provider, RateLimit: 2, RateLimitPeriod: time.Hour})
+	var rejectedByCallback []string
+	srv.OnRejected = func(ctx context.Context, device *Device, err error) {
+		rejectedByCallback = append(rejectedByCallback, device.PushKey)
+	}
+	server := httptest.NewServer(srv.Handler())
+	defer server.Close()
+
+	notification := &PushNotification{
+		EventID: "$event",
+		RoomID:  "!room:example.com",
+		Devices: []Device{
+			{BaseDevice: BaseDevice{AppID: "com.example.app", PushKey: "valid"}},
+			{BaseDevice: BaseDevice{AppID: "com.example.app", PushKey: "gone"}},
+			{BaseDevice: BaseDevice{AppID: "com.example.unknown", PushKey: "unknown"}},
+		},
+	}
+	err := notification.Push(context.Background(), server.URL+NotifyPath)
+	require.ErrorIs(t, err, &RespPush{Rejected: []string{"gone", "unknown"}})
+	assert.Equal(t, []string{"gone", "unknown"}, rejectedByCallback)
+	assert.Equal(t, []string{"valid"}, provider.pushed)
+
+	// The third notification to the same push key is dropped by the rate limiter, but the key isn't rejected.
+	single := &PushNotification{EventID: "$event2", Devices: notification.Devices[:1]}
+	require.NoError(t, single.Push(context.Background(), server.URL+NotifyPath))
+	require.NoError(t, single.Push(context.Background(), server.URL+NotifyPath))
+	assert.Equal(t, []string{"valid", "valid"}, provider.pushed)
+
+	resp, err := http.Post(server.URL+NotifyPath, "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	large := fmt.Sprintf(`{"notification":{"event_id":"$event","content":{"body":"%s"}}}`, strings.Repeat("a", DefaultMaxRequestSize))
+	resp, err = http.Post(server.URL+NotifyPath, "application/json", strings.NewReader(large))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
+
+type barrierProvider struct {
+	waiting  sync.WaitGroup
+	released chan struct{}
+	lock     sync.Mutex
+	errs     []error
+}
+
+func (bp *barrierProvider) Push(ctx context.Context, device *Device, notification *PushNotification) error {
+	bp.waiting.Done()
+	var err error
+	select {
+	case <-bp.released:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	bp.lock.Lock()
+	bp.errs = append(bp.errs, err)
+	bp.lock.Unlock()
+	return err
+}
+
+func TestServer_NotifyConcurrent(t *testing.T) {
+	provider := &barrierProvider{released: make(chan struct{})}
+	provider.waiting.Add(3)
+	go func() {
+		// Only release the pushes once all of them are in progress at the same time
+		provider.waiting.Wait()
+		close(provider.released)
+	}()
+	srv := NewServer(zerolog.Nop())
+	srv.PushTimeout = 5 * time.Second
+	srv.RegisterApp("com.example.app", &AppConfig{Provider: provider})
+	notification := &PushNotification{EventID: "$event", Devices: []Device{
+		{BaseDevice: BaseDevice{AppID: "com.example.app", PushKey: "one"}},
+		{BaseDevice: BaseDevice{AppID: "com.example.app", PushKey: "two"}},
+		{BaseDevice: BaseDevice{AppID: "com.example.app", PushKey: "three"}},
+	}}
+	resp := srv.Notify(context.Background(), notification)
+	assert.Empty(t, resp.Rejected)
+	assert.Equal(t, []error{nil, nil, nil}, provider.errs)
+
+	// Pushes that never finish are all cancelled by the overall deadline
+	provider = &barrierProvider{released: make(chan struct{})}
+	provider.waiting.Add(3)
+	srv.RegisterApp("com.example.app", &AppConfig{Provider: provider})
+	srv.PushTimeout = 100 * time.Millisecond
+	start := time.Now()
+	srv.Notify(context.Background(), notification)
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Len(t, provider.errs, 3)
+	for _, err := range provider.errs {
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+}
+
+func TestUnifiedPushProvider_PrivateAddress(t *testing.T) {
+	var called bool
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer endpoint.Close()
+
+	provider := &UnifiedPushProvider{AllowInsecure: true}
+	device := &Device{BaseDevice: BaseDevice{AppID: "com.example.up", PushKey: endpoint.URL + "/push"}}
+	err := provider.Push(context.Background(), device, &PushNotification{Devices: []Device{*device}})
+	assert.ErrorIs(t, err, ErrPushKeyRejected)
+	assert.ErrorIs(t, err, ErrEndpointAddressNotAllowed)
+	assert.False(t, called)
+
+	for addr, public := range map[string]bool{
+		"127.0.0.1": false, "10.1.2.3": false, "172.16.0.1": false, "192.168.1.1": false, "169.254.169.254": false,
+		"100.64.0.1": false, "0.0.0.0": false, "::1": false, "fe80::1": false, "fd00::1": false, "::ffff:127.0.0.1": false,
+		"1.1.1.1": true, "2606:4700::1111": true,
+	} {
+		assert.Equal(t, public, isPublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func decryptWebPush(t *testing.T, body []byte, userAgentKey *ecdh.PrivateKey, authSecret []byte) []byte {
+	t.Helper()
+	require.Greater(t, len(body), 21)
+	salt := body[:16]
+	assert.Equal(t, uint32(webPushRecordSize), binary.BigEndian.Uint32(body[16:20]))
+	keyLen := int(body[20])
+	serverKey, err := ecdh.P256().NewPublicKey(body[21 : 21+keyLen])
+	require.NoError(t, err)
+	cek, nonce, err := deriveWebPushKeys(userAgentKey, serverKey, serverKey, userAgentKey.PublicKey(), authSecret, salt)
+	require.NoError(t, err)
+	block, err := aes.NewCipher(cek)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	plaintext, err := gcm.Open(nil, nonce, body[21+keyLen:], nil)
+	require.NoError(t, err)
+	require.Equal(t, byte(0x02), plaintext[len(plaintext)-1])
+	return plaintext[:len(plaintext)-1]
+}
+
+func TestWebPushProvider_Push(t *testing.T) {
+	vapidKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	userAgentKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	authSecret := make([]byte, 16)
+	_, err = rand.Read(authSecret)
+	require.NoError(t, err)
+
+	var received *ReqPush
+	endpoint := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		assert.Equal(t, "aes128gcm", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "high", r.Header.Get("Urgency"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "vapid t="))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		received = &ReqPush{}
+		require.NoError(t, json.Unmarshal(decryptWebPush(t, body, userAgentKey, authSecret), received))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer endpoint.Close()
+
+	provider := &WebPushProvider{VAPIDKey: vapidKey, Subject: "mailto:admin@example.com", Client: endpoint.Client()}
+	device := &Device{BaseDevice: BaseDevice{
+		AppID:   "com.example.web",
+		PushKey: b64.EncodeToString(userAgentKey.PublicKey().Bytes()),
+		Data:    PusherData{"endpoint": endpoint.URL + "/push", "auth": b64.EncodeToString(authSecret)},
+	}}
+	notification := &PushNotification{
+		EventID:  "$event",
+		RoomID:   "!room:example.com",
+		Priority: PushPriorityHigh,
+		Content:  json.RawMessage(`{"body":"` + string(bytes.Repeat([]byte("a"), 5000)) + `"}`),
+		Devices:  []Device{*device},
+	}
+	require.NoError(t, provider.Push(context.Background(), device, notification))
+	require.NotNil(t, received)
+	assert.Equal(t, id.EventID("$event"), received.Notification.EventID)
+	// The content didn't fit in a single record, so it was dropped.
+	assert.Nil(t, received.Notification.Content)
+
+	device.Data["endpoint"] = endpoint.URL + "/gone"
+	require.ErrorIs(t, provider.Push(context.Background(), device, notification), ErrPushKeyRejected)
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package pushgateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+)
+
+const (
+	webPushRecordSize = 4096
+	// The maximum plaintext size that fits in a single record: the record size minus the header (86 bytes),
+	// the AEAD tag (16 bytes) and the padding delimiter (1 byte).
+	webPushMaxPlaintextSize = webPushRecordSize - 86 - 16 - 1
+
+	DefaultWebPushTTL = 24 * time.Hour
+)
+
+var ErrWebPushPayloadTooLarge = errors.New("notification payload is too large for web push")
+
+// WebPushProvider delivers notifications using the [Web Push protocol] with [VAPID] authentication.
+//
+// The push key of the device is the p256dh public key of the subscription (base64url encoded),
+// and the pusher data must contain the subscription endpoint URL in "endpoint" and the auth secret in "auth".
+//
+// The payload is the push gateway request body encrypted with aes128gcm. If it doesn't fit in a single record,
+// the event content is left out.
+//
+// [Web Push protocol]: https://www.rfc-editor.org/rfc/rfc8030
+// [VAPID]: https://www.rfc-editor.org/rfc/rfc8292
+type WebPushProvider struct {
+	// The VAPID key pair of the application server, e.g. loaded with x509.ParseECPrivateKey.
+	VAPIDKey *ecdsa.PrivateKey
+	// The contact URI of the application server, such as a mailto: link.
+	Subject string
+	// How long the push service should store the notification if the device is offline. Defaults to [DefaultWebPushTTL].
+	TTL time.Duration
+	// The HTTP client to use. Defaults to a client created with [NewEndpointClient],
+	// which only connects to public addresses.
+	Client *http.Client
+}
+
+var _ Provider = (*WebPushProvider)(nil)
+
+var b64 = base64.RawURLEncoding
+
+func (wp *WebPushProvider) Push(ctx context.Context, device *Device, notification *PushNotification) error {
+	endpoint, _ := device.Data["endpoint"].(string)
+	authSecretString, _ := device.Data["auth"].(string)
+	if endpoint == "" || authSecretString == "" {
+		return fmt.Errorf("%w: missing endpoint or auth secret in pusher data", ErrPushKeyRejected)
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Scheme != "https" {
+		return fmt.Errorf("%w: invalid endpoint URL", ErrPushKeyRejected)
+	}
+	authSecret, err := b64.DecodeString(authSecretString)
+	if err != nil {
+		return fmt.Errorf("%w: invalid auth secret: %w", ErrPushKeyRejected, err)
+	}
+	rawUserAgentKey, err := b64.DecodeString(device.PushKey)
+	if err != nil {
+		return fmt.Errorf("%w: invalid public key: %w", ErrPushKeyRejected, err)
+	}
+	userAgentKey, err := ecdh.P256().NewPublicKey(rawUserAgentKey)
+	if err != nil {
+		return fmt.Errorf("%w: invalid public key: %w", ErrPushKeyRejected, err)
+	}
+
+	payload, err := json.Marshal(&ReqPush{Notification: notification})
+	if err == nil && len(payload) > webPushMaxPlaintextSize {
+		withoutContent := *notification
+		withoutContent.Content = nil
+		payload, err = json.Marshal(&ReqPush{Notification: &withoutContent})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	} else if len(payload) > webPushMaxPlaintextSize {
+		return ErrWebPushPayloadTooLarge
+	}
+	body, err := encryptWebPush(payload, userAgentKey, authSecret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt payload: %w", err)
+	}
+	authHeader, err := wp.vapidAuthorization(endpointURL)
+	if err != nil {
+		return fmt.Errorf("failed to create VAPID authorization: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to prepare push request: %w", err)
+	}
+	ttl := wp.TTL
+	if ttl <= 0 {
+		ttl = DefaultWebPushTTL
+	}
+	urgency := "normal"
+	if notification.Priority == PushPriorityHigh {
+		urgency = "high"
+	} else if notification.Priority == PushPriorityLow {
+		urgency = "low"
+	}
+	req.Header.Set("User-Agent", mautrix.DefaultUserAgent+" (push gateway)")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	req.Header.Set("Urgency", urgency)
+	req.Header.Set("Authorization", authHeader)
+	client := wp.Client
+	if client == nil {
+		client = defaultEndpointClient
+	}
+	return doEndpointRequest(client, req)
+}
+
+// vapidAuthorization creates the Authorization header value for the given push service endpoint (RFC 8292).
+func (wp *WebPushProvider) vapidAuthorization(endpoint *url.URL) (string, error) {
+	publicKey, err := wp.VAPIDKey.PublicKey.ECDH()
+	if err != nil {
+		return "", err
+	}
+	header := b64.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]any{
+		"aud": endpoint.Scheme + "://" + endpoint.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": wp.Subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + b64.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, wp.VAPIDKey, hash[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return fmt.Sprintf("vapid t=%s.%s, k=%s", unsigned, b64.EncodeToString(signature), b64.EncodeToString(publicKey.Bytes())), nil
+}
+
+// encryptWebPush encrypts the given plaintext as a single aes128gcm record (RFC 8291).
+func encryptWebPush(plaintext []byte, userAgentKey *ecdh.PublicKey, authSecret []byte) ([]byte, error) {
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+	cek, nonce, err := deriveWebPushKeys(serverKey, userAgentKey, serverKey.PublicKey(), userAgentKey, authSecret, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	serverPublicKey := serverKey.PublicKey().Bytes()
+	header := make([]byte, 0, 16+4+1+len(serverPublicKey))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, webPushRecordSize)
+	header = append(header, byte(len(serverPublicKey)))
+	header = append(header, serverPublicKey...)
+	// The padding delimiter 0x02 marks the last (and only) record.
+	padded := append(bytes.Clone(plaintext), 0x02)
+	return gcm.Seal(header, nonce, padded, nil), nil
+}
+
+// deriveWebPushKeys derives the content encryption key and nonce for aes128gcm (RFC 8291 section 3.4).
+// The private key is used for ECDH with the peer key, while the server and user agent keys are used for the key info.
+func deriveWebPushKeys(
+	privateKey *ecdh.PrivateKey,
+	peerKey, serverKey, userAgentKey *ecdh.PublicKey,
+	authSecret, salt []byte,
+) (cek, nonce []byte, err error) {
+	sharedSecret, err := privateKey.ECDH(peerKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyInfo := "WebPush: info\x00" + string(userAgentKey.Bytes()) + string(serverKey.Bytes())
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+	cek, err = hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce, err = hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	return
+}
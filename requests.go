@@ -113,6 +113,11 @@ type ReqLogin struct {
 	StoreHomeserverURL bool `json:"-"`
 }
 
+// ReqRefresh is the JSON request for https://spec.matrix.org/v1.15/client-server-api/#post_matrixclientv3refresh
+type ReqRefresh struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 type ReqPutDevice struct {
 	DisplayName string `json:"display_name,omitempty"`
 }
@@ -129,6 +134,14 @@ type ReqUIAuthLogin struct {
 	Token    string `json:"token,omitempty"`
 }
 
+// ReqUpgradeRoom is the JSON request for https://spec.matrix.org/v1.16/client-server-api/#post_matrixclientv3roomsroomidupgrade
+type ReqUpgradeRoom struct {
+	NewVersion id.RoomVersion `json:"new_version"`
+
+	// Room v12+ only
+	AdditionalCreators []id.UserID `json:"additional_creators,omitempty"`
+}
+
 // ReqCreateRoom is the JSON request for https://spec.matrix.org/v1.2/client-server-api/#post_matrixclientv3createroom
 type ReqCreateRoom struct {
 	Visibility      string                 `json:"visibility,omitempty"`
@@ -304,6 +317,20 @@ type DeviceKeys struct {
 	Keys       KeyMap                 `json:"keys"`
 	Signatures signatures.Signatures  `json:"signatures"`
 	Unsigned   map[string]interface{} `json:"unsigned,omitempty"`
+	Dehydrated bool                   `json:"dehydrated,omitempty"`
+}
+
+type ReqPutDehydratedDevice struct {
+	DeviceID                 id.DeviceID             `json:"device_id"`
+	DeviceData               json.RawMessage         `json:"device_data"`
+	InitialDeviceDisplayName string                  `json:"initial_device_display_name,omitempty"`
+	DeviceKeys               *DeviceKeys             `json:"device_keys,omitempty"`
+	OneTimeKeys              map[id.KeyID]OneTimeKey `json:"one_time_keys,omitempty"`
+	FallbackKeys             map[id.KeyID]OneTimeKey `json:"fallback_keys,omitempty"`
+}
+
+type ReqDehydratedDeviceEvents struct {
+	NextBatch string `json:"next_batch,omitempty"`
 }
 
 type CrossSigningKeys struct {
@@ -512,6 +539,92 @@ func (req *ReqHierarchy) Query() map[string]string {
 	return query
 }
 
+// ReqNotifications contains the query parameters for https://spec.matrix.org/v1.12/client-server-api/#get_matrixclientv3notifications
+type ReqNotifications struct {
+	// A pagination token from a previous Notifications call.
+	From string
+	// Limit for the maximum number of notifications to include in the response.
+	Limit int
+	// If true, only highlighted notifications are returned.
+	OnlyHighlight bool
+}
+
+func (req *ReqNotifications) Query() map[string]string {
+	query := map[string]string{}
+	if req == nil {
+		return query
+	}
+	if req.From != "" {
+		query["from"] = req.From
+	}
+	if req.Limit > 0 {
+		query["limit"] = strconv.Itoa(req.Limit)
+	}
+	if req.OnlyHighlight {
+		query["only"] = "highlight"
+	}
+	return query
+}
+
+type SearchOrderBy string
+
+const (
+	SearchOrderByRank   SearchOrderBy = "rank"
+	SearchOrderByRecent SearchOrderBy = "recent"
+)
+
+type SearchGroupKey string
+
+const (
+	SearchGroupByRoomID SearchGroupKey = "room_id"
+	SearchGroupBySender SearchGroupKey = "sender"
+)
+
+const (
+	SearchKeyContentBody  = "content.body"
+	SearchKeyContentName  = "content.name"
+	SearchKeyContentTopic = "content.topic"
+)
+
+// ReqSearch is the JSON request for https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3search
+type ReqSearch struct {
+	SearchCategories ReqSearchCategories `json:"search_categories"`
+}
+
+type ReqSearchCategories struct {
+	RoomEvents *ReqSearchRoomEvents `json:"room_events,omitempty"`
+}
+
+type ReqSearchRoomEvents struct {
+	SearchTerm string `json:"search_term"`
+	// The keys to search. Defaults to all of [SearchKeyContentBody], [SearchKeyContentName] and [SearchKeyContentTopic].
+	Keys    []string      `json:"keys,omitempty"`
+	Filter  *FilterPart   `json:"filter,omitempty"`
+	OrderBy SearchOrderBy `json:"order_by,omitempty"`
+	// If set, the server will include events before and after each result.
+	EventContext *ReqSearchEventContext `json:"event_context,omitempty"`
+	// If true, the current state of each room in the results is included in the response.
+	IncludeState bool                `json:"include_state,omitempty"`
+	Groupings    *ReqSearchGroupings `json:"groupings,omitempty"`
+}
+
+type ReqSearchEventContext struct {
+	// The number of events to include before each result. The server will default to 5 if this isn't provided.
+	BeforeLimit *int `json:"before_limit,omitempty"`
+	// The number of events to include after each result. The server will default to 5 if this isn't provided.
+	AfterLimit *int `json:"after_limit,omitempty"`
+	// If true, the profiles of the senders of the context events are included.
+	IncludeProfile bool `json:"include_profile,omitempty"`
+}
+
+type ReqSearchGroupings struct {
+	GroupBy []ReqSearchGroup `json:"group_by,omitempty"`
+}
+
+type ReqSearchGroup struct {
+	Key SearchGroupKey `json:"key"`
+}
+
 type ReqAppservicePing struct {
 	TxnID string `json:"transaction_id,omitempty"`
 }
@@ -617,3 +730,82 @@ type ReqSuspend struct {
 type ReqLocked struct {
 	Locked bool `json:"locked"`
 }
+
+// ReqSlidingSync is the request body for simplified sliding sync ([MSC4186]).
+//
+// [MSC4186]: https://github.com/matrix-org/matrix-spec-proposals/pull/4186
+type ReqSlidingSync struct {
+	Pos         string         `json:"-"`
+	Timeout     int            `json:"-"`
+	SetPresence event.Presence `json:"-"`
+
+	ConnID            string                                     `json:"conn_id,omitempty"`
+	TxnID             string                                     `json:"txn_id,omitempty"`
+	Lists             map[string]*SlidingSyncList                `json:"lists,omitempty"`
+	RoomSubscriptions map[id.RoomID]*SlidingSyncRoomSubscription `json:"room_subscriptions,omitempty"`
+	Extensions        *SlidingSyncExtensions                     `json:"extensions,omitempty"`
+}
+
+func (req *ReqSlidingSync) BuildQuery() map[string]string {
+	query := map[string]string{
+		"timeout": strconv.Itoa(req.Timeout),
+	}
+	if req.Pos != "" {
+		query["pos"] = req.Pos
+	}
+	if req.SetPresence != "" {
+		query["set_presence"] = string(req.SetPresence)
+	}
+	return query
+}
+
+// SlidingSyncRequiredState is a (event type, state key) pair of state events to include in sliding sync rooms.
+// The state key may be "*" to include all state events of the type, or "$LAZY" to lazy-load members.
+type SlidingSyncRequiredState [2]string
+
+type SlidingSyncRoomSubscription struct {
+	RequiredState []SlidingSyncRequiredState `json:"required_state"`
+	TimelineLimit int                        `json:"timeline_limit"`
+}
+
+type SlidingSyncList struct {
+	SlidingSyncRoomSubscription
+	Ranges  [][2]int            `json:"ranges,omitempty"`
+	Filters *SlidingSyncFilters `json:"filters,omitempty"`
+}
+
+type SlidingSyncFilters struct {
+	IsDM         *bool             `json:"is_dm,omitempty"`
+	Spaces       []id.RoomID       `json:"spaces,omitempty"`
+	IsEncrypted  *bool             `json:"is_encrypted,omitempty"`
+	IsInvite     *bool             `json:"is_invite,omitempty"`
+	RoomTypes    []*event.RoomType `json:"room_types,omitempty"`
+	NotRoomTypes []*event.RoomType `json:"not_room_types,omitempty"`
+	RoomNameLike string            `json:"room_name_like,omitempty"`
+	Tags         []event.RoomTag   `json:"tags,omitempty"`
+	NotTags      []event.RoomTag   `json:"not_tags,omitempty"`
+}
+
+type SlidingSyncExtensions struct {
+	ToDevice    *SlidingSyncToDeviceExtension `json:"to_device,omitempty"`
+	E2EE        *SlidingSyncExtension         `json:"e2ee,omitempty"`
+	AccountData *SlidingSyncScopedExtension   `json:"account_data,omitempty"`
+	Receipts    *SlidingSyncScopedExtension   `json:"receipts,omitempty"`
+	Typing      *SlidingSyncScopedExtension   `json:"typing,omitempty"`
+}
+
+type SlidingSyncExtension struct {
+	Enabled bool `json:"enabled"`
+}
+
+type SlidingSyncScopedExtension struct {
+	Enabled bool        `json:"enabled"`
+	Lists   []string    `json:"lists,omitempty"`
+	Rooms   []id.RoomID `json:"rooms,omitempty"`
+}
+
+type SlidingSyncToDeviceExtension struct {
+	Enabled bool   `json:"enabled"`
+	Limit   int    `json:"limit,omitempty"`
+	Since   string `json:"since,omitempty"`
+}
@@ -17,6 +17,7 @@ import (
 
 	"github.com/iKonoTelecomunicaciones/go/event"
 	"github.com/iKonoTelecomunicaciones/go/id"
+	"github.com/iKonoTelecomunicaciones/go/pushrules"
 )
 
 // RespWhoami is the JSON response for https://spec.matrix.org/v1.2/client-server-api/#get_matrixclientv3accountwhoami
@@ -329,6 +330,18 @@ type RespLogin struct {
 // RespLogout is the JSON response for https://spec.matrix.org/v1.2/client-server-api/#post_matrixclientv3logout
 type RespLogout struct{}
 
+// RespRefresh is the JSON response for https://spec.matrix.org/v1.15/client-server-api/#post_matrixclientv3refresh
+type RespRefresh struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresInMS  int64  `json:"expires_in_ms,omitempty"`
+}
+
+// RespUpgradeRoom is the JSON response for https://spec.matrix.org/v1.16/client-server-api/#post_matrixclientv3roomsroomidupgrade
+type RespUpgradeRoom struct {
+	ReplacementRoom id.RoomID `json:"replacement_room"`
+}
+
 // RespCreateRoom is the JSON response for https://spec.matrix.org/v1.2/client-server-api/#post_matrixclientv3createroom
 type RespCreateRoom struct {
 	RoomID id.RoomID `json:"room_id"`
@@ -512,6 +525,24 @@ type RespQueryKeys struct {
 	UserSigningKeys map[id.UserID]CrossSigningKeys           `json:"user_signing_keys"`
 }
 
+type RespPutDehydratedDevice struct {
+	DeviceID id.DeviceID `json:"device_id"`
+}
+
+type RespGetDehydratedDevice struct {
+	DeviceID   id.DeviceID     `json:"device_id"`
+	DeviceData json.RawMessage `json:"device_data"`
+}
+
+type RespDeleteDehydratedDevice struct {
+	DeviceID id.DeviceID `json:"device_id"`
+}
+
+type RespDehydratedDeviceEvents struct {
+	Events    []*event.Event `json:"events"`
+	NextBatch string         `json:"next_batch"`
+}
+
 type RespClaimKeys struct {
 	Failures    map[string]interface{}                                `json:"failures,omitempty"`
 	OneTimeKeys map[id.UserID]map[id.DeviceID]map[id.KeyID]OneTimeKey `json:"one_time_keys"`
@@ -698,6 +729,65 @@ type ChildRoomsChunk struct {
 	ChildrenState []*event.Event `json:"children_state"`
 }
 
+// RespNotifications is the JSON response for https://spec.matrix.org/v1.12/client-server-api/#get_matrixclientv3notifications
+type RespNotifications struct {
+	NextToken     string          `json:"next_token,omitempty"`
+	Notifications []*Notification `json:"notifications"`
+}
+
+type Notification struct {
+	Actions    pushrules.PushActionArray `json:"actions"`
+	Event      *event.Event              `json:"event"`
+	ProfileTag string                    `json:"profile_tag,omitempty"`
+	Read       bool                      `json:"read"`
+	RoomID     id.RoomID                 `json:"room_id"`
+	Timestamp  jsontime.UnixMilli        `json:"ts"`
+}
+
+// RespSearch is the JSON response for https://spec.matrix.org/v1.12/client-server-api/#post_matrixclientv3search
+type RespSearch struct {
+	SearchCategories RespSearchCategories `json:"search_categories"`
+}
+
+type RespSearchCategories struct {
+	RoomEvents *RespSearchRoomEvents `json:"room_events,omitempty"`
+}
+
+type RespSearchRoomEvents struct {
+	// An approximate count of the total number of results.
+	Count *int `json:"count,omitempty"`
+	// Words that should be highlighted in the results, which may include variants of the search term.
+	Highlights []string `json:"highlights"`
+	// A token for fetching more results. Empty if there are no more results.
+	NextBatch string          `json:"next_batch,omitempty"`
+	Results   []*SearchResult `json:"results"`
+	// The current state of each room in the results, if include_state was set in the request.
+	State map[id.RoomID][]*event.Event `json:"state,omitempty"`
+	// The results grouped by the keys requested in groupings.
+	// The inner map is keyed by the value of the group key, i.e. a room ID or a user ID.
+	Groups map[SearchGroupKey]map[string]*SearchResultGroup `json:"groups,omitempty"`
+}
+
+type SearchResult struct {
+	Rank    float64              `json:"rank"`
+	Result  *event.Event         `json:"result"`
+	Context *SearchResultContext `json:"context,omitempty"`
+}
+
+type SearchResultContext struct {
+	Start        string                         `json:"start,omitempty"`
+	End          string                         `json:"end,omitempty"`
+	EventsBefore []*event.Event                 `json:"events_before"`
+	EventsAfter  []*event.Event                 `json:"events_after"`
+	ProfileInfo  map[id.UserID]*RespUserProfile `json:"profile_info,omitempty"`
+}
+
+type SearchResultGroup struct {
+	NextBatch string       `json:"next_batch,omitempty"`
+	Order     int          `json:"order"`
+	Results   []id.EventID `json:"results"`
+}
+
 type RespAppservicePing struct {
 	DurationMS int64 `json:"duration_ms"`
 }
@@ -788,3 +878,76 @@ type RespWhoIs struct {
 	UserID  id.UserID                  `json:"user_id,omitempty"`
 	Devices map[id.DeviceID]DeviceInfo `json:"devices,omitempty"`
 }
+
+// RespSlidingSync is the response body for simplified sliding sync ([MSC4186]).
+//
+// [MSC4186]: https://github.com/matrix-org/matrix-spec-proposals/pull/4186
+type RespSlidingSync struct {
+	Pos        string                            `json:"pos"`
+	TxnID      string                            `json:"txn_id,omitempty"`
+	Lists      map[string]*SlidingSyncListResult `json:"lists,omitempty"`
+	Rooms      map[id.RoomID]*SlidingSyncRoom    `json:"rooms,omitempty"`
+	Extensions SlidingSyncExtensionsResponse     `json:"extensions"`
+}
+
+type SlidingSyncListResult struct {
+	Count int `json:"count"`
+}
+
+type SlidingSyncHero struct {
+	UserID      id.UserID           `json:"user_id"`
+	Displayname string              `json:"displayname,omitempty"`
+	AvatarURL   id.ContentURIString `json:"avatar_url,omitempty"`
+}
+
+type SlidingSyncRoom struct {
+	Name             string              `json:"name,omitempty"`
+	Avatar           id.ContentURIString `json:"avatar,omitempty"`
+	Heroes           []SlidingSyncHero   `json:"heroes,omitempty"`
+	IsDM             bool                `json:"is_dm,omitempty"`
+	Initial          bool                `json:"initial,omitempty"`
+	ExpandedTimeline bool                `json:"expanded_timeline,omitempty"`
+
+	RequiredState []*event.Event `json:"required_state,omitempty"`
+	InviteState   []*event.Event `json:"invite_state,omitempty"`
+	KnockState    []*event.Event `json:"knock_state,omitempty"`
+
+	Timeline  []*event.Event `json:"timeline,omitempty"`
+	PrevBatch string         `json:"prev_batch,omitempty"`
+	Limited   bool           `json:"limited,omitempty"`
+	NumLive   int            `json:"num_live,omitempty"`
+	BumpStamp int64          `json:"bump_stamp,omitempty"`
+
+	JoinedCount       *int `json:"joined_count,omitempty"`
+	InvitedCount      *int `json:"invited_count,omitempty"`
+	NotificationCount int  `json:"notification_count"`
+	HighlightCount    int  `json:"highlight_count"`
+}
+
+type SlidingSyncExtensionsResponse struct {
+	ToDevice    *SlidingSyncToDeviceResponse    `json:"to_device,omitempty"`
+	E2EE        *SlidingSyncE2EEResponse        `json:"e2ee,omitempty"`
+	AccountData *SlidingSyncAccountDataResponse `json:"account_data,omitempty"`
+	Receipts    *SlidingSyncEphemeralResponse   `json:"receipts,omitempty"`
+	Typing      *SlidingSyncEphemeralResponse   `json:"typing,omitempty"`
+}
+
+type SlidingSyncToDeviceResponse struct {
+	NextBatch string         `json:"next_batch"`
+	Events    []*event.Event `json:"events,omitempty"`
+}
+
+type SlidingSyncE2EEResponse struct {
+	DeviceLists    DeviceLists       `json:"device_lists"`
+	DeviceOTKCount OTKCount          `json:"device_one_time_keys_count"`
+	FallbackKeys   []id.KeyAlgorithm `json:"device_unused_fallback_key_types"`
+}
+
+type SlidingSyncAccountDataResponse struct {
+	Global []*event.Event               `json:"global,omitempty"`
+	Rooms  map[id.RoomID][]*event.Event `json:"rooms,omitempty"`
+}
+
+type SlidingSyncEphemeralResponse struct {
+	Rooms map[id.RoomID]*event.Event `json:"rooms,omitempty"`
+}
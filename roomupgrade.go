@@ -0,0 +1,153 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+// RoomUpgradeExtraStateTypes contains state event types that servers don't copy to the replacement room when
+// upgrading, but which are usually wanted in the new room. They can be copied using [Client.CopyRoomState].
+var RoomUpgradeExtraStateTypes = []event.Type{
+	event.StateBridge,
+	event.StateHalfShotBridge,
+	event.StateSpaceParent,
+	event.StatePinnedEvents,
+	event.StateElementFunctionalMembers,
+	event.StateBeeperRoomFeatures,
+	event.StateBeeperDisappearingTimer,
+	event.StateBotCommands,
+}
+
+var ErrRoomPredecessorLoop = errors.New("room predecessor chain contains a loop")
+
+// GetRoomPredecessors follows the predecessor links in m.room.create events and returns the IDs of all rooms
+// that the given room replaced, starting from the direct predecessor.
+//
+// If one of the create events can't be fetched (e.g. because the user isn't in the old room),
+// the chain found so far is returned along with the error.
+func (cli *Client) GetRoomPredecessors(ctx context.Context, roomID id.RoomID) ([]id.RoomID, error) {
+	var chain []id.RoomID
+	seen := map[id.RoomID]struct{}{roomID: {}}
+	for {
+		var content event.CreateEventContent
+		err := cli.StateEvent(ctx, roomID, event.StateCreate, "", &content)
+		if err != nil {
+			return chain, fmt.Errorf("failed to get create event of %s: %w", roomID, err)
+		}
+		predecessor := content.GetPredecessor().RoomID
+		if predecessor == "" {
+			return chain, nil
+		} else if _, alreadySeen := seen[predecessor]; alreadySeen {
+			return chain, ErrRoomPredecessorLoop
+		}
+		seen[predecessor] = struct{}{}
+		chain = append(chain, predecessor)
+		roomID = predecessor
+	}
+}
+
+// CopyRoomState copies all state events of the given types from one room to another, e.g. after a room upgrade.
+// Events whose content is already identical in the target room are skipped.
+//
+// If power levels are copied into a room version where the creators have infinite power,
+// the creators are removed from the users map, as the new room would reject them otherwise.
+func (cli *Client) CopyRoomState(ctx context.Context, fromRoomID, toRoomID id.RoomID, types ...event.Type) error {
+	oldState, err := cli.State(ctx, fromRoomID)
+	if err != nil {
+		return fmt.Errorf("failed to get state of old room: %w", err)
+	}
+	newState, err := cli.State(ctx, toRoomID)
+	if err != nil {
+		return fmt.Errorf("failed to get state of new room: %w", err)
+	}
+	for _, evtType := range types {
+		for stateKey, evt := range oldState[evtType] {
+			if len(evt.Content.Raw) == 0 {
+				continue
+			}
+			content := evt.Content.Raw
+			if evtType == event.StatePowerLevels {
+				content = removeCreatorsFromPowerLevels(content, newState[event.StateCreate][""])
+			}
+			if existing := newState[evtType][stateKey]; existing != nil && reflect.DeepEqual(existing.Content.Raw, content) {
+				continue
+			}
+			_, err = cli.SendStateEvent(ctx, toRoomID, evtType, stateKey, content)
+			if err != nil {
+				return fmt.Errorf("failed to copy %s/%s: %w", evtType.Type, stateKey, err)
+			}
+		}
+	}
+	return nil
+}
+
+func removeCreatorsFromPowerLevels(content map[string]any, createEvt *event.Event) map[string]any {
+	if createEvt == nil {
+		return content
+	}
+	createContent, _ := createEvt.Content.Parsed.(*event.CreateEventContent)
+	if createContent == nil || !createContent.RoomVersion.PrivilegedRoomCreators() {
+		return content
+	}
+	users, ok := content["users"].(map[string]any)
+	if !ok {
+		return content
+	}
+	users = maps.Clone(users)
+	delete(users, createEvt.Sender.String())
+	for _, creator := range createContent.AdditionalCreators {
+		delete(users, creator.String())
+	}
+	content = maps.Clone(content)
+	content["users"] = users
+	return content
+}
+
+// MoveRoomAliases moves all local aliases and the canonical alias of the old room to the new room.
+//
+// Servers move aliases automatically when using [Client.UpgradeRoom],
+// so this is only necessary when replacing a room manually.
+func (cli *Client) MoveRoomAliases(ctx context.Context, fromRoomID, toRoomID id.RoomID) error {
+	aliases, err := cli.GetAliases(ctx, fromRoomID)
+	if err != nil {
+		return fmt.Errorf("failed to get local aliases of old room: %w", err)
+	}
+	for _, alias := range aliases.Aliases {
+		_, err = cli.DeleteAlias(ctx, alias)
+		if err != nil {
+			return fmt.Errorf("failed to remove alias %s from old room: %w", alias, err)
+		}
+		_, err = cli.CreateAlias(ctx, alias, toRoomID)
+		if err != nil {
+			return fmt.Errorf("failed to add alias %s to new room: %w", alias, err)
+		}
+	}
+	var canonicalAlias event.CanonicalAliasEventContent
+	err = cli.StateEvent(ctx, fromRoomID, event.StateCanonicalAlias, "", &canonicalAlias)
+	if errors.Is(err, MNotFound) || (err == nil && canonicalAlias.Alias == "" && len(canonicalAlias.AltAliases) == 0) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get canonical alias of old room: %w", err)
+	}
+	_, err = cli.SendStateEvent(ctx, toRoomID, event.StateCanonicalAlias, "", &canonicalAlias)
+	if err != nil {
+		return fmt.Errorf("failed to set canonical alias in new room: %w", err)
+	}
+	_, err = cli.SendStateEvent(ctx, fromRoomID, event.StateCanonicalAlias, "", &event.CanonicalAliasEventContent{})
+	if err != nil {
+		return fmt.Errorf("failed to remove canonical alias from old room: %w", err)
+	}
+	return nil
+}
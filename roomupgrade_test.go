@@ -0,0 +1,74 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+	"github.com/iKonoTelecomunicaciones/go/mockserver"
+)
+
+func TestClient_UpgradeRoom(t *testing.T) {
+	ctx := context.Background()
+	server := mockserver.Create(t)
+	alice := server.NewClient(t, ctx, "@alice:localhost", "ALICE")
+
+	createResp, err := alice.CreateRoom(ctx, &mautrix.ReqCreateRoom{
+		Name:          "Upgrade me",
+		RoomAliasName: "upgrade",
+		RoomVersion:   id.RoomV11,
+	})
+	require.NoError(t, err)
+	oldRoomID := createResp.RoomID
+	_, err = alice.SendStateEvent(ctx, oldRoomID, event.StateBridge, "net.example://bridge", map[string]any{
+		"bridgebot": "@bot:localhost",
+		"protocol":  map[string]any{"id": "example"},
+	})
+	require.NoError(t, err)
+
+	firstUpgrade, err := alice.UpgradeRoom(ctx, oldRoomID, &mautrix.ReqUpgradeRoom{NewVersion: id.RoomV11})
+	require.NoError(t, err)
+	secondUpgrade, err := alice.UpgradeRoom(ctx, firstUpgrade.ReplacementRoom, &mautrix.ReqUpgradeRoom{NewVersion: id.RoomV12})
+	require.NoError(t, err)
+	newRoomID := secondUpgrade.ReplacementRoom
+
+	var tombstone event.TombstoneEventContent
+	require.NoError(t, alice.StateEvent(ctx, firstUpgrade.ReplacementRoom, event.StateTombstone, "", &tombstone))
+	assert.Equal(t, newRoomID, tombstone.ReplacementRoom)
+
+	predecessors, err := alice.GetRoomPredecessors(ctx, newRoomID)
+	require.NoError(t, err)
+	assert.Equal(t, []id.RoomID{firstUpgrade.ReplacementRoom, oldRoomID}, predecessors)
+
+	var name event.RoomNameEventContent
+	require.NoError(t, alice.StateEvent(ctx, newRoomID, event.StateRoomName, "", &name))
+	assert.Equal(t, "Upgrade me", name.Name)
+	resolved, err := alice.ResolveAlias(ctx, "#upgrade:localhost")
+	require.NoError(t, err)
+	assert.Equal(t, newRoomID, resolved.RoomID)
+
+	// The server doesn't copy bridge info, so it has to be copied manually.
+	var bridgeInfo event.BridgeEventContent
+	err = alice.StateEvent(ctx, newRoomID, event.StateBridge, "net.example://bridge", &bridgeInfo)
+	require.ErrorIs(t, err, mautrix.MNotFound)
+	require.NoError(t, alice.CopyRoomState(ctx, oldRoomID, newRoomID, mautrix.RoomUpgradeExtraStateTypes...))
+	require.NoError(t, alice.StateEvent(ctx, newRoomID, event.StateBridge, "net.example://bridge", &bridgeInfo))
+	assert.Equal(t, "example", bridgeInfo.Protocol.ID)
+
+	// Power levels in v12 rooms must not contain the creator.
+	require.NoError(t, alice.CopyRoomState(ctx, oldRoomID, newRoomID, event.StatePowerLevels))
+	var powerLevels event.PowerLevelsEventContent
+	require.NoError(t, alice.StateEvent(ctx, newRoomID, event.StatePowerLevels, "", &powerLevels))
+	assert.NotContains(t, powerLevels.Users, alice.UserID)
+}
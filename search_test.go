@@ -0,0 +1,86 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/util/ptr"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/id"
+	"github.com/iKonoTelecomunicaciones/go/mockserver"
+)
+
+func TestClient_Search(t *testing.T) {
+	ctx := context.Background()
+	server := mockserver.Create(t)
+	alice := server.NewClient(t, ctx, "@alice:localhost", "ALICE")
+	bob := server.NewClient(t, ctx, "@bob:localhost", "BOB")
+
+	roomA, err := alice.CreateRoom(ctx, &mautrix.ReqCreateRoom{Invite: []id.UserID{bob.UserID}})
+	require.NoError(t, err)
+	roomB, err := alice.CreateRoom(ctx, &mautrix.ReqCreateRoom{})
+	require.NoError(t, err)
+	_, err = bob.JoinRoom(ctx, roomA.RoomID.String(), nil)
+	require.NoError(t, err)
+
+	first, err := alice.SendText(ctx, roomA.RoomID, "Meow, said the cat")
+	require.NoError(t, err)
+	_, err = bob.SendText(ctx, roomA.RoomID, "Woof")
+	require.NoError(t, err)
+	second, err := bob.SendText(ctx, roomA.RoomID, "The CAT is on the roof")
+	require.NoError(t, err)
+	third, err := alice.SendText(ctx, roomB.RoomID, "cats are great")
+	require.NoError(t, err)
+
+	resp, err := alice.Search(ctx, &mautrix.ReqSearch{SearchCategories: mautrix.ReqSearchCategories{
+		RoomEvents: &mautrix.ReqSearchRoomEvents{
+			SearchTerm: "cat",
+			Filter:     &mautrix.FilterPart{Limit: 2},
+			OrderBy:    mautrix.SearchOrderByRecent,
+			EventContext: &mautrix.ReqSearchEventContext{
+				BeforeLimit: ptr.Ptr(1),
+				AfterLimit:  ptr.Ptr(0),
+			},
+			Groupings: &mautrix.ReqSearchGroupings{GroupBy: []mautrix.ReqSearchGroup{{Key: mautrix.SearchGroupByRoomID}}},
+		},
+	}}, "")
+	require.NoError(t, err)
+	roomEvents := resp.SearchCategories.RoomEvents
+	require.NotNil(t, roomEvents)
+	require.NotNil(t, roomEvents.Count)
+	assert.Equal(t, 3, *roomEvents.Count)
+	require.Len(t, roomEvents.Results, 2)
+	assert.Equal(t, third.EventID, roomEvents.Results[0].Result.ID)
+	assert.Equal(t, second.EventID, roomEvents.Results[1].Result.ID)
+	require.NotNil(t, roomEvents.Results[1].Context)
+	require.Len(t, roomEvents.Results[1].Context.EventsBefore, 1)
+	assert.Equal(t, "Woof", roomEvents.Results[1].Context.EventsBefore[0].Content.Raw["body"])
+	assert.Empty(t, roomEvents.Results[1].Context.EventsAfter)
+	require.Contains(t, roomEvents.Groups, mautrix.SearchGroupByRoomID)
+	assert.Equal(t, []id.EventID{second.EventID}, roomEvents.Groups[mautrix.SearchGroupByRoomID][roomA.RoomID.String()].Results)
+	require.NotEmpty(t, roomEvents.NextBatch)
+
+	resp, err = alice.Search(ctx, &mautrix.ReqSearch{SearchCategories: mautrix.ReqSearchCategories{
+		RoomEvents: &mautrix.ReqSearchRoomEvents{SearchTerm: "cat", Filter: &mautrix.FilterPart{Limit: 2}},
+	}}, roomEvents.NextBatch)
+	require.NoError(t, err)
+	require.Len(t, resp.SearchCategories.RoomEvents.Results, 1)
+	assert.Equal(t, first.EventID, resp.SearchCategories.RoomEvents.Results[0].Result.ID)
+	assert.Empty(t, resp.SearchCategories.RoomEvents.NextBatch)
+
+	// Bob isn't in room B, so he only sees results from room A.
+	resp, err = bob.Search(ctx, &mautrix.ReqSearch{SearchCategories: mautrix.ReqSearchCategories{
+		RoomEvents: &mautrix.ReqSearchRoomEvents{SearchTerm: "cat"},
+	}}, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, *resp.SearchCategories.RoomEvents.Count)
+}
@@ -0,0 +1,397 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+var ErrSyncerDoesntSupportSlidingSync = errors.New("syncer doesn't implement SlidingSyncer")
+
+// DefaultSlidingSyncExtensions are the extensions that are requested by SlidingSyncWithContext
+// if SetSlidingSyncExtensions hasn't been called.
+var DefaultSlidingSyncExtensions = SlidingSyncExtensions{
+	ToDevice:    &SlidingSyncToDeviceExtension{Enabled: true},
+	E2EE:        &SlidingSyncExtension{Enabled: true},
+	AccountData: &SlidingSyncScopedExtension{Enabled: true},
+	Receipts:    &SlidingSyncScopedExtension{Enabled: true},
+	Typing:      &SlidingSyncScopedExtension{Enabled: true},
+}
+
+type slidingSyncState struct {
+	lock          sync.Mutex
+	connID        string
+	lists         map[string]*SlidingSyncList
+	subscriptions map[id.RoomID]*SlidingSyncRoomSubscription
+	extensions    *SlidingSyncExtensions
+	changed       chan struct{}
+
+	// Tokens used if the client's store doesn't implement SlidingSyncStore
+	pos           string
+	toDeviceSince string
+}
+
+// markChanged wakes up the sync loop so that the new parameters are sent immediately. The lock must be held.
+func (sss *slidingSyncState) markChanged() {
+	if sss.changed != nil {
+		close(sss.changed)
+		sss.changed = nil
+	}
+}
+
+func (sss *slidingSyncState) buildRequest() (*ReqSlidingSync, <-chan struct{}) {
+	sss.lock.Lock()
+	defer sss.lock.Unlock()
+	if sss.changed == nil {
+		sss.changed = make(chan struct{})
+	}
+	extensions := sss.extensions
+	if extensions == nil {
+		extensions = &DefaultSlidingSyncExtensions
+	}
+	extensionsCopy := *extensions
+	if extensionsCopy.ToDevice != nil {
+		toDeviceCopy := *extensionsCopy.ToDevice
+		extensionsCopy.ToDevice = &toDeviceCopy
+	}
+	return &ReqSlidingSync{
+		ConnID:            sss.connID,
+		Lists:             maps.Clone(sss.lists),
+		RoomSubscriptions: maps.Clone(sss.subscriptions),
+		Extensions:        &extensionsCopy,
+	}, sss.changed
+}
+
+// SetSlidingSyncConnID sets the conn_id to use for sliding sync requests.
+// This is only necessary if the same device runs multiple sliding sync connections in parallel.
+func (cli *Client) SetSlidingSyncConnID(connID string) {
+	cli.slidingSync.lock.Lock()
+	defer cli.slidingSync.lock.Unlock()
+	cli.slidingSync.connID = connID
+	cli.slidingSync.markChanged()
+}
+
+// SetSlidingSyncList adds or replaces a sliding sync list. If list is nil, the list is removed.
+//
+// If SlidingSyncWithContext is running, the pending request is interrupted and resent with the new list.
+func (cli *Client) SetSlidingSyncList(name string, list *SlidingSyncList) {
+	cli.slidingSync.lock.Lock()
+	defer cli.slidingSync.lock.Unlock()
+	if list == nil {
+		delete(cli.slidingSync.lists, name)
+	} else {
+		if cli.slidingSync.lists == nil {
+			cli.slidingSync.lists = make(map[string]*SlidingSyncList)
+		}
+		cli.slidingSync.lists[name] = list
+	}
+	cli.slidingSync.markChanged()
+}
+
+// GetSlidingSyncList returns the sliding sync list with the given name, or nil if it doesn't exist.
+func (cli *Client) GetSlidingSyncList(name string) *SlidingSyncList {
+	cli.slidingSync.lock.Lock()
+	defer cli.slidingSync.lock.Unlock()
+	return cli.slidingSync.lists[name]
+}
+
+// SubscribeToRoom adds a sliding sync room subscription, which makes the server send updates for the room
+// regardless of whether it's in the range of any list.
+func (cli *Client) SubscribeToRoom(roomID id.RoomID, sub *SlidingSyncRoomSubscription) {
+	cli.slidingSync.lock.Lock()
+	defer cli.slidingSync.lock.Unlock()
+	if cli.slidingSync.subscriptions == nil {
+		cli.slidingSync.subscriptions = make(map[id.RoomID]*SlidingSyncRoomSubscription)
+	}
+	cli.slidingSync.subscriptions[roomID] = sub
+	cli.slidingSync.markChanged()
+}
+
+// UnsubscribeFromRoom removes a sliding sync room subscription added with SubscribeToRoom.
+func (cli *Client) UnsubscribeFromRoom(roomID id.RoomID) {
+	cli.slidingSync.lock.Lock()
+	defer cli.slidingSync.lock.Unlock()
+	if _, ok := cli.slidingSync.subscriptions[roomID]; ok {
+		delete(cli.slidingSync.subscriptions, roomID)
+		cli.slidingSync.markChanged()
+	}
+}
+
+// SetSlidingSyncExtensions sets the extensions to request in sliding sync. If nil, DefaultSlidingSyncExtensions is used.
+// The since token of the to-device extension is managed automatically.
+func (cli *Client) SetSlidingSyncExtensions(extensions *SlidingSyncExtensions) {
+	cli.slidingSync.lock.Lock()
+	defer cli.slidingSync.lock.Unlock()
+	cli.slidingSync.extensions = extensions
+	cli.slidingSync.markChanged()
+}
+
+// SlidingSyncRequest makes a single simplified sliding sync request.
+//
+// See https://github.com/matrix-org/matrix-spec-proposals/pull/4186
+func (cli *Client) SlidingSyncRequest(ctx context.Context, req *ReqSlidingSync) (resp *RespSlidingSync, err error) {
+	urlPath := cli.BuildURLWithQuery(ClientURLPath{"unstable", "org.matrix.simplified_msc3575", "sync"}, req.BuildQuery())
+	_, err = cli.MakeFullRequest(ctx, FullRequest{
+		Method:       http.MethodPost,
+		URL:          urlPath,
+		RequestJSON:  req,
+		ResponseJSON: &resp,
+		// We don't want automatic retries here, the SlidingSync() wrapper handles those.
+		MaxAttempts: 1,
+	})
+	return
+}
+
+func (cli *Client) loadSlidingSyncTokens(ctx context.Context) (pos, toDeviceSince string, err error) {
+	if store, ok := cli.Store.(SlidingSyncStore); ok {
+		return store.LoadSlidingSyncTokens(ctx, cli.UserID)
+	}
+	// The sliding sync position can't be stored in the next_batch slot, as it would break /sync
+	cli.slidingSync.lock.Lock()
+	defer cli.slidingSync.lock.Unlock()
+	return cli.slidingSync.pos, cli.slidingSync.toDeviceSince, nil
+}
+
+func (cli *Client) saveSlidingSyncTokens(ctx context.Context, pos, toDeviceSince string) error {
+	if store, ok := cli.Store.(SlidingSyncStore); ok {
+		return store.SaveSlidingSyncTokens(ctx, cli.UserID, pos, toDeviceSince)
+	}
+	cli.slidingSync.lock.Lock()
+	cli.slidingSync.pos, cli.slidingSync.toDeviceSince = pos, toDeviceSince
+	cli.slidingSync.lock.Unlock()
+	return nil
+}
+
+// SlidingSync starts syncing with the provided homeserver using simplified sliding sync (MSC4186).
+// It's equivalent to calling SlidingSyncWithContext with a background context.
+func (cli *Client) SlidingSync() error {
+	return cli.SlidingSyncWithContext(context.Background())
+}
+
+// SlidingSyncWithContext syncs using simplified sliding sync ([MSC4186]) instead of /sync.
+//
+// The lists, room subscriptions and extensions set on the client (e.g. with SetSlidingSyncList and SubscribeToRoom)
+// are sent in each request, and changing them while syncing interrupts the pending request. Responses are passed to
+// Client.Syncer, which must implement SlidingSyncer (DefaultSyncer does). Position tokens are persisted in
+// Client.Store if it implements SlidingSyncStore, otherwise they're only kept in memory.
+//
+// Like Sync, this blocks until a fatal error occurs or StopSync is called.
+//
+// [MSC4186]: https://github.com/matrix-org/matrix-spec-proposals/pull/4186
+func (cli *Client) SlidingSyncWithContext(ctx context.Context) error {
+	syncer, ok := cli.Syncer.(SlidingSyncer)
+	if !ok {
+		return ErrSyncerDoesntSupportSlidingSync
+	}
+	syncingID := cli.incrementSyncingID()
+	pos, toDeviceSince, err := cli.loadSlidingSyncTokens(ctx)
+	if err != nil {
+		return err
+	}
+	// Always do first sync with 0 timeout
+	isFailing := true
+	for {
+		req, changed := cli.slidingSync.buildRequest()
+		req.Pos = pos
+		req.SetPresence = cli.SyncPresence
+		req.Timeout = 30000
+		if isFailing || pos == "" {
+			req.Timeout = 0
+		}
+		if req.Extensions.ToDevice != nil && req.Extensions.ToDevice.Enabled {
+			req.Extensions.ToDevice.Since = toDeviceSince
+		}
+		reqCtx, cancelReq := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-changed:
+				cancelReq()
+			case <-reqCtx.Done():
+			}
+		}()
+		resp, err := cli.SlidingSyncRequest(reqCtx, req)
+		interrupted := reqCtx.Err() != nil
+		cancelReq()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			} else if interrupted {
+				cli.Log.Debug().Msg("Sliding sync parameters changed, restarting request")
+				continue
+			} else if errors.Is(err, MUnknownPos) {
+				cli.Log.Warn().Str("pos", pos).Msg("Sliding sync position expired, starting new connection")
+				pos = ""
+				continue
+			}
+			isFailing = true
+			duration, err2 := syncer.OnFailedSync(nil, err)
+			if err2 != nil {
+				return err2
+			}
+			if duration <= 0 {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(duration):
+				continue
+			}
+		}
+		isFailing = false
+
+		// Check that the syncing state hasn't changed
+		if cli.getSyncingID() != syncingID {
+			return nil
+		}
+
+		newToDeviceSince := toDeviceSince
+		if resp.Extensions.ToDevice != nil && resp.Extensions.ToDevice.NextBatch != "" {
+			newToDeviceSince = resp.Extensions.ToDevice.NextBatch
+		}
+		// Like in Sync, save the tokens before processing to avoid getting stuck on a broken response.
+		err = cli.saveSlidingSyncTokens(ctx, resp.Pos, newToDeviceSince)
+		if err != nil {
+			return err
+		}
+		if err = syncer.ProcessSlidingSyncResponse(ctx, cli.UserID, resp, pos); err != nil {
+			return err
+		}
+
+		pos = resp.Pos
+		toDeviceSince = newToDeviceSince
+	}
+}
+
+func getOwnMembership(userID id.UserID, evts []*event.Event) event.Membership {
+	for i := len(evts) - 1; i >= 0; i-- {
+		evt := evts[i]
+		if evt.Type == event.StateMember && evt.GetStateKey() == userID.String() {
+			membership, _ := evt.Content.Raw["membership"].(string)
+			return event.Membership(membership)
+		}
+	}
+	return ""
+}
+
+func addEphemeralEvent(room *SyncJoinedRoom, evt *event.Event) {
+	if evt != nil {
+		room.Ephemeral.Events = append(room.Ephemeral.Events, evt)
+	}
+}
+
+// ToSyncResponse converts the sliding sync response into the equivalent /sync response, which allows reusing
+// existing sync handlers. The user ID is used to find rooms that the user has left.
+//
+// Rooms with invite or knock state are placed in the invite and knock sections, rooms where the most recent
+// membership event of the user is a leave or ban are placed in the leave section, and all other rooms are
+// considered joined.
+func (resp *RespSlidingSync) ToSyncResponse(userID id.UserID) *RespSync {
+	syncResp := &RespSync{
+		NextBatch: resp.Pos,
+		Rooms: RespSyncRooms{
+			Join:   make(map[id.RoomID]*SyncJoinedRoom),
+			Invite: make(map[id.RoomID]*SyncInvitedRoom),
+			Leave:  make(map[id.RoomID]*SyncLeftRoom),
+			Knock:  make(map[id.RoomID]*SyncKnockedRoom),
+		},
+	}
+	ext := resp.Extensions
+	if ext.ToDevice != nil {
+		syncResp.ToDevice.Events = ext.ToDevice.Events
+	}
+	if ext.E2EE != nil {
+		syncResp.DeviceLists = ext.E2EE.DeviceLists
+		syncResp.DeviceOTKCount = ext.E2EE.DeviceOTKCount
+		syncResp.FallbackKeys = ext.E2EE.FallbackKeys
+	}
+	if ext.AccountData != nil {
+		syncResp.AccountData.Events = ext.AccountData.Global
+	}
+	getJoinedRoom := func(roomID id.RoomID) *SyncJoinedRoom {
+		room, ok := syncResp.Rooms.Join[roomID]
+		if !ok {
+			room = &SyncJoinedRoom{}
+			syncResp.Rooms.Join[roomID] = room
+		}
+		return room
+	}
+	for roomID, room := range resp.Rooms {
+		if room.InviteState != nil {
+			syncResp.Rooms.Invite[roomID] = &SyncInvitedRoom{State: SyncEventsList{Events: room.InviteState}}
+			continue
+		} else if room.KnockState != nil {
+			syncResp.Rooms.Knock[roomID] = &SyncKnockedRoom{State: SyncEventsList{Events: room.KnockState}}
+			continue
+		}
+		timeline := SyncTimeline{
+			SyncEventsList: SyncEventsList{Events: room.Timeline},
+			Limited:        room.Limited,
+			PrevBatch:      room.PrevBatch,
+		}
+		membership := getOwnMembership(userID, room.Timeline)
+		if membership == "" {
+			membership = getOwnMembership(userID, room.RequiredState)
+		}
+		if membership == event.MembershipLeave || membership == event.MembershipBan {
+			syncResp.Rooms.Leave[roomID] = &SyncLeftRoom{
+				State:    SyncEventsList{Events: room.RequiredState},
+				Timeline: timeline,
+			}
+			continue
+		}
+		joinedRoom := getJoinedRoom(roomID)
+		joinedRoom.State.Events = room.RequiredState
+		joinedRoom.Timeline = timeline
+		joinedRoom.UnreadNotifications = &UnreadNotificationCounts{
+			HighlightCount:    room.HighlightCount,
+			NotificationCount: room.NotificationCount,
+		}
+		joinedRoom.Summary.JoinedMemberCount = room.JoinedCount
+		joinedRoom.Summary.InvitedMemberCount = room.InvitedCount
+		for _, hero := range room.Heroes {
+			joinedRoom.Summary.Heroes = append(joinedRoom.Summary.Heroes, hero.UserID)
+		}
+	}
+	// Extensions may include data for rooms that aren't in the rooms section of this response
+	isNotJoined := func(roomID id.RoomID) bool {
+		_, isInvite := syncResp.Rooms.Invite[roomID]
+		_, isLeave := syncResp.Rooms.Leave[roomID]
+		_, isKnock := syncResp.Rooms.Knock[roomID]
+		return isInvite || isLeave || isKnock
+	}
+	if ext.AccountData != nil {
+		for roomID, evts := range ext.AccountData.Rooms {
+			if !isNotJoined(roomID) {
+				getJoinedRoom(roomID).AccountData.Events = evts
+			}
+		}
+	}
+	if ext.Typing != nil {
+		for roomID, evt := range ext.Typing.Rooms {
+			if !isNotJoined(roomID) {
+				addEphemeralEvent(getJoinedRoom(roomID), evt)
+			}
+		}
+	}
+	if ext.Receipts != nil {
+		for roomID, evt := range ext.Receipts.Rooms {
+			if !isNotJoined(roomID) {
+				addEphemeralEvent(getJoinedRoom(roomID), evt)
+			}
+		}
+	}
+	return syncResp
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+const slidingSyncResponse = `{
+  "pos": "pos1",
+  "lists": {"all": {"count": 3}},
+  "rooms": {
+    "!joined:example.com": {
+      "name": "Joined room",
+      "initial": true,
+      "required_state": [
+        {"type": "m.room.create", "state_key": "", "sender": "@alice:example.com", "event_id": "$create", "content": {}}
+      ],
+      "timeline": [
+        {"type": "m.room.message", "sender": "@alice:example.com", "event_id": "$msg", "content": {"msgtype": "m.text", "body": "hi"}}
+      ],
+      "limited": true,
+      "prev_batch": "prev",
+      "joined_count": 2,
+      "notification_count": 1,
+      "highlight_count": 0
+    },
+    "!invited:example.com": {
+      "invite_state": [
+        {"type": "m.room.member", "state_key": "@bot:example.com", "sender": "@alice:example.com", "content": {"membership": "invite"}}
+      ]
+    },
+    "!left:example.com": {
+      "timeline": [
+        {"type": "m.room.member", "state_key": "@bot:example.com", "sender": "@bot:example.com", "event_id": "$leave", "content": {"membership": "leave"}}
+      ]
+    }
+  },
+  "extensions": {
+    "to_device": {"next_batch": "td1", "events": [{"type": "m.dummy", "sender": "@alice:example.com", "content": {}}]},
+    "e2ee": {"device_lists": {"changed": ["@alice:example.com"]}, "device_one_time_keys_count": {"signed_curve25519": 50}},
+    "account_data": {"global": [{"type": "m.direct", "content": {}}], "rooms": {"!joined:example.com": [{"type": "m.tag", "content": {"tags": {}}}]}},
+    "typing": {"rooms": {"!joined:example.com": {"type": "m.typing", "content": {"user_ids": ["@alice:example.com"]}}}}
+  }
+}`
+
+func TestRespSlidingSync_ToSyncResponse(t *testing.T) {
+	var resp mautrix.RespSlidingSync
+	require.NoError(t, json.Unmarshal([]byte(slidingSyncResponse), &resp))
+	syncResp := resp.ToSyncResponse("@bot:example.com")
+
+	assert.Equal(t, "pos1", syncResp.NextBatch)
+	assert.Len(t, syncResp.ToDevice.Events, 1)
+	assert.Len(t, syncResp.AccountData.Events, 1)
+	assert.Equal(t, []id.UserID{"@alice:example.com"}, syncResp.DeviceLists.Changed)
+	assert.Equal(t, 50, syncResp.DeviceOTKCount.SignedCurve25519)
+
+	require.Contains(t, syncResp.Rooms.Join, id.RoomID("!joined:example.com"))
+	joined := syncResp.Rooms.Join["!joined:example.com"]
+	assert.Len(t, joined.State.Events, 1)
+	assert.Len(t, joined.Timeline.Events, 1)
+	assert.True(t, joined.Timeline.Limited)
+	assert.Equal(t, "prev", joined.Timeline.PrevBatch)
+	assert.Len(t, joined.Ephemeral.Events, 1)
+	assert.Len(t, joined.AccountData.Events, 1)
+	assert.Equal(t, 1, joined.UnreadNotifications.NotificationCount)
+	assert.Equal(t, 2, *joined.Summary.JoinedMemberCount)
+
+	assert.Contains(t, syncResp.Rooms.Invite, id.RoomID("!invited:example.com"))
+	assert.Contains(t, syncResp.Rooms.Leave, id.RoomID("!left:example.com"))
+	assert.Len(t, syncResp.Rooms.Join, 1)
+}
+
+func TestClient_SlidingSync(t *testing.T) {
+	var lock sync.Mutex
+	var requests []*mautrix.ReqSlidingSync
+	var queries []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/_matrix/client/unstable/org.matrix.simplified_msc3575/sync", r.URL.Path)
+		var req mautrix.ReqSlidingSync
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		lock.Lock()
+		requests = append(requests, &req)
+		queries = append(queries, map[string]string{"pos": r.URL.Query().Get("pos")})
+		reqCount := len(requests)
+		lock.Unlock()
+		switch reqCount {
+		case 1:
+			_, _ = w.Write([]byte(slidingSyncResponse))
+		case 2:
+			mautrix.MUnknownPos.WithMessage("Unknown position").Write(w)
+		default:
+			_, _ = w.Write([]byte(`{"pos": "pos2"}`))
+		}
+	}))
+	defer server.Close()
+
+	cli, err := mautrix.NewClient(server.URL, "@bot:example.com", "token")
+	require.NoError(t, err)
+	cli.SetSlidingSyncList("all", &mautrix.SlidingSyncList{
+		SlidingSyncRoomSubscription: mautrix.SlidingSyncRoomSubscription{TimelineLimit: 10},
+		Ranges:                      [][2]int{{0, 99}},
+	})
+	cli.SubscribeToRoom("!sub:example.com", &mautrix.SlidingSyncRoomSubscription{TimelineLimit: 50})
+
+	syncer := cli.Syncer.(*mautrix.DefaultSyncer)
+	var messages []*event.Event
+	var positions []string
+	syncer.OnEventType(event.EventMessage, func(ctx context.Context, evt *event.Event) {
+		messages = append(messages, evt)
+	})
+	syncer.OnSlidingSync(func(ctx context.Context, resp *mautrix.RespSlidingSync, pos string) bool {
+		positions = append(positions, pos)
+		if len(positions) == 2 {
+			cli.StopSync()
+		}
+		return true
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, cli.SlidingSyncWithContext(ctx))
+
+	require.Len(t, messages, 1)
+	assert.Equal(t, id.RoomID("!joined:example.com"), messages[0].RoomID)
+	assert.Equal(t, "hi", messages[0].Content.AsMessage().Body)
+	// The second response was M_UNKNOWN_POS, so the connection should've been restarted without a pos
+	assert.Equal(t, []string{"", ""}, positions)
+
+	// Like Sync, StopSync only takes effect after the pending request returns
+	require.Len(t, requests, 4)
+	assert.Equal(t, "", queries[0]["pos"])
+	assert.Equal(t, "pos1", queries[1]["pos"])
+	assert.Equal(t, "", queries[2]["pos"])
+	assert.Equal(t, "pos2", queries[3]["pos"])
+	assert.Contains(t, requests[0].Lists, "all")
+	assert.Contains(t, requests[0].RoomSubscriptions, id.RoomID("!sub:example.com"))
+	assert.Equal(t, "td1", requests[1].Extensions.ToDevice.Since)
+
+	pos, toDeviceSince, err := cli.Store.(mautrix.SlidingSyncStore).LoadSlidingSyncTokens(ctx, cli.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, "pos2", pos)
+	assert.Equal(t, "td1", toDeviceSince)
+}
+
+func TestClient_SlidingSync_PlainSyncStore(t *testing.T) {
+	var lock sync.Mutex
+	var positions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		positions = append(positions, r.URL.Query().Get("pos"))
+		lock.Unlock()
+		_, _ = w.Write([]byte(`{"pos": "pos1"}`))
+	}))
+	defer server.Close()
+
+	cli, err := mautrix.NewClient(server.URL, "@bot:example.com", "token")
+	require.NoError(t, err)
+	store := mautrix.NewMemorySyncStore()
+	// Hide the SlidingSyncStore methods
+	cli.Store = struct{ mautrix.SyncStore }{store}
+	require.NoError(t, store.SaveNextBatch(context.Background(), cli.UserID, "s123"))
+	cli.Syncer.(*mautrix.DefaultSyncer).OnSlidingSync(func(ctx context.Context, resp *mautrix.RespSlidingSync, pos string) bool {
+		cli.StopSync()
+		return true
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, cli.SlidingSyncWithContext(ctx))
+	require.NoError(t, cli.SlidingSyncWithContext(ctx))
+
+	// The position is kept in memory without overwriting the /sync token.
+	// StopSync only takes effect after the pending request, so each call makes two requests.
+	assert.Equal(t, []string{"", "pos1", "pos1", "pos1"}, positions)
+	nextBatch, err := store.LoadNextBatch(ctx, cli.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, "s123", nextBatch)
+}
@@ -34,6 +34,19 @@ type Syncer interface {
 	GetFilterJSON(userID id.UserID) *Filter
 }
 
+// SlidingSyncHandler handles a whole simplified sliding sync response. If the return value is false, handling will be stopped completely.
+type SlidingSyncHandler func(ctx context.Context, resp *RespSlidingSync, pos string) bool
+
+// SlidingSyncer is an extension of Syncer that can process simplified sliding sync ([MSC4186]) responses.
+//
+// [MSC4186]: https://github.com/matrix-org/matrix-spec-proposals/pull/4186
+type SlidingSyncer interface {
+	Syncer
+	// ProcessSlidingSyncResponse processes a sliding sync response. The pos parameter is the pos= value that was used
+	// to produce the response, so it's empty for the first response of a new connection.
+	ProcessSlidingSyncResponse(ctx context.Context, userID id.UserID, resp *RespSlidingSync, pos string) error
+}
+
 type ExtensibleSyncer interface {
 	OnSync(callback SyncHandler)
 	OnEvent(callback EventHandler)
@@ -50,6 +63,8 @@ type DispatchableSyncer interface {
 type DefaultSyncer struct {
 	// syncListeners want the whole sync response, e.g. the crypto machine
 	syncListeners []SyncHandler
+	// slidingSyncListeners want the whole sliding sync response, e.g. to read list counts
+	slidingSyncListeners []SlidingSyncHandler
 	// globalListeners want all events
 	globalListeners []EventHandler
 	// listeners want a specific event type
@@ -64,6 +79,7 @@ type DefaultSyncer struct {
 }
 
 var _ Syncer = (*DefaultSyncer)(nil)
+var _ SlidingSyncer = (*DefaultSyncer)(nil)
 var _ ExtensibleSyncer = (*DefaultSyncer)(nil)
 
 // NewDefaultSyncer returns an instantiated DefaultSyncer
@@ -123,6 +139,25 @@ func (s *DefaultSyncer) ProcessResponse(ctx context.Context, res *RespSync, sinc
 	return
 }
 
+// ProcessSlidingSyncResponse processes a simplified sliding sync response by converting it into a /sync response
+// (see RespSlidingSync.ToSyncResponse) and passing it to ProcessResponse, so that the same OnSync and OnEventType
+// handlers are called for both sync methods. Handlers registered with OnSlidingSync are called before the conversion.
+func (s *DefaultSyncer) ProcessSlidingSyncResponse(ctx context.Context, userID id.UserID, resp *RespSlidingSync, pos string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("ProcessSlidingSyncResponse panicked! pos=%s panic=%s\n%s", pos, r, debug.Stack())
+		}
+	}()
+	ctx = context.WithValue(ctx, SyncTokenContextKey, pos)
+
+	for _, listener := range s.slidingSyncListeners {
+		if !listener(ctx, resp, pos) {
+			return
+		}
+	}
+	return s.ProcessResponse(ctx, resp.ToSyncResponse(userID), pos)
+}
+
 func (s *DefaultSyncer) processSyncEvents(ctx context.Context, roomID id.RoomID, events []*event.Event, source event.Source, ignoreState bool) {
 	for _, evt := range events {
 		s.processSyncEvent(ctx, roomID, evt, source, ignoreState)
@@ -185,6 +220,12 @@ func (s *DefaultSyncer) OnSync(callback SyncHandler) {
 	s.syncListeners = append(s.syncListeners, callback)
 }
 
+// OnSlidingSync allows callers to be notified about whole simplified sliding sync responses
+// before the events in them are dispatched to other handlers.
+func (s *DefaultSyncer) OnSlidingSync(callback SlidingSyncHandler) {
+	s.slidingSyncListeners = append(s.slidingSyncListeners, callback)
+}
+
 func (s *DefaultSyncer) OnEvent(callback EventHandler) {
 	s.globalListeners = append(s.globalListeners, callback)
 }
@@ -10,6 +10,7 @@ import (
 
 var _ SyncStore = (*MemorySyncStore)(nil)
 var _ SyncStore = (*AccountDataStore)(nil)
+var _ SlidingSyncStore = (*MemorySyncStore)(nil)
 
 // SyncStore is an interface which must be satisfied to store client data.
 //
@@ -26,6 +27,15 @@ type SyncStore interface {
 // Deprecated: renamed to SyncStore
 type Storer = SyncStore
 
+// SlidingSyncStore is an optional extension to SyncStore for storing simplified sliding sync tokens.
+//
+// If the SyncStore doesn't implement this interface, the sliding sync tokens are only kept in memory,
+// so a new sliding sync connection is started after restarting.
+type SlidingSyncStore interface {
+	SaveSlidingSyncTokens(ctx context.Context, userID id.UserID, pos, toDeviceSince string) error
+	LoadSlidingSyncTokens(ctx context.Context, userID id.UserID) (pos, toDeviceSince string, err error)
+}
+
 // MemorySyncStore implements the Storer interface.
 //
 // Everything is persisted in-memory as maps. It is not safe to load/save filter IDs
@@ -34,6 +44,9 @@ type Storer = SyncStore
 type MemorySyncStore struct {
 	Filters   map[id.UserID]string
 	NextBatch map[id.UserID]string
+
+	SlidingSyncPos           map[id.UserID]string
+	SlidingSyncToDeviceSince map[id.UserID]string
 }
 
 // SaveFilterID to memory.
@@ -58,11 +71,26 @@ func (s *MemorySyncStore) LoadNextBatch(ctx context.Context, userID id.UserID) (
 	return s.NextBatch[userID], nil
 }
 
+// SaveSlidingSyncTokens to memory.
+func (s *MemorySyncStore) SaveSlidingSyncTokens(ctx context.Context, userID id.UserID, pos, toDeviceSince string) error {
+	s.SlidingSyncPos[userID] = pos
+	s.SlidingSyncToDeviceSince[userID] = toDeviceSince
+	return nil
+}
+
+// LoadSlidingSyncTokens from memory.
+func (s *MemorySyncStore) LoadSlidingSyncTokens(ctx context.Context, userID id.UserID) (string, string, error) {
+	return s.SlidingSyncPos[userID], s.SlidingSyncToDeviceSince[userID], nil
+}
+
 // NewMemorySyncStore constructs a new MemorySyncStore.
 func NewMemorySyncStore() *MemorySyncStore {
 	return &MemorySyncStore{
 		Filters:   make(map[id.UserID]string),
 		NextBatch: make(map[id.UserID]string),
+
+		SlidingSyncPos:           make(map[id.UserID]string),
+		SlidingSyncToDeviceSince: make(map[id.UserID]string),
 	}
 }
 
@@ -0,0 +1,145 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+type ThreadInclude string
+
+const (
+	ThreadIncludeAll          ThreadInclude = "all"
+	ThreadIncludeParticipated ThreadInclude = "participated"
+)
+
+// ReqGetThreads contains the query parameters for https://spec.matrix.org/v1.12/client-server-api/#get_matrixclientv1roomsroomidthreads
+type ReqGetThreads struct {
+	// A pagination token from a previous GetThreads call.
+	From string
+	// Which threads to include. The server defaults to [ThreadIncludeAll].
+	Include ThreadInclude
+	// Limit for the maximum number of threads to include in the response.
+	Limit int
+}
+
+func (req *ReqGetThreads) Query() map[string]string {
+	query := map[string]string{}
+	if req == nil {
+		return query
+	}
+	if req.From != "" {
+		query["from"] = req.From
+	}
+	if req.Include != "" {
+		query["include"] = string(req.Include)
+	}
+	if req.Limit > 0 {
+		query["limit"] = strconv.Itoa(req.Limit)
+	}
+	return query
+}
+
+// RespGetThreads is the JSON response for https://spec.matrix.org/v1.12/client-server-api/#get_matrixclientv1roomsroomidthreads
+type RespGetThreads struct {
+	// The thread root events, ordered by the latest event in each thread, most recent first.
+	Chunk     []*event.Event `json:"chunk"`
+	NextBatch string         `json:"next_batch,omitempty"`
+}
+
+// GetThreads returns a paginated list of threads in a room.
+//
+// See https://spec.matrix.org/v1.12/client-server-api/#get_matrixclientv1roomsroomidthreads
+func (cli *Client) GetThreads(ctx context.Context, roomID id.RoomID, req *ReqGetThreads) (resp *RespGetThreads, err error) {
+	urlPath := cli.BuildURLWithQuery(ClientURLPath{"v1", "rooms", roomID, "threads"}, req.Query())
+	_, err = cli.MakeRequest(ctx, http.MethodGet, urlPath, nil, &resp)
+	return
+}
+
+// ThreadSummary contains information about a thread built from its m.thread relations.
+type ThreadSummary struct {
+	Root *event.Event
+	// The number of events in the thread, not including the root.
+	Count       int
+	LatestEvent *event.Event
+	// The users who have sent the root or a reply in the thread, in the order they first participated.
+	Participants            []id.UserID
+	CurrentUserParticipated bool
+}
+
+// threadSummaryPageSize is the number of relations requested per page when building thread summaries.
+const threadSummaryPageSize = 100
+
+// GetThreadSummary fetches the given thread root and walks through all its m.thread relations to build a summary.
+func (cli *Client) GetThreadSummary(ctx context.Context, roomID id.RoomID, rootID id.EventID) (*ThreadSummary, error) {
+	root, err := cli.GetEvent(ctx, roomID, rootID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get thread root: %w", err)
+	}
+	return cli.buildThreadSummary(ctx, roomID, root)
+}
+
+// GetThreadSummaries lists threads in a room with [Client.GetThreads] and builds a summary of each one.
+// The returned token can be used as the From field to fetch the next page.
+func (cli *Client) GetThreadSummaries(ctx context.Context, roomID id.RoomID, req *ReqGetThreads) ([]*ThreadSummary, string, error) {
+	resp, err := cli.GetThreads(ctx, roomID, req)
+	if err != nil {
+		return nil, "", err
+	}
+	summaries := make([]*ThreadSummary, len(resp.Chunk))
+	for i, root := range resp.Chunk {
+		summaries[i], err = cli.buildThreadSummary(ctx, roomID, root)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	return summaries, resp.NextBatch, nil
+}
+
+func (cli *Client) buildThreadSummary(ctx context.Context, roomID id.RoomID, root *event.Event) (*ThreadSummary, error) {
+	summary := &ThreadSummary{Root: root}
+	var senders []id.UserID
+	req := &ReqGetRelations{
+		RelationType: event.RelThread,
+		Dir:          DirectionBackward,
+		Limit:        threadSummaryPageSize,
+	}
+	for {
+		resp, err := cli.GetRelations(ctx, roomID, root.ID, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get thread relations: %w", err)
+		}
+		if summary.LatestEvent == nil && len(resp.Chunk) > 0 {
+			summary.LatestEvent = resp.Chunk[0]
+		}
+		summary.Count += len(resp.Chunk)
+		for _, evt := range resp.Chunk {
+			senders = append(senders, evt.Sender)
+		}
+		if resp.NextBatch == "" || len(resp.Chunk) == 0 {
+			break
+		}
+		req.From = resp.NextBatch
+	}
+	// The relations were fetched newest first, so reverse them to get the order of first participation.
+	senders = append(senders, root.Sender)
+	slices.Reverse(senders)
+	for _, sender := range senders {
+		if !slices.Contains(summary.Participants, sender) {
+			summary.Participants = append(summary.Participants, sender)
+		}
+	}
+	summary.CurrentUserParticipated = slices.Contains(summary.Participants, cli.UserID)
+	return summary, nil
+}
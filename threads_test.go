@@ -0,0 +1,112 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+	"github.com/iKonoTelecomunicaciones/go/event"
+	"github.com/iKonoTelecomunicaciones/go/id"
+	"github.com/iKonoTelecomunicaciones/go/mockserver"
+)
+
+func sendThreadReply(t *testing.T, ctx context.Context, cli *mautrix.Client, roomID id.RoomID, rootID id.EventID, body string) id.EventID {
+	t.Helper()
+	resp, err := cli.SendMessageEvent(ctx, roomID, event.EventMessage, &event.MessageEventContent{
+		MsgType:   event.MsgText,
+		Body:      body,
+		RelatesTo: (&event.RelatesTo{}).SetThread(rootID, rootID),
+	})
+	require.NoError(t, err)
+	return resp.EventID
+}
+
+func TestClient_GetThreads(t *testing.T) {
+	ctx := context.Background()
+	server := mockserver.Create(t)
+	alice := server.NewClient(t, ctx, "@alice:localhost", "ALICE")
+	bob := server.NewClient(t, ctx, "@bob:localhost", "BOB")
+
+	room, err := alice.CreateRoom(ctx, &mautrix.ReqCreateRoom{Invite: []id.UserID{bob.UserID}})
+	require.NoError(t, err)
+	_, err = bob.JoinRoom(ctx, room.RoomID.String(), nil)
+	require.NoError(t, err)
+
+	aliceRoot, err := alice.SendText(ctx, room.RoomID, "Alice's thread")
+	require.NoError(t, err)
+	bobRoot, err := bob.SendText(ctx, room.RoomID, "Bob's thread")
+	require.NoError(t, err)
+	sendThreadReply(t, ctx, bob, room.RoomID, bobRoot.EventID, "Only bob here")
+	sendThreadReply(t, ctx, bob, room.RoomID, aliceRoot.EventID, "Bob replying to alice")
+
+	resp, err := alice.GetThreads(ctx, room.RoomID, &mautrix.ReqGetThreads{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, resp.Chunk, 1)
+	assert.Equal(t, aliceRoot.EventID, resp.Chunk[0].ID)
+	require.NotEmpty(t, resp.NextBatch)
+	resp, err = alice.GetThreads(ctx, room.RoomID, &mautrix.ReqGetThreads{Limit: 1, From: resp.NextBatch})
+	require.NoError(t, err)
+	require.Len(t, resp.Chunk, 1)
+	assert.Equal(t, bobRoot.EventID, resp.Chunk[0].ID)
+	assert.Empty(t, resp.NextBatch)
+
+	resp, err = alice.GetThreads(ctx, room.RoomID, &mautrix.ReqGetThreads{Include: mautrix.ThreadIncludeParticipated})
+	require.NoError(t, err)
+	require.Len(t, resp.Chunk, 1)
+	assert.Equal(t, aliceRoot.EventID, resp.Chunk[0].ID)
+}
+
+func TestClient_GetThreadSummary(t *testing.T) {
+	ctx := context.Background()
+	server := mockserver.Create(t)
+	alice := server.NewClient(t, ctx, "@alice:localhost", "ALICE")
+	bob := server.NewClient(t, ctx, "@bob:localhost", "BOB")
+	carol := server.NewClient(t, ctx, "@carol:localhost", "CAROL")
+
+	room, err := alice.CreateRoom(ctx, &mautrix.ReqCreateRoom{Invite: []id.UserID{bob.UserID, carol.UserID}})
+	require.NoError(t, err)
+	_, err = bob.JoinRoom(ctx, room.RoomID.String(), nil)
+	require.NoError(t, err)
+	_, err = carol.JoinRoom(ctx, room.RoomID.String(), nil)
+	require.NoError(t, err)
+
+	root, err := bob.SendText(ctx, room.RoomID, "Thread root")
+	require.NoError(t, err)
+	// Send more replies than fit in a single page of relations.
+	var lastReply id.EventID
+	for i := range 150 {
+		sender := carol
+		if i%2 == 1 {
+			sender = bob
+		}
+		lastReply = sendThreadReply(t, ctx, sender, room.RoomID, root.EventID, fmt.Sprintf("Reply #%d", i))
+	}
+	_, err = alice.SendText(ctx, room.RoomID, "Not in the thread")
+	require.NoError(t, err)
+
+	summary, err := alice.GetThreadSummary(ctx, room.RoomID, root.EventID)
+	require.NoError(t, err)
+	assert.Equal(t, root.EventID, summary.Root.ID)
+	assert.Equal(t, 150, summary.Count)
+	require.NotNil(t, summary.LatestEvent)
+	assert.Equal(t, lastReply, summary.LatestEvent.ID)
+	assert.Equal(t, []id.UserID{bob.UserID, carol.UserID}, summary.Participants)
+	assert.False(t, summary.CurrentUserParticipated)
+
+	summaries, nextBatch, err := carol.GetThreadSummaries(ctx, room.RoomID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, nextBatch)
+	require.Len(t, summaries, 1)
+	assert.Equal(t, 150, summaries[0].Count)
+	assert.True(t, summaries[0].CurrentUserParticipated)
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+var ErrNoRefreshToken = errors.New("no refresh token available")
+
+// tokenRefreshMargin is how long before expiry access tokens are refreshed proactively.
+const tokenRefreshMargin = 30 * time.Second
+
+// TokenSet contains the credentials of a client after a login or token refresh.
+type TokenSet struct {
+	UserID       id.UserID
+	DeviceID     id.DeviceID
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// TokenStore is an interface for persisting access and refresh tokens.
+//
+// Refresh tokens are usually single-use, so the new tokens must be saved every time they're rotated,
+// otherwise the session will be lost after a restart.
+type TokenStore interface {
+	SaveTokens(ctx context.Context, tokens *TokenSet) error
+}
+
+// RefreshAccessToken uses the refresh token to get a new access token. If the client has OAuth info set,
+// the OAuth token endpoint is used, otherwise the legacy /refresh endpoint is used.
+//
+// Requests made with MakeRequest will automatically call this if the access token is about to expire
+// or if the server responds with M_UNKNOWN_TOKEN, so calling this manually is usually not necessary.
+func (cli *Client) RefreshAccessToken(ctx context.Context) error {
+	return cli.refreshAccessToken(ctx, "")
+}
+
+func (cli *Client) refreshAccessToken(ctx context.Context, expiredToken string) error {
+	cli.tokenRefreshLock.Lock()
+	defer cli.tokenRefreshLock.Unlock()
+	oldRefreshToken := cli.getRefreshToken()
+	if expiredToken != "" && cli.getAccessToken() != expiredToken {
+		// Another request already refreshed the token
+		return nil
+	} else if oldRefreshToken == "" {
+		return ErrNoRefreshToken
+	}
+	var accessToken, refreshToken string
+	var expiresIn time.Duration
+	if cli.OAuth != nil {
+		resp, err := cli.requestOAuthToken(ctx, "refresh_token", map[string]string{
+			"refresh_token": oldRefreshToken,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to refresh access token: %w", err)
+		}
+		accessToken, refreshToken = resp.AccessToken, resp.RefreshToken
+		expiresIn = time.Duration(resp.ExpiresIn) * time.Second
+	} else {
+		var resp *RespRefresh
+		_, _, err := cli.makeFullRequestWithResp(ctx, FullRequest{
+			Method:           http.MethodPost,
+			URL:              cli.BuildClientURL("v3", "refresh"),
+			RequestJSON:      &ReqRefresh{RefreshToken: oldRefreshToken},
+			ResponseJSON:     &resp,
+			SensitiveContent: true,
+			omitAccessToken:  true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to refresh access token: %w", err)
+		}
+		accessToken, refreshToken = resp.AccessToken, resp.RefreshToken
+		expiresIn = time.Duration(resp.ExpiresInMS) * time.Millisecond
+	}
+	if refreshToken == "" {
+		// The server didn't rotate the refresh token, so the old one is still valid
+		refreshToken = oldRefreshToken
+	}
+	err := cli.updateTokens(ctx, accessToken, refreshToken, expiresIn)
+	cli.cliOrContextLog(ctx).Debug().
+		Dur("expires_in", expiresIn).
+		Msg("Refreshed access token")
+	return err
+}
+
+func (cli *Client) getAccessToken() string {
+	cli.tokenLock.RLock()
+	defer cli.tokenLock.RUnlock()
+	return cli.AccessToken
+}
+
+func (cli *Client) getRefreshToken() string {
+	cli.tokenLock.RLock()
+	defer cli.tokenLock.RUnlock()
+	return cli.RefreshToken
+}
+
+func (cli *Client) setAccessToken(accessToken string) {
+	cli.tokenLock.Lock()
+	cli.AccessToken = accessToken
+	cli.tokenLock.Unlock()
+}
+
+func (cli *Client) shouldRefreshAccessToken() bool {
+	cli.tokenLock.RLock()
+	defer cli.tokenLock.RUnlock()
+	return cli.RefreshToken != "" &&
+		!cli.AccessTokenExpiresAt.IsZero() &&
+		time.Until(cli.AccessTokenExpiresAt) < tokenRefreshMargin
+}
+
+func (cli *Client) updateTokens(ctx context.Context, accessToken, refreshToken string, expiresIn time.Duration) error {
+	tokens := &TokenSet{
+		UserID:       cli.UserID,
+		DeviceID:     cli.DeviceID,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}
+	if expiresIn > 0 {
+		tokens.ExpiresAt = time.Now().Add(expiresIn)
+	}
+	cli.tokenLock.Lock()
+	cli.AccessToken = tokens.AccessToken
+	cli.RefreshToken = tokens.RefreshToken
+	cli.AccessTokenExpiresAt = tokens.ExpiresAt
+	cli.tokenLock.Unlock()
+	if cli.TokenStore == nil {
+		return nil
+	}
+	err := cli.TokenStore.SaveTokens(ctx, tokens)
+	if err != nil {
+		cli.cliOrContextLog(ctx).Err(err).Msg("Failed to save tokens")
+		return fmt.Errorf("failed to save tokens: %w", err)
+	}
+	return nil
+}
+
+func canRetryRequestBody(body io.Reader) bool {
+	if body == nil {
+		return true
+	}
+	seeker, ok := body.(io.Seeker)
+	if !ok {
+		return false
+	}
+	_, err := seeker.Seek(0, io.SeekStart)
+	return err == nil
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix
+
+import (
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+)
+
+// TracerName is the instrumentation scope name of the OpenTelemetry tracer used by the client.
+//
+// Spans are only recorded if the application has configured a global tracer provider using [otel.SetTracerProvider].
+const TracerName = "github.com/iKonoTelecomunicaciones/go"
+
+var tracer = otel.Tracer(TracerName)
+
+func (cli *Client) isHomeserverURL(u *url.URL) bool {
+	return cli.HomeserverURL != nil && u.Scheme == cli.HomeserverURL.Scheme && u.Host == cli.HomeserverURL.Host
+}
+
+// urlPathForSpan strips the query and fragment from a request URL,
+// as they may contain sensitive values that shouldn't be sent to the tracing backend.
+func urlPathForSpan(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Path
+}
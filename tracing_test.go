@@ -0,0 +1,55 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	mautrix "github.com/iKonoTelecomunicaciones/go"
+)
+
+func TestClient_TraceContextOnlySentToHomeserver(t *testing.T) {
+	prevPropagator := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	var homeserverHeader, externalHeader string
+	homeserver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		homeserverHeader = r.Header.Get("traceparent")
+		w.Write([]byte("{}"))
+	}))
+	t.Cleanup(homeserver.Close)
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		externalHeader = r.Header.Get("traceparent")
+		w.Write([]byte("{}"))
+	}))
+	t.Cleanup(external.Close)
+
+	cli, err := mautrix.NewClient(homeserver.URL, "", "")
+	require.NoError(t, err)
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	_, err = cli.MakeRequest(ctx, http.MethodGet, cli.BuildClientURL("v3", "account", "whoami"), nil, nil)
+	require.NoError(t, err)
+	_, err = cli.MakeRequest(ctx, http.MethodGet, external.URL+"/.well-known/openid-configuration", nil, nil)
+	require.NoError(t, err)
+	assert.Contains(t, homeserverHeader, "0102030405060708090a0b0c0d0e0f10")
+	assert.Empty(t, externalHeader)
+}
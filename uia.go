@@ -0,0 +1,214 @@
+// Copyright (c) 2026 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package mautrix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/iKonoTelecomunicaciones/go/id"
+)
+
+var (
+	ErrNoSupportedUIAFlow = errors.New("no supported user-interactive auth flow")
+	ErrTooManyUIASteps    = errors.New("too many user-interactive auth steps")
+)
+
+// DefaultUIAMaxSteps is the default limit for the number of auth stages submitted by [UIAHelper.Do].
+const DefaultUIAMaxSteps = 10
+
+// UIAuthPassword is the auth data for the m.login.password stage.
+type UIAuthPassword struct {
+	BaseAuthData
+	Identifier UserIdentifier `json:"identifier"`
+	Password   string         `json:"password"`
+}
+
+// ThreePIDCredentials are the credentials of a third-party identifier validation session.
+type ThreePIDCredentials struct {
+	ClientSecret  string `json:"client_secret"`
+	SID           string `json:"sid"`
+	IDServer      string `json:"id_server,omitempty"`
+	IDAccessToken string `json:"id_access_token,omitempty"`
+}
+
+// UIAuthThreePID is the auth data for the m.login.email.identity and m.login.msisdn stages.
+type UIAuthThreePID struct {
+	BaseAuthData
+	ThreePIDCreds ThreePIDCredentials `json:"threepid_creds"`
+}
+
+// UIAuthReCAPTCHA is the auth data for the m.login.recaptcha stage.
+type UIAuthReCAPTCHA struct {
+	BaseAuthData
+	Response string `json:"response"`
+}
+
+// UIAStageHandler creates the auth data for a single stage of a user-interactive auth flow.
+// The returned value must include the session ID from the UIA response.
+type UIAStageHandler func(ctx context.Context, uia *RespUserInteractive, stage AuthType) (any, error)
+
+// UIAPassword returns a stage handler for m.login.password that authenticates as the given user.
+func UIAPassword(userID id.UserID, password string) UIAStageHandler {
+	return func(ctx context.Context, uia *RespUserInteractive, stage AuthType) (any, error) {
+		return &UIAuthPassword{
+			BaseAuthData: BaseAuthData{Type: stage, Session: uia.Session},
+			Identifier:   UserIdentifier{Type: IdentifierTypeUser, User: userID.String()},
+			Password:     password,
+		}, nil
+	}
+}
+
+// UIADummy is a stage handler for m.login.dummy.
+func UIADummy(ctx context.Context, uia *RespUserInteractive, stage AuthType) (any, error) {
+	return &BaseAuthData{Type: stage, Session: uia.Session}, nil
+}
+
+// UIAThreePID returns a stage handler for m.login.email.identity or m.login.msisdn. The callback must return
+// the credentials of an already validated session, e.g. by waiting for the user to click the link in the email.
+func UIAThreePID(getCredentials func(ctx context.Context) (*ThreePIDCredentials, error)) UIAStageHandler {
+	return func(ctx context.Context, uia *RespUserInteractive, stage AuthType) (any, error) {
+		creds, err := getCredentials(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &UIAuthThreePID{
+			BaseAuthData:  BaseAuthData{Type: stage, Session: uia.Session},
+			ThreePIDCreds: *creds,
+		}, nil
+	}
+}
+
+// UIAReCAPTCHA returns a stage handler for m.login.recaptcha.
+// The callback receives the public key from the UIA params and must return the captcha response.
+func UIAReCAPTCHA(solve func(ctx context.Context, publicKey string) (string, error)) UIAStageHandler {
+	return func(ctx context.Context, uia *RespUserInteractive, stage AuthType) (any, error) {
+		params, _ := uia.Params[stage].(map[string]any)
+		publicKey, _ := params["public_key"].(string)
+		response, err := solve(ctx, publicKey)
+		if err != nil {
+			return nil, err
+		}
+		return &UIAuthReCAPTCHA{
+			BaseAuthData: BaseAuthData{Type: stage, Session: uia.Session},
+			Response:     response,
+		}, nil
+	}
+}
+
+// UIAHelper drives user-interactive authentication flows using a set of stage handlers.
+//
+// A flow is only chosen if there's a handler for every stage that hasn't been completed yet.
+// If multiple flows are possible, the first one in the order the server listed them is used.
+type UIAHelper struct {
+	Stages map[AuthType]UIAStageHandler
+	// The maximum number of stages to submit before giving up. Defaults to [DefaultUIAMaxSteps].
+	MaxSteps int
+}
+
+// NewUIAHelper creates a UIA helper with the given stage handlers.
+func NewUIAHelper(stages map[AuthType]UIAStageHandler) *UIAHelper {
+	return &UIAHelper{Stages: stages}
+}
+
+// nextStage finds the next stage to complete in the first flow that can be completed with the available handlers.
+func (uh *UIAHelper) nextStage(uia *RespUserInteractive) (AuthType, error) {
+Flows:
+	for _, flow := range uia.Flows {
+		var next AuthType
+		for i, stage := range flow.Stages {
+			if i < len(uia.Completed) {
+				if uia.Completed[i] != string(stage) {
+					continue Flows
+				}
+			} else if _, ok := uh.Stages[stage]; !ok {
+				continue Flows
+			} else if next == "" {
+				next = stage
+			}
+		}
+		if next != "" {
+			return next, nil
+		}
+	}
+	return "", fmt.Errorf("%w (server offered %v)", ErrNoSupportedUIAFlow, uia.Flows)
+}
+
+// Do calls the given function until it succeeds or fails with an error that isn't a UIA response.
+//
+// The function should make a request with the given auth data, which is nil on the first call,
+// and return the response body along with the error, like [Client.MakeFullRequest] does.
+// If the helper is nil, the function is only called once.
+func (uh *UIAHelper) Do(ctx context.Context, fn func(ctx context.Context, auth any) ([]byte, error)) error {
+	var auth any
+	for step := 0; ; step++ {
+		body, err := fn(ctx, auth)
+		if err == nil || uh == nil {
+			return err
+		}
+		uia := parseUIAResponse(body, err)
+		if uia == nil {
+			return err
+		} else if auth != nil && uia.ErrCode != "" {
+			// The server rejected the auth data for the previous stage
+			return err
+		}
+		maxSteps := uh.MaxSteps
+		if maxSteps <= 0 {
+			maxSteps = DefaultUIAMaxSteps
+		}
+		if step >= maxSteps {
+			return ErrTooManyUIASteps
+		}
+		stage, err := uh.nextStage(uia)
+		if err != nil {
+			return err
+		}
+		auth, err = uh.Stages[stage](ctx, uia, stage)
+		if err != nil {
+			return fmt.Errorf("failed to complete %s stage: %w", stage, err)
+		}
+	}
+}
+
+// parseUIAResponse returns the UIA response in the given error response body, or nil if it isn't one.
+func parseUIAResponse(body []byte, err error) *RespUserInteractive {
+	var httpErr HTTPError
+	if !errors.As(err, &httpErr) || !httpErr.IsStatus(http.StatusUnauthorized) {
+		return nil
+	}
+	var uia RespUserInteractive
+	if json.Unmarshal(body, &uia) != nil || len(uia.Flows) == 0 {
+		return nil
+	}
+	return &uia
+}
+
+// makeUIARequest makes a JSON request that requires user-interactive auth.
+// The setAuth function is called with the auth data to include in the request body before each attempt.
+func (cli *Client) makeUIARequest(
+	ctx context.Context,
+	uia *UIAHelper,
+	method, url string,
+	reqBody any,
+	setAuth func(auth any),
+	resp any,
+) error {
+	return uia.Do(ctx, func(ctx context.Context, auth any) ([]byte, error) {
+		setAuth(auth)
+		return cli.MakeFullRequest(ctx, FullRequest{
+			Method:           method,
+			URL:              url,
+			RequestJSON:      reqBody,
+			ResponseJSON:     resp,
+			SensitiveContent: true,
+		})
+	})
+}